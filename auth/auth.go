@@ -0,0 +1,115 @@
+// This file contains the authenticated principal and the authenticator checking the request credentials.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const (
+	MethodApiKey = "api_key"
+	MethodJwt    = "jwt"
+
+	HeaderApiKey = "X-API-Key"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject is the api key id or the jwt subject
+	Subject        string
+	OrganisationId string
+	Role           rbac.Role // empty when the subject is not a member of the organisation
+	Method         string
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by ctx
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
+
+// HashApiKey returns the SHA-256 hex digest stored in place of the plain api key
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type Authenticator struct {
+	Repository repository.RepositoryInterface
+	Jwt        *JwtVerifier
+}
+
+type NewAuthenticatorOptions struct {
+	Repository repository.RepositoryInterface
+	// Jwt is optional, bearer tokens are rejected when not set
+	Jwt *JwtVerifier
+}
+
+func NewAuthenticator(opts NewAuthenticatorOptions) *Authenticator {
+	return &Authenticator{
+		Repository: opts.Repository,
+		Jwt:        opts.Jwt,
+	}
+}
+
+// Authenticate resolve the principal from the api key header or the bearer token
+func (a *Authenticator) Authenticate(ctx context.Context, apiKey string, authorization string) (Principal, error) {
+	if apiKey != "" {
+		key, err := a.Repository.GetApiKeyByHash(ctx, repository.GetApiKeyByHashInput{
+			KeyHash: HashApiKey(apiKey),
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return Principal{}, ErrInvalidCredentials
+			}
+			return Principal{}, err
+		}
+		return Principal{Subject: key.Id, OrganisationId: key.OrganisationId, Role: rbac.Role(key.Role), Method: MethodApiKey}, nil
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+	if a.Jwt == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	claims, err := a.Jwt.Verify(token)
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	principal := Principal{Subject: claims.Subject, OrganisationId: claims.OrganisationId, Method: MethodJwt}
+
+	// the role of a jwt subject is managed in the memberships of the organisation
+	membership, err := a.Repository.GetMembership(ctx, repository.GetMembershipInput{
+		OrganisationId: claims.OrganisationId,
+		Subject:        claims.Subject,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return principal, nil
+		}
+		return Principal{}, err
+	}
+	principal.Role = rbac.Role(membership.Role)
+	return principal, nil
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func writeJwks(t *testing.T, kid string, key *rsa.PublicKey) string {
+	jwks := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func signToken(t *testing.T, kid string, key *rsa.PrivateKey, claims Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifier, err := NewJwtVerifier(NewJwtVerifierOptions{
+		JwksFile: writeJwks(t, "key-1", &privateKey.PublicKey),
+		Issuer:   "https://issuer.example",
+	})
+	require.NoError(t, err)
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	authenticator := NewAuthenticator(NewAuthenticatorOptions{
+		Repository: mockRepository,
+		Jwt:        verifier,
+	})
+
+	validClaims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    "https://issuer.example",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		OrganisationId: "org-1",
+	}
+	expiredClaims := validClaims
+	expiredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noOrgClaims := validClaims
+	noOrgClaims.OrganisationId = ""
+
+	testCases := []struct {
+		name              string
+		apiKey            string
+		authorization     string
+		setupMocks        func()
+		expectedPrincipal Principal
+		expectedErr       error
+	}{
+		{
+			name:        "MISSING_CREDENTIALS",
+			expectedErr: ErrMissingCredentials,
+		},
+		{
+			name:   "API_KEY_OK",
+			apiKey: "secret",
+			setupMocks: func() {
+				mockRepository.EXPECT().GetApiKeyByHash(gomock.Any(), repository.GetApiKeyByHashInput{
+					KeyHash: HashApiKey("secret"),
+				}).Return(repository.ApiKey{Id: "key-1", OrganisationId: "org-1", Role: "surveyor"}, nil)
+			},
+			expectedPrincipal: Principal{Subject: "key-1", OrganisationId: "org-1", Role: rbac.RoleSurveyor, Method: MethodApiKey},
+		},
+		{
+			name:   "API_KEY_UNKNOWN",
+			apiKey: "unknown",
+			setupMocks: func() {
+				mockRepository.EXPECT().GetApiKeyByHash(gomock.Any(), gomock.Any()).Return(repository.ApiKey{}, sql.ErrNoRows)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:          "JWT_OK",
+			authorization: "Bearer " + signToken(t, "key-1", privateKey, validClaims),
+			setupMocks: func() {
+				mockRepository.EXPECT().GetMembership(gomock.Any(), repository.GetMembershipInput{
+					OrganisationId: "org-1",
+					Subject:        "user-1",
+				}).Return(repository.Membership{OrganisationId: "org-1", Subject: "user-1", Role: "operator"}, nil)
+			},
+			expectedPrincipal: Principal{Subject: "user-1", OrganisationId: "org-1", Role: rbac.RoleOperator, Method: MethodJwt},
+		},
+		{
+			name:          "JWT_NOT_A_MEMBER",
+			authorization: "Bearer " + signToken(t, "key-1", privateKey, validClaims),
+			setupMocks: func() {
+				mockRepository.EXPECT().GetMembership(gomock.Any(), gomock.Any()).Return(repository.Membership{}, sql.ErrNoRows)
+			},
+			expectedPrincipal: Principal{Subject: "user-1", OrganisationId: "org-1", Method: MethodJwt},
+		},
+		{
+			name:          "JWT_EXPIRED",
+			authorization: "Bearer " + signToken(t, "key-1", privateKey, expiredClaims),
+			expectedErr:   ErrInvalidCredentials,
+		},
+		{
+			name:          "JWT_WRONG_SIGNATURE",
+			authorization: "Bearer " + signToken(t, "key-1", otherKey, validClaims),
+			expectedErr:   ErrInvalidCredentials,
+		},
+		{
+			name:          "JWT_UNKNOWN_KID",
+			authorization: "Bearer " + signToken(t, "key-2", privateKey, validClaims),
+			expectedErr:   ErrInvalidCredentials,
+		},
+		{
+			name:          "JWT_WITHOUT_ORGANISATION",
+			authorization: "Bearer " + signToken(t, "key-1", privateKey, noOrgClaims),
+			expectedErr:   ErrInvalidCredentials,
+		},
+		{
+			name:          "BASIC_AUTH",
+			authorization: "Basic dXNlcjpwYXNz",
+			expectedErr:   ErrMissingCredentials,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setupMocks != nil {
+				tc.setupMocks()
+			}
+			principal, err := authenticator.Authenticate(context.Background(), tc.apiKey, tc.authorization)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPrincipal, principal)
+		})
+	}
+}
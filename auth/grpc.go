@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor reject the call with Unauthenticated unless it carries a valid api key or bearer
+// token in its metadata, the principal is then available with PrincipalFromContext
+func UnaryServerInterceptor(authenticator *Authenticator, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateGrpc(ctx, authenticator, logger)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for the streaming calls
+func StreamServerInterceptor(authenticator *Authenticator, logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGrpc(stream.Context(), authenticator, logger)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func authenticateGrpc(ctx context.Context, authenticator *Authenticator, logger *slog.Logger) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := authenticator.Authenticate(ctx,
+		firstValue(md, strings.ToLower(HeaderApiKey)),
+		firstValue(md, "authorization"),
+	)
+	if err != nil {
+		if errors.Is(err, ErrMissingCredentials) || errors.Is(err, ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		logger.ErrorContext(ctx, "authentication failed", slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return WithPrincipal(ctx, principal), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// principalStream carries the context with the principal to the stream handler
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware reject the request with 401 unless it carries a valid api key or bearer token,
+// the principal is then available with PrincipalFromContext
+func Middleware(authenticator *Authenticator, logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			principal, err := authenticator.Authenticate(req.Context(),
+				req.Header.Get(HeaderApiKey),
+				req.Header.Get(echo.HeaderAuthorization),
+			)
+			if err != nil {
+				if errors.Is(err, ErrMissingCredentials) || errors.Is(err, ErrInvalidCredentials) {
+					c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="drone-patrol"`)
+					return c.JSON(http.StatusUnauthorized, generated.ErrorResponse{Message: err.Error()})
+				}
+				logger.ErrorContext(req.Context(), "authentication failed", slog.String("error", err.Error()))
+				return c.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: "internal server error"})
+			}
+			c.SetRequest(req.WithContext(WithPrincipal(req.Context(), principal)))
+			return next(c)
+		}
+	}
+}
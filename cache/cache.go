@@ -0,0 +1,92 @@
+// This file contains the cache of the computed estate responses (stats, drone plans).
+//
+// Entries are keyed by estate id and estate version. The version is bumped by the database on every
+// tree change of the estate, so a changed estate is looked up under a new key and its stale entries
+// are never read again, they are simply evicted by the backend.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Backend stores the encoded entries, the in process LRU is enough for a single instance while
+// a shared backend (e.g. redis) is needed when the service is scaled horizontally
+type Backend interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+// LRU is an in process Backend keeping the most recently used entries
+type LRU struct {
+	entries *lru.Cache[string, []byte]
+}
+
+func NewLRU(size int) (*LRU, error) {
+	entries, err := lru.New[string, []byte](size)
+	if err != nil {
+		return nil, err
+	}
+	return &LRU{entries: entries}, nil
+}
+
+func (l *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := l.entries.Get(key)
+	return value, ok, nil
+}
+
+func (l *LRU) Set(_ context.Context, key string, value []byte) error {
+	l.entries.Add(key, value)
+	return nil
+}
+
+type Cache struct {
+	backend Backend
+}
+
+type NewCacheOptions struct {
+	Backend Backend
+}
+
+func New(opts NewCacheOptions) *Cache {
+	return &Cache{backend: opts.Backend}
+}
+
+// Key returns the key of an estate response, params separate the variants of the same response
+func Key(kind, estateId string, version int64, params ...interface{}) string {
+	key := fmt.Sprintf("%s:%s:%d", kind, estateId, version)
+	for _, param := range params {
+		key += fmt.Sprintf(":%v", param)
+	}
+	return key
+}
+
+// Get decode the entry of key into v and returns true on a hit. A nil cache always misses.
+func (c *Cache) Get(ctx context.Context, key string, v interface{}) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	value, ok, err := c.backend.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Set store v under key. A nil cache does nothing.
+func (c *Cache) Set(ctx context.Context, key string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.backend.Set(ctx, key, value)
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	Distance int `json:"distance"`
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewLRU(2)
+	require.NoError(t, err)
+	c := New(NewCacheOptions{Backend: backend})
+
+	var got entry
+	ok, err := c.Get(ctx, Key("drone-plan", "estate", 1), &got)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Set(ctx, Key("drone-plan", "estate", 1), entry{Distance: 112}))
+	ok, _ = c.Get(ctx, Key("drone-plan", "estate", 1), &got)
+	assert.True(t, ok)
+	assert.Equal(t, entry{Distance: 112}, got)
+
+	// a new estate version or other params is an other entry
+	ok, _ = c.Get(ctx, Key("drone-plan", "estate", 2), &got)
+	assert.False(t, ok)
+	ok, _ = c.Get(ctx, Key("drone-plan", "estate", 1, 40), &got)
+	assert.False(t, ok)
+
+	// the least recently used entry is evicted
+	_ = c.Set(ctx, Key("drone-plan", "estate", 2), entry{Distance: 1})
+	_ = c.Set(ctx, Key("drone-plan", "estate", 3), entry{Distance: 2})
+	ok, _ = c.Get(ctx, Key("drone-plan", "estate", 1), &got)
+	assert.False(t, ok)
+}
+
+func TestCache_Nil(t *testing.T) {
+	var c *Cache
+	var got entry
+	ok, err := c.Get(context.Background(), "key", &got)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, c.Set(context.Background(), "key", entry{}))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "stats:estate:3", Key("stats", "estate", 3))
+	assert.Equal(t, "drone-plan:estate:3:40", Key("drone-plan", "estate", 3, 40))
+}
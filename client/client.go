@@ -0,0 +1,184 @@
+// Package client is the Go client of the drone patrol API.
+//
+// It is built on the client generated from api.yml and adds the authentication, a timeout, retries of the
+// rate limited and failed requests, the Error type for the error responses and iterators over the lists.
+// The operations without a method here are available on the generated client returned by Raw.
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultMinBackoff = 200 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+)
+
+type Client struct {
+	api *generated.ClientWithResponses
+}
+
+type NewClientOptions struct {
+	// BaseURL of the API, e.g. http://localhost:8080
+	BaseURL string
+	// ApiKey or Token authenticate the requests, the api key is used when both are set
+	ApiKey string
+	Token  string
+	// HTTPClient sends the requests, default to a client with Timeout
+	HTTPClient *http.Client
+	// Timeout of every attempt of a request when HTTPClient is not set, default to 30 seconds. The context
+	// given to the methods bounds the request with its retries.
+	Timeout time.Duration
+	// MaxRetries of a request, default to 3, negative to never retry
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the wait before a retry, default to 200ms and 10s. The wait of the
+	// Retry-After header is used when the API sends one, a request asked to wait more than MaxBackoff is
+	// not retried.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// UserAgent of the requests, default to the one of net/http
+	UserAgent string
+}
+
+func New(opts NewClientOptions) (*Client, error) {
+	if opts.BaseURL == "" {
+		return nil, errors.New("base url is required")
+	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		httpClient = &http.Client{Timeout: timeout}
+	}
+	doer := &retryDoer{
+		doer:       httpClient,
+		maxRetries: opts.MaxRetries,
+		minBackoff: opts.MinBackoff,
+		maxBackoff: opts.MaxBackoff,
+	}
+	if doer.maxRetries == 0 {
+		doer.maxRetries = defaultMaxRetries
+	}
+	if doer.minBackoff <= 0 {
+		doer.minBackoff = defaultMinBackoff
+	}
+	if doer.maxBackoff <= 0 {
+		doer.maxBackoff = defaultMaxBackoff
+	}
+
+	api, err := generated.NewClientWithResponses(opts.BaseURL,
+		generated.WithHTTPClient(doer),
+		generated.WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+			if opts.ApiKey != "" {
+				req.Header.Set("X-API-Key", opts.ApiKey)
+			} else if opts.Token != "" {
+				req.Header.Set("Authorization", "Bearer "+opts.Token)
+			}
+			if opts.UserAgent != "" {
+				req.Header.Set("User-Agent", opts.UserAgent)
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{api: api}, nil
+}
+
+// Raw returns the generated client, with the authentication and the retries of the client. Its responses are
+// not checked, responseError is not applied.
+func (c *Client) Raw() *generated.ClientWithResponses {
+	return c.api
+}
+
+// retryDoer send a request again when it is rate limited, or when an idempotent request fails on the network
+// or on a gateway error. A rate limited request was not handled by the API so it is sent again whatever
+// its method. A request with a body that can not be read again, as a streamed body, is sent once.
+type retryDoer struct {
+	doer       generated.HttpRequestDoer
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		res, err := d.doer.Do(req)
+		if attempt >= d.maxRetries || !retryable(req, res, err) || (req.Body != nil && req.GetBody == nil) {
+			return res, err
+		}
+
+		wait := d.backoff(attempt)
+		if res != nil {
+			if after := retryAfter(res); after > d.maxBackoff {
+				// the caller gets the Error with the wait instead of being blocked
+				return res, err
+			} else if after > 0 {
+				wait = after
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a random wait between the min backoff and the exponential backoff of the attempt, so the
+// clients rate limited together do not retry together
+func (d *retryDoer) backoff(attempt int) time.Duration {
+	max := d.minBackoff << attempt
+	if max > d.maxBackoff || max <= 0 {
+		max = d.maxBackoff
+	}
+	if max <= d.minBackoff {
+		return d.minBackoff
+	}
+	return d.minBackoff + time.Duration(rand.Int63n(int64(max-d.minBackoff)))
+}
+
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if res != nil && res.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts NewClientOptions) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	opts.BaseURL = srv.URL
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 5 * time.Millisecond
+	}
+	c, err := New(opts)
+	require.NoError(t, err)
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(NewClientOptions{})
+	assert.EqualError(t, err, "base url is required")
+}
+
+func TestClient_Authentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       NewClientOptions
+		wantApiKey string
+		wantAuth   string
+	}{
+		{
+			name:       "api key",
+			opts:       NewClientOptions{ApiKey: "key", Token: "token"},
+			wantApiKey: "key",
+		},
+		{
+			name:     "token",
+			opts:     NewClientOptions{Token: "token"},
+			wantAuth: "Bearer token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tt.wantApiKey, r.Header.Get("X-API-Key"))
+				assert.Equal(t, tt.wantAuth, r.Header.Get("Authorization"))
+				writeJSON(w, http.StatusOK, `{"count":0,"max":0,"median":0,"min":0}`)
+			}, tt.opts)
+			_, err := c.Stats(context.Background(), "e-1")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"message":"estate is not found"}`)
+	}, NewClientOptions{})
+
+	_, err := c.Stats(context.Background(), "e-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrBadRequest)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "estate is not found", apiErr.Message)
+	assert.EqualError(t, err, "404 Not Found: estate is not found")
+}
+
+func createEstate(c *Client) error {
+	_, err := c.CreateEstate(context.Background(), CreateEstateRequest{Length: 1, Width: 1})
+	return err
+}
+
+func getStats(c *Client) error {
+	_, err := c.Stats(context.Background(), "e-1")
+	return err
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       func(c *Client) error
+		status       int
+		retryAfter   string
+		opts         NewClientOptions
+		wantAttempts int32
+		wantErr      error
+	}{
+		{
+			name:         "rate limited post is retried",
+			method:       createEstate,
+			status:       http.StatusTooManyRequests,
+			retryAfter:   "0",
+			wantAttempts: 4,
+			wantErr:      ErrRateLimited,
+		},
+		{
+			name:         "unavailable get is retried",
+			method:       getStats,
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 4,
+			wantErr:      ErrUnavailable,
+		},
+		{
+			name:         "unavailable post is not retried",
+			method:       createEstate,
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 1,
+			wantErr:      ErrUnavailable,
+		},
+		{
+			name:         "retry after above the max backoff is not waited",
+			method:       getStats,
+			status:       http.StatusTooManyRequests,
+			retryAfter:   "60",
+			wantAttempts: 1,
+			wantErr:      ErrRateLimited,
+		},
+		{
+			name:         "retries disabled",
+			method:       getStats,
+			status:       http.StatusServiceUnavailable,
+			opts:         NewClientOptions{MaxRetries: -1},
+			wantAttempts: 1,
+			wantErr:      ErrUnavailable,
+		},
+		{
+			name:         "bad request is not retried",
+			method:       getStats,
+			status:       http.StatusBadRequest,
+			wantAttempts: 1,
+			wantErr:      ErrBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				writeJSON(w, tt.status, `{"message":"try again"}`)
+			}, tt.opts)
+
+			err := tt.method(c)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantAttempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestClient_RetrySendBodyAgain(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body [64]byte
+		n, _ := r.Body.Read(body[:])
+		assert.JSONEq(t, `{"height":10,"x":1,"y":2}`, string(body[:n]))
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			writeJSON(w, http.StatusTooManyRequests, `{"message":"too many requests"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"id":"t-1"}`)
+	}, NewClientOptions{})
+
+	id, err := c.CreateTree(context.Background(), "e-1", CreateTreeRequest{Height: 10, X: 1, Y: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "t-1", id)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestClient_RetryStreamedBodyOnce(t *testing.T) {
+	var attempts int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		writeJSON(w, http.StatusTooManyRequests, `{"message":"too many requests"}`)
+	}, NewClientOptions{})
+
+	// a reader of another type than the readers of net/http, the request has no GetBody
+	body := io.MultiReader(strings.NewReader(`{"length":10,"width":5}`))
+	res, err := c.Raw().PostEstateWithBodyWithResponse(context.Background(), "application/json", body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestIterator(t *testing.T) {
+	pages := map[string][]int{"": {1, 2}, "a": {}, "b": {3}}
+	next := map[string]string{"": "a", "a": "b"}
+	fetchErr := errors.New("fetch failed")
+
+	t.Run("every page", func(t *testing.T) {
+		it := newIterator(func(_ context.Context, cursor string) ([]int, string, error) {
+			return pages[cursor], next[cursor], nil
+		})
+		items, err := it.All(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, items)
+		assert.False(t, it.Next(context.Background()))
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		it := newIterator(func(_ context.Context, cursor string) ([]int, string, error) {
+			if cursor == "b" {
+				return nil, "", fetchErr
+			}
+			return pages[cursor], next[cursor], nil
+		})
+		items, err := it.All(context.Background())
+		assert.ErrorIs(t, err, fetchErr)
+		assert.Equal(t, []int{1, 2}, items)
+	})
+}
+
+func TestClient_DronePlan(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/estate/e-1/drone-plan", r.URL.Path)
+		assert.Equal(t, "100", r.URL.Query().Get("max-distance"))
+		writeJSON(w, http.StatusOK, `{"distance":100,"rest":{"x":4,"y":2}}`)
+	}, NewClientOptions{})
+
+	plan, err := c.DronePlan(context.Background(), "e-1", DronePlanOptions{MaxDistance: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, DronePlan{Distance: 100, Rest: Plot{X: 4, Y: 2}}, plan)
+}
+
+func TestClient_DeleteWebhook(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		if r.URL.Path == "/webhooks/w-2" {
+			writeJSON(w, http.StatusNotFound, `{"message":"webhook is not found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}, NewClientOptions{})
+
+	assert.NoError(t, c.DeleteWebhook(context.Background(), "w-1"))
+	assert.ErrorIs(t, c.DeleteWebhook(context.Background(), "w-2"), ErrNotFound)
+}
+
+func TestClient_Events(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/estate/e-1/events", r.URL.Path)
+		assert.Equal(t, "e-0", r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(": keep-alive\n\nid: e-1\nevent: stats\ndata: {\"count\":1,\ndata: \"max\":3}\n\n" +
+			"id: e-2\nevent: tree.created\ndata: {\"height\":3}\n\n"))
+	}, NewClientOptions{})
+
+	var received []Event
+	last, err := c.Events(context.Background(), "e-1", "e-0", func(event Event) error {
+		received = append(received, event)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "e-2", last)
+	assert.Equal(t, []Event{
+		{Id: "e-1", Type: "stats", Data: []byte("{\"count\":1,\n\"max\":3}")},
+		{Id: "e-2", Type: "tree.created", Data: []byte(`{"height":3}`)},
+	}, received)
+
+	// the handler stops the stream, the last event handled is the one before
+	stop := errors.New("stop")
+	last, err = c.Events(context.Background(), "e-1", "e-0", func(event Event) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, "e-0", last)
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The errors matched by the Error of a response with errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnavailable  = errors.New("unavailable")
+)
+
+// Error is returned for an error response of the API, Message is the message of its ErrorResponse
+type Error struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the wait asked by a rate limited or unavailable response, zero otherwise
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is match the Err sentinel of the status code, errors.Is(err, client.ErrNotFound)
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrBadRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return target == ErrUnavailable
+	}
+	return false
+}
+
+// responseError returns the Error of an unexpected response
+func responseError(res *http.Response, body []byte) error {
+	if res == nil {
+		return errors.New("no response")
+	}
+	e := &Error{StatusCode: res.StatusCode, RetryAfter: retryAfter(res)}
+	var message struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &message); err == nil {
+		e.Message = message.Message
+	}
+	return e
+}
+
+// retryAfter returns the wait of the Retry-After header in seconds, zero when there is none
+func retryAfter(res *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+type (
+	CreateEstateRequest = generated.CreateEstateRequest
+	CreateTreeRequest   = generated.CreateTreeRequest
+	Estate              = generated.EstateResponse
+	Tree                = generated.TreeResponse
+	Stats               = generated.GetEstateStatsResponse
+	PlanJob             = generated.PlanJobResponse
+)
+
+type Plot struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type DronePlan struct {
+	// Distance flown in meters, capped to the max distance when one is given
+	Distance int `json:"distance"`
+	// Rest is the plot where the drone lands
+	Rest Plot `json:"rest"`
+}
+
+type DronePlanOptions struct {
+	// MaxDistance the drone can fly in meters, zero for no limit
+	MaxDistance int
+}
+
+func (o DronePlanOptions) maxDistance() *int {
+	if o.MaxDistance == 0 {
+		return nil
+	}
+	return &o.MaxDistance
+}
+
+// CreateEstate create an estate in the organisation of the caller and returns its id
+func (c *Client) CreateEstate(ctx context.Context, request CreateEstateRequest) (string, error) {
+	res, err := c.api.PostEstateWithResponse(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if res.JSON200 == nil {
+		return "", responseError(res.HTTPResponse, res.Body)
+	}
+	return res.JSON200.Id, nil
+}
+
+// Estates returns an iterator over the estates of the organisation of the caller, oldest first
+func (c *Client) Estates() *Iterator[Estate] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Estate, string, error) {
+		res, err := c.api.GetEstateWithResponse(ctx, &generated.GetEstateParams{Cursor: pageCursor(cursor)})
+		if err != nil {
+			return nil, "", err
+		}
+		if res.JSON200 == nil {
+			return nil, "", responseError(res.HTTPResponse, res.Body)
+		}
+		return res.JSON200.Estates, nextCursor(res.JSON200.NextCursor), nil
+	})
+}
+
+// Trees returns an iterator over the trees of the estate, ordered by y then x
+func (c *Client) Trees(estateId string) *Iterator[Tree] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Tree, string, error) {
+		res, err := c.api.GetEstateIdTreeWithResponse(ctx, estateId, &generated.GetEstateIdTreeParams{Cursor: pageCursor(cursor)})
+		if err != nil {
+			return nil, "", err
+		}
+		if res.JSON200 == nil {
+			return nil, "", responseError(res.HTTPResponse, res.Body)
+		}
+		return res.JSON200.Trees, nextCursor(res.JSON200.NextCursor), nil
+	})
+}
+
+// CreateTree add a tree to a free plot of the estate and returns its id
+func (c *Client) CreateTree(ctx context.Context, estateId string, request CreateTreeRequest) (string, error) {
+	res, err := c.api.PostEstateIdTreeWithResponse(ctx, estateId, request)
+	if err != nil {
+		return "", err
+	}
+	if res.JSON200 == nil {
+		return "", responseError(res.HTTPResponse, res.Body)
+	}
+	return res.JSON200.Id, nil
+}
+
+// Stats returns the count and the min, max and median height of the trees of the estate
+func (c *Client) Stats(ctx context.Context, estateId string) (Stats, error) {
+	res, err := c.api.GetEstateIdStatsWithResponse(ctx, estateId)
+	if err != nil {
+		return Stats{}, err
+	}
+	if res.JSON200 == nil {
+		return Stats{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+// DronePlan returns the drone plan of the estate
+func (c *Client) DronePlan(ctx context.Context, estateId string, opts DronePlanOptions) (DronePlan, error) {
+	res, err := c.api.GetEstateIdDronePlanWithResponse(ctx, estateId, &generated.GetEstateIdDronePlanParams{
+		MaxDistance: opts.maxDistance(),
+	})
+	if err != nil {
+		return DronePlan{}, err
+	}
+	if res.JSON200 == nil {
+		return DronePlan{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return dronePlan(*res.JSON200), nil
+}
+
+// CreatePlanJob queue the computation of the drone plan of a large estate, the job is then polled with PlanJob
+// or WaitPlanJob
+func (c *Client) CreatePlanJob(ctx context.Context, estateId string, opts DronePlanOptions) (PlanJob, error) {
+	res, err := c.api.PostEstateIdDronePlanJobsWithResponse(ctx, estateId, generated.CreatePlanJobRequest{
+		MaxDistance: opts.maxDistance(),
+	})
+	if err != nil {
+		return PlanJob{}, err
+	}
+	if res.JSON202 == nil {
+		return PlanJob{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON202, nil
+}
+
+// PlanJob returns the drone plan job, its Result is set once it succeeded
+func (c *Client) PlanJob(ctx context.Context, estateId, jobId string) (PlanJob, error) {
+	res, err := c.api.GetEstateIdDronePlanJobsJobIdWithResponse(ctx, estateId, jobId)
+	if err != nil {
+		return PlanJob{}, err
+	}
+	if res.JSON200 == nil {
+		return PlanJob{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+// CancelPlanJob cancel a pending or running drone plan job, a finished job is answered with ErrConflict
+func (c *Client) CancelPlanJob(ctx context.Context, estateId, jobId string) (PlanJob, error) {
+	res, err := c.api.DeleteEstateIdDronePlanJobsJobIdWithResponse(ctx, estateId, jobId)
+	if err != nil {
+		return PlanJob{}, err
+	}
+	if res.JSON200 == nil {
+		return PlanJob{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
+
+// WaitPlanJob poll the drone plan job every interval until it succeeded, failed or was cancelled
+func (c *Client) WaitPlanJob(ctx context.Context, estateId, jobId string, interval time.Duration) (PlanJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.PlanJob(ctx, estateId, jobId)
+		if err != nil {
+			return job, err
+		}
+		switch job.Status {
+		case generated.PlanJobResponseStatusSucceeded, generated.PlanJobResponseStatusFailed, generated.PlanJobResponseStatusCancelled:
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// dronePlan returns the plan of a response, its rest plot is decoded as a map of floats
+func dronePlan(res generated.GetEstateDronePlanResponse) DronePlan {
+	plan := DronePlan{Distance: res.Distance}
+	if res.Rest != nil {
+		x, _ := (*res.Rest)["x"].(float64)
+		y, _ := (*res.Rest)["y"].(float64)
+		plan.Rest = Plot{X: int(x), Y: int(y)}
+	}
+	return plan
+}
+
+// PlanJobResult returns the drone plan computed by a succeeded job
+func PlanJobResult(job PlanJob) (DronePlan, bool) {
+	if job.Result == nil {
+		return DronePlan{}, false
+	}
+	return dronePlan(*job.Result), true
+}
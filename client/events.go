@@ -0,0 +1,78 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+// maxEventSize bound a line of the event stream
+const maxEventSize = 1 << 20
+
+// Event is an event of the stream of an estate, Type is "stats" or "tree.created"
+type Event struct {
+	Id   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Events read the event stream of the estate and call handle for every event, until the stream ends, ctx is
+// done or handle returns an error. The stream resumes after lastEventId when it is set, the ID of the last event
+// handled is returned so the caller can reconnect from it. The stream is also ended by the Timeout of the client.
+func (c *Client) Events(ctx context.Context, estateId, lastEventId string, handle func(Event) error) (string, error) {
+	params := &generated.GetEstateIdEventsParams{}
+	if lastEventId != "" {
+		params.LastEventID = &lastEventId
+	}
+	res, err := c.api.GetEstateIdEvents(ctx, estateId, params)
+	if err != nil {
+		return lastEventId, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return lastEventId, responseError(res, body)
+	}
+
+	// the text/event-stream format, an event is a block of "field: value" lines ended by an empty line
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+	var event Event
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				event.Data = json.RawMessage(strings.Join(data, "\n"))
+				if err := handle(event); err != nil {
+					return lastEventId, err
+				}
+				if event.Id != "" {
+					lastEventId = event.Id
+				}
+			}
+			event, data = Event{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// a comment keeping the stream open
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.Id = value
+		case "event":
+			event.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return lastEventId, scanner.Err()
+}
@@ -0,0 +1,92 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+func Example() {
+	c, err := client.New(client.NewClientOptions{
+		BaseURL: "http://localhost:8080",
+		ApiKey:  "my-api-key",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	estateId, err := c.CreateEstate(ctx, client.CreateEstateRequest{Length: 10, Width: 5})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := c.CreateTree(ctx, estateId, client.CreateTreeRequest{Height: 12, X: 3, Y: 2}); err != nil {
+		log.Fatal(err)
+	}
+	plan, err := c.DronePlan(ctx, estateId, client.DronePlanOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(plan.Distance)
+}
+
+func ExampleError() {
+	c, err := client.New(client.NewClientOptions{BaseURL: "http://localhost:8080", ApiKey: "my-api-key"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = c.Stats(context.Background(), "a2d3c1e4-0000-0000-0000-000000000000")
+	var apiErr *client.Error
+	switch {
+	case errors.Is(err, client.ErrNotFound):
+		fmt.Println("no such estate")
+	case errors.As(err, &apiErr):
+		fmt.Println(apiErr.StatusCode, apiErr.Message)
+	case err != nil:
+		log.Fatal(err)
+	}
+}
+
+func ExampleClient_Estates() {
+	c, err := client.New(client.NewClientOptions{BaseURL: "http://localhost:8080", Token: "my-token"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	it := c.Estates()
+	for it.Next(ctx) {
+		estate := it.Value()
+		fmt.Println(estate.Id, estate.Length, estate.Width)
+	}
+	if err := it.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleClient_WaitPlanJob() {
+	c, err := client.New(client.NewClientOptions{BaseURL: "http://localhost:8080", ApiKey: "my-api-key"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	estateId := "a2d3c1e4-0000-0000-0000-000000000000"
+
+	job, err := c.CreatePlanJob(ctx, estateId, client.DronePlanOptions{MaxDistance: 5000})
+	if err != nil {
+		log.Fatal(err)
+	}
+	job, err = c.WaitPlanJob(ctx, estateId, job.Id, time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if plan, ok := client.PlanJobResult(job); ok {
+		fmt.Println(plan.Distance, plan.Rest.X, plan.Rest.Y)
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+type (
+	UploadFlightRequest = generated.UploadFlightRequest
+	FlightPoint         = generated.FlightPoint
+	Flight              = generated.FlightResponse
+	FlightComparison    = generated.FlightComparisonResponse
+)
+
+// UploadFlight store the log of a completed flight of the estate
+func (c *Client) UploadFlight(ctx context.Context, estateId string, request UploadFlightRequest) (Flight, error) {
+	res, err := c.api.PostEstateIdFlightsWithResponse(ctx, estateId, request)
+	if err != nil {
+		return Flight{}, err
+	}
+	if res.JSON201 == nil {
+		return Flight{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+// UploadFlightCSV store the log of a completed flight of the estate, a CSV with a timestamp,x,y,altitude,battery
+// header as exported by the drones
+func (c *Client) UploadFlightCSV(ctx context.Context, estateId string, log []byte) (Flight, error) {
+	res, err := c.api.PostEstateIdFlightsWithBodyWithResponse(ctx, estateId, "text/csv", bytes.NewReader(log))
+	if err != nil {
+		return Flight{}, err
+	}
+	if res.JSON201 == nil {
+		return Flight{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+// FlightComparison compare the flight with the planned route of the estate
+func (c *Client) FlightComparison(ctx context.Context, estateId, flightId string) (FlightComparison, error) {
+	res, err := c.api.GetEstateIdFlightsFlightIdComparisonWithResponse(ctx, estateId, flightId)
+	if err != nil {
+		return FlightComparison{}, err
+	}
+	if res.JSON200 == nil {
+		return FlightComparison{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON200, nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import "context"
+
+// Iterator iterate over the items of a list, the pages are fetched as they are needed:
+//
+//	it := c.Estates()
+//	for it.Next(ctx) {
+//		estate := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator[T any] struct {
+	// fetch returns the page of the cursor and the cursor of the next page, empty on the last page
+	fetch   func(ctx context.Context, cursor string) ([]T, string, error)
+	items   []T
+	index   int
+	cursor  string
+	started bool
+	value   T
+	err     error
+}
+
+func newIterator[T any](fetch func(ctx context.Context, cursor string) ([]T, string, error)) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch}
+}
+
+// Next advance to the next item and returns false at the end of the list or on an error
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for it.index >= len(it.items) {
+		if it.err != nil || (it.started && it.cursor == "") {
+			return false
+		}
+		items, next, err := it.fetch(ctx, it.cursor)
+		it.started = true
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items, it.index, it.cursor = items, 0, next
+	}
+	it.value = it.items[it.index]
+	it.index++
+	return true
+}
+
+// Value returns the current item
+func (it *Iterator[T]) Value() T {
+	return it.value
+}
+
+// Err returns the error which stopped the iteration
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All returns the items left in the list
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for it.Next(ctx) {
+		items = append(items, it.Value())
+	}
+	return items, it.Err()
+}
+
+// pageCursor returns the cursor parameter of a page, nil for the first page
+func pageCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
+}
+
+// nextCursor returns the cursor of the next page of a response, empty on the last page
+func nextCursor(cursor *string) string {
+	if cursor == nil {
+		return ""
+	}
+	return *cursor
+}
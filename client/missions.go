@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+type (
+	CreateMissionScheduleRequest = generated.CreateMissionScheduleRequest
+	MissionSchedule              = generated.MissionScheduleResponse
+	Mission                      = generated.MissionResponse
+)
+
+type MissionsOptions struct {
+	// Past lists the past missions from the latest, instead of the upcoming missions from the soonest
+	Past bool
+}
+
+// CreateMissionSchedule create a recurring patrol of the estate
+func (c *Client) CreateMissionSchedule(ctx context.Context, estateId string, request CreateMissionScheduleRequest) (MissionSchedule, error) {
+	res, err := c.api.PostEstateIdMissionsSchedulesWithResponse(ctx, estateId, request)
+	if err != nil {
+		return MissionSchedule{}, err
+	}
+	if res.JSON201 == nil {
+		return MissionSchedule{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+// MissionSchedules returns an iterator over the active patrol schedules of the estate, oldest first
+func (c *Client) MissionSchedules(estateId string) *Iterator[MissionSchedule] {
+	return newIterator(func(ctx context.Context, cursor string) ([]MissionSchedule, string, error) {
+		res, err := c.api.GetEstateIdMissionsSchedulesWithResponse(ctx, estateId, &generated.GetEstateIdMissionsSchedulesParams{
+			Cursor: pageCursor(cursor),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if res.JSON200 == nil {
+			return nil, "", responseError(res.HTTPResponse, res.Body)
+		}
+		return res.JSON200.Schedules, nextCursor(res.JSON200.NextCursor), nil
+	})
+}
+
+// DeleteMissionSchedule stop a patrol schedule, its upcoming missions are cancelled
+func (c *Client) DeleteMissionSchedule(ctx context.Context, estateId, scheduleId string) error {
+	res, err := c.api.DeleteEstateIdMissionsSchedulesScheduleIdWithResponse(ctx, estateId, scheduleId)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode() != http.StatusNoContent {
+		return responseError(res.HTTPResponse, res.Body)
+	}
+	return nil
+}
+
+// Missions returns an iterator over the upcoming missions of the estate, or the past ones
+func (c *Client) Missions(estateId string, opts MissionsOptions) *Iterator[Mission] {
+	when := generated.Upcoming
+	if opts.Past {
+		when = generated.Past
+	}
+	return newIterator(func(ctx context.Context, cursor string) ([]Mission, string, error) {
+		res, err := c.api.GetEstateIdMissionsWithResponse(ctx, estateId, &generated.GetEstateIdMissionsParams{
+			When:   &when,
+			Cursor: pageCursor(cursor),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if res.JSON200 == nil {
+			return nil, "", responseError(res.HTTPResponse, res.Body)
+		}
+		return res.JSON200.Missions, nextCursor(res.JSON200.NextCursor), nil
+	})
+}
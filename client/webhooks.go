@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+type (
+	CreateWebhookRequest = generated.CreateWebhookRequest
+	Webhook              = generated.WebhookResponse
+)
+
+// CreateWebhook subscribe an https URL to the events of the estates of the organisation
+func (c *Client) CreateWebhook(ctx context.Context, request CreateWebhookRequest) (Webhook, error) {
+	res, err := c.api.PostWebhooksWithResponse(ctx, request)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if res.JSON201 == nil {
+		return Webhook{}, responseError(res.HTTPResponse, res.Body)
+	}
+	return *res.JSON201, nil
+}
+
+// Webhooks returns an iterator over the webhook subscriptions of the organisation, oldest first
+func (c *Client) Webhooks() *Iterator[Webhook] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Webhook, string, error) {
+		res, err := c.api.GetWebhooksWithResponse(ctx, &generated.GetWebhooksParams{Cursor: pageCursor(cursor)})
+		if err != nil {
+			return nil, "", err
+		}
+		if res.JSON200 == nil {
+			return nil, "", responseError(res.HTTPResponse, res.Body)
+		}
+		return res.JSON200.Webhooks, nextCursor(res.JSON200.NextCursor), nil
+	})
+}
+
+// DeleteWebhook unsubscribe, the pending deliveries are not sent
+func (c *Client) DeleteWebhook(ctx context.Context, webhookId string) error {
+	res, err := c.api.DeleteWebhooksWebhookIdWithResponse(ctx, webhookId)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode() != http.StatusNoContent {
+		return responseError(res.HTTPResponse, res.Body)
+	}
+	return nil
+}
+
+// ReplayWebhook send again the deliveries which failed after their last attempt and returns their number
+func (c *Client) ReplayWebhook(ctx context.Context, webhookId string) (int, error) {
+	res, err := c.api.PostWebhooksWebhookIdReplayWithResponse(ctx, webhookId)
+	if err != nil {
+		return 0, err
+	}
+	if res.JSON200 == nil {
+		return 0, responseError(res.HTTPResponse, res.Body)
+	}
+	return res.JSON200.Replayed, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+// parseFlags parse the flags of a command and check the required ones are set
+func (c *cli) parseFlags(flags *flag.FlagSet, args []string, required ...string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range required {
+		if !set[name] {
+			fmt.Fprintf(c.stderr, "flag -%s is required\n", name)
+			flags.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+func estateCreate(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("estate create")
+	width := flags.Int("width", 0, "width of the estate in plots, north to south")
+	length := flags.Int("length", 0, "length of the estate in plots, west to east")
+	if err := c.parseFlags(flags, args, "width", "length"); err != nil {
+		return output{}, err
+	}
+
+	id, err := c.client.CreateEstate(ctx, client.CreateEstateRequest{
+		Width:  *width,
+		Length: *length,
+	})
+	if err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"id"},
+		rows:   [][]string{{id}},
+		value:  map[string]string{"id": id},
+	}, nil
+}
+
+func estateList(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("estate list")
+	if err := c.parseFlags(flags, args); err != nil {
+		return output{}, err
+	}
+
+	estates, err := c.client.Estates().All(ctx)
+	if err != nil {
+		return output{}, err
+	}
+	out := output{
+		header: []string{"id", "width", "length", "created_at"},
+		rows:   [][]string{},
+		value:  estates,
+		geo:    newFeatureCollection(),
+	}
+	if estates == nil {
+		out.value = []client.Estate{}
+	}
+	for _, estate := range estates {
+		out.rows = append(out.rows, []string{
+			estate.Id,
+			strconv.Itoa(estate.Width),
+			strconv.Itoa(estate.Length),
+			estate.CreatedAt.Format(time.RFC3339),
+		})
+		out.geo.Features = append(out.geo.Features, estateFeature(estate.Length, estate.Width, map[string]interface{}{
+			"id":         estate.Id,
+			"created_at": estate.CreatedAt,
+		}))
+	}
+	return out, nil
+}
+
+func treeAdd(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("tree add")
+	estateId := flags.String("estate", "", "id of the estate")
+	x := flags.Int("x", 0, "plot of the tree, west to east from 1")
+	y := flags.Int("y", 0, "plot of the tree, south to north from 1")
+	height := flags.Int("height", 0, "height of the tree in meters, 1 to 30")
+	if err := c.parseFlags(flags, args, "estate", "x", "y", "height"); err != nil {
+		return output{}, err
+	}
+
+	id, err := c.client.CreateTree(ctx, *estateId, client.CreateTreeRequest{
+		X:      *x,
+		Y:      *y,
+		Height: *height,
+	})
+	if err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"id"},
+		rows:   [][]string{{id}},
+		value:  map[string]string{"id": id},
+		geo: newFeatureCollection(pointFeature(*x, *y, map[string]interface{}{
+			"id":     id,
+			"height": *height,
+		})),
+	}, nil
+}
+
+func treeList(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("tree list")
+	estateId := flags.String("estate", "", "id of the estate")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	trees, err := c.client.Trees(*estateId).All(ctx)
+	if err != nil {
+		return output{}, err
+	}
+	out := output{
+		header: []string{"id", "x", "y", "height", "health"},
+		rows:   [][]string{},
+		value:  trees,
+		geo:    newFeatureCollection(),
+	}
+	if trees == nil {
+		out.value = []client.Tree{}
+	}
+	for _, tree := range trees {
+		out.rows = append(out.rows, []string{
+			tree.Id,
+			strconv.Itoa(tree.X),
+			strconv.Itoa(tree.Y),
+			strconv.Itoa(tree.Height),
+			string(tree.Health),
+		})
+		out.geo.Features = append(out.geo.Features, pointFeature(tree.X, tree.Y, map[string]interface{}{
+			"id":     tree.Id,
+			"height": tree.Height,
+		}))
+	}
+	return out, nil
+}
+
+// The statuses of the lines of an imported file
+const (
+	importCreated = "created"
+	importExists  = "exists"
+	importFailed  = "failed"
+
+	// importProgressLines is the number of lines between two progress lines on stderr
+	importProgressLines = 100
+	// defaultRateLimitWait is the wait of a rate limited line when the API does not tell it
+	defaultRateLimitWait = time.Second
+)
+
+// importedTree is the result of a line of an imported file
+type importedTree struct {
+	Line   int    `json:"line"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Height int    `json:"height"`
+	Status string `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// existingTree is a tree of the estate before the import
+type existingTree struct {
+	id     string
+	height int
+}
+
+// treeImport add the trees line by line, a failed line does not stop the import. The trees of the estate are
+// listed first, the line of a tree already added is skipped, so a file partly imported can be fixed and
+// imported again. A line rate limited longer than the client waits is sent again after the wait of the API.
+func treeImport(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("tree import")
+	estateId := flags.String("estate", "", "id of the estate")
+	file := flags.String("file", "", "csv file of x,y,height with an optional header line, - for stdin")
+	if err := c.parseFlags(flags, args, "estate", "file"); err != nil {
+		return output{}, err
+	}
+
+	var r io.Reader = c.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return output{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+	records := csv.NewReader(r)
+	records.FieldsPerRecord = 3
+	records.TrimLeadingSpace = true
+
+	existing := map[[2]int]existingTree{}
+	it := c.client.Trees(*estateId)
+	for it.Next(ctx) {
+		tree := it.Value()
+		existing[[2]int{tree.X, tree.Y}] = existingTree{id: tree.Id, height: tree.Height}
+	}
+	if err := it.Err(); err != nil {
+		return output{}, err
+	}
+
+	var trees []importedTree
+	failed := 0
+	for line := 1; ; line++ {
+		record, err := records.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return importOutput(trees), err
+		}
+		if line == 1 && strings.EqualFold(record[0], "x") {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return importOutput(trees), err
+		}
+
+		tree := importedTree{Line: line}
+		if err := parseTree(record, &tree); err != nil {
+			tree.Error = err.Error()
+		} else if found, ok := existing[[2]int{tree.X, tree.Y}]; ok {
+			if found.height == tree.Height {
+				tree.Status, tree.Id = importExists, found.id
+			} else {
+				tree.Error = fmt.Sprintf("plot already has the tree %s of height %d", found.id, found.height)
+			}
+		} else if tree.Id, err = c.createTree(ctx, *estateId, tree); err != nil {
+			if ctx.Err() != nil {
+				return importOutput(trees), ctx.Err()
+			}
+			tree.Error = err.Error()
+		} else {
+			tree.Status = importCreated
+			existing[[2]int{tree.X, tree.Y}] = existingTree{id: tree.Id, height: tree.Height}
+		}
+		if tree.Error != "" {
+			tree.Status = importFailed
+			failed++
+		}
+		trees = append(trees, tree)
+		if len(trees)%importProgressLines == 0 {
+			fmt.Fprintf(c.stderr, "%d lines imported, %d failed\n", len(trees), failed)
+		}
+	}
+
+	if failed > 0 {
+		return importOutput(trees), fmt.Errorf("%d of %d trees failed", failed, len(trees))
+	}
+	return importOutput(trees), nil
+}
+
+// createTree add a tree of an imported file, waiting as long as the API asks while it is rate limited
+func (c *cli) createTree(ctx context.Context, estateId string, tree importedTree) (string, error) {
+	for {
+		id, err := c.client.CreateTree(ctx, estateId, client.CreateTreeRequest{
+			X:      tree.X,
+			Y:      tree.Y,
+			Height: tree.Height,
+		})
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrRateLimited) {
+			return id, err
+		}
+		wait := apiErr.RetryAfter
+		if wait <= 0 {
+			wait = defaultRateLimitWait
+		}
+		fmt.Fprintf(c.stderr, "line %d is rate limited, waiting %s\n", tree.Line, wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func parseTree(record []string, tree *importedTree) error {
+	values := make([]int, len(record))
+	for i, field := range record {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return fmt.Errorf("invalid number %q", field)
+		}
+		values[i] = v
+	}
+	tree.X, tree.Y, tree.Height = values[0], values[1], values[2]
+	return nil
+}
+
+func importOutput(trees []importedTree) output {
+	out := output{
+		header: []string{"line", "x", "y", "height", "status", "id", "error"},
+		rows:   [][]string{},
+		value:  trees,
+		geo:    newFeatureCollection(),
+	}
+	if trees == nil {
+		out.value = []importedTree{}
+	}
+	for _, tree := range trees {
+		out.rows = append(out.rows, []string{
+			strconv.Itoa(tree.Line),
+			strconv.Itoa(tree.X),
+			strconv.Itoa(tree.Y),
+			strconv.Itoa(tree.Height),
+			tree.Status,
+			tree.Id,
+			tree.Error,
+		})
+		if tree.Id != "" {
+			out.geo.Features = append(out.geo.Features, pointFeature(tree.X, tree.Y, map[string]interface{}{
+				"id":     tree.Id,
+				"height": tree.Height,
+			}))
+		}
+	}
+	return out
+}
+
+func stats(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("stats")
+	estateId := flags.String("estate", "", "id of the estate")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	s, err := c.client.Stats(ctx, *estateId)
+	if err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"count", "min", "max", "median"},
+		rows:   [][]string{{strconv.Itoa(s.Count), strconv.Itoa(s.Min), strconv.Itoa(s.Max), strconv.Itoa(s.Median)}},
+		value:  s,
+	}, nil
+}
+
+func dronePlan(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("drone-plan")
+	estateId := flags.String("estate", "", "id of the estate")
+	maxDistance := flags.Int("max-distance", 0, "distance the drone can fly in meters, unlimited when not set")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	plan, err := c.client.DronePlan(ctx, *estateId, client.DronePlanOptions{MaxDistance: *maxDistance})
+	if err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"distance", "rest_x", "rest_y"},
+		rows:   [][]string{{strconv.Itoa(plan.Distance), strconv.Itoa(plan.Rest.X), strconv.Itoa(plan.Rest.Y)}},
+		value:  plan,
+		geo: newFeatureCollection(pointFeature(plan.Rest.X, plan.Rest.Y, map[string]interface{}{
+			"distance": plan.Distance,
+		})),
+	}, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// config is read from a JSON file:
+//
+//	{"base_url": "http://localhost:8080", "api_key": "dev-api-key"}
+//
+// token may be given instead of api_key to authenticate with a bearer token.
+type config struct {
+	BaseURL string `json:"base_url"`
+	ApiKey  string `json:"api_key"`
+	Token   string `json:"token"`
+}
+
+// defaultConfigPath returns the config file of the user, ~/.config/dronectl/config.json on linux
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dronectl", "config.json")
+}
+
+// loadConfig read the config file, DRONECTL_CONFIG is used when path is empty and the default path after it
+func loadConfig(path string) (config, error) {
+	if path == "" {
+		path = os.Getenv("DRONECTL_CONFIG")
+	}
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	var c config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, fmt.Errorf("config file %s does not exist, create it with the base_url and the api_key of the API", path)
+		}
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if c.BaseURL == "" {
+		return c, fmt.Errorf("invalid config file %s: base_url is missing", path)
+	}
+	if c.ApiKey == "" && c.Token == "" {
+		return c, fmt.Errorf("invalid config file %s: api_key or token is missing", path)
+	}
+	return c, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+// errEnoughEvents stops the stream once -count events are written
+var errEnoughEvents = errors.New("enough events")
+
+// eventsStream write the events of the estate as they arrive, a JSON object per line whatever the output
+// format. The stream is resumed from the last event when it ends, it stops on an interrupt or after -count events.
+func eventsStream(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("events")
+	estateId := flags.String("estate", "", "id of the estate")
+	lastEventId := flags.String("last-event-id", "", "resume after this event instead of starting with the stats")
+	count := flags.Int("count", 0, "stop after this number of events, unlimited when not set")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	encoder := json.NewEncoder(c.stdout)
+	written := 0
+	handle := func(event client.Event) error {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		written++
+		if *count > 0 && written >= *count {
+			return errEnoughEvents
+		}
+		return nil
+	}
+	last := *lastEventId
+	for {
+		var err error
+		last, err = c.client.Events(ctx, *estateId, last, handle)
+		if errors.Is(err, errEnoughEvents) || ctx.Err() != nil {
+			return output{}, nil
+		}
+		// the stream is closed by the server or by the timeout of the requests, it is resumed
+		var netErr net.Error
+		if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			return output{}, err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+func flightUpload(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("flight upload")
+	estateId := flags.String("estate", "", "id of the estate")
+	file := flags.String("file", "", "log of the flight, csv of timestamp,x,y,altitude,battery or json, - for stdin")
+	if err := c.parseFlags(flags, args, "estate", "file"); err != nil {
+		return output{}, err
+	}
+
+	var r io.Reader = c.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return output{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+	// the log is read first so a rate limited upload can be sent again
+	log, err := io.ReadAll(r)
+	if err != nil {
+		return output{}, err
+	}
+
+	var flight client.Flight
+	if bytes.HasPrefix(bytes.TrimSpace(log), []byte("{")) {
+		var request client.UploadFlightRequest
+		if err := json.Unmarshal(log, &request); err != nil {
+			return output{}, fmt.Errorf("invalid json flight log: %w", err)
+		}
+		flight, err = c.client.UploadFlight(ctx, *estateId, request)
+	} else {
+		flight, err = c.client.UploadFlightCSV(ctx, *estateId, log)
+	}
+	if err != nil {
+		return output{}, err
+	}
+	batteryUsed := ""
+	if flight.BatteryUsed != nil {
+		batteryUsed = formatFloat(*flight.BatteryUsed)
+	}
+	return output{
+		header: []string{"id", "points", "distance", "battery_used", "started_at", "finished_at"},
+		rows: [][]string{{
+			flight.Id,
+			strconv.Itoa(flight.PointCount),
+			formatFloat(flight.Distance),
+			batteryUsed,
+			flight.StartedAt.Format(time.RFC3339),
+			flight.FinishedAt.Format(time.RFC3339),
+		}},
+		value: flight,
+	}, nil
+}
+
+func flightCompare(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("flight compare")
+	estateId := flags.String("estate", "", "id of the estate")
+	flightId := flags.String("flight", "", "id of the flight")
+	if err := c.parseFlags(flags, args, "estate", "flight"); err != nil {
+		return output{}, err
+	}
+
+	comparison, err := c.client.FlightComparison(ctx, *estateId, *flightId)
+	if err != nil {
+		return output{}, err
+	}
+	out := output{
+		header: []string{"planned_distance", "actual_distance", "mean_deviation", "max_deviation", "plots_visited", "plots_missed"},
+		rows: [][]string{{
+			strconv.Itoa(comparison.PlannedDistance),
+			formatFloat(comparison.ActualDistance),
+			formatFloat(comparison.MeanDeviation),
+			formatFloat(comparison.MaxDeviation),
+			strconv.Itoa(comparison.PlotsVisited),
+			strconv.Itoa(comparison.PlotsMissed),
+		}},
+		value: comparison,
+		// the first missed plots
+		geo: newFeatureCollection(),
+	}
+	for _, plot := range comparison.MissedPlots {
+		x, _ := plot["x"].(float64)
+		y, _ := plot["y"].(float64)
+		out.geo.Features = append(out.geo.Features, pointFeature(int(x), int(y), map[string]interface{}{
+			"missed": true,
+		}))
+	}
+	return out, nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,172 @@
+// dronectl is the command line client of the drone patrol API, for the field teams scripting against the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+const usage = `Usage: dronectl [-config file] [-o table|json|csv|geojson] [-timeout 30s] <command> [flags]
+
+Commands:
+  estate create -width W -length L           create an estate
+  estate list                                list the estates of the organisation
+  tree add -estate ID -x X -y Y -height H    add a tree to an estate
+  tree list -estate ID                       list the trees of an estate
+  tree import -estate ID -file trees.csv     add the trees of a csv file of x,y,height, - reads stdin
+  stats -estate ID                           show the stats of the trees of an estate
+  drone-plan -estate ID [-max-distance D]    show the drone plan of an estate
+  plan-job create -estate ID [-max-distance D] [-wait]
+                                             compute the drone plan of a large estate in the background
+  plan-job get -estate ID -job ID            show a drone plan job, with its plan once it succeeded
+  plan-job cancel -estate ID -job ID         cancel a pending or running drone plan job
+  flight upload -estate ID -file log.csv     upload the log of a flight, csv or json, - reads stdin
+  flight compare -estate ID -flight ID       compare a flight with the planned route
+  schedule create -estate ID -name N -cron C [-time-zone TZ] [-max-distance D]
+                                             create a recurring patrol of an estate
+  schedule list -estate ID                   list the patrol schedules of an estate
+  schedule delete -estate ID -schedule ID    stop a patrol schedule
+  mission list -estate ID [-past]            list the upcoming or past missions of an estate
+  webhook create -url U -secret S -events E  subscribe an URL to the comma separated event types
+  webhook list                               list the webhook subscriptions of the organisation
+  webhook delete -webhook ID                 delete a webhook subscription
+  webhook replay -webhook ID                 send again the failed deliveries of a webhook
+  events -estate ID [-count N]               write the events of an estate as JSON lines until interrupted
+
+The config file is a JSON object with the base_url of the API and an api_key or a token, its path defaults to
+$DRONECTL_CONFIG then to ~/.config/dronectl/config.json.
+`
+
+// errUsage is returned for invalid arguments, the flag package already printed why
+var errUsage = errors.New("invalid usage")
+
+type cli struct {
+	client *client.Client
+	stdin  io.Reader
+	// stdout is only written by the commands streaming their result, the others return an output
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name string
+	run  func(ctx context.Context, c *cli, args []string) (output, error)
+}
+
+var commands = []command{
+	{name: "estate create", run: estateCreate},
+	{name: "estate list", run: estateList},
+	{name: "tree add", run: treeAdd},
+	{name: "tree list", run: treeList},
+	{name: "tree import", run: treeImport},
+	{name: "stats", run: stats},
+	{name: "drone-plan", run: dronePlan},
+	{name: "plan-job create", run: planJobCreate},
+	{name: "plan-job get", run: planJobGet},
+	{name: "plan-job cancel", run: planJobCancel},
+	{name: "flight upload", run: flightUpload},
+	{name: "flight compare", run: flightCompare},
+	{name: "schedule create", run: scheduleCreate},
+	{name: "schedule list", run: scheduleList},
+	{name: "schedule delete", run: scheduleDelete},
+	{name: "mission list", run: missionList},
+	{name: "webhook create", run: webhookCreate},
+	{name: "webhook list", run: webhookList},
+	{name: "webhook delete", run: webhookDelete},
+	{name: "webhook replay", run: webhookReplay},
+	{name: "events", run: eventsStream},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run the command of args and returns the exit code, 2 for an invalid usage and 1 for a failed command
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dronectl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	configPath := flags.String("config", "", "config file")
+	format := flags.String("o", formatTable, "output format: table, json, csv or geojson")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of a request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !validFormat(*format) {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *format)
+		return 2
+	}
+
+	cmd, cmdArgs, ok := lookupCommand(flags.Args())
+	if !ok {
+		flags.Usage()
+		return 2
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	api, err := client.New(client.NewClientOptions{
+		BaseURL:   cfg.BaseURL,
+		ApiKey:    cfg.ApiKey,
+		Token:     cfg.Token,
+		Timeout:   *timeout,
+		UserAgent: "dronectl",
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	out, err := cmd.run(ctx, &cli{client: api, stdin: stdin, stdout: stdout, stderr: stderr}, cmdArgs)
+	if errors.Is(err, errUsage) {
+		return 2
+	}
+	// a partly failed command still shows what succeeded
+	if out.header != nil {
+		if renderErr := render(stdout, *format, out); renderErr != nil {
+			fmt.Fprintln(stderr, renderErr)
+			return 1
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// lookupCommand returns the command named by the first words of args and the args left for its flags
+func lookupCommand(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// flags returns the flag set of a command, parse errors are printed to stderr
+func (c *cli) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("dronectl "+name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeApi answers the requests of the commands as the API does
+func fakeApi(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/estate", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.Header.Get("X-API-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"estates":[{"id":"e-1","width":3,"length":5,"created_at":"2024-01-01T00:00:00Z"}]}`))
+	})
+	mux.HandleFunc("/estate/e-1/tree", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"trees":[{"id":"t-1","x":1,"y":1,"height":10,"health":"healthy","attributes":{},` +
+				`"created_at":"2024-01-01T00:00:00Z"}]}`))
+			return
+		}
+		var tree map[string]int
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&tree))
+		w.Header().Set("Content-Type", "application/json")
+		if tree["x"] > 5 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"index out of bound"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"t-1"}`))
+	})
+	mux.HandleFunc("/estate/e-1/drone-plan", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "100", r.URL.Query().Get("max-distance"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"distance":100,"rest":{"x":4,"y":2}}`))
+	})
+	mux.HandleFunc("/estate/e-1/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count":3,"max":20,"median":10,"min":5}`))
+	})
+	mux.HandleFunc("/estate/e-2/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"estate is not found"}`))
+	})
+	mux.HandleFunc("/estate/e-1/drone-plan/jobs", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"id":"j-1","estate_id":"e-1","status":"pending","progress":0,"created_at":"2024-01-01T00:00:00Z"}`))
+	})
+	mux.HandleFunc("/estate/e-1/drone-plan/jobs/j-1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"j-1","estate_id":"e-1","status":"succeeded","progress":100,` +
+			`"created_at":"2024-01-01T00:00:00Z","result":{"distance":100,"rest":{"x":4,"y":2}}}`))
+	})
+	mux.HandleFunc("/estate/e-1/flights", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"f-1","estate_id":"e-1","point_count":2,"distance":10,"battery_used":0.5,` +
+			`"started_at":"2024-01-01T06:00:00Z","finished_at":"2024-01-01T06:00:05Z"}`))
+	})
+	mux.HandleFunc("/estate/e-1/flights/f-1/comparison", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"planned_distance":100,"actual_distance":90.5,"distance_difference":-9.5,"mean_deviation":1.5,` +
+			`"max_deviation":4,"mean_altitude_deviation":0,"max_altitude_deviation":0,"plots_visited":8,"plots_missed":2,` +
+			`"missed_plots":[{"x":3,"y":1},{"x":4,"y":1}]}`))
+	})
+	mux.HandleFunc("/estate/e-1/missions/schedules/s-1", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/estate/e-1/missions", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "past", r.URL.Query().Get("when"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"missions":[{"id":"m-1","schedule_id":"s-1","status":"dispatched",` +
+			`"scheduled_at":"2024-01-01T06:00:00Z","dispatched_at":"2024-01-01T06:00:01Z","plan":{"distance":100}}]}`))
+	})
+	mux.HandleFunc("/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		var webhook map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&webhook))
+		assert.Equal(t, []interface{}{"estate.created", "tree.created"}, webhook["event_types"])
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"w-1","url":"https://example.com/hook","event_types":["estate.created","tree.created"],` +
+			`"created_at":"2024-01-01T00:00:00Z"}`))
+	})
+	mux.HandleFunc("/webhooks/w-1/replay", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"replayed":3}`))
+	})
+	mux.HandleFunc("/estate/e-1/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("id: 1\nevent: stats\ndata: {\"count\":0}\n\n: keep-alive\n\n" +
+			"id: 2\nevent: tree.created\ndata: {\"height\":3}\n\n"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func writeConfig(t *testing.T, baseURL string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base_url":"`+baseURL+`","api_key":"test-key"}`), 0o600))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	srv := fakeApi(t)
+	defer srv.Close()
+	configPath := writeConfig(t, srv.URL)
+
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "unknown command",
+			args:       []string{"-config", configPath, "estate", "delete"},
+			wantCode:   2,
+			wantStderr: "Usage: dronectl",
+		},
+		{
+			name:       "missing flag",
+			args:       []string{"-config", configPath, "stats"},
+			wantCode:   2,
+			wantStderr: "flag -estate is required",
+		},
+		{
+			name:       "missing config",
+			args:       []string{"-config", filepath.Join(t.TempDir(), "missing.json"), "estate", "list"},
+			wantCode:   1,
+			wantStderr: "does not exist",
+		},
+		{
+			name:       "estate list as table",
+			args:       []string{"-config", configPath, "estate", "list"},
+			wantStdout: "ID   WIDTH  LENGTH  CREATED_AT\ne-1  3      5       2024-01-01T00:00:00Z\n",
+		},
+		{
+			name:       "estate list as csv",
+			args:       []string{"-config", configPath, "-o", "csv", "estate", "list"},
+			wantStdout: "id,width,length,created_at\ne-1,3,5,2024-01-01T00:00:00Z\n",
+		},
+		{
+			name:       "api error",
+			args:       []string{"-config", configPath, "stats", "-estate", "e-2"},
+			wantCode:   1,
+			wantStderr: "404 Not Found: estate is not found",
+		},
+		{
+			name:       "stats as json",
+			args:       []string{"-config", configPath, "-o", "json", "stats", "-estate", "e-1"},
+			wantStdout: "{\n  \"count\": 3,\n  \"max\": 20,\n  \"median\": 10,\n  \"min\": 5\n}\n",
+		},
+		{
+			name:       "geojson not available",
+			args:       []string{"-config", configPath, "-o", "geojson", "stats", "-estate", "e-1"},
+			wantCode:   1,
+			wantStderr: "geojson output is not available for this command",
+		},
+		{
+			name:     "drone plan as geojson",
+			args:     []string{"-config", configPath, "-o", "geojson", "drone-plan", "-estate", "e-1", "-max-distance", "100"},
+			wantCode: 0,
+			wantStdout: `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "geometry": {
+        "type": "Point",
+        "coordinates": [
+          4,
+          2
+        ]
+      },
+      "properties": {
+        "distance": 100
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			name:     "tree import",
+			args:     []string{"-config", configPath, "-o", "csv", "tree", "import", "-estate", "e-1", "-file", "-"},
+			stdin:    "x,y,height\n2,1,10\n6,1,10\n2,a,10\n",
+			wantCode: 1,
+			wantStdout: "line,x,y,height,status,id,error\n2,2,1,10,created,t-1,\n3,6,1,10,failed,,400 Bad Request: index out of bound\n" +
+				"4,0,0,0,failed,,\"invalid number \"\"a\"\"\"\n",
+			wantStderr: "2 of 3 trees failed",
+		},
+		{
+			name:       "tree list",
+			args:       []string{"-config", configPath, "-o", "csv", "tree", "list", "-estate", "e-1"},
+			wantStdout: "id,x,y,height,health\nt-1,1,1,10,healthy\n",
+		},
+		{
+			name:       "plan job create and wait",
+			args:       []string{"-config", configPath, "-o", "csv", "plan-job", "create", "-estate", "e-1", "-wait", "-interval", "1ms"},
+			wantStdout: "id,status,progress,distance,rest_x,rest_y,error\nj-1,succeeded,100,100,4,2,\n",
+		},
+		{
+			name:       "flight upload",
+			args:       []string{"-config", configPath, "-o", "csv", "flight", "upload", "-estate", "e-1", "-file", "-"},
+			stdin:      "timestamp,x,y,altitude,battery\n2024-01-01T06:00:00Z,1,1,1,100\n2024-01-01T06:00:05Z,2,1,1,99.5\n",
+			wantStdout: "id,points,distance,battery_used,started_at,finished_at\nf-1,2,10,0.5,2024-01-01T06:00:00Z,2024-01-01T06:00:05Z\n",
+		},
+		{
+			name: "flight compare",
+			args: []string{"-config", configPath, "-o", "csv", "flight", "compare", "-estate", "e-1", "-flight", "f-1"},
+			wantStdout: "planned_distance,actual_distance,mean_deviation,max_deviation,plots_visited,plots_missed\n" +
+				"100,90.5,1.5,4,8,2\n",
+		},
+		{
+			name:       "schedule delete",
+			args:       []string{"-config", configPath, "-o", "csv", "schedule", "delete", "-estate", "e-1", "-schedule", "s-1"},
+			wantStdout: "id\ns-1\n",
+		},
+		{
+			name: "past missions",
+			args: []string{"-config", configPath, "-o", "csv", "mission", "list", "-estate", "e-1", "-past"},
+			wantStdout: "id,schedule_id,status,scheduled_at,dispatched_at,distance\n" +
+				"m-1,s-1,dispatched,2024-01-01T06:00:00Z,2024-01-01T06:00:01Z,100\n",
+		},
+		{
+			name: "webhook create",
+			args: []string{"-config", configPath, "-o", "csv", "webhook", "create", "-url", "https://example.com/hook",
+				"-secret", "0123456789abcdef", "-events", "estate.created, tree.created"},
+			wantStdout: "id,url,event_types,created_at\nw-1,https://example.com/hook,\"estate.created,tree.created\",2024-01-01T00:00:00Z\n",
+		},
+		{
+			name:       "webhook replay",
+			args:       []string{"-config", configPath, "-o", "csv", "webhook", "replay", "-webhook", "w-1"},
+			wantStdout: "replayed\n3\n",
+		},
+		{
+			name: "events",
+			args: []string{"-config", configPath, "events", "-estate", "e-1", "-count", "2"},
+			wantStdout: `{"id":"1","type":"stats","data":{"count":0}}` + "\n" +
+				`{"id":"2","type":"tree.created","data":{"height":3}}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			assert.Equal(t, tt.wantCode, code, stderr.String())
+			if tt.wantStdout != "" {
+				assert.Equal(t, tt.wantStdout, stdout.String())
+			}
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+		})
+	}
+}
+
+func TestRun_TreeImportAgain(t *testing.T) {
+	// the trees of the estate are kept so a second import sees the trees of the first one
+	var mu sync.Mutex
+	trees := map[[2]int]string{}
+	created := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/estate/e-1/tree", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			list := []map[string]interface{}{}
+			for plot, id := range trees {
+				list = append(list, map[string]interface{}{"id": id, "x": plot[0], "y": plot[1], "height": 10,
+					"health": "healthy", "attributes": map[string]interface{}{}, "created_at": "2024-01-01T00:00:00Z"})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"trees": list})
+			return
+		}
+		var tree map[string]int
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&tree))
+		if tree["x"] > 5 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"index out of bound"}`))
+			return
+		}
+		created++
+		id := "t-" + strconv.Itoa(created)
+		trees[[2]int{tree["x"], tree["y"]}] = id
+		_, _ = w.Write([]byte(`{"id":"` + id + `"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	configPath := writeConfig(t, srv.URL)
+	args := []string{"-config", configPath, "-o", "csv", "tree", "import", "-estate", "e-1", "-file", "-"}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader("1,1,10\n6,1,10\n2,1,10\n"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line,x,y,height,status,id,error\n1,1,1,10,created,t-1,\n"+
+		"2,6,1,10,failed,,400 Bad Request: index out of bound\n3,2,1,10,created,t-2,\n", stdout.String())
+	assert.Contains(t, stderr.String(), "1 of 3 trees failed")
+
+	// the fixed file is imported again, only the failed line is sent
+	stdout.Reset()
+	stderr.Reset()
+	code = run(context.Background(), args, strings.NewReader("1,1,10\n5,1,10\n2,1,10\n"), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "line,x,y,height,status,id,error\n1,1,1,10,exists,t-1,\n"+
+		"2,5,1,10,created,t-3,\n3,2,1,10,exists,t-2,\n", stdout.String())
+	assert.Equal(t, 3, created)
+
+	// a tree of another height on a plot already taken fails
+	stdout.Reset()
+	stderr.Reset()
+	code = run(context.Background(), args, strings.NewReader("1,1,12\n"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "line,x,y,height,status,id,error\n1,1,1,12,failed,,plot already has the tree t-1 of height 10\n",
+		stdout.String())
+
+	// the progress is shown on a large file
+	var file strings.Builder
+	for y := 1; y <= 20; y++ {
+		for x := 1; x <= 5; x++ {
+			fmt.Fprintf(&file, "%d,%d,10\n", x, y+10)
+		}
+	}
+	stdout.Reset()
+	stderr.Reset()
+	code = run(context.Background(), args, strings.NewReader(file.String()), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "100 lines imported, 0 failed\n", stderr.String())
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+func scheduleCreate(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("schedule create")
+	estateId := flags.String("estate", "", "id of the estate")
+	name := flags.String("name", "", "name of the schedule")
+	cron := flags.String("cron", "", "cron expression of the patrols, 5 fields or a descriptor such as @daily")
+	timeZone := flags.String("time-zone", "", "IANA time zone of the cron expression, UTC when not set")
+	maxDistance := flags.Int("max-distance", 0, "distance the drone can fly in meters, unlimited when not set")
+	if err := c.parseFlags(flags, args, "estate", "name", "cron"); err != nil {
+		return output{}, err
+	}
+
+	request := client.CreateMissionScheduleRequest{Name: *name, Cron: *cron}
+	if *timeZone != "" {
+		request.TimeZone = timeZone
+	}
+	if *maxDistance != 0 {
+		request.MaxDistance = maxDistance
+	}
+	schedule, err := c.client.CreateMissionSchedule(ctx, *estateId, request)
+	if err != nil {
+		return output{}, err
+	}
+	return schedulesOutput([]client.MissionSchedule{schedule}, schedule), nil
+}
+
+func scheduleList(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("schedule list")
+	estateId := flags.String("estate", "", "id of the estate")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	schedules, err := c.client.MissionSchedules(*estateId).All(ctx)
+	if err != nil {
+		return output{}, err
+	}
+	if schedules == nil {
+		schedules = []client.MissionSchedule{}
+	}
+	return schedulesOutput(schedules, schedules), nil
+}
+
+func scheduleDelete(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("schedule delete")
+	estateId := flags.String("estate", "", "id of the estate")
+	scheduleId := flags.String("schedule", "", "id of the schedule")
+	if err := c.parseFlags(flags, args, "estate", "schedule"); err != nil {
+		return output{}, err
+	}
+
+	if err := c.client.DeleteMissionSchedule(ctx, *estateId, *scheduleId); err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"id"},
+		rows:   [][]string{{*scheduleId}},
+		value:  map[string]string{"id": *scheduleId},
+	}, nil
+}
+
+func schedulesOutput(schedules []client.MissionSchedule, value interface{}) output {
+	out := output{
+		header: []string{"id", "name", "cron", "time_zone", "max_distance", "next_run_at"},
+		rows:   [][]string{},
+		value:  value,
+	}
+	for _, schedule := range schedules {
+		out.rows = append(out.rows, []string{
+			schedule.Id,
+			schedule.Name,
+			schedule.Cron,
+			schedule.TimeZone,
+			optionalInt(schedule.MaxDistance),
+			optionalTime(schedule.NextRunAt),
+		})
+	}
+	return out
+}
+
+func missionList(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("mission list")
+	estateId := flags.String("estate", "", "id of the estate")
+	past := flags.Bool("past", false, "list the past missions from the latest instead of the upcoming ones")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	missions, err := c.client.Missions(*estateId, client.MissionsOptions{Past: *past}).All(ctx)
+	if err != nil {
+		return output{}, err
+	}
+	out := output{
+		header: []string{"id", "schedule_id", "status", "scheduled_at", "dispatched_at", "distance"},
+		rows:   [][]string{},
+		value:  missions,
+	}
+	if missions == nil {
+		out.value = []client.Mission{}
+	}
+	for _, mission := range missions {
+		distance := ""
+		if mission.Plan != nil {
+			distance = strconv.Itoa(mission.Plan.Distance)
+		}
+		out.rows = append(out.rows, []string{
+			mission.Id,
+			mission.ScheduleId,
+			string(mission.Status),
+			mission.ScheduledAt.Format(time.RFC3339),
+			optionalTime(mission.DispatchedAt),
+			distance,
+		})
+	}
+	return out, nil
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func optionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable   = "table"
+	formatJSON    = "json"
+	formatCSV     = "csv"
+	formatGeoJSON = "geojson"
+)
+
+// output is the result of a command in every format, header and rows for the table and the csv, value for the
+// json and geo for the geojson
+type output struct {
+	header []string
+	rows   [][]string
+	value  interface{}
+	// geo is nil when the result has no geometry
+	geo *featureCollection
+}
+
+// featureCollection is a GeoJSON feature collection. The coordinates are the plots of the estate, x to the
+// east and y to the north in the 10 m grid of the estate, not longitudes and latitudes.
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func newFeatureCollection(features ...feature) *featureCollection {
+	return &featureCollection{Type: "FeatureCollection", Features: features}
+}
+
+func pointFeature(x, y int, properties map[string]interface{}) feature {
+	return feature{
+		Type:       "Feature",
+		Geometry:   geometry{Type: "Point", Coordinates: []int{x, y}},
+		Properties: properties,
+	}
+}
+
+// estateFeature returns the boundary of an estate, the plots go from (1, 1) to (length, width)
+func estateFeature(length, width int, properties map[string]interface{}) feature {
+	return feature{
+		Type: "Feature",
+		Geometry: geometry{Type: "Polygon", Coordinates: [][][]int{{
+			{1, 1}, {length, 1}, {length, width}, {1, width}, {1, 1},
+		}}},
+		Properties: properties,
+	}
+}
+
+func validFormat(format string) bool {
+	switch format {
+	case formatTable, formatJSON, formatCSV, formatGeoJSON:
+		return true
+	}
+	return false
+}
+
+// render write the output in the format
+func render(w io.Writer, format string, out output) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, out.value)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(out.header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(out.rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case formatGeoJSON:
+		if out.geo == nil {
+			return fmt.Errorf("geojson output is not available for this command")
+		}
+		return writeJSON(w, out.geo)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(out.header, "\t")))
+		for _, row := range out.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
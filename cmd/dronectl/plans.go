@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+)
+
+func planJobCreate(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("plan-job create")
+	estateId := flags.String("estate", "", "id of the estate")
+	maxDistance := flags.Int("max-distance", 0, "distance the drone can fly in meters, unlimited when not set")
+	wait := flags.Bool("wait", false, "wait until the job is finished")
+	interval := flags.Duration("interval", 2*time.Second, "interval of the polls of the job with -wait")
+	if err := c.parseFlags(flags, args, "estate"); err != nil {
+		return output{}, err
+	}
+
+	job, err := c.client.CreatePlanJob(ctx, *estateId, client.DronePlanOptions{MaxDistance: *maxDistance})
+	if err != nil {
+		return output{}, err
+	}
+	if *wait {
+		if job, err = c.client.WaitPlanJob(ctx, *estateId, job.Id, *interval); err != nil {
+			return planJobOutput(job), err
+		}
+	}
+	return planJobOutput(job), nil
+}
+
+func planJobGet(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("plan-job get")
+	estateId := flags.String("estate", "", "id of the estate")
+	jobId := flags.String("job", "", "id of the drone plan job")
+	if err := c.parseFlags(flags, args, "estate", "job"); err != nil {
+		return output{}, err
+	}
+
+	job, err := c.client.PlanJob(ctx, *estateId, *jobId)
+	if err != nil {
+		return output{}, err
+	}
+	return planJobOutput(job), nil
+}
+
+func planJobCancel(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("plan-job cancel")
+	estateId := flags.String("estate", "", "id of the estate")
+	jobId := flags.String("job", "", "id of the drone plan job")
+	if err := c.parseFlags(flags, args, "estate", "job"); err != nil {
+		return output{}, err
+	}
+
+	job, err := c.client.CancelPlanJob(ctx, *estateId, *jobId)
+	if err != nil {
+		return output{}, err
+	}
+	return planJobOutput(job), nil
+}
+
+// planJobOutput shows the job, with its drone plan once it succeeded
+func planJobOutput(job client.PlanJob) output {
+	row := []string{job.Id, string(job.Status), strconv.Itoa(job.Progress), "", "", "", ""}
+	out := output{
+		header: []string{"id", "status", "progress", "distance", "rest_x", "rest_y", "error"},
+		value:  job,
+		geo:    newFeatureCollection(),
+	}
+	if plan, ok := client.PlanJobResult(job); ok {
+		row[3], row[4], row[5] = strconv.Itoa(plan.Distance), strconv.Itoa(plan.Rest.X), strconv.Itoa(plan.Rest.Y)
+		out.geo.Features = append(out.geo.Features, pointFeature(plan.Rest.X, plan.Rest.Y, map[string]interface{}{
+			"distance": plan.Distance,
+		}))
+	}
+	if job.Error != nil {
+		row[6] = *job.Error
+	}
+	out.rows = [][]string{row}
+	return out
+}
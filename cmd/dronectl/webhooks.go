@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/client"
+	"github.com/SawitProRecruitment/UserService/generated"
+)
+
+func webhookCreate(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("webhook create")
+	url := flags.String("url", "", "https URL receiving the events")
+	secret := flags.String("secret", "", "key of the HMAC-SHA256 signature of the deliveries, 16 characters at least")
+	eventTypes := flags.String("events", "", "comma separated event types, e.g. estate.created,tree.created")
+	if err := c.parseFlags(flags, args, "url", "secret", "events"); err != nil {
+		return output{}, err
+	}
+
+	request := client.CreateWebhookRequest{Url: *url, Secret: *secret}
+	for _, eventType := range strings.Split(*eventTypes, ",") {
+		request.EventTypes = append(request.EventTypes, generated.CreateWebhookRequestEventTypes(strings.TrimSpace(eventType)))
+	}
+	webhook, err := c.client.CreateWebhook(ctx, request)
+	if err != nil {
+		return output{}, err
+	}
+	return webhooksOutput([]client.Webhook{webhook}, webhook), nil
+}
+
+func webhookList(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("webhook list")
+	if err := c.parseFlags(flags, args); err != nil {
+		return output{}, err
+	}
+
+	webhooks, err := c.client.Webhooks().All(ctx)
+	if err != nil {
+		return output{}, err
+	}
+	if webhooks == nil {
+		webhooks = []client.Webhook{}
+	}
+	return webhooksOutput(webhooks, webhooks), nil
+}
+
+func webhookDelete(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("webhook delete")
+	webhookId := flags.String("webhook", "", "id of the webhook subscription")
+	if err := c.parseFlags(flags, args, "webhook"); err != nil {
+		return output{}, err
+	}
+
+	if err := c.client.DeleteWebhook(ctx, *webhookId); err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"id"},
+		rows:   [][]string{{*webhookId}},
+		value:  map[string]string{"id": *webhookId},
+	}, nil
+}
+
+func webhookReplay(ctx context.Context, c *cli, args []string) (output, error) {
+	flags := c.flags("webhook replay")
+	webhookId := flags.String("webhook", "", "id of the webhook subscription")
+	if err := c.parseFlags(flags, args, "webhook"); err != nil {
+		return output{}, err
+	}
+
+	replayed, err := c.client.ReplayWebhook(ctx, *webhookId)
+	if err != nil {
+		return output{}, err
+	}
+	return output{
+		header: []string{"replayed"},
+		rows:   [][]string{{strconv.Itoa(replayed)}},
+		value:  map[string]int{"replayed": replayed},
+	}, nil
+}
+
+func webhooksOutput(webhooks []client.Webhook, value interface{}) output {
+	out := output{
+		header: []string{"id", "url", "event_types", "created_at"},
+		rows:   [][]string{},
+		value:  value,
+	}
+	for _, webhook := range webhooks {
+		out.rows = append(out.rows, []string{
+			webhook.Id,
+			webhook.Url,
+			strings.Join(webhook.EventTypes, ","),
+			webhook.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return out
+}
@@ -1,32 +1,304 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/SawitProRecruitment/UserService/auth"
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/events"
 	"github.com/SawitProRecruitment/UserService/generated"
+	dronepatrolv1 "github.com/SawitProRecruitment/UserService/generated/dronepatrol/v1"
 	"github.com/SawitProRecruitment/UserService/handler"
+	"github.com/SawitProRecruitment/UserService/jobs"
+	"github.com/SawitProRecruitment/UserService/logging"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/missions"
+	"github.com/SawitProRecruitment/UserService/ratelimit"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/SawitProRecruitment/UserService/telemetry"
+	"github.com/SawitProRecruitment/UserService/tracing"
+	"github.com/SawitProRecruitment/UserService/webhooks"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := logging.NewLogger(logging.NewLoggerOptions{
+		Writer: os.Stdout,
+		Level:  os.Getenv("LOG_LEVEL"),
+	})
+	slog.SetDefault(logger)
+
 	e := echo.New()
-	var server generated.ServerInterface = newServer()
+	e.HideBanner = true
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	ipExtractor, err := ratelimit.IPExtractor(trustedProxies)
+	if err != nil {
+		logger.Error("failed to setup the client ip", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	e.IPExtractor = ipExtractor
+	shutdownTracer, err := tracing.NewTracerProvider(ctx, tracing.NewTracerProviderOptions{
+		ServiceName: "drone-patrol-api",
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+	})
+	if err != nil {
+		logger.Error("failed to setup tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	a, err := newApp(logger)
+	if err != nil {
+		logger.Error("failed to setup server", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	e.Use(logging.Middleware(logger))
+	e.Use(tracing.Middleware())
+	e.Use(a.metrics.Middleware())
+	limits := ratelimit.NewMemoryStore()
+	// a drone plan of a max size estate is expensive to compute, the routes running the planner share the bucket
+	dronePlanLimit := ratelimit.Limit{Name: "drone-plan", Rate: 0.2, Burst: 3}
+	api := e.Group("",
+		// every request of an ip is counted before the authentication, the invalid credentials included
+		ratelimit.Middleware(ratelimit.MiddlewareOptions{
+			Store:   limits,
+			Default: ratelimit.Limit{Name: "ip", Rate: 20, Burst: 40},
+			Key:     ratelimit.IPKey,
+			Logger:  logger,
+		}),
+		auth.Middleware(a.authenticator, logger),
+		ratelimit.Middleware(ratelimit.MiddlewareOptions{
+			Store:   limits,
+			Default: ratelimit.Limit{Name: "default", Rate: 10, Burst: 20},
+			Routes: map[string]ratelimit.Limit{
+				"/estate/:id/drone-plan":          dronePlanLimit,
+				"/estate/:id/drone-plan/targeted": dronePlanLimit,
+				// submitting a job runs the planner, listing and polling the jobs does not
+				"POST /estate/:id/drone-plan/jobs": dronePlanLimit,
+			},
+			Logger: logger,
+		}),
+	)
+	generated.RegisterHandlers(api, a.server)
+
+	if err := a.planJobs.Start(ctx); err != nil {
+		logger.Error("failed to start drone plan jobs", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	a.missions.Start(ctx)
+	a.webhooks.Start(ctx)
 
-	generated.RegisterHandlers(e, server)
-	e.Use(middleware.Logger())
-	e.Logger.Fatal(e.Start(":1323"))
+	// the gRPC API runs next to the REST API with the same authentication, limits and observability
+	ipLimit := ratelimit.InterceptorOptions{
+		Store:   limits,
+		Default: ratelimit.Limit{Name: "ip", Rate: 20, Burst: 40},
+		Key:     ratelimit.GrpcIPKey,
+		Logger:  logger,
+	}
+	clientLimit := ratelimit.InterceptorOptions{
+		Store:   limits,
+		Default: ratelimit.Limit{Name: "default", Rate: 10, Burst: 20},
+		Methods: map[string]ratelimit.Limit{
+			dronepatrolv1.DronePatrolService_GetDronePlan_FullMethodName: dronePlanLimit,
+		},
+		Logger: logger,
+	}
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(tracing.GrpcServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryServerInterceptor(logger),
+			a.metrics.UnaryServerInterceptor(),
+			ratelimit.UnaryServerInterceptor(ipLimit),
+			auth.UnaryServerInterceptor(a.authenticator, logger),
+			ratelimit.UnaryServerInterceptor(clientLimit),
+		),
+		grpc.ChainStreamInterceptor(
+			logging.StreamServerInterceptor(logger),
+			a.metrics.StreamServerInterceptor(),
+			ratelimit.StreamServerInterceptor(ipLimit),
+			auth.StreamServerInterceptor(a.authenticator, logger),
+			ratelimit.StreamServerInterceptor(clientLimit),
+		),
+	)
+	dronepatrolv1.RegisterDronePatrolServiceServer(grpcServer, handler.NewGrpcServer(a.server))
+	listener, err := net.Listen("tcp", ":9090")
+	if err != nil {
+		logger.Error("failed to listen for grpc", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	// the metrics are served on an internal listener, apart from the public API
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9100"
+	}
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", a.metrics.Handler())
+	metricsServer := &http.Server{Addr: metricsAddr, Handler: metricsMux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server stopped", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}()
+	go func() {
+		if err := e.Start(":1323"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server stopped", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}()
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			logger.Error("grpc server stopped", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}()
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// end the event streams and the telemetry connections, the server waits for the open requests
+	a.events.Close()
+	a.telemetry.Close()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown server", slog.String("error", err.Error()))
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown metrics server", slog.String("error", err.Error()))
+	}
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		// a drone plan stream of a large estate may not end in time
+		grpcServer.Stop()
+	}
+	// the running jobs are stopped with ctx and resumed on the next start
+	a.planJobs.Wait()
+	a.missions.Wait()
+	a.webhooks.Wait()
+	if err := shutdownTracer(shutdownCtx); err != nil {
+		logger.Error("failed to shutdown tracing", slog.String("error", err.Error()))
+	}
+}
+
+type app struct {
+	metrics       *metrics.Metrics
+	authenticator *auth.Authenticator
+	server        *handler.Server
+	planJobs      *jobs.Runner
+	missions      *missions.Scheduler
+	webhooks      *webhooks.Dispatcher
+	events        *events.Hub
+	telemetry     *telemetry.Hub
 }
 
-func newServer() *handler.Server {
+func newApp(logger *slog.Logger) (*app, error) {
 	dbDsn := os.Getenv("DATABASE_URL")
-	var repo repository.RepositoryInterface = repository.NewRepository(repository.NewRepositoryOptions{
+	db := repository.NewRepository(repository.NewRepositoryOptions{
 		Dsn: dbDsn,
 	})
-	opts := handler.NewServerOptions{
-		Repository: repo,
+	m := metrics.New(metrics.NewMetricsOptions{
+		Db: db.Db,
+	})
+	repo := logging.WrapRepository(tracing.WrapRepository(m.WrapRepository(db)), logger)
+
+	var jwtVerifier *auth.JwtVerifier
+	if jwksFile := os.Getenv("JWKS_FILE"); jwksFile != "" {
+		var err error
+		jwtVerifier, err = auth.NewJwtVerifier(auth.NewJwtVerifierOptions{
+			JwksFile: jwksFile,
+			Issuer:   os.Getenv("JWT_ISSUER"),
+			Audience: os.Getenv("JWT_AUDIENCE"),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cacheSize := 1024
+	if size, err := strconv.Atoi(os.Getenv("CACHE_SIZE")); err == nil && size > 0 {
+		cacheSize = size
 	}
-	return handler.NewServer(opts)
+	lru, err := cache.NewLRU(cacheSize)
+	if err != nil {
+		return nil, err
+	}
+	// the api, the jobs and the missions share the cached plans
+	estateCache := cache.New(cache.NewCacheOptions{Backend: lru})
+	dronePlans := service.NewDronePlanService(service.NewDronePlanServiceOptions{
+		Repository: repo,
+		Metrics:    m,
+		Cache:      estateCache,
+		Logger:     logger,
+	})
+
+	workers, _ := strconv.Atoi(os.Getenv("PLAN_JOB_WORKERS"))
+	planJobs := jobs.NewRunner(jobs.NewRunnerOptions{
+		Repository: repo,
+		DronePlans: dronePlans,
+		Logger:     logger,
+		Workers:    workers,
+	})
+
+	scheduler := missions.NewScheduler(missions.NewSchedulerOptions{
+		Repository: repo,
+		DronePlans: dronePlans,
+		Logger:     logger,
+	})
+
+	hub := events.NewHub(events.NewHubOptions{})
+	threshold, _ := strconv.ParseFloat(os.Getenv("DRONE_DEVIATION_THRESHOLD"), 64)
+	tracking := telemetry.NewHub(telemetry.NewHubOptions{
+		Threshold: threshold,
+	})
+
+	return &app{
+		metrics: m,
+		authenticator: auth.NewAuthenticator(auth.NewAuthenticatorOptions{
+			Repository: repo,
+			Jwt:        jwtVerifier,
+		}),
+		server: handler.NewServer(handler.NewServerOptions{
+			Repository: repo,
+			Metrics:    m,
+			Logger:     logger,
+			Cache:      estateCache,
+			PlanJobs:   planJobs,
+			Missions:   scheduler,
+			Events:     hub,
+			Telemetry:  tracking,
+		}),
+		planJobs: planJobs,
+		missions: scheduler,
+		webhooks: webhooks.NewDispatcher(webhooks.NewDispatcherOptions{
+			Repository: repo,
+			Logger:     logger,
+		}),
+		events:    hub,
+		telemetry: tracking,
+	}, nil
 }
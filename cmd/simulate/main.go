@@ -0,0 +1,131 @@
+// simulate generates synthetic estates and writes them through the api or directly in the database, for the load
+// tests and the demos.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/SawitProRecruitment/UserService/client"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/simulate"
+)
+
+const (
+	targetApi        = "api"
+	targetRepository = "repository"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run the simulation of args and returns the exit code, 2 for an invalid usage and 1 for a failed simulation
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	seed := flags.Int64("seed", 1, "seed of the generator, the same seed generates the same estates")
+	estates := flags.Int("estates", 1, "number of estates")
+	length := flags.Int("length", 100, "length of an estate in plots, west to east")
+	width := flags.Int("width", 100, "width of an estate in plots, south to north")
+	pattern := flags.String("pattern", simulate.PatternSquare, "planting pattern: square, triangular or random")
+	density := flags.Float64("density", 0.25, "share of the plots planted, more than 0 and up to 1")
+	heightMean := flags.Float64("height-mean", 15, "mean height of the trees in meters")
+	heightStdDev := flags.Float64("height-stddev", 5, "standard deviation of the height of the trees in meters")
+	missingRate := flags.Float64("missing-rate", 0.05, "share of the planted trees missing")
+	target := flags.String("target", targetApi, "where the estates are written: api or repository")
+	workers := flags.Int("workers", 4, "number of trees written at the same time")
+	dryRun := flags.Bool("dry-run", false, "generate the estates without writing them")
+	baseURL := flags.String("base-url", "http://localhost:8080", "base url of the api, for the api target")
+	apiKey := flags.String("api-key", os.Getenv("SIMULATE_API_KEY"), "api key, for the api target, default to $SIMULATE_API_KEY")
+	token := flags.String("token", "", "bearer token used when there is no api key, for the api target")
+	organisationId := flags.String("organisation", "", "id of the organisation owning the estates, for the repository target")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var writer simulate.Writer
+	switch {
+	case *dryRun:
+		writer = &dryRunWriter{}
+	case *target == targetApi:
+		api, err := client.New(client.NewClientOptions{
+			BaseURL:   *baseURL,
+			ApiKey:    *apiKey,
+			Token:     *token,
+			UserAgent: "simulate",
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		writer = simulate.NewApiWriter(simulate.NewApiWriterOptions{Client: api})
+	case *target == targetRepository:
+		if *organisationId == "" {
+			fmt.Fprintln(stderr, "flag -organisation is required for the repository target")
+			return 2
+		}
+		// the database is the one of the service, DATABASE_URL as cmd/main.go reads it
+		writer = simulate.NewRepositoryWriter(simulate.NewRepositoryWriterOptions{
+			Repository: repository.NewRepository(repository.NewRepositoryOptions{
+				Dsn: os.Getenv("DATABASE_URL"),
+			}),
+			OrganisationId: *organisationId,
+		})
+	default:
+		fmt.Fprintf(stderr, "unknown target %q\n", *target)
+		return 2
+	}
+
+	generator := simulate.NewGenerator(simulate.NewGeneratorOptions{Seed: *seed})
+	results, err := generator.Run(ctx, simulate.RunOptions{
+		Writer:  writer,
+		Estates: *estates,
+		Workers: *workers,
+		Estate: simulate.EstateOptions{
+			Length:       *length,
+			Width:        *width,
+			Pattern:      *pattern,
+			Density:      *density,
+			HeightMean:   *heightMean,
+			HeightStdDev: *heightStdDev,
+			MissingRate:  *missingRate,
+		},
+	})
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ESTATE_ID\tLENGTH\tWIDTH\tTREES")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", result.EstateId, result.Length, result.Width, result.Trees)
+	}
+	_ = tw.Flush()
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// dryRunWriter writes nothing, the estates are numbered in the order they are generated
+type dryRunWriter struct {
+	estates int
+}
+
+func (w *dryRunWriter) CreateEstate(_ context.Context, _ simulate.Estate) (string, error) {
+	w.estates++
+	return fmt.Sprintf("dry-run-%d", w.estates), nil
+}
+
+func (w *dryRunWriter) CreateTree(_ context.Context, _ string, _ simulate.Tree) error {
+	return nil
+}
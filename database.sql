@@ -7,10 +7,57 @@
 
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+CREATE TABLE IF NOT EXISTS organisations (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  name                  VARCHAR(255)     NOT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  deleted_at            TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id)
+);
+
+-- Only the SHA-256 hex digest of the key is stored, the plain key is shown once to its owner.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  organisation_id       UUID             NOT NULL REFERENCES organisations (id),
+  name                  VARCHAR(255)     NOT NULL,
+  role                  VARCHAR(32)      NOT NULL CHECK (role IN ('viewer', 'surveyor', 'operator', 'admin')),
+  key_hash              CHAR(64)         NOT NULL UNIQUE,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  revoked_at            TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id)
+);
+
+-- Role of the JWT subjects in an organisation.
+CREATE TABLE IF NOT EXISTS memberships (
+  organisation_id       UUID             NOT NULL REFERENCES organisations (id),
+  subject               VARCHAR(255)     NOT NULL,
+  role                  VARCHAR(32)      NOT NULL CHECK (role IN ('viewer', 'surveyor', 'operator', 'admin')),
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (organisation_id, subject)
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  organisation_id       UUID             NOT NULL,
+  subject               VARCHAR(255)     NOT NULL,
+  role                  VARCHAR(32)      NOT NULL,
+  action                VARCHAR(64)      NOT NULL,
+  resource              VARCHAR(255)     NOT NULL,
+  outcome               VARCHAR(16)      NOT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_audit_log_organisation ON audit_logs(organisation_id, created_at);
+
 CREATE TABLE IF NOT EXISTS estates (
   id                    UUID             DEFAULT uuid_generate_v4(),
+  organisation_id       UUID             NOT NULL REFERENCES organisations (id),
   width               	INTEGER        	NOT NULL,
   length               	INTEGER         NOT NULL,
+  version               BIGINT           NOT NULL DEFAULT 0,
   created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
   updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
   deleted_at            TIMESTAMP        DEFAULT NULL,
@@ -23,10 +70,189 @@ CREATE TABLE IF NOT EXISTS trees (
   x       				INTEGER        	NOT NULL,
   y         			INTEGER         NOT NULL,
   height               	INTEGER         NOT NULL,
+  species               VARCHAR(64)      DEFAULT NULL,
+  variety               VARCHAR(64)      DEFAULT NULL,
+  planted_at            DATE             DEFAULT NULL,
+  health                VARCHAR(16)      NOT NULL DEFAULT 'healthy' CHECK (health IN ('healthy', 'diseased', 'dead', 'replanted')),
+  -- free-form attributes of the plantation, such as the seed supplier or the last fertilisation
+  attributes            JSONB            NOT NULL DEFAULT '{}',
   created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
   updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
   deleted_at            TIMESTAMP        DEFAULT NULL,
   PRIMARY KEY (id)
 );
 
-CREATE INDEX IF NOT EXISTS index_tree ON trees(estate_id, x, y);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS index_tree ON trees(estate_id, x, y);
+CREATE INDEX IF NOT EXISTS index_tree_species ON trees(estate_id, species);
+CREATE INDEX IF NOT EXISTS index_tree_health ON trees(estate_id, health);
+CREATE INDEX IF NOT EXISTS index_estate_organisation ON estates(organisation_id);
+
+-- bump the estate version on every tree change, the cached stats and drone plans are keyed by it
+CREATE OR REPLACE FUNCTION bump_estate_version() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    UPDATE estates SET version = version + 1, updated_at = NOW() WHERE id = OLD.estate_id;
+  END IF;
+  IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.estate_id <> OLD.estate_id) THEN
+    UPDATE estates SET version = version + 1, updated_at = NOW() WHERE id = NEW.estate_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_tree_estate_version ON trees;
+CREATE TRIGGER trigger_tree_estate_version AFTER INSERT OR UPDATE OR DELETE ON trees
+  FOR EACH ROW EXECUTE FUNCTION bump_estate_version();
+
+CREATE TABLE IF NOT EXISTS plan_jobs (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  estate_id             UUID             NOT NULL REFERENCES estates (id),
+  max_distance          INTEGER          DEFAULT NULL,
+  status                VARCHAR(16)      NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'succeeded', 'failed', 'cancelled')),
+  progress              INTEGER          NOT NULL DEFAULT 0,
+  distance              BIGINT           DEFAULT NULL,
+  rest_x                INTEGER          DEFAULT NULL,
+  rest_y                INTEGER          DEFAULT NULL,
+  error                 TEXT             DEFAULT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  started_at            TIMESTAMP        DEFAULT NULL,
+  finished_at           TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_plan_job_estate ON plan_jobs(estate_id);
+CREATE INDEX IF NOT EXISTS index_plan_job_unfinished ON plan_jobs(created_at) WHERE status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS flights (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  estate_id             UUID             NOT NULL REFERENCES estates (id),
+  started_at            TIMESTAMP        NOT NULL,
+  finished_at           TIMESTAMP        NOT NULL,
+  point_count           INTEGER          NOT NULL,
+  distance              DOUBLE PRECISION NOT NULL,
+  battery_used          DOUBLE PRECISION DEFAULT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_flight_estate ON flights(estate_id, started_at);
+
+CREATE TABLE IF NOT EXISTS flight_points (
+  flight_id             UUID             NOT NULL REFERENCES flights (id) ON DELETE CASCADE,
+  seq                   INTEGER          NOT NULL,
+  recorded_at           TIMESTAMP        NOT NULL,
+  x                     DOUBLE PRECISION NOT NULL,
+  y                     DOUBLE PRECISION NOT NULL,
+  altitude              DOUBLE PRECISION NOT NULL,
+  battery               DOUBLE PRECISION DEFAULT NULL,
+  PRIMARY KEY (flight_id, seq)
+);
+
+-- Findings are the issues detected by the drones on the plots, linked to the tree of the plot when there is one.
+CREATE TABLE IF NOT EXISTS findings (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  estate_id             UUID             NOT NULL REFERENCES estates (id),
+  flight_id             UUID             NOT NULL REFERENCES flights (id),
+  tree_id               UUID             DEFAULT NULL REFERENCES trees (id) ON DELETE SET NULL,
+  x                     INTEGER          NOT NULL,
+  y                     INTEGER          NOT NULL,
+  type                  VARCHAR(32)      NOT NULL CHECK (type IN ('pest_damage', 'yellowing_fronds', 'ripe_bunches', 'disease', 'missing_tree', 'other')),
+  severity              INTEGER          NOT NULL CHECK (severity BETWEEN 1 AND 5),
+  confidence            DOUBLE PRECISION NOT NULL CHECK (confidence BETWEEN 0 AND 1),
+  image_ref             TEXT             DEFAULT NULL,
+  status                VARCHAR(16)      NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+  detected_at           TIMESTAMP        NOT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  resolved_at           TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_finding_estate ON findings(estate_id, detected_at);
+CREATE INDEX IF NOT EXISTS index_finding_tree ON findings(tree_id, detected_at);
+CREATE INDEX IF NOT EXISTS index_finding_open ON findings(estate_id, severity) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS mission_schedules (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  estate_id             UUID             NOT NULL REFERENCES estates (id),
+  name                  VARCHAR(255)     NOT NULL,
+  cron                  VARCHAR(255)     NOT NULL,
+  time_zone             VARCHAR(64)      NOT NULL,
+  max_distance          INTEGER          DEFAULT NULL,
+  active                BOOLEAN          NOT NULL DEFAULT TRUE,
+  materialised_until    TIMESTAMP        DEFAULT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_mission_schedule_estate ON mission_schedules(estate_id) WHERE active;
+
+CREATE TABLE IF NOT EXISTS missions (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  schedule_id           UUID             NOT NULL REFERENCES mission_schedules (id),
+  estate_id             UUID             NOT NULL REFERENCES estates (id),
+  scheduled_at          TIMESTAMP        NOT NULL,
+  status                VARCHAR(16)      NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'dispatched', 'cancelled')),
+  distance              BIGINT           DEFAULT NULL,
+  rest_x                INTEGER          DEFAULT NULL,
+  rest_y                INTEGER          DEFAULT NULL,
+  estate_version        BIGINT           NOT NULL DEFAULT 0,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  dispatched_at         TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (schedule_id, scheduled_at)
+);
+
+CREATE INDEX IF NOT EXISTS index_mission_estate ON missions(estate_id, scheduled_at);
+CREATE INDEX IF NOT EXISTS index_mission_due ON missions(scheduled_at) WHERE status = 'scheduled';
+
+-- Events are written in the transaction of the change they describe and fanned out to the webhook deliveries
+-- by the delivery worker.
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  organisation_id       UUID             NOT NULL REFERENCES organisations (id),
+  estate_id             UUID             NOT NULL REFERENCES estates (id),
+  event_type            VARCHAR(64)      NOT NULL,
+  payload               JSONB            NOT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  processed_at          TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_outbox_event_unprocessed ON outbox_events(created_at) WHERE processed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  organisation_id       UUID             NOT NULL REFERENCES organisations (id),
+  url                   TEXT             NOT NULL,
+  event_types           TEXT[]           NOT NULL,
+  secret                VARCHAR(255)     NOT NULL,
+  active                BOOLEAN          NOT NULL DEFAULT TRUE,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_subscription_organisation ON webhook_subscriptions(organisation_id) WHERE active;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id                    UUID             DEFAULT uuid_generate_v4(),
+  subscription_id       UUID             NOT NULL REFERENCES webhook_subscriptions (id),
+  event_id              UUID             NOT NULL REFERENCES outbox_events (id),
+  status                VARCHAR(16)      NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+  attempts              INTEGER          NOT NULL DEFAULT 0,
+  next_attempt_at       TIMESTAMP        NOT NULL DEFAULT NOW(),
+  last_status_code      INTEGER          DEFAULT NULL,
+  last_error            TEXT             DEFAULT NULL,
+  created_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  updated_at            TIMESTAMP        NOT NULL DEFAULT NOW(),
+  delivered_at          TIMESTAMP        DEFAULT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS index_webhook_delivery_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS index_webhook_delivery_dead ON webhook_deliveries(subscription_id) WHERE status = 'dead';
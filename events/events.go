@@ -0,0 +1,253 @@
+// This file contains the in-process pub/sub hub of the estate events streamed to the dashboards.
+//
+// Every estate has a bounded log of its latest events so a client reconnecting with the ID of the last event it
+// received gets the events it missed. The IDs are "<epoch>-<sequence>", the sequence is shared by the estates and
+// the epoch changes on every start of the process so the IDs of another process or of a previous start are not
+// mistaken for the IDs of the log.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TypeTreeCreated = "tree.created"
+	TypeStats       = "stats"
+
+	defaultLogSize    = 256
+	defaultBufferSize = 64
+	defaultMaxEstates = 1024
+)
+
+// ErrTooManyEstates is returned by Subscribe when every estate of the hub has subscribers
+var ErrTooManyEstates = errors.New("too many estates are streamed")
+
+type Event struct {
+	Id   string
+	Type string
+	Data json.RawMessage
+}
+
+type Hub struct {
+	mu         sync.Mutex
+	epoch      string
+	logSize    int
+	bufferSize int
+	maxEstates int
+	seq        uint64
+	estates    map[string]*estateLog
+	closed     bool
+}
+
+type estateLog struct {
+	// floor is the sequence up to which the events are not in the log anymore, or were published before the log
+	floor       uint64
+	events      []loggedEvent
+	subscribers map[*Subscription]struct{}
+}
+
+type loggedEvent struct {
+	seq   uint64
+	event Event
+}
+
+type NewHubOptions struct {
+	// LogSize is the number of events kept per estate for the reconnecting clients, default to 256
+	LogSize int
+	// BufferSize is the number of events queued for a subscriber, a subscriber falling further behind is closed
+	// and resumes from the log when it reconnects. Default to 64.
+	BufferSize int
+	// MaxEstates bound the estates having a log, the log of an estate without subscriber is dropped first.
+	// Once every estate has subscribers the other estates can not be subscribed to. Default to 1024.
+	MaxEstates int
+}
+
+func NewHub(opts NewHubOptions) *Hub {
+	logSize := opts.LogSize
+	if logSize <= 0 {
+		logSize = defaultLogSize
+	}
+	bufferSize := opts.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	maxEstates := opts.MaxEstates
+	if maxEstates <= 0 {
+		maxEstates = defaultMaxEstates
+	}
+	return &Hub{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		logSize:    logSize,
+		bufferSize: bufferSize,
+		maxEstates: maxEstates,
+		estates:    map[string]*estateLog{},
+	}
+}
+
+// Subscription receives the events of an estate on C until it is closed
+type Subscription struct {
+	C <-chan Event
+	// Backlog is the events missed since the last event ID given to Subscribe
+	Backlog []Event
+	// Resumed is false when the events since the last event ID are not in the log anymore, or no ID was given.
+	// The client should then be sent the current state.
+	Resumed bool
+	// LastEventId is the ID of the latest event of the estate when subscribing
+	LastEventId string
+
+	c        chan Event
+	hub      *Hub
+	estateId string
+}
+
+// Publish append an event to the log of the estate and send it to the subscribers. A nil hub does nothing.
+func (h *Hub) Publish(estateId string, eventType string, data any) error {
+	if h == nil {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	log, ok := h.estateLog(estateId)
+	if !ok {
+		// nobody listens to the estate, there is no room to log the event for a reconnecting client
+		return nil
+	}
+	h.seq++
+	event := Event{Id: h.eventId(h.seq), Type: eventType, Data: payload}
+	log.events = append(log.events, loggedEvent{seq: h.seq, event: event})
+	if len(log.events) > h.logSize {
+		log.floor = log.events[len(log.events)-h.logSize-1].seq
+		log.events = append([]loggedEvent(nil), log.events[len(log.events)-h.logSize:]...)
+	}
+	for sub := range log.subscribers {
+		select {
+		case sub.c <- event:
+		default:
+			// too slow, the client resumes from the log when it reconnects
+			delete(log.subscribers, sub)
+			close(sub.c)
+		}
+	}
+	return nil
+}
+
+// HasSubscribers returns true when a client listens to the events of the estate
+func (h *Hub) HasSubscribers(estateId string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	log, ok := h.estates[estateId]
+	return ok && len(log.subscribers) > 0
+}
+
+// Subscribe listen to the events of the estate published after the event lastEventId, empty for the events
+// published from now on. ErrTooManyEstates is returned when the hub has no room for the estate.
+func (h *Hub) Subscribe(estateId string, lastEventId string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan Event, h.bufferSize)
+	sub := &Subscription{C: c, c: c, hub: h, estateId: estateId}
+	if h.closed {
+		close(c)
+		return sub, nil
+	}
+	log, ok := h.estateLog(estateId)
+	if !ok {
+		return nil, ErrTooManyEstates
+	}
+	sub.LastEventId = h.eventId(h.seq)
+	sub.Backlog, sub.Resumed = h.since(log, lastEventId)
+	log.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close stop the subscription, C is closed
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	log, ok := s.hub.estates[s.estateId]
+	if !ok {
+		return
+	}
+	if _, ok := log.subscribers[s]; ok {
+		delete(log.subscribers, s)
+		close(s.c)
+	}
+}
+
+// Close stop every subscription, the streams end so the server can shut down
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, log := range h.estates {
+		for sub := range log.subscribers {
+			close(sub.c)
+		}
+		log.subscribers = map[*Subscription]struct{}{}
+	}
+}
+
+// estateLog returns the log of the estate, created when there is room for it, dropping the log of an estate
+// without subscriber when the hub is full
+func (h *Hub) estateLog(estateId string) (*estateLog, bool) {
+	if log, ok := h.estates[estateId]; ok {
+		return log, true
+	}
+	if len(h.estates) >= h.maxEstates {
+		for id, log := range h.estates {
+			if len(log.subscribers) == 0 {
+				delete(h.estates, id)
+				break
+			}
+		}
+		if len(h.estates) >= h.maxEstates {
+			return nil, false
+		}
+	}
+	log := &estateLog{floor: h.seq, subscribers: map[*Subscription]struct{}{}}
+	h.estates[estateId] = log
+	return log, true
+}
+
+func (h *Hub) eventId(seq uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// since returns the events of the log after the event lastEventId, and false when some of them are not in the log
+func (h *Hub) since(log *estateLog, lastEventId string) ([]Event, bool) {
+	epoch, s, ok := strings.Cut(lastEventId, "-")
+	if !ok || epoch != h.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || seq < log.floor || seq > h.seq {
+		return nil, false
+	}
+	var backlog []Event
+	for _, logged := range log.events {
+		if logged.seq > seq {
+			backlog = append(backlog, logged.event)
+		}
+	}
+	return backlog, true
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func payloads(events []Event) []string {
+	result := []string{}
+	for _, event := range events {
+		result = append(result, string(event.Data))
+	}
+	return result
+}
+
+func subscribe(t *testing.T, h *Hub, estateId string, lastEventId string) *Subscription {
+	sub, err := h.Subscribe(estateId, lastEventId)
+	require.NoError(t, err)
+	return sub
+}
+
+func TestHub_PublishSubscribe(t *testing.T) {
+	h := NewHub(NewHubOptions{})
+	sub := subscribe(t, h, "estate", "")
+	defer sub.Close()
+	other := subscribe(t, h, "other", "")
+	defer other.Close()
+	assert.False(t, sub.Resumed)
+	assert.True(t, h.HasSubscribers("estate"))
+
+	require.NoError(t, h.Publish("estate", TypeTreeCreated, map[string]int{"x": 1}))
+	event := <-sub.C
+	assert.Equal(t, TypeTreeCreated, event.Type)
+	assert.Equal(t, `{"x":1}`, string(event.Data))
+	assert.Len(t, other.C, 0)
+
+	sub.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	assert.False(t, h.HasSubscribers("estate"))
+}
+
+func TestHub_Resume(t *testing.T) {
+	h := NewHub(NewHubOptions{LogSize: 3})
+	first := subscribe(t, h, "estate", "")
+	for _, height := range []string{"1", "2"} {
+		require.NoError(t, h.Publish("estate", TypeTreeCreated, height))
+	}
+	<-first.C
+	second := <-first.C
+	first.Close()
+
+	// events of another estate share the sequence
+	require.NoError(t, h.Publish("other", TypeTreeCreated, "0"))
+	require.NoError(t, h.Publish("estate", TypeTreeCreated, "3"))
+
+	testCases := []struct {
+		name            string
+		lastEventId     string
+		expectedResumed bool
+		expectedBacklog []string
+	}{
+		{
+			name:            "WITHIN_THE_LOG",
+			lastEventId:     second.Id,
+			expectedResumed: true,
+			expectedBacklog: []string{`"3"`},
+		},
+		{
+			name:            "FROM_THE_FIRST_EVENT",
+			lastEventId:     first.LastEventId,
+			expectedResumed: true,
+			expectedBacklog: []string{`"1"`, `"2"`, `"3"`},
+		},
+		{
+			name:            "UP_TO_DATE",
+			lastEventId:     subscribe(t, h, "estate", "").LastEventId,
+			expectedResumed: true,
+			expectedBacklog: []string{},
+		},
+		{
+			name:        "ANOTHER_PROCESS",
+			lastEventId: "otherepoch-1",
+		},
+		{
+			name:        "INVALID",
+			lastEventId: "garbage",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub := subscribe(t, h, "estate", tc.lastEventId)
+			defer sub.Close()
+			assert.Equal(t, tc.expectedResumed, sub.Resumed)
+			if tc.expectedResumed {
+				assert.Equal(t, tc.expectedBacklog, payloads(sub.Backlog))
+			}
+		})
+	}
+
+	// the first event is out of the log once a fourth is published
+	require.NoError(t, h.Publish("estate", TypeTreeCreated, "4"))
+	sub := subscribe(t, h, "estate", first.LastEventId)
+	defer sub.Close()
+	assert.False(t, sub.Resumed)
+}
+
+func TestHub_SlowSubscriber(t *testing.T) {
+	h := NewHub(NewHubOptions{BufferSize: 1})
+	sub := subscribe(t, h, "estate", "")
+	require.NoError(t, h.Publish("estate", TypeTreeCreated, 1))
+	require.NoError(t, h.Publish("estate", TypeTreeCreated, 2))
+
+	// the first event is buffered, then the subscription is closed
+	_, ok := <-sub.C
+	assert.True(t, ok)
+	_, ok = <-sub.C
+	assert.False(t, ok)
+	assert.False(t, h.HasSubscribers("estate"))
+	sub.Close()
+}
+
+func TestHub_MaxEstates(t *testing.T) {
+	h := NewHub(NewHubOptions{MaxEstates: 2})
+	first := subscribe(t, h, "first", "")
+	second := subscribe(t, h, "second", "")
+
+	// every estate has a subscriber, the hub does not grow past its cap
+	_, err := h.Subscribe("third", "")
+	assert.ErrorIs(t, err, ErrTooManyEstates)
+	require.NoError(t, h.Publish("third", TypeTreeCreated, 1))
+	assert.Len(t, h.estates, 2)
+
+	// the log of an estate without subscriber is dropped for the new estate
+	second.Close()
+	third := subscribe(t, h, "third", "")
+	assert.Len(t, h.estates, 2)
+	assert.True(t, h.HasSubscribers("first"))
+	assert.False(t, h.HasSubscribers("second"))
+
+	first.Close()
+	third.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	h := NewHub(NewHubOptions{})
+	sub := subscribe(t, h, "estate", "")
+	h.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	sub.Close()
+
+	_, ok = <-subscribe(t, h, "estate", "").C
+	assert.False(t, ok)
+	assert.NoError(t, h.Publish("estate", TypeTreeCreated, 1))
+
+	var nilHub *Hub
+	assert.NoError(t, nilHub.Publish("estate", TypeTreeCreated, 1))
+	assert.False(t, nilHub.HasSubscribers("estate"))
+}
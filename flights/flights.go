@@ -0,0 +1,433 @@
+// This file contains the parsing of the flight logs uploaded after a patrol and their comparison with the
+// planned route.
+//
+// A flight log is a list of timestamped positions: x and y are plot coordinates, (1, 1) being the center of
+// the south west plot and a plot being 10 meters wide, the altitude is in meters above the ground and the
+// battery is the optional charge left in percent.
+package flights
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const (
+	// MaxPoints is the max number of positions of a flight log
+	MaxPoints = 100000
+	// MaxRowsCrossed is the max number of rows crossed by a flight log, 20 sweeps of a max size estate. It bounds
+	// the work of a comparison, which follows the flight row by row.
+	MaxRowsCrossed = 1000000
+	// MaxMissedPlots is the max number of missed plots listed by a comparison, the count is always exact
+	MaxMissedPlots = 100
+
+	plotDistance = 10 // meters between two plots
+	// maxCoordinate bound the positions to the max size estate plus a margin
+	maxCoordinate = 50001
+	// flyOver is the altitude of the drone above the ground or the top of the tree
+	flyOver = 1
+)
+
+var (
+	ErrNoPoints      = errors.New("flight log has no position")
+	ErrTooManyPoints = fmt.Errorf("flight log has more than %d positions", MaxPoints)
+	ErrTooManyRows   = fmt.Errorf("flight log crosses more than %d rows", MaxRowsCrossed)
+)
+
+type jsonLog struct {
+	Points []jsonPoint `json:"points"`
+}
+
+type jsonPoint struct {
+	Timestamp *time.Time `json:"timestamp"`
+	X         *float64   `json:"x"`
+	Y         *float64   `json:"y"`
+	Altitude  *float64   `json:"altitude"`
+	Battery   *float64   `json:"battery"`
+}
+
+// ParseJSON read a flight log of the form {"points": [{"timestamp", "x", "y", "altitude", "battery"}]}
+func ParseJSON(r io.Reader) ([]repository.FlightPoint, error) {
+	var log jsonLog
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, fmt.Errorf("invalid flight log: %w", err)
+	}
+
+	points := make([]repository.FlightPoint, 0, len(log.Points))
+	for i, p := range log.Points {
+		if p.Timestamp == nil || p.X == nil || p.Y == nil || p.Altitude == nil {
+			return nil, fmt.Errorf("position %d: timestamp, x, y and altitude are required", i+1)
+		}
+		points = append(points, repository.FlightPoint{
+			Seq:        i + 1,
+			RecordedAt: p.Timestamp.UTC(),
+			X:          *p.X,
+			Y:          *p.Y,
+			Altitude:   *p.Altitude,
+			Battery:    p.Battery,
+		})
+	}
+	return points, Validate(points)
+}
+
+// ParseCSV read a flight log with a header line naming the timestamp, x, y, altitude and optional battery
+// columns, in any order. Timestamps are RFC 3339.
+func ParseCSV(r io.Reader) ([]repository.FlightPoint, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoPoints
+		}
+		return nil, fmt.Errorf("invalid flight log: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"timestamp", "x", "y", "altitude"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("invalid flight log: missing %s column", name)
+		}
+	}
+	batteryColumn, hasBattery := columns["battery"]
+
+	var points []repository.FlightPoint
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid flight log: %w", err)
+		}
+		if len(points) == MaxPoints {
+			return nil, ErrTooManyPoints
+		}
+
+		line := len(points) + 2
+		point := repository.FlightPoint{Seq: len(points) + 1}
+		if point.RecordedAt, err = time.Parse(time.RFC3339, record[columns["timestamp"]]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp", line)
+		}
+		point.RecordedAt = point.RecordedAt.UTC()
+		for name, v := range map[string]*float64{"x": &point.X, "y": &point.Y, "altitude": &point.Altitude} {
+			if *v, err = strconv.ParseFloat(record[columns[name]], 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s", line, name)
+			}
+		}
+		if hasBattery && record[batteryColumn] != "" {
+			battery, err := strconv.ParseFloat(record[batteryColumn], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid battery", line)
+			}
+			point.Battery = &battery
+		}
+		points = append(points, point)
+	}
+	return points, Validate(points)
+}
+
+// Validate check the positions are in chronological order and in range, and the flight does not cross more
+// than MaxRowsCrossed rows
+func Validate(points []repository.FlightPoint) error {
+	if len(points) == 0 {
+		return ErrNoPoints
+	}
+	if len(points) > MaxPoints {
+		return ErrTooManyPoints
+	}
+	rowsCrossed := 0
+	for i, p := range points {
+		if i > 0 && p.RecordedAt.Before(points[i-1].RecordedAt) {
+			return fmt.Errorf("position %d: timestamps are not in chronological order", i+1)
+		}
+		if !inRange(p.X, 0, maxCoordinate) || !inRange(p.Y, 0, maxCoordinate) {
+			return fmt.Errorf("position %d: x and y must be between 0 and %d", i+1, maxCoordinate)
+		}
+		if !inRange(p.Altitude, 0, math.MaxFloat64) {
+			return fmt.Errorf("position %d: altitude must be positive", i+1)
+		}
+		if p.Battery != nil && !inRange(*p.Battery, 0, 100) {
+			return fmt.Errorf("position %d: battery must be between 0 and 100", i+1)
+		}
+		if i > 0 {
+			rowsCrossed += abs(round(p.Y) - round(points[i-1].Y))
+			if rowsCrossed > MaxRowsCrossed {
+				return ErrTooManyRows
+			}
+		}
+	}
+	return nil
+}
+
+// Distance returns the meters flown along the positions
+func Distance(points []repository.FlightPoint) float64 {
+	distance := 0.0
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		distance += math.Sqrt(
+			math.Pow((b.X-a.X)*plotDistance, 2) +
+				math.Pow((b.Y-a.Y)*plotDistance, 2) +
+				math.Pow(b.Altitude-a.Altitude, 2),
+		)
+	}
+	return distance
+}
+
+// BatteryUsed returns the charge used between the first and last positions reporting the battery, nil
+// when less than two positions report it
+func BatteryUsed(points []repository.FlightPoint) *float64 {
+	var first, last *float64
+	for _, p := range points {
+		if p.Battery == nil {
+			continue
+		}
+		if first == nil {
+			first = p.Battery
+		}
+		last = p.Battery
+	}
+	if first == nil || first == last {
+		return nil
+	}
+	used := *first - *last
+	return &used
+}
+
+type Comparison struct {
+	PlannedDistance int
+	ActualDistance  float64
+	// DistanceDifference is the actual minus the planned distance
+	DistanceDifference float64
+	// MaxDeviation and MeanDeviation are the horizontal meters between the positions and the planned route
+	MaxDeviation  float64
+	MeanDeviation float64
+	// MaxAltitudeDeviation and MeanAltitudeDeviation are the meters between the altitude of the positions
+	// and the planned altitude, 1 meter above the ground or the top of the tree
+	MaxAltitudeDeviation  float64
+	MeanAltitudeDeviation float64
+	PlotsVisited          int
+	PlotsMissed           int
+	// MissedPlots are the first missed plots in the route order, at most MaxMissedPlots
+	MissedPlots []planner.Plot
+	BatteryUsed *float64
+}
+
+// Compare a flight with the planned route of the estate, p is the planner of the estate with its trees added
+func Compare(estate repository.Estate, p *planner.Planner, points []repository.FlightPoint) Comparison {
+	c := Comparison{
+		PlannedDistance: p.Plan(nil).Distance,
+		ActualDistance:  Distance(points),
+		BatteryUsed:     BatteryUsed(points),
+	}
+	c.DistanceDifference = c.ActualDistance - float64(c.PlannedDistance)
+
+	for _, point := range points {
+		deviation, _ := routePosition(estate, point.X, point.Y)
+		height := p.Height(clamp(round(point.X), 1, estate.Length), clamp(round(point.Y), 1, estate.Width))
+		altitudeDeviation := math.Abs(point.Altitude - float64(height+flyOver))
+
+		c.MaxDeviation = math.Max(c.MaxDeviation, deviation)
+		c.MeanDeviation += deviation / float64(len(points))
+		c.MaxAltitudeDeviation = math.Max(c.MaxAltitudeDeviation, altitudeDeviation)
+		c.MeanAltitudeDeviation += altitudeDeviation / float64(len(points))
+	}
+
+	visited := visitedPlots(estate, points)
+	for _, intervals := range visited {
+		for _, in := range intervals {
+			c.PlotsVisited += in.to - in.from + 1
+		}
+	}
+	c.PlotsMissed = estate.Width*estate.Length - c.PlotsVisited
+	c.MissedPlots = missedPlots(estate, visited, MaxMissedPlots)
+	return c
+}
+
+// Track returns the horizontal meters between a position and the planned route, and the progress along the
+// route in percent of its horizontal length at the closest point of the route
+func Track(estate repository.Estate, x, y float64) (deviation float64, progress float64) {
+	deviation, along := routePosition(estate, x, y)
+	length := float64(estate.Width*estate.Length - 1)
+	if length <= 0 {
+		return deviation, 100
+	}
+	return deviation, along / length * 100
+}
+
+// routePosition returns the horizontal meters between a position and the closest point of the route, and the
+// plots flown along the route to that point. The route follows every row from x = 1 to x = length, and moves
+// to the next row at the east end after an odd row and at the west end after an even row.
+func routePosition(estate repository.Estate, x, y float64) (float64, float64) {
+	length, width := float64(estate.Length), float64(estate.Width)
+
+	// closest row
+	row := clamp(round(y), 1, estate.Width)
+	closestX := math.Max(1, math.Min(x, length))
+	deviation := math.Hypot(x-closestX, y-float64(row))
+	// a row starts after the rows before and the moves between them, length plots each
+	along := float64(row-1) * length
+	if row%2 == 1 {
+		along += closestX - 1
+	} else {
+		along += length - closestX
+	}
+
+	// move between the two rows around the position
+	if y > 1 && y < width {
+		between := math.Floor(y)
+		column := length
+		if int(between)%2 == 0 {
+			column = 1
+		}
+		if moveDeviation := math.Abs(x - column); moveDeviation < deviation {
+			deviation = moveDeviation
+			along = between*length - 1 + (y - between)
+		}
+	}
+	return deviation * plotDistance, along
+}
+
+// interval is a range of visited plots of a row, from and to included
+type interval struct {
+	from, to int
+}
+
+// visitedPlots returns the merged intervals of visited plots by row. The drone is considered flying over
+// every plot crossed by the straight line between two positions. The segments are clipped to the rows of the
+// estate, and the rows followed are bounded by MaxRowsCrossed for the logs stored before it was checked.
+func visitedPlots(estate repository.Estate, points []repository.FlightPoint) map[int][]interval {
+	rows := map[int][]interval{}
+	mark := func(y int, x1, x2 float64) {
+		if y < 1 || y > estate.Width {
+			return
+		}
+		from, to := round(math.Min(x1, x2)), round(math.Max(x1, x2))
+		if to < 1 || from > estate.Length {
+			return
+		}
+		in := interval{from: clamp(from, 1, estate.Length), to: clamp(to, 1, estate.Length)}
+		// the positions follow each other, an interval mostly extends the last one of its row
+		if n := len(rows[y]); n > 0 {
+			last := &rows[y][n-1]
+			if in.from <= last.to+1 && in.to >= last.from-1 {
+				last.from, last.to = min(last.from, in.from), max(last.to, in.to)
+				return
+			}
+		}
+		rows[y] = append(rows[y], in)
+	}
+
+	mark(round(points[0].Y), points[0].X, points[0].X)
+	rowsCrossed := 0
+	for i := 1; i < len(points) && rowsCrossed <= MaxRowsCrossed; i++ {
+		a, b := points[i-1], points[i]
+		dx, dy := b.X-a.X, b.Y-a.Y
+		if dy == 0 {
+			mark(round(a.Y), a.X, b.X)
+			continue
+		}
+		// the part of the segment in the band of each row of the estate it crosses
+		first := max(round(math.Min(a.Y, b.Y)), 1)
+		last := min(round(math.Max(a.Y, b.Y)), estate.Width)
+		rowsCrossed += max(last-first, 0)
+		for y := first; y <= last; y++ {
+			t1 := (float64(y) - 0.5 - a.Y) / dy
+			t2 := (float64(y) + 0.5 - a.Y) / dy
+			t1, t2 = math.Max(0, math.Min(t1, t2)), math.Min(1, math.Max(t1, t2))
+			if t1 > t2 {
+				continue
+			}
+			mark(y, a.X+dx*t1, a.X+dx*t2)
+		}
+	}
+
+	for y, intervals := range rows {
+		sort.Slice(intervals, func(i, j int) bool { return intervals[i].from < intervals[j].from })
+		merged := intervals[:1]
+		for _, in := range intervals[1:] {
+			last := &merged[len(merged)-1]
+			if in.from <= last.to+1 {
+				last.to = max(last.to, in.to)
+				continue
+			}
+			merged = append(merged, in)
+		}
+		rows[y] = merged
+	}
+	return rows
+}
+
+// missedPlots returns the first plots not visited, in the route order
+func missedPlots(estate repository.Estate, visited map[int][]interval, limit int) []planner.Plot {
+	missed := []planner.Plot{}
+	for y := 1; y <= estate.Width && len(missed) < limit; y++ {
+		// gaps between the visited intervals, west to east
+		var gaps []interval
+		next := 1
+		for _, in := range visited[y] {
+			if in.from > next {
+				gaps = append(gaps, interval{from: next, to: in.from - 1})
+			}
+			next = in.to + 1
+		}
+		if next <= estate.Length {
+			gaps = append(gaps, interval{from: next, to: estate.Length})
+		}
+
+		if y%2 == 1 {
+			for _, gap := range gaps {
+				for x := gap.from; x <= gap.to && len(missed) < limit; x++ {
+					missed = append(missed, planner.Plot{X: x, Y: y})
+				}
+			}
+		} else {
+			// even rows are flown east to west
+			for i := len(gaps) - 1; i >= 0; i-- {
+				for x := gaps[i].to; x >= gaps[i].from && len(missed) < limit; x-- {
+					missed = append(missed, planner.Plot{X: x, Y: y})
+				}
+			}
+		}
+	}
+	return missed
+}
+
+func inRange(v, min, max float64) bool {
+	return !math.IsNaN(v) && v >= min && v <= max
+}
+
+// round returns the plot of a coordinate, a plot spans half a plot around its center
+func round(v float64) int {
+	return int(math.Floor(v + 0.5))
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
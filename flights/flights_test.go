@@ -0,0 +1,297 @@
+package flights
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// flight returns one position per second at the given (x, y, altitude)
+func flight(positions ...[3]float64) []repository.FlightPoint {
+	points := make([]repository.FlightPoint, 0, len(positions))
+	for i, p := range positions {
+		points = append(points, repository.FlightPoint{
+			Seq:        i + 1,
+			RecordedAt: start.Add(time.Duration(i) * time.Second),
+			X:          p[0],
+			Y:          p[1],
+			Altitude:   p[2],
+		})
+	}
+	return points
+}
+
+func TestParseCSV(t *testing.T) {
+	testCases := []struct {
+		name           string
+		log            string
+		expectedPoints []repository.FlightPoint
+		expectedError  string
+	}{
+		{
+			name: "OK",
+			log: "timestamp,x,y,altitude,battery\n" +
+				"2024-01-01T13:00:00+07:00,1,1,1,100\n" +
+				"2024-01-01T06:00:05Z,2.5,1,1.5,\n",
+			expectedPoints: []repository.FlightPoint{
+				{Seq: 1, RecordedAt: start, X: 1, Y: 1, Altitude: 1, Battery: floatPtr(100)},
+				{Seq: 2, RecordedAt: start.Add(5 * time.Second), X: 2.5, Y: 1, Altitude: 1.5},
+			},
+		},
+		{
+			name:           "COLUMNS_IN_ANY_ORDER_WITHOUT_BATTERY",
+			log:            "altitude, y, x, timestamp\n2,3,4,2024-01-01T06:00:00Z\n",
+			expectedPoints: []repository.FlightPoint{{Seq: 1, RecordedAt: start, X: 4, Y: 3, Altitude: 2}},
+		},
+		{
+			name:          "EMPTY",
+			log:           "",
+			expectedError: "flight log has no position",
+		},
+		{
+			name:          "MISSING_COLUMN",
+			log:           "timestamp,x,y\n",
+			expectedError: "invalid flight log: missing altitude column",
+		},
+		{
+			name:          "INVALID_VALUE",
+			log:           "timestamp,x,y,altitude\n2024-01-01T06:00:00Z,1,one,1\n",
+			expectedError: "line 2: invalid y",
+		},
+		{
+			name: "NOT_CHRONOLOGICAL",
+			log: "timestamp,x,y,altitude\n" +
+				"2024-01-01T06:00:05Z,1,1,1\n" +
+				"2024-01-01T06:00:00Z,1,1,1\n",
+			expectedError: "position 2: timestamps are not in chronological order",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			points, err := ParseCSV(strings.NewReader(tc.log))
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPoints, points)
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	points, err := ParseJSON(strings.NewReader(`{"points":[{"timestamp":"2024-01-01T06:00:00Z","x":1,"y":1,"altitude":1,"battery":99.5}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []repository.FlightPoint{{Seq: 1, RecordedAt: start, X: 1, Y: 1, Altitude: 1, Battery: floatPtr(99.5)}}, points)
+
+	_, err = ParseJSON(strings.NewReader(`{"points":[{"timestamp":"2024-01-01T06:00:00Z","x":1,"y":1}]}`))
+	assert.EqualError(t, err, "position 1: timestamp, x, y and altitude are required")
+
+	_, err = ParseJSON(strings.NewReader(`{"points":[{"timestamp":"2024-01-01T06:00:00Z","x":1,"y":1,"altitude":1,"battery":101}]}`))
+	assert.EqualError(t, err, "position 1: battery must be between 0 and 100")
+
+	_, err = ParseJSON(strings.NewReader(`{"points":[]}`))
+	assert.ErrorIs(t, err, ErrNoPoints)
+}
+
+func TestDistanceAndBatteryUsed(t *testing.T) {
+	points := flight([3]float64{1, 1, 1}, [3]float64{2, 1, 1}, [3]float64{2, 1, 6}, [3]float64{2, 4, 2})
+	assert.InDelta(t, 10+5+math.Sqrt(900+16), Distance(points), 1e-9)
+
+	assert.Nil(t, BatteryUsed(points))
+	points[0].Battery = floatPtr(100)
+	assert.Nil(t, BatteryUsed(points))
+	points[3].Battery = floatPtr(87.5)
+	assert.Equal(t, floatPtr(12.5), BatteryUsed(points))
+}
+
+func TestCompare(t *testing.T) {
+	estate := repository.Estate{Width: 2, Length: 5}
+	route := planner.New(estate.Width, estate.Length)
+	route.AddTree(3, 1, 5)
+	route.AddTree(3, 2, 5)
+
+	testCases := []struct {
+		name               string
+		points             []repository.FlightPoint
+		expectedComparison Comparison
+	}{
+		{
+			name: "FOLLOWED_THE_PLAN",
+			points: flight(
+				[3]float64{1, 1, 1}, [3]float64{2, 1, 1}, [3]float64{3, 1, 6}, [3]float64{4, 1, 1}, [3]float64{5, 1, 1},
+				[3]float64{5, 2, 1}, [3]float64{4, 2, 1}, [3]float64{3, 2, 6}, [3]float64{2, 2, 1}, [3]float64{1, 2, 1},
+			),
+			expectedComparison: Comparison{
+				PlannedDistance:    112,
+				ActualDistance:     50 + 4*math.Sqrt(125),
+				DistanceDifference: 50 + 4*math.Sqrt(125) - 112,
+				PlotsVisited:       10,
+				MissedPlots:        []planner.Plot{},
+			},
+		},
+		{
+			name:   "STOPPED_EARLY_OFF_ROUTE",
+			points: flight([3]float64{1, 1, 1}, [3]float64{3, 1.5, 1}),
+			expectedComparison: Comparison{
+				PlannedDistance:    112,
+				ActualDistance:     math.Sqrt(400 + 25),
+				DistanceDifference: math.Sqrt(400+25) - 112,
+				// the second position is half a plot away from the first row
+				MaxDeviation:  5,
+				MeanDeviation: 2.5,
+				// and 5 meters under the top of the tree
+				MaxAltitudeDeviation:  5,
+				MeanAltitudeDeviation: 2.5,
+				PlotsVisited:          4,
+				PlotsMissed:           6,
+				MissedPlots: []planner.Plot{
+					{X: 4, Y: 1}, {X: 5, Y: 1}, {X: 5, Y: 2}, {X: 4, Y: 2}, {X: 2, Y: 2}, {X: 1, Y: 2},
+				},
+			},
+		},
+		{
+			name:   "OUTSIDE_THE_ESTATE",
+			points: flight([3]float64{7, 1, 1}, [3]float64{7, 2, 1}),
+			expectedComparison: Comparison{
+				PlannedDistance:    112,
+				ActualDistance:     10,
+				DistanceDifference: 10 - 112,
+				MaxDeviation:       20,
+				MeanDeviation:      20,
+				PlotsMissed:        10,
+				MissedPlots: []planner.Plot{
+					{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}, {X: 4, Y: 1}, {X: 5, Y: 1},
+					{X: 5, Y: 2}, {X: 4, Y: 2}, {X: 3, Y: 2}, {X: 2, Y: 2}, {X: 1, Y: 2},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Compare(estate, route, tc.points)
+			assert.InDelta(t, tc.expectedComparison.ActualDistance, c.ActualDistance, 1e-9)
+			assert.InDelta(t, tc.expectedComparison.DistanceDifference, c.DistanceDifference, 1e-9)
+			assert.InDelta(t, tc.expectedComparison.MaxDeviation, c.MaxDeviation, 1e-9)
+			assert.InDelta(t, tc.expectedComparison.MeanDeviation, c.MeanDeviation, 1e-9)
+			assert.InDelta(t, tc.expectedComparison.MaxAltitudeDeviation, c.MaxAltitudeDeviation, 1e-9)
+			assert.InDelta(t, tc.expectedComparison.MeanAltitudeDeviation, c.MeanAltitudeDeviation, 1e-9)
+			assert.Equal(t, tc.expectedComparison.PlannedDistance, c.PlannedDistance)
+			assert.Equal(t, tc.expectedComparison.PlotsVisited, c.PlotsVisited)
+			assert.Equal(t, tc.expectedComparison.PlotsMissed, c.PlotsMissed)
+			assert.Equal(t, tc.expectedComparison.MissedPlots, c.MissedPlots)
+		})
+	}
+}
+
+func TestCompare_MaxSizeEstate(t *testing.T) {
+	estate := repository.Estate{Width: 50000, Length: 50000}
+	// sweep the whole first row then cut diagonally across the estate
+	c := Compare(estate, planner.New(estate.Width, estate.Length), flight([3]float64{1, 1, 1}, [3]float64{50000, 1, 1}, [3]float64{1, 50000, 1}))
+	// the first row and about two plots per row along the diagonal
+	assert.InDelta(t, 50000+2*49999, c.PlotsVisited, 50000)
+	assert.Equal(t, 50000*50000-c.PlotsVisited, c.PlotsMissed)
+	assert.Len(t, c.MissedPlots, MaxMissedPlots)
+	// the second row is flown east to west, the diagonal starts from its east end
+	assert.Equal(t, planner.Plot{X: 49998, Y: 2}, c.MissedPlots[0])
+}
+
+// zigzag returns a flight log crossing the whole max size estate back and forth on every position
+func zigzag(positions int) []repository.FlightPoint {
+	points := make([]repository.FlightPoint, 0, positions)
+	for i := 0; i < positions; i++ {
+		y := 0.0
+		if i%2 == 1 {
+			y = maxCoordinate
+		}
+		points = append(points, repository.FlightPoint{
+			Seq:        i + 1,
+			RecordedAt: start.Add(time.Duration(i) * time.Second),
+			X:          float64(i%50000 + 1),
+			Y:          y,
+			Altitude:   1,
+		})
+	}
+	return points
+}
+
+func TestValidate_TooManyRows(t *testing.T) {
+	// 100 crossings of the max size estate, 5 millions rows
+	assert.ErrorIs(t, Validate(zigzag(101)), ErrTooManyRows)
+	assert.NoError(t, Validate(zigzag(20)))
+
+	var log strings.Builder
+	log.WriteString("timestamp,x,y,altitude\n")
+	for _, p := range zigzag(101) {
+		fmt.Fprintf(&log, "%s,%g,%g,%g\n", p.RecordedAt.Format(time.RFC3339), p.X, p.Y, p.Altitude)
+	}
+	_, err := ParseCSV(strings.NewReader(log.String()))
+	assert.ErrorIs(t, err, ErrTooManyRows)
+}
+
+func TestCompare_Pathological(t *testing.T) {
+	// a log stored before the rows were bounded, every position crossing the estate
+	points := zigzag(MaxPoints)
+
+	// the segments are clipped to the rows of a small estate
+	estate := repository.Estate{Width: 2, Length: 5}
+	c := Compare(estate, planner.New(estate.Width, estate.Length), points)
+	assert.Equal(t, 10, c.PlotsVisited+c.PlotsMissed)
+	assert.NotZero(t, c.PlotsVisited)
+
+	// and the rows followed are bounded on a max size estate
+	estate = repository.Estate{Width: 50000, Length: 50000}
+	done := make(chan Comparison)
+	go func() { done <- Compare(estate, planner.New(estate.Width, estate.Length), points) }()
+	select {
+	case c = <-done:
+		assert.Equal(t, estate.Width*estate.Length-c.PlotsVisited, c.PlotsMissed)
+	case <-time.After(20 * time.Second):
+		t.Fatal("the comparison of a pathological log does not end")
+	}
+}
+
+func TestTrack(t *testing.T) {
+	estate := repository.Estate{Length: 5, Width: 3}
+
+	testCases := []struct {
+		name              string
+		x, y              float64
+		expectedDeviation float64
+		expectedProgress  float64
+	}{
+		{name: "START", x: 1, y: 1, expectedDeviation: 0, expectedProgress: 0},
+		{name: "FIRST_ROW", x: 3, y: 1, expectedDeviation: 0, expectedProgress: 2.0 / 14 * 100},
+		{name: "MOVE_TO_THE_SECOND_ROW", x: 5, y: 1.5, expectedDeviation: 0, expectedProgress: 4.5 / 14 * 100},
+		{name: "SECOND_ROW_FLOWN_WEST", x: 4, y: 2, expectedDeviation: 0, expectedProgress: 6.0 / 14 * 100},
+		{name: "END", x: 5, y: 3, expectedDeviation: 0, expectedProgress: 100},
+		{name: "BETWEEN_ROWS", x: 3, y: 2.5, expectedDeviation: 5, expectedProgress: 12.0 / 14 * 100},
+		{name: "OUT_OF_THE_ESTATE", x: 7, y: 1, expectedDeviation: 20, expectedProgress: 4.0 / 14 * 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deviation, progress := Track(estate, tc.x, tc.y)
+			assert.InDelta(t, tc.expectedDeviation, deviation, 1e-9)
+			assert.InDelta(t, tc.expectedProgress, progress, 1e-9)
+		})
+	}
+
+	_, progress := Track(repository.Estate{Length: 1, Width: 1}, 1, 1)
+	assert.Equal(t, 100.0, progress)
+}
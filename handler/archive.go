@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/labstack/echo/v4"
+)
+
+// maxArchiveSize is the size of the archive of an estate of about half a million trees
+const maxArchiveSize = 64 << 20
+
+const contentTypeGzip = "application/gzip"
+
+func (s *Server) GetEstateIdExport(ctx echo.Context, id string, params generated.GetEstateIdExportParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	format := service.ArchiveFormatJSON
+	if params.Format != nil {
+		format = string(*params.Format)
+	}
+	if format != service.ArchiveFormatJSON && format != service.ArchiveFormatTarGz {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: "format must be json or tar.gz"})
+	}
+
+	reqCtx := ctx.Request().Context()
+	archive, trees, err := s.Estates.Export(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	contentType := echo.MIMEApplicationJSON
+	if format == service.ArchiveFormatTarGz {
+		contentType = contentTypeGzip
+	}
+	ctx.Response().Header().Set(echo.HeaderContentType, contentType)
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="estate-%s.%s"`, id, format))
+	ctx.Response().WriteHeader(http.StatusOK)
+	// the status is sent, a failed write can only be logged
+	if err := service.WriteArchive(ctx.Response(), archive, trees, format); err != nil {
+		s.Logger.WarnContext(reqCtx, "estate export interrupted",
+			slog.String("estate_id", id),
+			slog.String("error", err.Error()),
+		)
+	}
+	return nil
+}
+
+func (s *Server) PostEstateImport(ctx echo.Context, params generated.PostEstateImportParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateManage) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+
+	// Parse the archive
+	format := service.ArchiveFormatJSON
+	if mediaType, _, _ := mime.ParseMediaType(ctx.Request().Header.Get(echo.HeaderContentType)); mediaType == contentTypeGzip || mediaType == "application/x-gzip" {
+		format = service.ArchiveFormatTarGz
+	}
+	body := http.MaxBytesReader(ctx.Response(), ctx.Request().Body, maxArchiveSize)
+	archive, err := service.ReadArchive(body, format)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return ctx.JSON(http.StatusRequestEntityTooLarge, generated.ErrorResponse{Message: "archive is too large"})
+		}
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Import Estate
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.Import(reqCtx, callerOrganisation(reqCtx), archive, params.PreserveIds != nil && *params.PreserveIds)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.ImportEstateResponse{Id: estate.Id, Trees: len(archive.Trees)})
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_GetEstateIdExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	// the generated wrapper binds the format parameter
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/export", wrapper.GetEstateIdExport)
+
+	expectEstate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+			Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 10, Width: 5, CreatedAt: createdAt}, nil)
+		mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: id}, gomock.Any()).
+			DoAndReturn(streamTrees([]repository.Tree{{Id: "t-1", X: 2, Y: 3, Height: 10, CreatedAt: createdAt}}))
+	}
+
+	testCases := []struct {
+		name                string
+		query               string
+		setupMocks          func()
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:  "NOT_FOUND",
+			query: "",
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: echo.MIMEApplicationJSON,
+			expectedBody:        `{"message":"estate is not found"}`,
+		},
+		{
+			name:                "INVALID_FORMAT",
+			query:               "?format=zip",
+			setupMocks:          func() {},
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: echo.MIMEApplicationJSON,
+		},
+		{
+			name:                "JSON",
+			query:               "",
+			setupMocks:          expectEstate,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: echo.MIMEApplicationJSON,
+			expectedBody: fmt.Sprintf(`"estate":{"id":"%s","length":10,"width":5,"created_at":"2024-01-01T00:00:00Z"},"trees":[{"id":"t-1","x":2,"y":3,"height":10,"created_at":"2024-01-01T00:00:00Z"}]}`,
+				id),
+		},
+		{
+			name:                "TAR_GZ",
+			query:               "?format=tar.gz",
+			setupMocks:          expectEstate,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: contentTypeGzip,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/export"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedContentType, strings.Split(rec.Header().Get(echo.HeaderContentType), ";")[0])
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "estate-"+id)
+			}
+			if tc.expectedContentType == contentTypeGzip {
+				archive, err := service.ReadArchive(rec.Body, service.ArchiveFormatTarGz)
+				require.NoError(t, err)
+				assert.Equal(t, id, archive.Estate.Id)
+				assert.Len(t, archive.Trees, 1)
+			}
+		})
+	}
+}
+
+func TestServer_PostEstateImport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	newId := uuid.New().String()
+	orgId := uuid.New().String()
+	archive := fmt.Sprintf(`{"version":1,"exported_at":"2024-01-01T00:00:00Z","estate":{"id":"%s","length":10,"width":5},"trees":[{"id":"%s","x":2,"y":3,"height":10}]}`,
+		id, uuid.New().String())
+	var tarGz bytes.Buffer
+	parsed, err := service.ReadArchive(strings.NewReader(archive), service.ArchiveFormatJSON)
+	require.NoError(t, err)
+	require.NoError(t, service.WriteArchive(&tarGz, parsed, parsed.VisitTrees, service.ArchiveFormatTarGz))
+
+	testCases := []struct {
+		name           string
+		role           rbac.Role
+		query          string
+		contentType    string
+		body           string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "FORBIDDEN",
+			role:        rbac.RoleViewer,
+			contentType: echo.MIMEApplicationJSON,
+			body:        archive,
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Return(repository.AuditLog{}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"forbidden"}`,
+		},
+		{
+			name:           "UNSUPPORTED_VERSION",
+			role:           rbac.RoleAdmin,
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"version":2}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"unsupported archive version 2, expected 1"}`,
+		},
+		{
+			name:        "ESTATE_EXIST",
+			role:        rbac.RoleAdmin,
+			query:       "?preserve-ids=true",
+			contentType: echo.MIMEApplicationJSON,
+			body:        archive,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).Return(repository.Estate{Id: id}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"message":"estate already exist"}`,
+		},
+		{
+			name:        "JSON",
+			role:        rbac.RoleAdmin,
+			contentType: echo.MIMEApplicationJSON,
+			body:        archive,
+			setupMocks: func() {
+				mockRepository.EXPECT().ImportEstate(gomock.Any(), repository.ImportEstateInput{
+					Estate: repository.Estate{OrganisationId: orgId, Length: 10, Width: 5},
+					Trees:  []repository.Tree{{X: 2, Y: 3, Height: 10}},
+				}).Return(repository.Estate{Id: newId}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   fmt.Sprintf(`{"id":"%s","trees":1}`, newId),
+		},
+		{
+			name:        "TAR_GZ",
+			role:        rbac.RoleAdmin,
+			contentType: contentTypeGzip,
+			body:        tarGz.String(),
+			setupMocks: func() {
+				mockRepository.EXPECT().ImportEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Id: newId}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   fmt.Sprintf(`{"id":"%s","trees":1}`, newId),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			e := echo.New()
+			e.Use(withRole(orgId, tc.role))
+			// the generated wrapper binds the preserve-ids parameter
+			wrapper := generated.ServerInterfaceWrapper{Handler: s}
+			e.POST("/estate/import", wrapper.PostEstateImport)
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/import"+tc.query, strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
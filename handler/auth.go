@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/auth"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const auditOutcomeDenied = "denied"
+
+// principal returns the authenticated caller set by the auth middleware
+func principal(ctx echo.Context) (auth.Principal, bool) {
+	return auth.PrincipalFromContext(ctx.Request().Context())
+}
+
+// callerOrganisation returns the organisation of the principal carried by ctx, empty without principal
+func callerOrganisation(ctx context.Context) string {
+	p, _ := auth.PrincipalFromContext(ctx)
+	return p.OrganisationId
+}
+
+// authorize check the role of the caller grants the permission, denied attempts are written to the audit log
+func (s *Server) authorize(ctx echo.Context, permission rbac.Permission) bool {
+	return s.authorizeResource(ctx.Request().Context(), permission, ctx.Request().Method+" "+ctx.Request().URL.Path)
+}
+
+// authorizeResource is authorize for the principal carried by reqCtx, resource names the operation in the
+// audit log
+func (s *Server) authorizeResource(reqCtx context.Context, permission rbac.Permission, resource string) bool {
+	p, ok := auth.PrincipalFromContext(reqCtx)
+	if ok && rbac.Allowed(p.Role, permission) {
+		return true
+	}
+
+	s.Logger.WarnContext(reqCtx, "permission denied",
+		slog.String("subject", p.Subject),
+		slog.String("organisation_id", p.OrganisationId),
+		slog.String("role", string(p.Role)),
+		slog.String("permission", string(permission)),
+		slog.String("resource", resource),
+	)
+	if !ok {
+		return false
+	}
+	_, err := s.Repository.CreateAuditLog(reqCtx, repository.AuditLog{
+		OrganisationId: p.OrganisationId,
+		Subject:        p.Subject,
+		Role:           string(p.Role),
+		Action:         string(permission),
+		Resource:       resource,
+		Outcome:        auditOutcomeDenied,
+	})
+	if err != nil {
+		s.Logger.ErrorContext(reqCtx, "failed to write audit log", slog.String("error", err.Error()))
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// estateETag is the entity tag of the responses computed from the estate trees, it changes
+// whenever a tree of the estate is created, updated or deleted
+func estateETag(estate repository.Estate) string {
+	return fmt.Sprintf(`"%s-%d"`, estate.Id, estate.Version)
+}
+
+// notModified set the ETag of the response and returns true when the client copy is still fresh
+func notModified(ctx echo.Context, etag string) bool {
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderCacheControl, "private, no-cache")
+	header.Set("ETag", etag)
+
+	for _, tag := range strings.Split(ctx.Request().Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
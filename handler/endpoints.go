@@ -1,96 +1,147 @@
 package handler
 
 import (
-	"github.com/SawitProRecruitment/UserService/repository"
-	"math"
+	"encoding/json"
+	"log/slog"
 	"net/http"
-	"sort"
+	"strings"
 
 	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
 	"github.com/labstack/echo/v4"
+	openapi_types "github.com/oapi-codegen/runtime/types"
 )
 
 func (s *Server) PostEstate(ctx echo.Context) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateManage) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
 	createEstateRequest := new(generated.CreateEstateRequest)
 	err := ctx.Bind(&createEstateRequest)
 	if err != nil {
 		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
 	}
-	// Request Validate
-	if err := s.Validator.Struct(createEstateRequest); err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
-	}
 
 	// Create Estate
-	output, err := s.Repository.CreateEstate(ctx.Request().Context(), repository.Estate{
-		Width:  createEstateRequest.Width,
+	reqCtx := ctx.Request().Context()
+	output, err := s.Estates.CreateEstate(reqCtx, callerOrganisation(reqCtx), service.CreateEstateRequest{
 		Length: createEstateRequest.Length,
+		Width:  createEstateRequest.Width,
 	})
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: err.Error()})
+		return s.serviceError(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, generated.CreateEstateResponse{Id: output.Id})
 }
 
+func (s *Server) GetEstate(ctx echo.Context, params generated.GetEstateParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(params); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	page, err := pagination.NewRequest(params.Cursor, params.Limit, defaultPageLimit, pagination.KindTimeId)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	reqCtx := ctx.Request().Context()
+	estates, err := s.Estates.ListEstates(reqCtx, callerOrganisation(reqCtx), page)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	response := generated.ListEstatesResponse{Estates: make([]generated.EstateResponse, 0, len(estates.Items))}
+	for _, estate := range estates.Items {
+		response.Estates = append(response.Estates, generated.EstateResponse{
+			Id:        estate.Id,
+			Width:     estate.Width,
+			Length:    estate.Length,
+			CreatedAt: estate.CreatedAt,
+		})
+	}
+	response.NextCursor = nextPage(ctx, estates.Next)
+	return ctx.JSON(http.StatusOK, response)
+}
+
 func (s *Server) GetEstateIdStats(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
 	// Request Validate
 	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
 		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
 	}
 
 	// Check estate exist
-	_, err := s.Repository.GetEstateById(ctx.Request().Context(), repository.GetEstateByIdInput{
-		Id: id,
-	})
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "estate is not found"})
-		} else {
-			return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: err.Error()})
-		}
+		return s.serviceError(ctx, err)
 	}
 
-	count, min, max, median := 0, 0, 0, 0
-	// Get list trees
-	trees, err := s.Repository.ListTreesByEstateId(ctx.Request().Context(), repository.ListTreesByEstateIdInput{
-		EstateId: id,
-	})
-	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: err.Error()})
+	etag := estateETag(estate)
+	if notModified(ctx, etag) {
+		return ctx.NoContent(http.StatusNotModified)
 	}
-	count = len(trees)
-	if count == 0 {
-		return ctx.JSON(http.StatusOK, generated.GetEstateStatsResponse{Count: count, Min: min, Max: max, Median: median})
+	stats, err := s.Trees.Stats(reqCtx, estate)
+	if err != nil {
+		return s.serviceError(ctx, err)
 	}
+	return ctx.JSON(http.StatusOK, statsResponse(stats))
+}
 
-	// find min, max
-	min, max = trees[0].Height, trees[0].Height
-	var heights []int
-	for _, tree := range trees {
-		if tree.Height < min {
-			min = tree.Height
-		}
-		if tree.Height > max {
-			max = tree.Height
+func statsResponse(stats service.Stats) generated.GetEstateStatsResponse {
+	response := generated.GetEstateStatsResponse{
+		Count:  stats.Count,
+		Max:    stats.Max,
+		Median: stats.Median,
+		Min:    stats.Min,
+	}
+	if len(stats.BySpecies) > 0 {
+		bySpecies := make([]generated.SpeciesStats, 0, len(stats.BySpecies))
+		for _, species := range stats.BySpecies {
+			bySpecies = append(bySpecies, generated.SpeciesStats{
+				Species: species.Species,
+				Count:   species.Count,
+				Max:     species.Max,
+				Median:  species.Median,
+				Min:     species.Min,
+			})
 		}
-		heights = append(heights, tree.Height)
+		response.BySpecies = &bySpecies
 	}
-
-	// find median
-	sort.Ints(heights)
-	if count%2 == 1 {
-		// Odd number of elements, return the middle one
-		median = int(float64(heights[count/2]))
+	if len(stats.ByHealth) > 0 {
+		byHealth := make([]generated.HealthStats, 0, len(stats.ByHealth))
+		for _, health := range stats.ByHealth {
+			byHealth = append(byHealth, generated.HealthStats{
+				Health: generated.TreeHealth(health.Health),
+				Count:  health.Count,
+				Max:    health.Max,
+				Median: health.Median,
+				Min:    health.Min,
+			})
+		}
+		response.ByHealth = &byHealth
 	}
-	// Even number of elements, return the average of the two middle ones
-	middle1 := heights[count/2-1]
-	middle2 := heights[count/2]
-	median = int(float64(middle1+middle2) / 2)
-	return ctx.JSON(http.StatusOK, generated.GetEstateStatsResponse{Count: count, Min: min, Max: max, Median: median})
+	return response
 }
 
 func (s *Server) PostEstateIdTree(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionTreeWrite) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
 	createTreeRequest := new(generated.CreateTreeRequest)
 	err := ctx.Bind(&createTreeRequest)
 	if err != nil {
@@ -100,141 +151,217 @@ func (s *Server) PostEstateIdTree(ctx echo.Context, id string) error {
 	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
 		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
 	}
-	if err := s.Validator.Struct(createTreeRequest); err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+
+	// Create Tree
+	reqCtx := ctx.Request().Context()
+	request := service.CreateTreeRequest{
+		Height:  createTreeRequest.Height,
+		X:       createTreeRequest.X,
+		Y:       createTreeRequest.Y,
+		Species: createTreeRequest.Species,
+		Variety: createTreeRequest.Variety,
+		Health:  (*string)(createTreeRequest.Health),
+	}
+	if createTreeRequest.PlantedAt != nil {
+		request.PlantedAt = &createTreeRequest.PlantedAt.Time
+	}
+	if createTreeRequest.Attributes != nil {
+		if request.Attributes, err = json.Marshal(createTreeRequest.Attributes); err != nil {
+			return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+		}
+	}
+	tree, err := s.Trees.CreateTree(reqCtx, callerOrganisation(reqCtx), id, request)
+	if err != nil {
+		return s.serviceError(ctx, err)
 	}
 
-	// Check estate exist
-	estate, err := s.Repository.GetEstateById(ctx.Request().Context(), repository.GetEstateByIdInput{
-		Id: id,
-	})
+	return ctx.JSON(http.StatusOK, generated.CreateTreeResponse{Id: tree.Id})
+}
+
+func (s *Server) GetEstateIdTree(ctx echo.Context, id string, params generated.GetEstateIdTreeParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(params); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	page, err := pagination.NewRequest(params.Cursor, params.Limit, defaultTreesPageLimit, pagination.KindPlot)
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "estate is not found"})
-		} else {
-			return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: err.Error()})
-		}
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
 	}
-	// Check if plot out of bound
-	if estate.Length < createTreeRequest.X || estate.Width < createTreeRequest.Y {
-		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: "index out of bound"})
+	filter := repository.TreeFilter{}
+	if params.Species != nil {
+		filter.Species = *params.Species
+	}
+	if params.Health != nil {
+		filter.Health = string(*params.Health)
+	}
+	if strings.Contains(ctx.Request().Header.Get(echo.HeaderAccept), contentTypeNDJSON) {
+		return s.streamTrees(ctx, id, filter, page.After)
 	}
 
-	// Check plot exist
-	_, err = s.Repository.GetTreeByPlot(ctx.Request().Context(), repository.GetTreeByPlot{
-		EstateId: id,
-		X:        createTreeRequest.X,
-		Y:        createTreeRequest.Y,
-	})
-	if err == nil {
-		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: "plot already exist"})
+	reqCtx := ctx.Request().Context()
+	trees, err := s.Trees.ListTrees(reqCtx, callerOrganisation(reqCtx), id, filter, page)
+	if err != nil {
+		return s.serviceError(ctx, err)
 	}
 
-	// Create Tree
-	tree, err := s.Repository.CreateTree(ctx.Request().Context(), repository.Tree{
-		X:        createTreeRequest.X,
-		Y:        createTreeRequest.Y,
-		Height:   createTreeRequest.Height,
-		EstateId: estate.Id,
+	response := generated.ListTreesResponse{Trees: make([]generated.TreeResponse, 0, len(trees.Items))}
+	for _, tree := range trees.Items {
+		response.Trees = append(response.Trees, treeResponse(tree))
+	}
+	response.NextCursor = nextPage(ctx, trees.Next)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// streamTrees answer every tree of the estate after the cursor as NDJSON. The trees are written as they are read
+// from the database, so a large estate is never held in memory.
+func (s *Server) streamTrees(ctx echo.Context, id string, filter repository.TreeFilter, after *pagination.Cursor) error {
+	reqCtx := ctx.Request().Context()
+	res := ctx.Response()
+	encoder := json.NewEncoder(res)
+	written := 0
+	err := s.Trees.StreamTrees(reqCtx, callerOrganisation(reqCtx), id, filter, after, func(tree repository.Tree) error {
+		if written == 0 {
+			res.Header().Set(echo.HeaderContentType, contentTypeNDJSON)
+			res.WriteHeader(http.StatusOK)
+		}
+		written++
+		if err := encoder.Encode(treeResponse(tree)); err != nil {
+			return err
+		}
+		if written%ndjsonFlushEvery == 0 {
+			res.Flush()
+		}
+		return nil
 	})
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+		if written == 0 {
+			return s.serviceError(ctx, err)
+		}
+		// the status is sent, a failed stream can only be logged
+		s.Logger.WarnContext(reqCtx, "tree stream interrupted",
+			slog.String("estate_id", id),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	if written == 0 {
+		res.Header().Set(echo.HeaderContentType, contentTypeNDJSON)
+		res.WriteHeader(http.StatusOK)
 	}
+	return nil
+}
 
-	return ctx.JSON(http.StatusOK, generated.CreateTreeResponse{Id: tree.Id})
+func treeResponse(tree repository.Tree) generated.TreeResponse {
+	response := generated.TreeResponse{
+		Id:         tree.Id,
+		X:          tree.X,
+		Y:          tree.Y,
+		Height:     tree.Height,
+		Species:    tree.Species,
+		Variety:    tree.Variety,
+		Health:     generated.TreeHealth(tree.Health),
+		Attributes: map[string]interface{}{},
+		CreatedAt:  tree.CreatedAt,
+	}
+	if tree.PlantedAt != nil {
+		response.PlantedAt = &openapi_types.Date{Time: *tree.PlantedAt}
+	}
+	if len(tree.Attributes) > 0 {
+		// the attributes are stored as a JSON object
+		_ = json.Unmarshal(tree.Attributes, &response.Attributes)
+	}
+	return response
 }
 
 func (s *Server) GetEstateIdDronePlan(ctx echo.Context, id string, params generated.GetEstateIdDronePlanParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
 	// Request Validate
 	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
 		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
 	}
-	if params.MaxDistance != nil && *params.MaxDistance < 1 {
-		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: "invalid max distance"})
+	if err := service.ValidateMaxDistance(params.MaxDistance); err != nil {
+		return s.serviceError(ctx, err)
 	}
 
 	// get estate
-	estate, err := s.Repository.GetEstateById(ctx.Request().Context(), repository.GetEstateByIdInput{
-		Id: id,
-	})
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "estate is not found"})
-		} else {
-			return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: err.Error()})
-		}
+		return s.serviceError(ctx, err)
 	}
 
-	// list trees
-	trees, err := s.Repository.ListTreesByEstateId(ctx.Request().Context(), repository.ListTreesByEstateIdInput{
-		EstateId: id,
-	})
+	etag := estateETag(estate)
+	if notModified(ctx, etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	plan, err := s.DronePlans.Plan(reqCtx, estate, params.MaxDistance)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: err.Error()})
+		return s.serviceError(ctx, err)
 	}
 
-	var maxDistance *int
-	if params.MaxDistance != nil {
-		maxDistance = params.MaxDistance
+	plot := map[string]interface{}{"x": plan.Rest.X, "y": plan.Rest.Y}
+	return ctx.JSON(http.StatusOK, generated.GetEstateDronePlanResponse{Distance: plan.Distance, Rest: &plot})
+}
+
+func (s *Server) PostEstateIdDronePlanTargeted(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	targetedRequest := new(generated.TargetedDronePlanRequest)
+	if err := ctx.Bind(&targetedRequest); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := service.ValidateMaxDistance(targetedRequest.MaxDistance); err != nil {
+		return s.serviceError(ctx, err)
 	}
 
-	// map the trees plot
-	mapTrees := make(map[int]map[int]int)
-	for _, tree := range trees {
-		if mapTrees[tree.X] == nil {
-			mapTrees[tree.X] = make(map[int]int)
-		}
-		mapTrees[tree.X][tree.Y] = tree.Height
-	}
-
-	plot := make(map[string]interface{})
-	x, y := 1, 1
-	turnBack := false // true: move from west to east, false: move from east to west
-	distance := 1
-	for y <= estate.Width {
-		if turnBack {
-			x = estate.Length
-			for x >= 1 {
-				plot["x"], plot["y"] = x, y
-				if x == 1 && y == estate.Width {
-					break // break if the end of bound
-				}
-				height := int(math.Abs(float64(mapTrees[x][y] - mapTrees[x-1][y])))
-				distance += 10 + height // sum distance by add 10 meter move and up tree
-				// checking max move, if reach max break all the loop
-				if maxDistance != nil && *maxDistance < distance {
-					x, y = estate.Length, estate.Width
-					break
-				}
-				x--
-			}
-			turnBack = false
-		} else {
-			x = 1
-			for x <= estate.Length {
-				plot["x"], plot["y"] = x, y
-				if x == estate.Length && y == estate.Width {
-					break // break if the end of bound
-				}
-				height := int(math.Abs(float64(mapTrees[x][y] - mapTrees[x+1][y])))
-				distance += 10 + height // sum distance by add 10 meter move and up tree
-				// checking max move, if reach max break all the loop
-				if maxDistance != nil && *maxDistance < distance {
-					x, y = estate.Length, estate.Width
-					break
-				}
-				x++
-			}
-			turnBack = true
+	request := service.TargetedPlanRequest{
+		MaxDistance: targetedRequest.MaxDistance,
+		MinSeverity: targetedRequest.MinSeverity,
+	}
+	if targetedRequest.Launch != nil {
+		request.Launch = &planner.Plot{X: targetedRequest.Launch.X, Y: targetedRequest.Launch.Y}
+	}
+	if targetedRequest.Plots != nil {
+		for _, plot := range *targetedRequest.Plots {
+			request.Plots = append(request.Plots, planner.Plot{X: plot.X, Y: plot.Y})
 		}
-		y++
 	}
-	distance += 1 // add 1 meter for drone landing
 
-	// put distance as max distance if distance more than max distance
-	if maxDistance != nil && *maxDistance < distance {
-		distance = *maxDistance
+	// get estate
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+	plan, err := s.DronePlans.Targeted(reqCtx, estate, request)
+	if err != nil {
+		return s.serviceError(ctx, err)
 	}
 
-	return ctx.JSON(http.StatusOK, generated.GetEstateDronePlanResponse{Distance: distance, Rest: &plot})
+	response := generated.TargetedDronePlanResponse{
+		Distance: plan.Distance,
+		Rest:     generated.PlanPlot{X: plan.Rest.X, Y: plan.Rest.Y},
+		Targets:  make([]generated.PlanPlot, 0, len(plan.Targets)),
+		Visited:  plan.Visited,
+	}
+	for _, target := range plan.Targets {
+		response.Targets = append(response.Targets, generated.PlanPlot{X: target.X, Y: target.Y})
+	}
+	return ctx.JSON(http.StatusOK, response)
 }
@@ -2,10 +2,16 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/SawitProRecruitment/UserService/auth"
+	"github.com/SawitProRecruitment/UserService/cache"
 	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/rbac"
 	"github.com/SawitProRecruitment/UserService/repository"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -18,6 +24,38 @@ import (
 	"time"
 )
 
+// withPrincipal authenticate every request as an admin of the organisation, as the auth middleware does
+func withPrincipal(organisationId string) echo.MiddlewareFunc {
+	return withRole(organisationId, rbac.RoleAdmin)
+}
+
+func withRole(organisationId string, role rbac.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := auth.WithPrincipal(c.Request().Context(), auth.Principal{
+				Subject:        "test",
+				OrganisationId: organisationId,
+				Role:           role,
+				Method:         auth.MethodApiKey,
+			})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// streamTrees returns a StreamTreesByEstateId visiting the trees
+func streamTrees(trees []repository.Tree) func(context.Context, repository.StreamTreesByEstateIdInput, func(repository.Tree) error) error {
+	return func(_ context.Context, _ repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) error {
+		for _, tree := range trees {
+			if err := visit(tree); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 func TestServer_PostEstate(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -27,8 +65,10 @@ func TestServer_PostEstate(t *testing.T) {
 		Repository: mockRepository,
 	})
 
-	e := echo.New()
 	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
 
 	testCases := []struct {
 		name           string
@@ -56,18 +96,20 @@ func TestServer_PostEstate(t *testing.T) {
 			},
 			setupMocks: func() {
 				mockRepository.EXPECT().CreateEstate(gomock.Any(), repository.Estate{
-					Width:  10,
-					Length: 20,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, errors.New(""))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"message":""}`,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name: "OK",
@@ -77,14 +119,16 @@ func TestServer_PostEstate(t *testing.T) {
 			},
 			setupMocks: func() {
 				mockRepository.EXPECT().CreateEstate(gomock.Any(), repository.Estate{
-					Width:  10,
-					Length: 20,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -112,7 +156,7 @@ func TestServer_PostEstate(t *testing.T) {
 	}
 }
 
-func TestServer_GetEstateIdStats(t *testing.T) {
+func TestServer_GetEstate(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -120,8 +164,289 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 	s := NewServer(NewServerOptions{
 		Repository: mockRepository,
 	})
+
+	id := uuid.New().String()
+	nextId := uuid.New().String()
+	orgId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursor := pagination.Cursor{Time: createdAt, Id: id}
 	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	// the generated wrapper binds the cursor and limit parameters
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate", wrapper.GetEstate)
+
+	testCases := []struct {
+		name           string
+		query          string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+		expectedLink   string
+	}{
+		{
+			name: "INTERNAL_SERVER_ERROR",
+			setupMocks: func() {
+				mockRepository.EXPECT().ListEstatesByOrganisationId(gomock.Any(), repository.ListEstatesByOrganisationIdInput{
+					OrganisationId: orgId,
+					Limit:          defaultPageLimit + 1,
+				}).Return(nil, errors.New(""))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"internal server error"}`,
+		},
+		{
+			name:           "INVALID_CURSOR",
+			query:          "?cursor=not-a-cursor",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"invalid cursor"}`,
+		},
+		{
+			name:           "TREE_CURSOR",
+			query:          "?cursor=" + pagination.Cursor{X: 2, Y: 1}.Encode(),
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"invalid cursor"}`,
+		},
+		{
+			name:           "INVALID_LIMIT",
+			query:          "?limit=1001",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'GetEstateParams.Limit' Error:Field validation for 'Limit' failed on the 'lte' tag"}`,
+		},
+		{
+			name: "EMPTY",
+			setupMocks: func() {
+				mockRepository.EXPECT().ListEstatesByOrganisationId(gomock.Any(), repository.ListEstatesByOrganisationIdInput{
+					OrganisationId: orgId,
+					Limit:          defaultPageLimit + 1,
+				}).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"estates":[]}`,
+		},
+		{
+			name: "OK",
+			setupMocks: func() {
+				mockRepository.EXPECT().ListEstatesByOrganisationId(gomock.Any(), repository.ListEstatesByOrganisationIdInput{
+					OrganisationId: orgId,
+					Limit:          defaultPageLimit + 1,
+				}).Return([]repository.Estate{{
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      createdAt,
+				}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   fmt.Sprintf(`{"estates":[{"created_at":"2024-01-01T00:00:00Z","id":"%s","length":20,"width":10}]}`, id),
+		},
+		{
+			name:  "NEXT_PAGE",
+			query: "?limit=1",
+			setupMocks: func() {
+				mockRepository.EXPECT().ListEstatesByOrganisationId(gomock.Any(), repository.ListEstatesByOrganisationIdInput{
+					OrganisationId: orgId,
+					Limit:          2,
+				}).Return([]repository.Estate{
+					{Id: id, OrganisationId: orgId, Width: 10, Length: 20, CreatedAt: createdAt},
+					{Id: nextId, OrganisationId: orgId, Width: 10, Length: 20, CreatedAt: createdAt},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`{"estates":[{"created_at":"2024-01-01T00:00:00Z","id":"%s","length":20,"width":10}],"next_cursor":"%s"}`,
+				id, cursor.Encode()),
+			expectedLink: fmt.Sprintf(`</estate?cursor=%s&limit=1>; rel="next"`, cursor.Encode()),
+		},
+		{
+			name:  "LAST_PAGE",
+			query: "?limit=1&cursor=" + cursor.Encode(),
+			setupMocks: func() {
+				mockRepository.EXPECT().ListEstatesByOrganisationId(gomock.Any(), repository.ListEstatesByOrganisationIdInput{
+					OrganisationId: orgId,
+					After:          &cursor,
+					Limit:          2,
+				}).Return([]repository.Estate{
+					{Id: nextId, OrganisationId: orgId, Width: 10, Length: 20, CreatedAt: createdAt},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   fmt.Sprintf(`{"estates":[{"created_at":"2024-01-01T00:00:00Z","id":"%s","length":20,"width":10}]}`, nextId),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+			assert.Equal(t, tc.expectedLink, rec.Header().Get("Link"))
+		})
+	}
+}
+
+func TestServer_GetEstateIdTree(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
 	id := uuid.New().String()
+	orgId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursor := pagination.Cursor{X: 2, Y: 1}
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/tree", wrapper.GetEstateIdTree)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+			Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 5, Length: 10}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		accept         string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+		expectedLink   string
+	}{
+		{
+			name:  "NOT_FOUND",
+			query: "",
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:           "ESTATE_CURSOR",
+			query:          "?cursor=" + pagination.Cursor{Time: createdAt, Id: id}.Encode(),
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"invalid cursor"}`,
+		},
+		{
+			name:  "NEXT_PAGE",
+			query: "?limit=1",
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{EstateId: id, Limit: 2}).
+					Return([]repository.Tree{
+						{Id: "t-1", X: 2, Y: 1, Height: 10, Health: repository.TreeHealthHealthy, CreatedAt: createdAt},
+						{Id: "t-2", X: 1, Y: 2, Height: 12, Health: repository.TreeHealthDiseased, Attributes: json.RawMessage(`{"note":"fungus"}`), CreatedAt: createdAt},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: fmt.Sprintf(`{"next_cursor":"%s","trees":[{"attributes":{},"created_at":"2024-01-01T00:00:00Z","health":"healthy","height":10,"id":"t-1","x":2,"y":1}]}`,
+				cursor.Encode()),
+			expectedLink: fmt.Sprintf(`</estate/%s/tree?cursor=%s&limit=1>; rel="next"`, id, cursor.Encode()),
+		},
+		{
+			name:  "LAST_PAGE",
+			query: "?cursor=" + cursor.Encode(),
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+					EstateId: id,
+					After:    &cursor,
+					Limit:    defaultTreesPageLimit + 1,
+				}).Return([]repository.Tree{{Id: "t-2", X: 1, Y: 2, Height: 12, Health: repository.TreeHealthDiseased, Attributes: json.RawMessage(`{"note":"fungus"}`), CreatedAt: createdAt}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"trees":[{"attributes":{"note":"fungus"},"created_at":"2024-01-01T00:00:00Z","health":"diseased","height":12,"id":"t-2","x":1,"y":2}]}`,
+		},
+		{
+			name:   "NDJSON",
+			query:  "?limit=1",
+			accept: contentTypeNDJSON,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: id}, gomock.Any()).
+					DoAndReturn(streamTrees([]repository.Tree{
+						{Id: "t-1", X: 2, Y: 1, Height: 10, Health: repository.TreeHealthHealthy, CreatedAt: createdAt},
+						{Id: "t-2", X: 1, Y: 2, Height: 12, Health: repository.TreeHealthDiseased, Attributes: json.RawMessage(`{"note":"fungus"}`), CreatedAt: createdAt},
+					}))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"attributes":{},"created_at":"2024-01-01T00:00:00Z","health":"healthy","height":10,"id":"t-1","x":2,"y":1}
+{"attributes":{"note":"fungus"},"created_at":"2024-01-01T00:00:00Z","health":"diseased","height":12,"id":"t-2","x":1,"y":2}`,
+		},
+		{
+			name:  "FILTER",
+			query: "?species=Elaeis+guineensis&health=diseased",
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+					EstateId: id,
+					Filter:   repository.TreeFilter{Species: "Elaeis guineensis", Health: repository.TreeHealthDiseased},
+					Limit:    defaultTreesPageLimit + 1,
+				}).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"trees":[]}`,
+		},
+		{
+			name:           "INVALID_HEALTH",
+			query:          "?health=sick",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"health must be healthy, diseased, dead or replanted"}`,
+		},
+		{
+			name:   "NDJSON_NOT_FOUND",
+			accept: contentTypeNDJSON,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/tree"+tc.query, nil)
+			req.Header.Set(echo.HeaderAccept, tc.accept)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+			assert.Equal(t, tc.expectedLink, rec.Header().Get("Link"))
+		})
+	}
+}
+
+func TestServer_GetEstateIdStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
 
 	testCases := []struct {
 		name           string
@@ -145,12 +470,29 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				}, errors.New("sql: no rows in result set"))
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
+				}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:      "ESTATE_OF_OTHER_ORGANISATION",
+			requestId: id,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{
+					Id:             id,
+					OrganisationId: uuid.New().String(),
+					Width:          10,
+					Length:         20,
+				}, nil)
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   `{"message":"estate is not found"}`,
@@ -162,15 +504,16 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, errors.New(""))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"message":""}`,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name:      "INTERNAL_SERVER_ERROR",
@@ -179,25 +522,19 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{{
-					Id:        id,
-					X:         5,
-					Y:         1,
-					Height:    15,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				}}, errors.New(""))
+				}, gomock.Any()).Return(errors.New(""))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"message":""}`,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name:      "OK_WITH_ZERO_STATS",
@@ -206,15 +543,16 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{}, nil)
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{}))
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"count":0,"max":0,"median":0,"min":0}`,
@@ -226,15 +564,16 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     10,
-					Length:    20,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
 					{
 						Id:        uuid.New().String(),
 						EstateId:  id,
@@ -262,10 +601,29 @@ func TestServer_GetEstateIdStats(t *testing.T) {
 						CreatedAt: time.Now(),
 						UpdatedAt: time.Now(),
 					},
+				}))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"count":3,"max":10,"median":5,"min":2}`,
+		},
+		{
+			name:      "OK_BY_SPECIES_AND_HEALTH",
+			requestId: id,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          10,
+					Length:         20,
 				}, nil)
+				oilPalm, coconut := "Elaeis guineensis", "Cocos nucifera"
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+					{X: 2, Y: 1, Height: 4, Species: &oilPalm, Health: repository.TreeHealthHealthy},
+					{X: 3, Y: 1, Height: 8, Species: &coconut, Health: repository.TreeHealthDead},
+				}))
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"count":3,"max":10,"median":3,"min":2}`,
+			expectedBody:   `{"by_health":[{"count":1,"health":"healthy","max":4,"median":4,"min":4},{"count":1,"health":"dead","max":8,"median":8,"min":8}],"by_species":[{"count":1,"max":8,"median":8,"min":8,"species":"Cocos nucifera"},{"count":1,"max":4,"median":4,"min":4,"species":"Elaeis guineensis"}],"count":2,"max":8,"median":6,"min":4}`,
 		},
 	}
 
@@ -300,14 +658,16 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		Repository: mockRepository,
 	})
 
-	e := echo.New()
 	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
 	treeId := uuid.New().String()
 
 	testCases := []struct {
 		name           string
 		pathId         string
-		requestBody    map[string]int
+		requestBody    map[string]any
 		setupMocks     func()
 		expectedStatus int
 		expectedBody   string
@@ -315,7 +675,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		{
 			name:        "BAD_REQUEST_VALIDATION_PATH",
 			pathId:      "123",
-			requestBody: map[string]int{},
+			requestBody: map[string]any{},
 			setupMocks: func() {
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -324,7 +684,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		{
 			name:   "BAD_REQUEST_VALIDATION_REQUEST",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": -1,
@@ -337,7 +697,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		{
 			name:   "ESTATE_NOT_FOUND",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": 10,
@@ -345,7 +705,28 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 			setupMocks: func() {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
-				}).Return(repository.Estate{}, errors.New("sql: no rows in result set"))
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:   "ESTATE_OF_OTHER_ORGANISATION",
+			pathId: id,
+			requestBody: map[string]any{
+				"x":      5,
+				"y":      1,
+				"height": 10,
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{
+					Id:             id,
+					OrganisationId: uuid.New().String(),
+					Width:          1,
+					Length:         5,
+				}, nil)
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   `{"message":"estate is not found"}`,
@@ -353,7 +734,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		{
 			name:   "INTERNAL_SERVER_ERROR",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": 10,
@@ -364,12 +745,12 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 				}).Return(repository.Estate{}, errors.New(""))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"message":""}`,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name:   "BAD_REQUEST_INDEX_OUT_OF_BOUND",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": 10,
@@ -378,11 +759,12 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     1,
-					Length:    4,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          1,
+					Length:         4,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
 			},
 			expectedStatus: http.StatusBadRequest,
@@ -391,7 +773,7 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 		{
 			name:   "BAD_REQUEST_PLOT_ALREADY_EXIST",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": 10,
@@ -400,11 +782,12 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     1,
-					Length:    5,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          1,
+					Length:         5,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
 				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), repository.GetTreeByPlot{
 					EstateId: id,
@@ -421,9 +804,9 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 			expectedBody:   `{"message":"plot already exist"}`,
 		},
 		{
-			name:   "BAD_REQUEST",
+			name:   "INTERNAL_SERVER_ERROR_CREATE_TREE",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": 10,
@@ -432,11 +815,12 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     1,
-					Length:    5,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          1,
+					Length:         5,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
 				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), repository.GetTreeByPlot{
 					EstateId: id,
@@ -450,13 +834,13 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 					Height:   10,
 				}).Return(repository.Tree{}, errors.New(""))
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":""}`,
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name:   "OK",
 			pathId: id,
-			requestBody: map[string]int{
+			requestBody: map[string]any{
 				"x":      5,
 				"y":      1,
 				"height": 10,
@@ -465,11 +849,12 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     1,
-					Length:    5,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          1,
+					Length:         5,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
 				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), repository.GetTreeByPlot{
 					EstateId: id,
@@ -492,6 +877,58 @@ func TestServer_PostEstateIdTree(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   fmt.Sprintf(`{"id":"%s"}`, treeId),
 		},
+		{
+			name:   "BAD_REQUEST_HEALTH",
+			pathId: id,
+			requestBody: map[string]any{
+				"x":      5,
+				"y":      1,
+				"height": 10,
+				"health": "sick",
+			},
+			setupMocks: func() {
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateTreeRequest.Health' Error:Field validation for 'Health' failed on the 'oneof' tag"}`,
+		},
+		{
+			name:   "OK_SPECIES_HEALTH_ATTRIBUTES",
+			pathId: id,
+			requestBody: map[string]any{
+				"x":          5,
+				"y":          1,
+				"height":     10,
+				"species":    "Elaeis guineensis",
+				"variety":    "Tenera",
+				"planted_at": "2019-03-01",
+				"health":     "diseased",
+				"attributes": map[string]any{"clone": "MPOB-7"},
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          1,
+					Length:         5,
+				}, nil)
+				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), gomock.Any()).Return(repository.Tree{}, errors.New("not exist"))
+				species, variety := "Elaeis guineensis", "Tenera"
+				plantedAt := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+				mockRepository.EXPECT().CreateTree(gomock.Any(), repository.Tree{
+					EstateId:   id,
+					X:          5,
+					Y:          1,
+					Height:     10,
+					Species:    &species,
+					Variety:    &variety,
+					PlantedAt:  &plantedAt,
+					Health:     repository.TreeHealthDiseased,
+					Attributes: json.RawMessage(`{"clone":"MPOB-7"}`),
+				}).Return(repository.Tree{Id: treeId}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   fmt.Sprintf(`{"id":"%s"}`, treeId),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -526,8 +963,10 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		Repository: mockRepository,
 	})
 
-	e := echo.New()
 	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
 	//treeId := uuid.New().String()
 	negInt := -1
 	posInt1 := 40
@@ -566,7 +1005,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 			setupMocks: func() {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
-				}).Return(repository.Estate{}, errors.New("sql: no rows in result set"))
+				}).Return(repository.Estate{}, sql.ErrNoRows)
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedBody:   `{"message":"estate is not found"}`,
@@ -581,7 +1020,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 				}).Return(repository.Estate{}, errors.New(""))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"message":""}`,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name:   "INTERNAL_SERVER_ERROR",
@@ -591,18 +1030,19 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     1,
-					Length:    4,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          1,
+					Length:         4,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{}, errors.New(""))
+				}, gomock.Any()).Return(errors.New(""))
 			},
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"message":""}`,
+			expectedBody:   `{"message":"internal server error"}`,
 		},
 		{
 			name:   "OK",
@@ -612,15 +1052,16 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     2,
-					Length:    5,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          2,
+					Length:         5,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{{
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{{
 					Id:       uuid.New().String(),
 					EstateId: id,
 					X:        3,
@@ -632,7 +1073,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 					X:        3,
 					Y:        2,
 					Height:   5,
-				}}, nil)
+				}}))
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"distance":112,"rest":{"x":1,"y":2}}`,
@@ -645,15 +1086,16 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     2,
-					Length:    5,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          2,
+					Length:         5,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{{
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{{
 					Id:       uuid.New().String(),
 					EstateId: id,
 					X:        3,
@@ -665,7 +1107,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 					X:        3,
 					Y:        2,
 					Height:   5,
-				}}, nil)
+				}}))
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"distance":40,"rest":{"x":3,"y":1}}`,
@@ -678,15 +1120,16 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
 					Id: id,
 				}).Return(repository.Estate{
-					Id:        id,
-					Width:     2,
-					Length:    5,
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
+					Id:             id,
+					OrganisationId: orgId,
+					Width:          2,
+					Length:         5,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
 				}, nil)
-				mockRepository.EXPECT().ListTreesByEstateId(gomock.Any(), repository.ListTreesByEstateIdInput{
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
 					EstateId: id,
-				}).Return([]repository.Tree{{
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{{
 					Id:       uuid.New().String(),
 					EstateId: id,
 					X:        3,
@@ -698,7 +1141,7 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 					X:        3,
 					Y:        2,
 					Height:   5,
-				}}, nil)
+				}}))
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"distance":90,"rest":{"x":3,"y":2}}`,
@@ -728,3 +1171,376 @@ func TestServer_GetEstateIdDronePlan(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_Authorize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+
+	testCases := []struct {
+		name           string
+		role           rbac.Role
+		method         string
+		path           string
+		handler        echo.HandlerFunc
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "VIEWER_CANNOT_CREATE_ESTATE",
+			role:   rbac.RoleViewer,
+			method: http.MethodPost,
+			path:   "/estate",
+			handler: func(c echo.Context) error {
+				return s.PostEstate(c)
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), repository.AuditLog{
+					OrganisationId: orgId,
+					Subject:        "test",
+					Role:           "viewer",
+					Action:         "estate:manage",
+					Resource:       "POST /estate",
+					Outcome:        "denied",
+				}).Return(repository.AuditLog{}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"forbidden"}`,
+		},
+		{
+			name:   "OPERATOR_CANNOT_CREATE_TREE",
+			role:   rbac.RoleOperator,
+			method: http.MethodPost,
+			path:   "/estate/" + id + "/tree",
+			handler: func(c echo.Context) error {
+				return s.PostEstateIdTree(c, id)
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), repository.AuditLog{
+					OrganisationId: orgId,
+					Subject:        "test",
+					Role:           "operator",
+					Action:         "tree:write",
+					Resource:       "POST /estate/" + id + "/tree",
+					Outcome:        "denied",
+				}).Return(repository.AuditLog{}, errors.New("audit failure does not change the answer"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"forbidden"}`,
+		},
+		{
+			name:   "NOT_A_MEMBER_CANNOT_READ_STATS",
+			role:   "",
+			method: http.MethodGet,
+			path:   "/estate/" + id + "/stats",
+			handler: func(c echo.Context) error {
+				return s.GetEstateIdStats(c, id)
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Return(repository.AuditLog{}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"forbidden"}`,
+		},
+		{
+			name:   "VIEWER_CANNOT_CANCEL_DRONE_PLAN_JOB",
+			role:   rbac.RoleViewer,
+			method: http.MethodDelete,
+			path:   "/estate/" + id + "/drone-plan/jobs/" + id,
+			handler: func(c echo.Context) error {
+				return s.DeleteEstateIdDronePlanJobsJobId(c, id, id)
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), repository.AuditLog{
+					OrganisationId: orgId,
+					Subject:        "test",
+					Role:           "viewer",
+					Action:         "mission:run",
+					Resource:       "DELETE /estate/" + id + "/drone-plan/jobs/" + id,
+					Outcome:        "denied",
+				}).Return(repository.AuditLog{}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"forbidden"}`,
+		},
+		{
+			name:   "VIEWER_CAN_READ_DRONE_PLAN",
+			role:   rbac.RoleViewer,
+			method: http.MethodGet,
+			path:   "/estate/" + id + "/drone-plan",
+			handler: func(c echo.Context) error {
+				return s.GetEstateIdDronePlan(c, id, generated.GetEstateIdDronePlanParams{})
+			},
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 1, Length: 1}, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+					EstateId: id,
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{}))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"distance":2,"rest":{"x":1,"y":1}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setupMocks != nil {
+				tc.setupMocks()
+			}
+
+			e := echo.New()
+			e.Use(withRole(orgId, tc.role))
+			e.Add(tc.method, tc.path, tc.handler)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"width":1,"length":1,"x":1,"y":1,"height":1}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_EstateCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	lru, _ := cache.NewLRU(16)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		Cache:      cache.New(cache.NewCacheOptions{Backend: lru}),
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		return s.GetEstateIdStats(c, id)
+	})
+	e.GET("/estate/:id/drone-plan", func(c echo.Context) error {
+		var params generated.GetEstateIdDronePlanParams
+		if v := c.QueryParam("max-distance"); v != "" {
+			var maxDistance int
+			_, _ = fmt.Sscan(v, &maxDistance)
+			params.MaxDistance = &maxDistance
+		}
+		return s.GetEstateIdDronePlan(c, id, params)
+	})
+
+	estate := func(version int64) {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{
+			Id:             id,
+			OrganisationId: orgId,
+			Width:          2,
+			Length:         5,
+			Version:        version,
+		}, nil)
+	}
+	trees := func() {
+		mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+			EstateId: id,
+		}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+			{EstateId: id, X: 3, Y: 1, Height: 5},
+			{EstateId: id, X: 3, Y: 2, Height: 5},
+		}))
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		ifNoneMatch    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "STATS_MISS",
+			path: "/stats",
+			setupMocks: func() {
+				estate(1)
+				trees()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"count":2,"max":5,"median":5,"min":5}`,
+		},
+		{
+			name: "STATS_HIT",
+			path: "/stats",
+			setupMocks: func() {
+				estate(1)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"count":2,"max":5,"median":5,"min":5}`,
+		},
+		{
+			name:        "STATS_NOT_MODIFIED",
+			path:        "/stats",
+			ifNoneMatch: fmt.Sprintf(`W/"other", "%s-1"`, id),
+			setupMocks: func() {
+				estate(1)
+			},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:        "STATS_TREE_CHANGED",
+			path:        "/stats",
+			ifNoneMatch: fmt.Sprintf(`"%s-1"`, id),
+			setupMocks: func() {
+				estate(2)
+				trees()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"count":2,"max":5,"median":5,"min":5}`,
+		},
+		{
+			name: "DRONE_PLAN_MISS",
+			path: "/drone-plan",
+			setupMocks: func() {
+				estate(1)
+				trees()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"distance":112,"rest":{"x":1,"y":2}}`,
+		},
+		{
+			name: "DRONE_PLAN_HIT",
+			path: "/drone-plan",
+			setupMocks: func() {
+				estate(1)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"distance":112,"rest":{"x":1,"y":2}}`,
+		},
+		{
+			name: "DRONE_PLAN_OTHER_MAX_DISTANCE_MISS",
+			path: "/drone-plan?max-distance=40",
+			setupMocks: func() {
+				estate(1)
+				trees()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"distance":40,"rest":{"x":3,"y":1}}`,
+		},
+		{
+			name:        "DRONE_PLAN_NOT_MODIFIED",
+			path:        "/drone-plan",
+			ifNoneMatch: fmt.Sprintf(`"%s-1"`, id),
+			setupMocks: func() {
+				estate(1)
+			},
+			expectedStatus: http.StatusNotModified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+tc.path, nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+			assert.NotEmpty(t, rec.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestServer_PostEstateIdDronePlanTargeted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.POST("/estate/:id/drone-plan/targeted", wrapper.PostEstateIdDronePlanTargeted)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+			Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 3}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		requestBody    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST_MAX_DISTANCE",
+			requestBody:    `{"max_distance":0,"min_severity":3}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"invalid max distance"}`,
+		},
+		{
+			name:           "BAD_REQUEST_NO_TARGET",
+			requestBody:    `{"launch":{"x":1,"y":1}}`,
+			setupMocks:     estate,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"targets must be either plots or a min severity"}`,
+		},
+		{
+			name:        "ESTATE_NOT_FOUND",
+			requestBody: `{"min_severity":3}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:        "OK",
+			requestBody: `{"launch":{"x":3,"y":2},"max_distance":25,"plots":[{"x":1,"y":2},{"x":3,"y":1}]}`,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees(nil))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"distance":25,"rest":{"x":2,"y":1},"targets":[{"x":3,"y":1},{"x":1,"y":2}],"visited":1}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/"+id+"/drone-plan/targeted", strings.NewReader(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
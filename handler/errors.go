@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/labstack/echo/v4"
+)
+
+const internalErrorMessage = "internal server error"
+
+// internalError log the full error server side and answer the client with a generic message,
+// so database details never leak in the response
+func (s *Server) internalError(ctx echo.Context, err error) error {
+	s.Logger.ErrorContext(ctx.Request().Context(), "request failed",
+		slog.String("route", ctx.Path()),
+		slog.String("error", err.Error()),
+	)
+	return ctx.JSON(http.StatusInternalServerError, generated.ErrorResponse{Message: internalErrorMessage})
+}
+
+// serviceError answer an error of the services with its status, the unexpected errors are internal
+func (s *Server) serviceError(ctx echo.Context, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrEstateNotFound),
+		errors.Is(err, service.ErrFlightNotFound),
+		errors.Is(err, service.ErrFindingNotFound):
+		return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: err.Error()})
+	case errors.As(err, &validationErr),
+		errors.Is(err, service.ErrOutOfBound),
+		errors.Is(err, service.ErrPlotExist),
+		errors.Is(err, service.ErrInvalidMaxDistance),
+		errors.Is(err, service.ErrInvalidTargets),
+		errors.Is(err, service.ErrTooManyTargets),
+		errors.Is(err, service.ErrInvalidTreeAttributes),
+		errors.Is(err, service.ErrInvalidTreeHealth),
+		errors.Is(err, service.ErrUnsupportedArchiveVersion),
+		errors.Is(err, service.ErrInvalidArchive):
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrEstateExist):
+		return ctx.JSON(http.StatusConflict, generated.ErrorResponse{Message: err.Error()})
+	}
+	return s.internalError(ctx, err)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/labstack/echo/v4"
+)
+
+// heartbeatInterval keep the idle streams open through the proxies
+const heartbeatInterval = 15 * time.Second
+
+func (s *Server) GetEstateIdEvents(ctx echo.Context, id string, params generated.GetEstateIdEventsParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+	if s.Events == nil {
+		return ctx.JSON(http.StatusServiceUnavailable, generated.ErrorResponse{Message: "events are not available"})
+	}
+
+	lastEventId := ""
+	if params.LastEventID != nil {
+		lastEventId = *params.LastEventID
+	}
+	sub, err := s.Events.Subscribe(estate.Id, lastEventId)
+	if err != nil {
+		return ctx.JSON(http.StatusServiceUnavailable, generated.ErrorResponse{Message: err.Error()})
+	}
+	defer sub.Close()
+
+	// the snapshot is read after subscribing so no change is missed in between
+	var snapshot *service.Stats
+	if !sub.Resumed {
+		stats, err := s.Trees.LiveStats(ctx.Request().Context(), estate.Id)
+		if err != nil {
+			return s.internalError(ctx, err)
+		}
+		snapshot = &stats
+	}
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// disable the buffering of nginx
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if snapshot != nil {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		if err := writeEvent(res, events.Event{Id: sub.LastEventId, Type: events.TypeStats, Data: data}); err != nil {
+			return nil
+		}
+	}
+	for _, event := range sub.Backlog {
+		if err := writeEvent(res, event); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				// closed for a slow client or on shutdown, the client reconnects with the last event ID
+				return nil
+			}
+			if err := writeEvent(res, event); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// writeEvent write an event in the text/event-stream format
+func writeEvent(res *echo.Response, event events.Event) error {
+	_, err := fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, event.Data)
+	return err
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_GetEstateIdEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	hub := events.NewHub(events.NewHubOptions{})
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		Events:     hub,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	// the generated wrapper binds the Last-Event-ID header
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/events", wrapper.GetEstateIdEvents)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 5, Length: 5}, nil)
+	}
+
+	// an event published before the client disconnected
+	first, err := hub.Subscribe(id, "")
+	require.NoError(t, err)
+	require.NoError(t, hub.Publish(id, events.TypeTreeCreated, map[string]int{"height": 3}))
+	missed := <-first.C
+	first.Close()
+
+	testCases := []struct {
+		name           string
+		requestId      string
+		lastEventId    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestId:      "11",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'IdPath.ID' Error:Field validation for 'ID' failed on the 'uuid4' tag"}` + "\n",
+		},
+		{
+			name:      "ESTATE_NOT_FOUND",
+			requestId: id,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}` + "\n",
+		},
+		{
+			name:      "STATS_SNAPSHOT",
+			requestId: id,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+					EstateId: id,
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{{Height: 3}, {Height: 5}}))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "id: " + missed.Id + "\nevent: stats\ndata: {\"count\":2,\"max\":5,\"median\":4,\"min\":3}\n\n",
+		},
+		{
+			name:        "RESUMED",
+			requestId:   id,
+			lastEventId: first.LastEventId,
+			setupMocks: func() {
+				estate()
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "id: " + missed.Id + "\nevent: tree.created\ndata: {\"height\":3}\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			// the stream ends once the client is gone
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+tc.requestId+"/events", nil).WithContext(ctx)
+			if tc.lastEventId != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventId)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestServer_PostEstateIdTree_PublishEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	hub := events.NewHub(events.NewHubOptions{})
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		Events:     hub,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	treeId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/events", wrapper.GetEstateIdEvents)
+	e.POST("/estate/:id/tree", func(c echo.Context) error {
+		return s.PostEstateIdTree(c, c.Param("id"))
+	})
+
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).
+		Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 5, Length: 5}, nil).Times(2)
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{}))
+	mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), gomock.Any()).Return(repository.Tree{}, sql.ErrNoRows)
+	mockRepository.EXPECT().CreateTree(gomock.Any(), gomock.Any()).
+		Return(repository.Tree{Id: treeId, EstateId: id, X: 1, Y: 2, Height: 7}, nil)
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{{Height: 7}}))
+
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	stream, err := http.Get(srv.URL + "/estate/" + id + "/events")
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	reader := bufio.NewReader(stream.Body)
+	// readEvent returns the next event without its id
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines[1:], "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, "event: stats\ndata: {\"count\":0,\"max\":0,\"median\":0,\"min\":0}\n", readEvent())
+
+	req := httptest.NewRequest(http.MethodPost, "/estate/"+id+"/tree", strings.NewReader(`{"x":1,"y":2,"height":7}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, "event: tree.created\ndata: {\"id\":\""+treeId+"\",\"estate_id\":\""+id+"\",\"x\":1,\"y\":2,\"height\":7,"+
+		"\"created_at\":\"0001-01-01T00:00:00Z\",\"updated_at\":\"0001-01-01T00:00:00Z\"}\n", readEvent())
+	assert.Equal(t, "event: stats\ndata: {\"count\":1,\"max\":7,\"median\":7,\"min\":7}\n", readEvent())
+
+	// closing the hub ends the stream
+	hub.Close()
+	_, err = reader.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestServer_GetEstateIdEvents_TooManyEstates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	hub := events.NewHub(events.NewHubOptions{MaxEstates: 1})
+	defer hub.Close()
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		Events:     hub,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.GET("/estate/:id/events", func(ctx echo.Context) error {
+		return s.GetEstateIdEvents(ctx, ctx.Param("id"), generated.GetEstateIdEventsParams{})
+	})
+
+	// another estate is streamed, the hub is full
+	other, err := hub.Subscribe(uuid.New().String(), "")
+	require.NoError(t, err)
+	defer other.Close()
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+		Id: id,
+	}).Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 5, Length: 5}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/events", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, `{"message":"too many estates are streamed"}`+"\n", rec.Body.String())
+}
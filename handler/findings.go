@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) PostEstateIdFlightsFlightIdFindings(ctx echo.Context, id string, flightId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionMissionRun) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	createFindingsRequest := new(generated.CreateFindingsRequest)
+	if err := ctx.Bind(&createFindingsRequest); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(FlightIdPath{ID: flightId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Create findings
+	request := service.CreateFindingsRequest{Findings: make([]service.CreateFindingRequest, 0, len(createFindingsRequest.Findings))}
+	for _, finding := range createFindingsRequest.Findings {
+		request.Findings = append(request.Findings, service.CreateFindingRequest{
+			X:          finding.X,
+			Y:          finding.Y,
+			Type:       string(finding.Type),
+			Severity:   finding.Severity,
+			Confidence: finding.Confidence,
+			ImageRef:   finding.ImageRef,
+			DetectedAt: finding.DetectedAt,
+		})
+	}
+	reqCtx := ctx.Request().Context()
+	findings, err := s.Inspections.CreateFindings(reqCtx, callerOrganisation(reqCtx), id, flightId, request)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	response := generated.ListFindingsResponse{Findings: make([]generated.FindingResponse, 0, len(findings))}
+	for _, finding := range findings {
+		response.Findings = append(response.Findings, findingResponse(finding))
+	}
+	return ctx.JSON(http.StatusCreated, response)
+}
+
+func (s *Server) GetEstateIdFindings(ctx echo.Context, id string, params generated.GetEstateIdFindingsParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(params); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	page, err := pagination.NewRequest(params.Cursor, params.Limit, defaultPageLimit, pagination.KindTimeId)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	filter := repository.FindingFilter{From: params.From, To: params.To}
+	if params.TreeId != nil {
+		filter.TreeId = *params.TreeId
+	}
+	if params.Type != nil {
+		filter.Type = string(*params.Type)
+	}
+	if params.Status != nil {
+		filter.Status = string(*params.Status)
+	}
+	if params.MinSeverity != nil {
+		filter.MinSeverity = *params.MinSeverity
+	}
+
+	reqCtx := ctx.Request().Context()
+	findings, err := s.Inspections.ListFindings(reqCtx, callerOrganisation(reqCtx), id, filter, page)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	response := generated.ListFindingsResponse{Findings: make([]generated.FindingResponse, 0, len(findings.Items))}
+	for _, finding := range findings.Items {
+		response.Findings = append(response.Findings, findingResponse(finding))
+	}
+	response.NextCursor = nextPage(ctx, findings.Next)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) GetEstateIdFindingsSummary(ctx echo.Context, id string, params generated.GetEstateIdFindingsSummaryParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	reqCtx := ctx.Request().Context()
+	summary, err := s.Inspections.Summary(reqCtx, callerOrganisation(reqCtx), id, params.From, params.To)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	response := generated.FindingsSummaryResponse{
+		Total:          summary.Total,
+		Open:           summary.Open,
+		LastDetectedAt: summary.LastDetectedAt,
+		ByType:         make([]generated.FindingTypeSummary, 0, len(summary.ByType)),
+		BySeverity:     make([]generated.FindingSeveritySummary, 0, len(summary.BySeverity)),
+	}
+	for _, findingType := range summary.ByType {
+		response.ByType = append(response.ByType, generated.FindingTypeSummary{
+			Type:            generated.FindingType(findingType.Type),
+			Count:           findingType.Count,
+			Open:            findingType.Open,
+			MaxOpenSeverity: findingType.MaxOpenSeverity,
+		})
+	}
+	for _, severity := range summary.BySeverity {
+		response.BySeverity = append(response.BySeverity, generated.FindingSeveritySummary{
+			Severity: severity.Severity,
+			Count:    severity.Count,
+			Open:     severity.Open,
+		})
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) PostEstateIdFindingsFindingIdResolve(ctx echo.Context, id string, findingId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionTreeWrite) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(FindingIdPath{ID: findingId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	reqCtx := ctx.Request().Context()
+	finding, err := s.Inspections.ResolveFinding(reqCtx, callerOrganisation(reqCtx), id, findingId)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, findingResponse(finding))
+}
+
+// findingResponse map a finding of the repository to its API response
+func findingResponse(finding repository.Finding) generated.FindingResponse {
+	return generated.FindingResponse{
+		Id:         finding.Id,
+		FlightId:   finding.FlightId,
+		TreeId:     finding.TreeId,
+		X:          finding.X,
+		Y:          finding.Y,
+		Type:       generated.FindingType(finding.Type),
+		Severity:   finding.Severity,
+		Confidence: finding.Confidence,
+		ImageRef:   finding.ImageRef,
+		Status:     generated.FindingStatus(finding.Status),
+		DetectedAt: finding.DetectedAt,
+		ResolvedAt: finding.ResolvedAt,
+	}
+}
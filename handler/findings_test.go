@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_PostEstateIdFlightsFlightIdFindings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	flightId := uuid.New().String()
+	findingId := uuid.New().String()
+	treeId := uuid.New().String()
+	finishedAt := time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.POST("/estate/:id/flights/:flightId/findings", wrapper.PostEstateIdFlightsFlightIdFindings)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+			Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+	}
+	flight := func() {
+		mockRepository.EXPECT().GetFlightById(gomock.Any(), repository.GetFlightByIdInput{Id: flightId, EstateId: id}).
+			Return(repository.Flight{Id: flightId, EstateId: id, FinishedAt: finishedAt}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		requestBody    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST_NO_FINDING",
+			requestBody:    `{"findings":[]}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateFindingsRequest.Findings' Error:Field validation for 'Findings' failed on the 'min' tag"}`,
+		},
+		{
+			name:           "BAD_REQUEST_SEVERITY",
+			requestBody:    `{"findings":[{"x":1,"y":1,"type":"pest_damage","severity":6,"confidence":0.9}]}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateFindingsRequest.Findings[0].Severity' Error:Field validation for 'Severity' failed on the 'lte' tag"}`,
+		},
+		{
+			name:        "FLIGHT_NOT_FOUND",
+			requestBody: `{"findings":[{"x":1,"y":1,"type":"pest_damage","severity":3,"confidence":0.9}]}`,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().GetFlightById(gomock.Any(), gomock.Any()).Return(repository.Flight{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"flight is not found"}`,
+		},
+		{
+			name:        "BAD_REQUEST_OUT_OF_BOUND",
+			requestBody: `{"findings":[{"x":1,"y":1,"type":"pest_damage","severity":3,"confidence":0.9},{"x":1,"y":3,"type":"disease","severity":2,"confidence":0.5}]}`,
+			setupMocks: func() {
+				estate()
+				flight()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"finding 1: index out of bound"}`,
+		},
+		{
+			name:        "CREATED",
+			requestBody: `{"findings":[{"x":2,"y":1,"type":"yellowing_fronds","severity":2,"confidence":0.75,"image_ref":"frame-0042.jpg"}]}`,
+			setupMocks: func() {
+				estate()
+				flight()
+				imageRef := "frame-0042.jpg"
+				mockRepository.EXPECT().CreateFindings(gomock.Any(), repository.CreateFindingsInput{
+					Findings: []repository.Finding{{
+						EstateId:   id,
+						FlightId:   flightId,
+						X:          2,
+						Y:          1,
+						Type:       repository.FindingTypeYellowingFronds,
+						Severity:   2,
+						Confidence: 0.75,
+						ImageRef:   &imageRef,
+						DetectedAt: finishedAt,
+					}},
+				}).Return([]repository.Finding{{
+					Id:         findingId,
+					EstateId:   id,
+					FlightId:   flightId,
+					TreeId:     &treeId,
+					X:          2,
+					Y:          1,
+					Type:       repository.FindingTypeYellowingFronds,
+					Severity:   2,
+					Confidence: 0.75,
+					ImageRef:   &imageRef,
+					Status:     repository.FindingStatusOpen,
+					DetectedAt: finishedAt,
+				}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"findings":[{"confidence":0.75,"detected_at":"2024-01-01T06:30:00Z","flight_id":"` + flightId + `","id":"` + findingId + `","image_ref":"frame-0042.jpg","severity":2,"status":"open","tree_id":"` + treeId + `","type":"yellowing_fronds","x":2,"y":1}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/"+id+"/flights/"+flightId+"/findings", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_GetEstateIdFindings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	flightId := uuid.New().String()
+	treeId := uuid.New().String()
+	detectedAt := time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	findings := []repository.Finding{
+		{Id: uuid.New().String(), FlightId: flightId, TreeId: &treeId, X: 2, Y: 1, Type: "pest_damage", Severity: 4, Confidence: 0.9, Status: "open", DetectedAt: detectedAt},
+		{Id: uuid.New().String(), FlightId: flightId, TreeId: &treeId, X: 2, Y: 1, Type: "pest_damage", Severity: 3, Confidence: 0.8, Status: "open", DetectedAt: detectedAt.Add(-time.Hour)},
+	}
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/findings", wrapper.GetEstateIdFindings)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+			Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+		expectedLink   string
+	}{
+		{
+			name:           "BAD_REQUEST_TYPE",
+			query:          "?type=locusts",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'GetEstateIdFindingsParams.Type' Error:Field validation for 'Type' failed on the 'oneof' tag"}`,
+		},
+		{
+			name:  "NOT_FOUND",
+			query: "",
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:  "FILTER",
+			query: "?tree_id=" + treeId + "&type=pest_damage&status=open&min_severity=3&from=2024-01-01T00:00:00Z&limit=1",
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ListFindingsByEstateId(gomock.Any(), repository.ListFindingsByEstateIdInput{
+					EstateId: id,
+					Filter: repository.FindingFilter{
+						TreeId:      treeId,
+						Type:        repository.FindingTypePestDamage,
+						Status:      repository.FindingStatusOpen,
+						MinSeverity: 3,
+						From:        &from,
+					},
+					Limit: 2,
+				}).Return(findings, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"findings":[{"confidence":0.9,"detected_at":"2024-01-01T06:30:00Z","flight_id":"` + flightId + `","id":"` + findings[0].Id + `","severity":4,"status":"open","tree_id":"` + treeId + `","type":"pest_damage","x":2,"y":1}],"next_cursor":"` + pagination.Cursor{Time: detectedAt, Id: findings[0].Id}.Encode() + `"}`,
+			expectedLink:   "rel=\"next\"",
+		},
+		{
+			name:  "INTERNAL_SERVER_ERROR",
+			query: "",
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ListFindingsByEstateId(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/findings"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+			assert.Contains(t, rec.Header().Get("Link"), tc.expectedLink)
+		})
+	}
+}
+
+func TestServer_GetEstateIdFindingsSummary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	detectedAt := time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/findings/summary", wrapper.GetEstateIdFindingsSummary)
+
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+		Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+	mockRepository.EXPECT().CountFindingsByEstateId(gomock.Any(), gomock.Any()).Return([]repository.FindingCount{
+		{Type: "pest_damage", Severity: 4, Status: "open", Count: 2, LastDetectedAt: detectedAt},
+		{Type: "ripe_bunches", Severity: 1, Status: "resolved", Count: 5, LastDetectedAt: detectedAt.Add(-time.Hour)},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/findings/summary", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"by_severity":[{"count":5,"open":0,"severity":1},{"count":2,"open":2,"severity":4}],"by_type":[{"count":2,"max_open_severity":4,"open":2,"type":"pest_damage"},{"count":5,"max_open_severity":0,"open":0,"type":"ripe_bunches"}],"last_detected_at":"2024-01-01T06:30:00Z","open":2,"total":7}`,
+		strings.TrimSuffix(rec.Body.String(), "\n"))
+}
+
+func TestServer_PostEstateIdFindingsFindingIdResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	findingId := uuid.New().String()
+	detectedAt := time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)
+	resolvedAt := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.POST("/estate/:id/findings/:findingId/resolve", wrapper.PostEstateIdFindingsFindingIdResolve)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+			Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		findingId      string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			findingId:      "123",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'FindingIdPath.ID' Error:Field validation for 'ID' failed on the 'uuid4' tag"}`,
+		},
+		{
+			name:      "FINDING_NOT_FOUND",
+			findingId: findingId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ResolveFinding(gomock.Any(), gomock.Any()).Return(repository.Finding{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"finding is not found"}`,
+		},
+		{
+			name:      "OK",
+			findingId: findingId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().ResolveFinding(gomock.Any(), repository.ResolveFindingInput{Id: findingId, EstateId: id}).Return(repository.Finding{
+					Id:         findingId,
+					X:          1,
+					Y:          2,
+					Type:       repository.FindingTypeDisease,
+					Severity:   5,
+					Confidence: 1,
+					Status:     repository.FindingStatusResolved,
+					DetectedAt: detectedAt,
+					ResolvedAt: &resolvedAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"confidence":1,"detected_at":"2024-01-01T06:30:00Z","flight_id":"","id":"` + findingId + `","resolved_at":"2024-01-02T09:00:00Z","severity":5,"status":"resolved","type":"disease","x":1,"y":2}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/"+id+"/findings/"+tc.findingId+"/resolve", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
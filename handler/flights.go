@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/flights"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// maxFlightLogSize is enough for flights.MaxPoints positions
+const maxFlightLogSize = 16 << 20
+
+func (s *Server) PostEstateIdFlights(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionMissionRun) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Parse the flight log
+	body := http.MaxBytesReader(ctx.Response(), ctx.Request().Body, maxFlightLogSize)
+	var points []repository.FlightPoint
+	var err error
+	if strings.HasPrefix(ctx.Request().Header.Get(echo.HeaderContentType), "text/csv") {
+		points, err = flights.ParseCSV(body)
+	} else {
+		points, err = flights.ParseJSON(body)
+	}
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return ctx.JSON(http.StatusRequestEntityTooLarge, generated.ErrorResponse{Message: "flight log is too large"})
+		}
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Create flight
+	flight, err := s.Repository.CreateFlight(ctx.Request().Context(), repository.Flight{
+		EstateId:    estate.Id,
+		StartedAt:   points[0].RecordedAt,
+		FinishedAt:  points[len(points)-1].RecordedAt,
+		PointCount:  len(points),
+		Distance:    flights.Distance(points),
+		BatteryUsed: flights.BatteryUsed(points),
+		Points:      points,
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, generated.FlightResponse{
+		Id:          flight.Id,
+		EstateId:    flight.EstateId,
+		StartedAt:   flight.StartedAt,
+		FinishedAt:  flight.FinishedAt,
+		PointCount:  flight.PointCount,
+		Distance:    roundMeters(flight.Distance),
+		BatteryUsed: flight.BatteryUsed,
+	})
+}
+
+func (s *Server) GetEstateIdFlightsFlightIdComparison(ctx echo.Context, id string, flightId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(FlightIdPath{ID: flightId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Check flight exist
+	flight, err := s.Repository.GetFlightById(ctx.Request().Context(), repository.GetFlightByIdInput{
+		Id:       flightId,
+		EstateId: estate.Id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "flight is not found"})
+		} else {
+			return s.internalError(ctx, err)
+		}
+	}
+
+	points, err := s.Repository.ListFlightPointsByFlightId(ctx.Request().Context(), repository.ListFlightPointsByFlightIdInput{
+		FlightId: flight.Id,
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	// compare with the route planned from the current trees, streamed so only the planner is in memory
+	route := planner.New(estate.Width, estate.Length)
+	err = s.Repository.StreamTreesByEstateId(ctx.Request().Context(), repository.StreamTreesByEstateIdInput{
+		EstateId: estate.Id,
+	}, func(tree repository.Tree) error {
+		route.AddTree(tree.X, tree.Y, tree.Height)
+		return nil
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	comparison := flights.Compare(estate, route, points)
+	missedPlots := make([]map[string]interface{}, 0, len(comparison.MissedPlots))
+	for _, plot := range comparison.MissedPlots {
+		missedPlots = append(missedPlots, map[string]interface{}{"x": plot.X, "y": plot.Y})
+	}
+	return ctx.JSON(http.StatusOK, generated.FlightComparisonResponse{
+		PlannedDistance:       comparison.PlannedDistance,
+		ActualDistance:        roundMeters(comparison.ActualDistance),
+		DistanceDifference:    roundMeters(comparison.DistanceDifference),
+		MaxDeviation:          roundMeters(comparison.MaxDeviation),
+		MeanDeviation:         roundMeters(comparison.MeanDeviation),
+		MaxAltitudeDeviation:  roundMeters(comparison.MaxAltitudeDeviation),
+		MeanAltitudeDeviation: roundMeters(comparison.MeanAltitudeDeviation),
+		PlotsVisited:          comparison.PlotsVisited,
+		PlotsMissed:           comparison.PlotsMissed,
+		MissedPlots:           missedPlots,
+		BatteryUsed:           comparison.BatteryUsed,
+	})
+}
+
+// roundMeters round to the centimeter, the telemetry is not more accurate
+func roundMeters(v float64) float64 {
+	return math.Round(v*100) / 100
+}
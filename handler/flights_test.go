@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_PostEstateIdFlights(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	flightId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.POST("/estate/:id/flights", func(c echo.Context) error {
+		return s.PostEstateIdFlights(c, c.Param("id"))
+	})
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{
+			Id:             id,
+			OrganisationId: orgId,
+			Width:          2,
+			Length:         5,
+		}, nil)
+	}
+	createFlight := func() {
+		mockRepository.EXPECT().CreateFlight(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, input repository.Flight) (repository.Flight, error) {
+				assert.Equal(t, 2, len(input.Points))
+				assert.Equal(t, 10.0, input.Distance)
+				input.Id = flightId
+				return input, nil
+			})
+	}
+
+	testCases := []struct {
+		name           string
+		contentType    string
+		requestBody    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			contentType:    echo.MIMEApplicationJSON,
+			requestBody:    `{"points":[]}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"flight log has no position"}`,
+		},
+		{
+			name:        "ESTATE_NOT_FOUND",
+			contentType: echo.MIMEApplicationJSON,
+			requestBody: `{"points":[{"timestamp":"2024-01-01T06:00:00Z","x":1,"y":1,"altitude":1}]}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:        "CREATED_FROM_JSON",
+			contentType: echo.MIMEApplicationJSON,
+			requestBody: `{"points":[{"timestamp":"2024-01-01T06:00:00Z","x":1,"y":1,"altitude":1,"battery":100},{"timestamp":"2024-01-01T06:00:05Z","x":2,"y":1,"altitude":1,"battery":87.5}]}`,
+			setupMocks: func() {
+				estate()
+				createFlight()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"battery_used":12.5,"distance":10,"estate_id":"` + id + `","finished_at":"2024-01-01T06:00:05Z","id":"` + flightId + `","point_count":2,"started_at":"2024-01-01T06:00:00Z"}`,
+		},
+		{
+			name:        "CREATED_FROM_CSV",
+			contentType: "text/csv; charset=utf-8",
+			requestBody: "timestamp,x,y,altitude,battery\n2024-01-01T06:00:00Z,1,1,1,100\n2024-01-01T06:00:05Z,2,1,1,87.5\n",
+			setupMocks: func() {
+				estate()
+				createFlight()
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"battery_used":12.5,"distance":10,"estate_id":"` + id + `","finished_at":"2024-01-01T06:00:05Z","id":"` + flightId + `","point_count":2,"started_at":"2024-01-01T06:00:00Z"}`,
+		},
+		{
+			name:        "INTERNAL_SERVER_ERROR",
+			contentType: echo.MIMEApplicationJSON,
+			requestBody: `{"points":[{"timestamp":"2024-01-01T06:00:00Z","x":1,"y":1,"altitude":1}]}`,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().CreateFlight(gomock.Any(), gomock.Any()).Return(repository.Flight{}, errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/"+id+"/flights", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_GetEstateIdFlightsFlightIdComparison(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	flightId := uuid.New().String()
+	start := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.GET("/estate/:id/flights/:flightId/comparison", func(c echo.Context) error {
+		return s.GetEstateIdFlightsFlightIdComparison(c, c.Param("id"), c.Param("flightId"))
+	})
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{
+			Id:             id,
+			OrganisationId: orgId,
+			Width:          2,
+			Length:         5,
+		}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		requestId      string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestId:      "11",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'FlightIdPath.ID' Error:Field validation for 'ID' failed on the 'uuid4' tag"}`,
+		},
+		{
+			name:      "FLIGHT_NOT_FOUND",
+			requestId: flightId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().GetFlightById(gomock.Any(), repository.GetFlightByIdInput{
+					Id:       flightId,
+					EstateId: id,
+				}).Return(repository.Flight{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"flight is not found"}`,
+		},
+		{
+			name:      "OK",
+			requestId: flightId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().GetFlightById(gomock.Any(), repository.GetFlightByIdInput{
+					Id:       flightId,
+					EstateId: id,
+				}).Return(repository.Flight{Id: flightId, EstateId: id}, nil)
+				mockRepository.EXPECT().ListFlightPointsByFlightId(gomock.Any(), repository.ListFlightPointsByFlightIdInput{
+					FlightId: flightId,
+				}).Return([]repository.FlightPoint{
+					{FlightId: flightId, Seq: 1, RecordedAt: start, X: 1, Y: 1, Altitude: 1},
+					{FlightId: flightId, Seq: 2, RecordedAt: start.Add(time.Minute), X: 5, Y: 1, Altitude: 1},
+					{FlightId: flightId, Seq: 3, RecordedAt: start.Add(2 * time.Minute), X: 5, Y: 2, Altitude: 1},
+					{FlightId: flightId, Seq: 4, RecordedAt: start.Add(3 * time.Minute), X: 4, Y: 2, Altitude: 1},
+				}, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+					EstateId: id,
+				}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+					{EstateId: id, X: 3, Y: 1, Height: 5},
+					{EstateId: id, X: 3, Y: 2, Height: 5},
+				}))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"actual_distance":60,"distance_difference":-52,"max_altitude_deviation":0,"max_deviation":0,"mean_altitude_deviation":0,"mean_deviation":0,"missed_plots":[{"x":3,"y":2},{"x":2,"y":2},{"x":1,"y":2}],"planned_distance":112,"plots_missed":3,"plots_visited":7}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/flights/"+tc.requestId+"/comparison", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	dronepatrolv1 "github.com/SawitProRecruitment/UserService/generated/dronepatrol/v1"
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcServer serves the gRPC API with the rules, the cache and the events of the REST API server
+type GrpcServer struct {
+	dronepatrolv1.UnimplementedDronePatrolServiceServer
+	server *Server
+}
+
+func NewGrpcServer(server *Server) *GrpcServer {
+	return &GrpcServer{server: server}
+}
+
+func (g *GrpcServer) CreateEstate(ctx context.Context, req *dronepatrolv1.CreateEstateRequest) (*dronepatrolv1.CreateEstateResponse, error) {
+	// Check permission
+	if !g.server.authorizeResource(ctx, rbac.PermissionEstateManage, dronepatrolv1.DronePatrolService_CreateEstate_FullMethodName) {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	estate, err := g.server.Estates.CreateEstate(ctx, callerOrganisation(ctx), service.CreateEstateRequest{
+		Length: int(req.GetLength()),
+		Width:  int(req.GetWidth()),
+	})
+	if err != nil {
+		return nil, g.grpcError(ctx, err)
+	}
+	return &dronepatrolv1.CreateEstateResponse{Id: estate.Id}, nil
+}
+
+func (g *GrpcServer) CreateTree(ctx context.Context, req *dronepatrolv1.CreateTreeRequest) (*dronepatrolv1.CreateTreeResponse, error) {
+	// Check permission
+	if !g.server.authorizeResource(ctx, rbac.PermissionTreeWrite, dronepatrolv1.DronePatrolService_CreateTree_FullMethodName) {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+	// Request Validate
+	if err := g.server.Validator.Struct(IdPath{ID: req.GetEstateId()}); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	tree, err := g.server.Trees.CreateTree(ctx, callerOrganisation(ctx), req.GetEstateId(), service.CreateTreeRequest{
+		Height: int(req.GetHeight()),
+		X:      int(req.GetX()),
+		Y:      int(req.GetY()),
+	})
+	if err != nil {
+		return nil, g.grpcError(ctx, err)
+	}
+	return &dronepatrolv1.CreateTreeResponse{Id: tree.Id}, nil
+}
+
+func (g *GrpcServer) GetEstateStats(ctx context.Context, req *dronepatrolv1.GetEstateStatsRequest) (*dronepatrolv1.GetEstateStatsResponse, error) {
+	// Check permission
+	if !g.server.authorizeResource(ctx, rbac.PermissionEstateRead, dronepatrolv1.DronePatrolService_GetEstateStats_FullMethodName) {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+	// Request Validate
+	if err := g.server.Validator.Struct(IdPath{ID: req.GetEstateId()}); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	estate, err := g.server.Estates.GetEstate(ctx, callerOrganisation(ctx), req.GetEstateId())
+	if err != nil {
+		return nil, g.grpcError(ctx, err)
+	}
+	stats, err := g.server.Trees.Stats(ctx, estate)
+	if err != nil {
+		return nil, g.grpcError(ctx, err)
+	}
+	return &dronepatrolv1.GetEstateStatsResponse{
+		Count:  int64(stats.Count),
+		Max:    int32(stats.Max),
+		Min:    int32(stats.Min),
+		Median: int32(stats.Median),
+	}, nil
+}
+
+// maxStreamedWaypoints bound the waypoints of a drone plan stream, the route of an estate of more plots is
+// streamed with a max distance short enough to stay under the bound
+const maxStreamedWaypoints = 1_000_000
+
+// GetDronePlan walk the route plot by plot, a stream of a large estate is long and ends when the client
+// cancels it
+func (g *GrpcServer) GetDronePlan(req *dronepatrolv1.GetDronePlanRequest, stream dronepatrolv1.DronePatrolService_GetDronePlanServer) error {
+	ctx := stream.Context()
+	// Check permission
+	if !g.server.authorizeResource(ctx, rbac.PermissionPlanRead, dronepatrolv1.DronePatrolService_GetDronePlan_FullMethodName) {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	// Request Validate
+	if err := g.server.Validator.Struct(IdPath{ID: req.GetEstateId()}); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	var maxDistance *int
+	if req.MaxDistance != nil {
+		v := int(req.GetMaxDistance())
+		maxDistance = &v
+	}
+	if err := service.ValidateMaxDistance(maxDistance); err != nil {
+		return g.grpcError(ctx, err)
+	}
+
+	estate, err := g.server.Estates.GetEstate(ctx, callerOrganisation(ctx), req.GetEstateId())
+	if err != nil {
+		return g.grpcError(ctx, err)
+	}
+	// every waypoint after the first one is at least 10 meters further
+	if estate.Length*estate.Width > maxStreamedWaypoints && (maxDistance == nil || *maxDistance/10 >= maxStreamedWaypoints) {
+		return status.Errorf(codes.InvalidArgument, "the route of an estate of more than %d plots needs a max_distance under %d meters", maxStreamedWaypoints, maxStreamedWaypoints*10)
+	}
+
+	var sendErr error
+	plan, err := g.server.DronePlans.Walk(ctx, estate, maxDistance, func(w planner.Waypoint) bool {
+		sendErr = stream.Send(&dronepatrolv1.GetDronePlanResponse{
+			Item: &dronepatrolv1.GetDronePlanResponse_Waypoint{Waypoint: &dronepatrolv1.Waypoint{
+				Plot:     &dronepatrolv1.Plot{X: int32(w.X), Y: int32(w.Y)},
+				Altitude: int32(w.Altitude),
+				Distance: int64(w.Distance),
+			}},
+		})
+		return sendErr == nil
+	})
+	if err != nil {
+		return g.grpcError(ctx, err)
+	}
+	if sendErr != nil {
+		// the client is gone
+		return sendErr
+	}
+
+	return stream.Send(&dronepatrolv1.GetDronePlanResponse{
+		Item: &dronepatrolv1.GetDronePlanResponse_Plan{Plan: &dronepatrolv1.DronePlan{
+			Distance: int64(plan.Distance),
+			Rest:     &dronepatrolv1.Plot{X: int32(plan.Rest.X), Y: int32(plan.Rest.Y)},
+		}},
+	})
+}
+
+// grpcError returns the status of an error of the services, the unexpected errors are logged and answered
+// with a generic message so database details never leak
+func (g *GrpcServer) grpcError(ctx context.Context, err error) error {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrEstateNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &validationErr),
+		errors.Is(err, service.ErrOutOfBound),
+		errors.Is(err, service.ErrInvalidMaxDistance),
+		errors.Is(err, service.ErrInvalidTreeAttributes),
+		errors.Is(err, service.ErrInvalidTreeHealth):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrPlotExist):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	method, _ := grpc.Method(ctx)
+	g.server.Logger.ErrorContext(ctx, "request failed",
+		slog.String("method", method),
+		slog.String("error", err.Error()),
+	)
+	return status.Error(codes.Internal, internalErrorMessage)
+}
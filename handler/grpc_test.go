@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/auth"
+	dronepatrolv1 "github.com/SawitProRecruitment/UserService/generated/dronepatrol/v1"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func grpcContext(ctx context.Context, organisationId string, role rbac.Role) context.Context {
+	return auth.WithPrincipal(ctx, auth.Principal{
+		Subject:        "test",
+		OrganisationId: organisationId,
+		Role:           role,
+		Method:         auth.MethodApiKey,
+	})
+}
+
+func TestGrpcServer_CreateTree(t *testing.T) {
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	treeId := uuid.New().String()
+	tests := []struct {
+		name     string
+		role     rbac.Role
+		req      *dronepatrolv1.CreateTreeRequest
+		mock     func(mockRepository *repository.MockRepositoryInterface)
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{
+			name: "forbidden",
+			role: rbac.RoleViewer,
+			req:  &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 1, Y: 1, Height: 10},
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Return(repository.AuditLog{}, nil)
+			},
+			wantCode: codes.PermissionDenied,
+			wantMsg:  "forbidden",
+		},
+		{
+			name:     "invalid height",
+			role:     rbac.RoleAdmin,
+			req:      &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 1, Y: 1, Height: 31},
+			mock:     func(mockRepository *repository.MockRepositoryInterface) {},
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "Key: 'CreateTreeRequest.Height' Error:Field validation for 'Height' failed on the 'lte' tag",
+		},
+		{
+			name: "estate not found",
+			role: rbac.RoleAdmin,
+			req:  &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 1, Y: 1, Height: 10},
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			wantCode: codes.NotFound,
+			wantMsg:  "estate is not found",
+		},
+		{
+			name: "out of bound",
+			role: rbac.RoleAdmin,
+			req:  &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 6, Y: 1, Height: 10},
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 5, Width: 3}, nil)
+			},
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "index out of bound",
+		},
+		{
+			name: "plot already exist",
+			role: rbac.RoleAdmin,
+			req:  &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 1, Y: 1, Height: 10},
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 5, Width: 3}, nil)
+				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), repository.GetTreeByPlot{
+					EstateId: id,
+					X:        1,
+					Y:        1,
+				}).Return(repository.Tree{Id: treeId}, nil)
+			},
+			wantCode: codes.AlreadyExists,
+			wantMsg:  "plot already exist",
+		},
+		{
+			name: "database error",
+			role: rbac.RoleAdmin,
+			req:  &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 1, Y: 1, Height: 10},
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, errors.New("connection refused"))
+			},
+			wantCode: codes.Internal,
+			wantMsg:  "internal server error",
+		},
+		{
+			name: "success",
+			role: rbac.RoleAdmin,
+			req:  &dronepatrolv1.CreateTreeRequest{EstateId: id, X: 1, Y: 1, Height: 10},
+			mock: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 5, Width: 3}, nil)
+				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), repository.GetTreeByPlot{
+					EstateId: id,
+					X:        1,
+					Y:        1,
+				}).Return(repository.Tree{}, sql.ErrNoRows)
+				mockRepository.EXPECT().CreateTree(gomock.Any(), repository.Tree{
+					X:        1,
+					Y:        1,
+					Height:   10,
+					EstateId: id,
+				}).Return(repository.Tree{Id: treeId}, nil)
+			},
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.mock(mockRepository)
+			g := NewGrpcServer(NewServer(NewServerOptions{
+				Repository: mockRepository,
+			}))
+
+			res, err := g.CreateTree(grpcContext(context.Background(), orgId, tt.role), tt.req)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantMsg, status.Convert(err).Message())
+				return
+			}
+			assert.Equal(t, treeId, res.GetId())
+		})
+	}
+}
+
+func TestGrpcServer_GetDronePlan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+		Id: id,
+	}).Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 3, Width: 2}, nil)
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+		EstateId: id,
+	}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+		{X: 2, Y: 1, Height: 5},
+		{X: 3, Y: 2, Height: 2},
+	}))
+
+	// serve over an in memory connection, the principal is set as the auth interceptor does
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.StreamInterceptor(
+		func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &principalStream{ServerStream: stream, ctx: grpcContext(stream.Context(), orgId, rbac.RoleViewer)})
+		},
+	))
+	dronepatrolv1.RegisterDronePatrolServiceServer(server, NewGrpcServer(NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := dronepatrolv1.NewDronePatrolServiceClient(conn).GetDronePlan(context.Background(),
+		&dronepatrolv1.GetDronePlanRequest{EstateId: id})
+	require.NoError(t, err)
+	var responses []*dronepatrolv1.GetDronePlanResponse
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		responses = append(responses, res)
+	}
+
+	waypoint := func(x, y, altitude int32, distance int64) *dronepatrolv1.GetDronePlanResponse {
+		return &dronepatrolv1.GetDronePlanResponse{Item: &dronepatrolv1.GetDronePlanResponse_Waypoint{
+			Waypoint: &dronepatrolv1.Waypoint{Plot: &dronepatrolv1.Plot{X: x, Y: y}, Altitude: altitude, Distance: distance},
+		}}
+	}
+	want := []*dronepatrolv1.GetDronePlanResponse{
+		waypoint(1, 1, 1, 1),
+		waypoint(2, 1, 6, 16),
+		waypoint(3, 1, 1, 31),
+		waypoint(3, 2, 3, 41),
+		waypoint(2, 2, 1, 53),
+		waypoint(1, 2, 1, 63),
+		{Item: &dronepatrolv1.GetDronePlanResponse_Plan{
+			Plan: &dronepatrolv1.DronePlan{Distance: 64, Rest: &dronepatrolv1.Plot{X: 1, Y: 2}},
+		}},
+	}
+	require.Len(t, responses, len(want))
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], responses[i]), "response %d: %v", i, responses[i])
+	}
+}
+
+func TestGrpcServer_GetDronePlan_Unbounded(t *testing.T) {
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	maxDistance := func(d int64) *int64 { return &d }
+	tests := []struct {
+		name        string
+		maxDistance *int64
+	}{
+		{name: "no max distance"},
+		{name: "max distance too long", maxDistance: maxDistance(10 * maxStreamedWaypoints)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the planner of the estate is not built
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+				Id: id,
+			}).Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 2000, Width: 1000}, nil)
+			g := NewGrpcServer(NewServer(NewServerOptions{Repository: mockRepository}))
+
+			stream := &sendStream{ctx: grpcContext(context.Background(), orgId, rbac.RoleViewer)}
+			err := g.GetDronePlan(&dronepatrolv1.GetDronePlanRequest{EstateId: id, MaxDistance: tt.maxDistance}, stream)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Empty(t, stream.sent)
+		})
+	}
+}
+
+// sendStream records the messages of a server stream
+type sendStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*dronepatrolv1.GetDronePlanResponse
+}
+
+func (s *sendStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sendStream) Send(res *dronepatrolv1.GetDronePlanResponse) error {
+	s.sent = append(s.sent, res)
+	return nil
+}
+
+// principalStream carries the context with the principal, as the one of the auth interceptor
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
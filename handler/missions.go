@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/missions"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultMissionsLimit = 20
+
+func (s *Server) PostEstateIdMissionsSchedules(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionMissionRun) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	createMissionScheduleRequest := new(generated.CreateMissionScheduleRequest)
+	err := ctx.Bind(&createMissionScheduleRequest)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(createMissionScheduleRequest); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	timeZone := "UTC"
+	if createMissionScheduleRequest.TimeZone != nil {
+		timeZone = *createMissionScheduleRequest.TimeZone
+	}
+	if _, err := missions.ParseSchedule(createMissionScheduleRequest.Cron, timeZone); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Create schedule
+	schedule, err := s.Repository.CreateMissionSchedule(ctx.Request().Context(), repository.MissionSchedule{
+		EstateId:    estate.Id,
+		Name:        createMissionScheduleRequest.Name,
+		Cron:        createMissionScheduleRequest.Cron,
+		TimeZone:    timeZone,
+		MaxDistance: createMissionScheduleRequest.MaxDistance,
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	// the upcoming missions are listed right away, the scheduler would create them on its next run anyway
+	if err := s.Missions.Materialise(ctx.Request().Context(), schedule); err != nil {
+		s.Logger.ErrorContext(ctx.Request().Context(), "failed to materialise missions", slog.String("schedule_id", schedule.Id), slog.String("error", err.Error()))
+	}
+
+	return ctx.JSON(http.StatusCreated, missionScheduleResponse(schedule, time.Now()))
+}
+
+func (s *Server) GetEstateIdMissionsSchedules(ctx echo.Context, id string, params generated.GetEstateIdMissionsSchedulesParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(params); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	page, err := pagination.NewRequest(params.Cursor, params.Limit, defaultPageLimit, pagination.KindTimeId)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	schedules, err := s.Repository.ListMissionSchedulesByEstateId(ctx.Request().Context(), repository.ListMissionSchedulesByEstateIdInput{
+		EstateId: estate.Id,
+		After:    page.After,
+		Limit:    page.Fetch(),
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	list := pagination.NewPage(schedules, page, func(schedule repository.MissionSchedule) pagination.Cursor {
+		return pagination.Cursor{Time: schedule.CreatedAt, Id: schedule.Id}
+	})
+
+	now := time.Now()
+	response := generated.ListMissionSchedulesResponse{Schedules: make([]generated.MissionScheduleResponse, 0, len(list.Items))}
+	for _, schedule := range list.Items {
+		response.Schedules = append(response.Schedules, missionScheduleResponse(schedule, now))
+	}
+	response.NextCursor = nextPage(ctx, list.Next)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) DeleteEstateIdMissionsSchedulesScheduleId(ctx echo.Context, id string, scheduleId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionMissionRun) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(ScheduleIdPath{ID: scheduleId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Stop schedule
+	_, err = s.Repository.DeactivateMissionSchedule(ctx.Request().Context(), repository.DeactivateMissionScheduleInput{
+		Id:       scheduleId,
+		EstateId: estate.Id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "schedule is not found"})
+		} else {
+			return s.internalError(ctx, err)
+		}
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) GetEstateIdMissions(ctx echo.Context, id string, params generated.GetEstateIdMissionsParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(params); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	upcoming := true
+	if params.When != nil {
+		switch *params.When {
+		case generated.Upcoming:
+		case generated.Past:
+			upcoming = false
+		default:
+			return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: "when must be upcoming or past"})
+		}
+	}
+	page, err := pagination.NewRequest(params.Cursor, params.Limit, defaultMissionsLimit, pagination.KindTimeId)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	found, err := s.Repository.ListMissionsByEstateId(ctx.Request().Context(), repository.ListMissionsByEstateIdInput{
+		EstateId: estate.Id,
+		Upcoming: upcoming,
+		Now:      time.Now().UTC(),
+		After:    page.After,
+		Limit:    page.Fetch(),
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	list := pagination.NewPage(found, page, func(mission repository.Mission) pagination.Cursor {
+		return pagination.Cursor{Time: mission.ScheduledAt, Id: mission.Id}
+	})
+
+	response := generated.ListMissionsResponse{Missions: make([]generated.MissionResponse, 0, len(list.Items))}
+	for _, mission := range list.Items {
+		response.Missions = append(response.Missions, missionResponse(mission))
+	}
+	response.NextCursor = nextPage(ctx, list.Next)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func missionScheduleResponse(schedule repository.MissionSchedule, now time.Time) generated.MissionScheduleResponse {
+	response := generated.MissionScheduleResponse{
+		Id:          schedule.Id,
+		EstateId:    schedule.EstateId,
+		Name:        schedule.Name,
+		Cron:        schedule.Cron,
+		TimeZone:    schedule.TimeZone,
+		MaxDistance: schedule.MaxDistance,
+		CreatedAt:   schedule.CreatedAt,
+	}
+	if parsed, err := missions.ParseSchedule(schedule.Cron, schedule.TimeZone); err == nil {
+		if next := parsed.Next(now); !next.IsZero() {
+			response.NextRunAt = &next
+		}
+	}
+	return response
+}
+
+func missionResponse(mission repository.Mission) generated.MissionResponse {
+	response := generated.MissionResponse{
+		Id:           mission.Id,
+		ScheduleId:   mission.ScheduleId,
+		ScheduledAt:  mission.ScheduledAt,
+		Status:       generated.MissionResponseStatus(mission.Status),
+		DispatchedAt: mission.DispatchedAt,
+	}
+	if mission.Distance != nil && mission.RestX != nil && mission.RestY != nil {
+		rest := map[string]interface{}{"x": *mission.RestX, "y": *mission.RestY}
+		response.Plan = &generated.GetEstateDronePlanResponse{Distance: *mission.Distance, Rest: &rest}
+	}
+	return response
+}
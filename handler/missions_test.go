@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_PostEstateIdMissionsSchedules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	scheduleId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.POST("/estate/:id/missions/schedules", func(c echo.Context) error {
+		return s.PostEstateIdMissionsSchedules(c, c.Param("id"))
+	})
+
+	testCases := []struct {
+		name           string
+		requestBody    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestBody:    `{"name":"Monday patrol"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateMissionScheduleRequest.Cron' Error:Field validation for 'Cron' failed on the 'required' tag"}`,
+		},
+		{
+			name:           "INVALID_TIME_ZONE",
+			requestBody:    `{"name":"Monday patrol","cron":"0 6 * * 1","time_zone":"Mars/Olympus"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"invalid schedule: unknown time zone Mars/Olympus"}`,
+		},
+		{
+			name:        "ESTATE_NOT_FOUND",
+			requestBody: `{"name":"Monday patrol","cron":"0 6 * * 1"}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name: "CREATED",
+			// on February 30, the schedule never runs and has no next run
+			requestBody: `{"name":"Leap patrol","cron":"0 6 30 2 *","time_zone":"Asia/Jakarta","max_distance":1000}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+				mockRepository.EXPECT().CreateMissionSchedule(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, input repository.MissionSchedule) (repository.MissionSchedule, error) {
+						assert.Equal(t, "Asia/Jakarta", input.TimeZone)
+						input.Id = scheduleId
+						input.Active = true
+						input.CreatedAt = createdAt
+						return input, nil
+					})
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"created_at":"2024-01-01T00:00:00Z","cron":"0 6 30 2 *","estate_id":"` + id + `","id":"` + scheduleId + `","max_distance":1000,"name":"Leap patrol","time_zone":"Asia/Jakarta"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/"+id+"/missions/schedules", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_DeleteEstateIdMissionsSchedulesScheduleId(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	scheduleId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.DELETE("/estate/:id/missions/schedules/:scheduleId", func(c echo.Context) error {
+		return s.DeleteEstateIdMissionsSchedulesScheduleId(c, c.Param("id"), c.Param("scheduleId"))
+	})
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		requestId      string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestId:      "11",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'ScheduleIdPath.ID' Error:Field validation for 'ID' failed on the 'uuid4' tag"}`,
+		},
+		{
+			name:      "SCHEDULE_NOT_FOUND",
+			requestId: scheduleId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().DeactivateMissionSchedule(gomock.Any(), repository.DeactivateMissionScheduleInput{
+					Id:       scheduleId,
+					EstateId: id,
+				}).Return(repository.MissionSchedule{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"schedule is not found"}`,
+		},
+		{
+			name:      "NO_CONTENT",
+			requestId: scheduleId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().DeactivateMissionSchedule(gomock.Any(), repository.DeactivateMissionScheduleInput{
+					Id:       scheduleId,
+					EstateId: id,
+				}).Return(repository.MissionSchedule{Id: scheduleId, EstateId: id}, nil)
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedBody:   ``,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodDelete, "/estate/"+id+"/missions/schedules/"+tc.requestId, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_GetEstateIdMissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	scheduleId := uuid.New().String()
+	missionId := uuid.New().String()
+	distance, restX, restY := 112, 1, 2
+	scheduledAt := time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	// the generated wrapper binds the query parameters
+	wrapper := generated.ServerInterfaceWrapper{Handler: s}
+	e.GET("/estate/:id/missions", wrapper.GetEstateIdMissions)
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{Id: id, OrganisationId: orgId, Width: 2, Length: 5}, nil)
+	}
+	listMissions := func(upcoming bool, limit int, missions []repository.Mission) {
+		mockRepository.EXPECT().ListMissionsByEstateId(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, input repository.ListMissionsByEstateIdInput) ([]repository.Mission, error) {
+				assert.Equal(t, id, input.EstateId)
+				assert.Equal(t, upcoming, input.Upcoming)
+				// one more mission tells whether there is a next page
+				assert.Equal(t, limit+1, input.Limit)
+				return missions, nil
+			})
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			query:          "?limit=101",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'GetEstateIdMissionsParams.Limit' Error:Field validation for 'Limit' failed on the 'lte' tag"}`,
+		},
+		{
+			name:           "INVALID_WHEN",
+			query:          "?when=tomorrow",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"when must be upcoming or past"}`,
+		},
+		{
+			name:  "UPCOMING",
+			query: "",
+			setupMocks: func() {
+				estate()
+				listMissions(true, 20, []repository.Mission{{
+					Id:          missionId,
+					ScheduleId:  scheduleId,
+					EstateId:    id,
+					ScheduledAt: scheduledAt,
+					Status:      repository.MissionStatusScheduled,
+					Distance:    &distance,
+					RestX:       &restX,
+					RestY:       &restY,
+				}})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"missions":[{"id":"` + missionId + `","plan":{"distance":112,"rest":{"x":1,"y":2}},"schedule_id":"` + scheduleId + `","scheduled_at":"2024-01-07T23:00:00Z","status":"scheduled"}]}`,
+		},
+		{
+			name:  "PAST",
+			query: "?when=past&limit=5",
+			setupMocks: func() {
+				estate()
+				listMissions(false, 5, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"missions":[]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/missions"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
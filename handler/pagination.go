@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageLimit      = 100
+	defaultTreesPageLimit = 500
+)
+
+const contentTypeNDJSON = "application/x-ndjson"
+
+// ndjsonFlushEvery is how many lines of a NDJSON stream are sent together
+const ndjsonFlushEvery = 500
+
+// nextPage returns the next_cursor of a page and set the Link header to the URL of the next page, with the same
+// parameters than the request. Nothing is set on the last page.
+func nextPage(ctx echo.Context, next *pagination.Cursor) *string {
+	if next == nil {
+		return nil
+	}
+	cursor := next.Encode()
+	link := *ctx.Request().URL
+	query := link.Query()
+	query.Set("cursor", cursor)
+	link.RawQuery = query.Encode()
+	ctx.Response().Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, link.RequestURI()))
+	return &cursor
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/jobs"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) PostEstateIdDronePlanJobs(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	createPlanJobRequest := new(generated.CreatePlanJobRequest)
+	err := ctx.Bind(&createPlanJobRequest)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(createPlanJobRequest); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Create job
+	job, err := s.Repository.CreatePlanJob(ctx.Request().Context(), repository.PlanJob{
+		EstateId:    estate.Id,
+		MaxDistance: createPlanJobRequest.MaxDistance,
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	if err := s.PlanJobs.Enqueue(job.Id); err != nil {
+		if !errors.Is(err, jobs.ErrQueueFull) {
+			return s.internalError(ctx, err)
+		}
+		// do not leave a job no worker will pick up
+		if _, err := s.Repository.CancelPlanJob(ctx.Request().Context(), repository.CancelPlanJobInput{
+			Id:       job.Id,
+			EstateId: estate.Id,
+		}); err != nil {
+			return s.internalError(ctx, err)
+		}
+		return ctx.JSON(http.StatusServiceUnavailable, generated.ErrorResponse{Message: "too many drone plan jobs, retry later"})
+	}
+
+	ctx.Response().Header().Set(echo.HeaderLocation, "/estate/"+estate.Id+"/drone-plan/jobs/"+job.Id)
+	return ctx.JSON(http.StatusAccepted, planJobResponse(job))
+}
+
+func (s *Server) GetEstateIdDronePlanJobsJobId(ctx echo.Context, id string, jobId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionPlanRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(JobIdPath{ID: jobId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Get job
+	job, err := s.Repository.GetPlanJobById(ctx.Request().Context(), repository.GetPlanJobByIdInput{
+		Id:       jobId,
+		EstateId: estate.Id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "job is not found"})
+		} else {
+			return s.internalError(ctx, err)
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, planJobResponse(job))
+}
+
+func (s *Server) DeleteEstateIdDronePlanJobsJobId(ctx echo.Context, id string, jobId string) error {
+	// Check permission, a viewer can start a job but only an operator stops the jobs of the others
+	if !s.authorize(ctx, rbac.PermissionMissionRun) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := s.Validator.Struct(JobIdPath{ID: jobId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+
+	// Cancel job
+	job, err := s.Repository.CancelPlanJob(ctx.Request().Context(), repository.CancelPlanJobInput{
+		Id:       jobId,
+		EstateId: estate.Id,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return s.internalError(ctx, err)
+		}
+		// not cancellable, tell apart a finished job from an unknown one
+		_, err := s.Repository.GetPlanJobById(ctx.Request().Context(), repository.GetPlanJobByIdInput{
+			Id:       jobId,
+			EstateId: estate.Id,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "job is not found"})
+			} else {
+				return s.internalError(ctx, err)
+			}
+		}
+		return ctx.JSON(http.StatusConflict, generated.ErrorResponse{Message: "job is already finished"})
+	}
+	s.PlanJobs.Cancel(job.Id)
+
+	return ctx.JSON(http.StatusOK, planJobResponse(job))
+}
+
+func planJobResponse(job repository.PlanJob) generated.PlanJobResponse {
+	response := generated.PlanJobResponse{
+		Id:          job.Id,
+		EstateId:    job.EstateId,
+		MaxDistance: job.MaxDistance,
+		Status:      generated.PlanJobResponseStatus(job.Status),
+		Progress:    job.Progress,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+	if job.Distance != nil && job.RestX != nil && job.RestY != nil {
+		rest := map[string]interface{}{"x": *job.RestX, "y": *job.RestY}
+		response.Result = &generated.GetEstateDronePlanResponse{Distance: *job.Distance, Rest: &rest}
+	}
+	return response
+}
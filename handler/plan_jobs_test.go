@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/jobs"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_PostEstateIdDronePlanJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		PlanJobs:   jobs.NewRunner(jobs.NewRunnerOptions{Repository: mockRepository, QueueSize: 1}),
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	jobId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.POST("/estate/:id/drone-plan/jobs", func(c echo.Context) error {
+		return s.PostEstateIdDronePlanJobs(c, c.Param("id"))
+	})
+
+	maxDistance := 40
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{
+			Id:             id,
+			OrganisationId: orgId,
+			Width:          2,
+			Length:         5,
+		}, nil)
+	}
+
+	testCases := []struct {
+		name             string
+		requestId        string
+		requestBody      string
+		setupMocks       func()
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestId:      id,
+			requestBody:    `{"max_distance":0}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreatePlanJobRequest.MaxDistance' Error:Field validation for 'MaxDistance' failed on the 'gte' tag"}`,
+		},
+		{
+			name:        "ESTATE_NOT_FOUND",
+			requestId:   id,
+			requestBody: `{}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"estate is not found"}`,
+		},
+		{
+			name:        "ACCEPTED",
+			requestId:   id,
+			requestBody: `{"max_distance":40}`,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().CreatePlanJob(gomock.Any(), repository.PlanJob{
+					EstateId:    id,
+					MaxDistance: &maxDistance,
+				}).Return(repository.PlanJob{
+					Id:          jobId,
+					EstateId:    id,
+					MaxDistance: &maxDistance,
+					Status:      repository.PlanJobStatusPending,
+					CreatedAt:   createdAt,
+				}, nil)
+			},
+			expectedStatus:   http.StatusAccepted,
+			expectedBody:     `{"created_at":"2024-01-02T03:04:05Z","estate_id":"` + id + `","id":"` + jobId + `","max_distance":40,"progress":0,"status":"pending"}`,
+			expectedLocation: "/estate/" + id + "/drone-plan/jobs/" + jobId,
+		},
+		{
+			name:        "QUEUE_FULL",
+			requestId:   id,
+			requestBody: `{}`,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().CreatePlanJob(gomock.Any(), repository.PlanJob{
+					EstateId: id,
+				}).Return(repository.PlanJob{Id: jobId, EstateId: id}, nil)
+				mockRepository.EXPECT().CancelPlanJob(gomock.Any(), repository.CancelPlanJobInput{
+					Id:       jobId,
+					EstateId: id,
+				}).Return(repository.PlanJob{}, nil)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"message":"too many drone plan jobs, retry later"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/estate/"+tc.requestId+"/drone-plan/jobs", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+			assert.Equal(t, tc.expectedLocation, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+}
+
+func TestServer_GetEstateIdDronePlanJobsJobId(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	jobId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	distance, restX, restY := 112, 1, 2
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.GET("/estate/:id/drone-plan/jobs/:jobId", func(c echo.Context) error {
+		return s.GetEstateIdDronePlanJobsJobId(c, c.Param("id"), c.Param("jobId"))
+	})
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{
+			Id:             id,
+			OrganisationId: orgId,
+		}, nil)
+	}
+
+	testCases := []struct {
+		name           string
+		requestJobId   string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestJobId:   "11",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'JobIdPath.ID' Error:Field validation for 'ID' failed on the 'uuid4' tag"}`,
+		},
+		{
+			name:         "JOB_NOT_FOUND",
+			requestJobId: jobId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().GetPlanJobById(gomock.Any(), repository.GetPlanJobByIdInput{
+					Id:       jobId,
+					EstateId: id,
+				}).Return(repository.PlanJob{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"job is not found"}`,
+		},
+		{
+			name:         "OK_SUCCEEDED",
+			requestJobId: jobId,
+			setupMocks: func() {
+				estate()
+				mockRepository.EXPECT().GetPlanJobById(gomock.Any(), repository.GetPlanJobByIdInput{
+					Id:       jobId,
+					EstateId: id,
+				}).Return(repository.PlanJob{
+					Id:         jobId,
+					EstateId:   id,
+					Status:     repository.PlanJobStatusSucceeded,
+					Progress:   100,
+					Distance:   &distance,
+					RestX:      &restX,
+					RestY:      &restY,
+					CreatedAt:  createdAt,
+					StartedAt:  &createdAt,
+					FinishedAt: &createdAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"created_at":"2024-01-02T03:04:05Z","estate_id":"` + id + `","finished_at":"2024-01-02T03:04:05Z","id":"` + jobId + `","progress":100,"result":{"distance":112,"rest":{"x":1,"y":2}},"started_at":"2024-01-02T03:04:05Z","status":"succeeded"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/drone-plan/jobs/"+tc.requestJobId, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_DeleteEstateIdDronePlanJobsJobId(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	id := uuid.New().String()
+	orgId := uuid.New().String()
+	jobId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.DELETE("/estate/:id/drone-plan/jobs/:jobId", func(c echo.Context) error {
+		return s.DeleteEstateIdDronePlanJobsJobId(c, c.Param("id"), c.Param("jobId"))
+	})
+
+	estate := func() {
+		mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+			Id: id,
+		}).Return(repository.Estate{
+			Id:             id,
+			OrganisationId: orgId,
+		}, nil)
+	}
+	cancel := func(err error) {
+		mockRepository.EXPECT().CancelPlanJob(gomock.Any(), repository.CancelPlanJobInput{
+			Id:       jobId,
+			EstateId: id,
+		}).Return(repository.PlanJob{
+			Id:         jobId,
+			EstateId:   id,
+			Status:     repository.PlanJobStatusCancelled,
+			Progress:   20,
+			CreatedAt:  createdAt,
+			FinishedAt: &createdAt,
+		}, err)
+	}
+
+	testCases := []struct {
+		name           string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "OK",
+			setupMocks: func() {
+				estate()
+				cancel(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"created_at":"2024-01-02T03:04:05Z","estate_id":"` + id + `","finished_at":"2024-01-02T03:04:05Z","id":"` + jobId + `","progress":20,"status":"cancelled"}`,
+		},
+		{
+			name: "ALREADY_FINISHED",
+			setupMocks: func() {
+				estate()
+				cancel(sql.ErrNoRows)
+				mockRepository.EXPECT().GetPlanJobById(gomock.Any(), repository.GetPlanJobByIdInput{
+					Id:       jobId,
+					EstateId: id,
+				}).Return(repository.PlanJob{Id: jobId, Status: repository.PlanJobStatusSucceeded}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"message":"job is already finished"}`,
+		},
+		{
+			name: "JOB_NOT_FOUND",
+			setupMocks: func() {
+				estate()
+				cancel(sql.ErrNoRows)
+				mockRepository.EXPECT().GetPlanJobById(gomock.Any(), repository.GetPlanJobByIdInput{
+					Id:       jobId,
+					EstateId: id,
+				}).Return(repository.PlanJob{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"job is not found"}`,
+		},
+		{
+			name: "INTERNAL_SERVER_ERROR",
+			setupMocks: func() {
+				estate()
+				cancel(errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"message":"internal server error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodDelete, "/estate/"+id+"/drone-plan/jobs/"+jobId, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
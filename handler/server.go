@@ -1,7 +1,16 @@
 package handler
 
 import (
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/jobs"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/missions"
 	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/SawitProRecruitment/UserService/telemetry"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -9,18 +18,90 @@ type IdPath struct {
 	ID string `param:"id" validate:"required,uuid4"`
 }
 
+type JobIdPath struct {
+	ID string `param:"jobId" validate:"required,uuid4"`
+}
+
+type FlightIdPath struct {
+	ID string `param:"flightId" validate:"required,uuid4"`
+}
+
+type ScheduleIdPath struct {
+	ID string `param:"scheduleId" validate:"required,uuid4"`
+}
+
+type FindingIdPath struct {
+	ID string `param:"findingId" validate:"required,uuid4"`
+}
+
+type WebhookIdPath struct {
+	ID string `param:"webhookId" validate:"required,uuid4"`
+}
+
 type Server struct {
-	Repository repository.RepositoryInterface
-	Validator  *validator.Validate
+	Repository  repository.RepositoryInterface
+	Validator   *validator.Validate
+	Metrics     *metrics.Metrics
+	Logger      *slog.Logger
+	Cache       *cache.Cache
+	PlanJobs    *jobs.Runner
+	Missions    *missions.Scheduler
+	Events      *events.Hub
+	Telemetry   *telemetry.Hub
+	Estates     *service.EstateService
+	Trees       *service.TreeService
+	DronePlans  *service.DronePlanService
+	Inspections *service.InspectionService
 }
 
 type NewServerOptions struct {
 	Repository repository.RepositoryInterface
+	Metrics    *metrics.Metrics
+	Logger     *slog.Logger
+	Cache      *cache.Cache
+	PlanJobs   *jobs.Runner
+	Missions   *missions.Scheduler
+	Events     *events.Hub
+	Telemetry  *telemetry.Hub
 }
 
 func NewServer(opts NewServerOptions) *Server {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &Server{
 		Repository: opts.Repository,
 		Validator:  validator.New(),
+		Metrics:    opts.Metrics,
+		Logger:     logger,
+		Cache:      opts.Cache,
+		PlanJobs:   opts.PlanJobs,
+		Missions:   opts.Missions,
+		Events:     opts.Events,
+		Telemetry:  opts.Telemetry,
+		Estates: service.NewEstateService(service.NewEstateServiceOptions{
+			Repository: opts.Repository,
+			Metrics:    opts.Metrics,
+			Logger:     logger,
+			Events:     opts.Events,
+		}),
+		Trees: service.NewTreeService(service.NewTreeServiceOptions{
+			Repository: opts.Repository,
+			Metrics:    opts.Metrics,
+			Cache:      opts.Cache,
+			Logger:     logger,
+			Events:     opts.Events,
+		}),
+		DronePlans: service.NewDronePlanService(service.NewDronePlanServiceOptions{
+			Repository: opts.Repository,
+			Metrics:    opts.Metrics,
+			Cache:      opts.Cache,
+			Logger:     logger,
+		}),
+		Inspections: service.NewInspectionService(service.NewInspectionServiceOptions{
+			Repository: opts.Repository,
+			Metrics:    opts.Metrics,
+		}),
 	}
 }
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/telemetry"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxPositionSize is enough for a position message
+	maxPositionSize = 1024
+	// droneIdleTimeout close the connection of a drone not publishing anymore
+	droneIdleTimeout = time.Minute
+	// pingInterval keep the connection of an idle dashboard open and detect when it is gone
+	pingInterval = 30 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// upgrader check the origin of the browsers is the host, the drones do not send any
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// telemetryError is sent to a drone publishing an invalid position
+type telemetryError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (s *Server) GetEstateIdTelemetry(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionEstateRead) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+	if s.Telemetry == nil {
+		return ctx.JSON(http.StatusServiceUnavailable, generated.ErrorResponse{Message: "telemetry is not available"})
+	}
+
+	// watch before the upgrade, the positions published once the dashboard is connected are not missed
+	watcher := s.Telemetry.Watch(estate.Id)
+	defer watcher.Close()
+	conn, err := upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		// the upgrader answered the request
+		return nil
+	}
+	defer conn.Close()
+
+	// the dashboard only sends control messages, reading them handles the pongs and notices the close
+	conn.SetReadLimit(maxPositionSize)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return nil
+		case message, ok := <-watcher.C:
+			if !ok {
+				// closed for a slow dashboard or on shutdown
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeTimeout))
+				return nil
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteJSON(message); err != nil {
+				return nil
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *Server) GetEstateIdTelemetryDrone(ctx echo.Context, id string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionMissionRun) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(IdPath{ID: id}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check estate exist
+	reqCtx := ctx.Request().Context()
+	estate, err := s.Estates.GetEstate(reqCtx, callerOrganisation(reqCtx), id)
+	if err != nil {
+		return s.serviceError(ctx, err)
+	}
+	if s.Telemetry == nil {
+		return ctx.JSON(http.StatusServiceUnavailable, generated.ErrorResponse{Message: "telemetry is not available"})
+	}
+
+	conn, err := upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		// the upgrader answered the request
+		return nil
+	}
+	defer conn.Close()
+	tracker := s.Telemetry.Track(estate)
+
+	// close the connection on shutdown, the read below does not end otherwise
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-s.Telemetry.Done():
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeTimeout))
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	conn.SetReadLimit(maxPositionSize)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(droneIdleTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			// closed by the drone, idle or too large message
+			return nil
+		}
+
+		var reply interface{}
+		var position telemetry.Position
+		if err := json.Unmarshal(data, &position); err != nil {
+			reply = telemetryError{Type: "error", Message: "invalid position: " + err.Error()}
+		} else if err := s.Validator.Struct(position); err != nil {
+			reply = telemetryError{Type: "error", Message: err.Error()}
+		} else if alert, err := tracker.Publish(position); err != nil {
+			reply = telemetryError{Type: "error", Message: err.Error()}
+		} else if alert != nil {
+			reply = alert
+		}
+		if reply == nil {
+			continue
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := conn.WriteJSON(reply); err != nil {
+			return nil
+		}
+	}
+}
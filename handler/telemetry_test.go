@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/telemetry"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_GetEstateIdTelemetryDrone_Forbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		Telemetry:  telemetry.NewHub(telemetry.NewHubOptions{}),
+	})
+
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	e := echo.New()
+	e.Use(withRole(orgId, rbac.RoleViewer))
+	e.GET("/estate/:id/telemetry/drone", func(c echo.Context) error {
+		return s.GetEstateIdTelemetryDrone(c, c.Param("id"))
+	})
+	mockRepository.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Return(repository.AuditLog{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/"+id+"/telemetry/drone", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, `{"message":"forbidden"}`, strings.TrimSuffix(rec.Body.String(), "\n"))
+}
+
+func TestServer_Telemetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	hub := telemetry.NewHub(telemetry.NewHubOptions{Threshold: 5})
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+		Telemetry:  hub,
+	})
+
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.GET("/estate/:id/telemetry", func(c echo.Context) error {
+		return s.GetEstateIdTelemetry(c, c.Param("id"))
+	})
+	e.GET("/estate/:id/telemetry/drone", func(c echo.Context) error {
+		return s.GetEstateIdTelemetryDrone(c, c.Param("id"))
+	})
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+		Id: id,
+	}).Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 5, Width: 3}, nil).Times(2)
+
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/estate/" + id + "/telemetry"
+	dashboard, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer dashboard.Close()
+	drone, _, err := websocket.DefaultDialer.Dial(url+"/drone", nil)
+	require.NoError(t, err)
+	defer drone.Close()
+
+	readMessage := func(conn *websocket.Conn) string {
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	// an invalid position is answered to the drone only
+	require.NoError(t, drone.WriteMessage(websocket.TextMessage, []byte(`{"drone_id":"drone-1","x":1,"y":1}`)))
+	assert.Equal(t, `{"type":"error","message":"Key: 'Position.Altitude' Error:Field validation for 'Altitude' failed on the 'required' tag"}`+"\n", readMessage(drone))
+
+	require.NoError(t, drone.WriteMessage(websocket.TextMessage, []byte(`{"drone_id":"drone-1","timestamp":"2024-01-01T06:00:00Z","x":3,"y":1,"altitude":2,"battery":80}`)))
+	assert.Equal(t, `{"type":"position","drone_id":"drone-1","timestamp":"2024-01-01T06:00:00Z","x":3,"y":1,"altitude":2,"battery":80,"deviation":0,"progress":14.29}`+"\n", readMessage(dashboard))
+
+	require.NoError(t, drone.WriteMessage(websocket.TextMessage, []byte(`{"drone_id":"drone-1","timestamp":"2024-01-01T06:00:01Z","x":3,"y":4,"altitude":2}`)))
+	alert := `{"type":"alert","alert":"off_route","drone_id":"drone-1","timestamp":"2024-01-01T06:00:01Z","x":3,"y":4,"altitude":2,"deviation":10,"progress":85.71}` + "\n"
+	assert.Equal(t, alert, readMessage(drone))
+	assert.Equal(t, `{"type":"position","drone_id":"drone-1","timestamp":"2024-01-01T06:00:01Z","x":3,"y":4,"altitude":2,"deviation":10,"progress":85.71}`+"\n", readMessage(dashboard))
+	assert.Equal(t, alert, readMessage(dashboard))
+
+	// closing the hub ends the connections
+	hub.Close()
+	_, _, err = dashboard.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+	_, _, err = drone.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/webhooks"
+	"github.com/labstack/echo/v4"
+)
+
+func (s *Server) PostWebhooks(ctx echo.Context) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionWebhookManage) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	createWebhookRequest := new(generated.CreateWebhookRequest)
+	err := ctx.Bind(&createWebhookRequest)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(createWebhookRequest); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	if err := webhooks.ValidateUrl(createWebhookRequest.Url); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	eventTypes := make([]string, 0, len(createWebhookRequest.EventTypes))
+	for _, eventType := range createWebhookRequest.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	p, _ := principal(ctx)
+	subscription, err := s.Repository.CreateWebhookSubscription(ctx.Request().Context(), repository.WebhookSubscription{
+		OrganisationId: p.OrganisationId,
+		Url:            createWebhookRequest.Url,
+		EventTypes:     eventTypes,
+		Secret:         createWebhookRequest.Secret,
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, webhookResponse(subscription))
+}
+
+func (s *Server) GetWebhooks(ctx echo.Context, params generated.GetWebhooksParams) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionWebhookManage) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(params); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+	page, err := pagination.NewRequest(params.Cursor, params.Limit, defaultPageLimit, pagination.KindTimeId)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	p, _ := principal(ctx)
+	subscriptions, err := s.Repository.ListWebhookSubscriptionsByOrganisationId(ctx.Request().Context(), repository.ListWebhookSubscriptionsByOrganisationIdInput{
+		OrganisationId: p.OrganisationId,
+		After:          page.After,
+		Limit:          page.Fetch(),
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+	list := pagination.NewPage(subscriptions, page, func(subscription repository.WebhookSubscription) pagination.Cursor {
+		return pagination.Cursor{Time: subscription.CreatedAt, Id: subscription.Id}
+	})
+
+	response := generated.ListWebhooksResponse{Webhooks: make([]generated.WebhookResponse, 0, len(list.Items))}
+	for _, subscription := range list.Items {
+		response.Webhooks = append(response.Webhooks, webhookResponse(subscription))
+	}
+	response.NextCursor = nextPage(ctx, list.Next)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+func (s *Server) DeleteWebhooksWebhookId(ctx echo.Context, webhookId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionWebhookManage) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(WebhookIdPath{ID: webhookId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	p, _ := principal(ctx)
+	_, err := s.Repository.DeactivateWebhookSubscription(ctx.Request().Context(), repository.DeactivateWebhookSubscriptionInput{
+		Id:             webhookId,
+		OrganisationId: p.OrganisationId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "webhook is not found"})
+		} else {
+			return s.internalError(ctx, err)
+		}
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) PostWebhooksWebhookIdReplay(ctx echo.Context, webhookId string) error {
+	// Check permission
+	if !s.authorize(ctx, rbac.PermissionWebhookManage) {
+		return ctx.JSON(http.StatusForbidden, generated.ErrorResponse{Message: "forbidden"})
+	}
+	// Request Validate
+	if err := s.Validator.Struct(WebhookIdPath{ID: webhookId}); err != nil {
+		return ctx.JSON(http.StatusBadRequest, generated.ErrorResponse{Message: err.Error()})
+	}
+
+	// Check webhook exist
+	p, _ := principal(ctx)
+	subscription, err := s.Repository.GetWebhookSubscriptionById(ctx.Request().Context(), repository.GetWebhookSubscriptionByIdInput{
+		Id:             webhookId,
+		OrganisationId: p.OrganisationId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx.JSON(http.StatusNotFound, generated.ErrorResponse{Message: "webhook is not found"})
+		} else {
+			return s.internalError(ctx, err)
+		}
+	}
+
+	replayed, err := s.Repository.ReplayWebhookDeliveries(ctx.Request().Context(), repository.ReplayWebhookDeliveriesInput{
+		SubscriptionId: subscription.Id,
+		Now:            time.Now().UTC(),
+	})
+	if err != nil {
+		return s.internalError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, generated.ReplayWebhookResponse{Replayed: replayed})
+}
+
+// webhookResponse leave the secret out, it is only known to the subscriber
+func webhookResponse(subscription repository.WebhookSubscription) generated.WebhookResponse {
+	return generated.WebhookResponse{
+		Id:         subscription.Id,
+		Url:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/rbac"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServer_PostWebhooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	orgId := uuid.New().String()
+	webhookId := uuid.New().String()
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		role           rbac.Role
+		requestBody    string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "FORBIDDEN",
+			role:        rbac.RoleOperator,
+			requestBody: `{"url":"https://example.com/hooks","event_types":["tree.created"],"secret":"0123456789abcdef"}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Return(repository.AuditLog{}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"forbidden"}`,
+		},
+		{
+			name:           "UNKNOWN_EVENT_TYPE",
+			role:           rbac.RoleAdmin,
+			requestBody:    `{"url":"https://example.com/hooks","event_types":["tree.deleted"],"secret":"0123456789abcdef"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateWebhookRequest.EventTypes[0]' Error:Field validation for 'EventTypes[0]' failed on the 'oneof' tag"}`,
+		},
+		{
+			name:           "PLAIN_HTTP",
+			role:           rbac.RoleAdmin,
+			requestBody:    `{"url":"http://example.com/hooks","event_types":["tree.created"],"secret":"0123456789abcdef"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateWebhookRequest.Url' Error:Field validation for 'Url' failed on the 'startswith' tag"}`,
+		},
+		{
+			name:           "METADATA_ADDRESS",
+			role:           rbac.RoleAdmin,
+			requestBody:    `{"url":"https://169.254.169.254/latest/meta-data","event_types":["tree.created"],"secret":"0123456789abcdef"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"webhook url must be an https url of a public host"}`,
+		},
+		{
+			name:           "SHORT_SECRET",
+			role:           rbac.RoleAdmin,
+			requestBody:    `{"url":"https://example.com/hooks","event_types":["tree.created"],"secret":"secret"}`,
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'CreateWebhookRequest.Secret' Error:Field validation for 'Secret' failed on the 'min' tag"}`,
+		},
+		{
+			name:        "CREATED",
+			role:        rbac.RoleAdmin,
+			requestBody: `{"url":"https://example.com/hooks","event_types":["tree.created","plan_job.succeeded"],"secret":"0123456789abcdef"}`,
+			setupMocks: func() {
+				mockRepository.EXPECT().CreateWebhookSubscription(gomock.Any(), repository.WebhookSubscription{
+					OrganisationId: orgId,
+					Url:            "https://example.com/hooks",
+					EventTypes:     []string{"tree.created", "plan_job.succeeded"},
+					Secret:         "0123456789abcdef",
+				}).Return(repository.WebhookSubscription{
+					Id:             webhookId,
+					OrganisationId: orgId,
+					Url:            "https://example.com/hooks",
+					EventTypes:     []string{"tree.created", "plan_job.succeeded"},
+					Secret:         "0123456789abcdef",
+					Active:         true,
+					CreatedAt:      createdAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"created_at":"2024-01-01T00:00:00Z","event_types":["tree.created","plan_job.succeeded"],"id":"` + webhookId + `","url":"https://example.com/hooks"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			e := echo.New()
+			e.Use(withRole(orgId, tc.role))
+			e.POST("/webhooks", s.PostWebhooks)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
+
+func TestServer_PostWebhooksWebhookIdReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewServer(NewServerOptions{
+		Repository: mockRepository,
+	})
+
+	orgId := uuid.New().String()
+	webhookId := uuid.New().String()
+	e := echo.New()
+	e.Use(withPrincipal(orgId))
+	e.POST("/webhooks/:webhookId/replay", func(c echo.Context) error {
+		return s.PostWebhooksWebhookIdReplay(c, c.Param("webhookId"))
+	})
+
+	testCases := []struct {
+		name           string
+		requestId      string
+		setupMocks     func()
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "BAD_REQUEST",
+			requestId:      "11",
+			setupMocks:     func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Key: 'WebhookIdPath.ID' Error:Field validation for 'ID' failed on the 'uuid4' tag"}`,
+		},
+		{
+			name:      "WEBHOOK_NOT_FOUND",
+			requestId: webhookId,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetWebhookSubscriptionById(gomock.Any(), repository.GetWebhookSubscriptionByIdInput{
+					Id:             webhookId,
+					OrganisationId: orgId,
+				}).Return(repository.WebhookSubscription{}, sql.ErrNoRows)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"message":"webhook is not found"}`,
+		},
+		{
+			name:      "REPLAYED",
+			requestId: webhookId,
+			setupMocks: func() {
+				mockRepository.EXPECT().GetWebhookSubscriptionById(gomock.Any(), repository.GetWebhookSubscriptionByIdInput{
+					Id:             webhookId,
+					OrganisationId: orgId,
+				}).Return(repository.WebhookSubscription{Id: webhookId, OrganisationId: orgId, Active: true}, nil)
+				mockRepository.EXPECT().ReplayWebhookDeliveries(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, input repository.ReplayWebhookDeliveriesInput) (int, error) {
+						assert.Equal(t, webhookId, input.SubscriptionId)
+						return 3, nil
+					})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"replayed":3}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMocks()
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/"+tc.requestId+"/replay", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedBody, strings.TrimSuffix(rec.Body.String(), "\n"))
+		})
+	}
+}
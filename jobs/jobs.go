@@ -0,0 +1,230 @@
+// This file contains the worker pool computing the drone plan jobs in the background.
+//
+// The jobs are persisted in the plan_jobs table: a job is created pending by the api, claimed as running
+// by a worker and finished with its result or error. The jobs still pending or running when the service
+// stops are queued again on the next start, so no job is lost by a restart.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+)
+
+// progressSteps is how many times the progress is reported while the trees are added to the planner
+const progressSteps = 10
+
+// progressTreesAdded is the progress once every tree is in the planner, the rest is the plan
+const progressTreesAdded = 90
+
+const failedMessage = "failed to compute the drone plan"
+
+var ErrQueueFull = errors.New("too many drone plan jobs queued")
+
+type Runner struct {
+	repository repository.RepositoryInterface
+	plans      *service.DronePlanService
+	logger     *slog.Logger
+	workers    int
+	queue      chan string
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type NewRunnerOptions struct {
+	Repository repository.RepositoryInterface
+	// DronePlans compute the plans, default to a service without cache
+	DronePlans *service.DronePlanService
+	Logger     *slog.Logger
+	// Workers is the number of jobs computed at the same time, default to the number of cpu
+	Workers int
+	// QueueSize is the number of jobs waiting for a worker before new jobs are rejected, default to 1024
+	QueueSize int
+}
+
+func NewRunner(opts NewRunnerOptions) *Runner {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	queueSize := opts.QueueSize
+	if queueSize < 1 {
+		queueSize = 1024
+	}
+	plans := opts.DronePlans
+	if plans == nil {
+		plans = service.NewDronePlanService(service.NewDronePlanServiceOptions{
+			Repository: opts.Repository,
+			Logger:     logger,
+		})
+	}
+	return &Runner{
+		repository: opts.Repository,
+		plans:      plans,
+		logger:     logger,
+		workers:    workers,
+		queue:      make(chan string, queueSize),
+		cancels:    make(map[string]context.CancelFunc),
+	}
+}
+
+// Start queue the unfinished jobs and start the workers, they stop once ctx is done
+func (r *Runner) Start(ctx context.Context) error {
+	unfinished, err := r.repository.ListUnfinishedPlanJobs(ctx, repository.ListUnfinishedPlanJobsInput{})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-r.queue:
+					r.run(ctx, id)
+				}
+			}
+		}()
+	}
+
+	// there may be more unfinished jobs than room in the queue, wait for the workers
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for _, job := range unfinished {
+			select {
+			case <-ctx.Done():
+				return
+			case r.queue <- job.Id:
+			}
+		}
+	}()
+	return nil
+}
+
+// Wait block until the workers stopped
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Enqueue queue a pending job. A nil runner does nothing, the job is then computed on the next start
+// of a runner.
+func (r *Runner) Enqueue(id string) error {
+	if r == nil {
+		return nil
+	}
+	select {
+	case r.queue <- id:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Cancel stop the computation of a running job, the job must already be cancelled in the repository
+func (r *Runner) Cancel(id string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.cancels[id]; ok {
+		cancel()
+	}
+}
+
+func (r *Runner) run(ctx context.Context, id string) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.cancels[id] = cancel
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.cancels, id)
+		r.mu.Unlock()
+		cancel()
+	}()
+
+	job, err := r.repository.StartPlanJob(ctx, repository.StartPlanJobInput{Id: id})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			r.logger.ErrorContext(ctx, "failed to start drone plan job", slog.String("job_id", id), slog.String("error", err.Error()))
+		}
+		// cancelled before a worker was available
+		return
+	}
+
+	plan, err := r.compute(ctx, job)
+	if err != nil {
+		if ctx.Err() != nil {
+			// cancelled, or the service is stopping and the job is resumed on the next start
+			return
+		}
+		r.logger.ErrorContext(ctx, "drone plan job failed", slog.String("job_id", id), slog.String("error", err.Error()))
+		message := failedMessage
+		r.finish(ctx, repository.FinishPlanJobInput{Id: id, Status: repository.PlanJobStatusFailed, Error: &message})
+		return
+	}
+
+	r.finish(ctx, repository.FinishPlanJobInput{
+		Id:       id,
+		Status:   repository.PlanJobStatusSucceeded,
+		Distance: &plan.Distance,
+		RestX:    &plan.Rest.X,
+		RestY:    &plan.Rest.Y,
+	})
+}
+
+func (r *Runner) compute(ctx context.Context, job repository.PlanJob) (planner.Plan, error) {
+	estate, err := r.repository.GetEstateById(ctx, repository.GetEstateByIdInput{Id: job.EstateId})
+	if err != nil {
+		return planner.Plan{}, err
+	}
+	// the trees are loaded row by row, the progress is the share of the rows done
+	step := estate.Width/progressSteps + 1
+	next := step
+	return r.plans.PlanWithProgress(ctx, estate, job.MaxDistance, func(rows int) error {
+		if rows < next && rows < estate.Width {
+			return nil
+		}
+		next = rows/step*step + step
+		return r.progress(ctx, job.Id, progressTreesAdded*rows/estate.Width)
+	})
+}
+
+// progress store the progress of the job, a job cancelled in the meantime stops the computation
+func (r *Runner) progress(ctx context.Context, id string, progress int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := r.repository.UpdatePlanJobProgress(ctx, repository.UpdatePlanJobProgressInput{Id: id, Progress: progress})
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		// cancelled by an other instance
+		r.Cancel(id)
+		return context.Canceled
+	}
+	return err
+}
+
+func (r *Runner) finish(ctx context.Context, input repository.FinishPlanJobInput) {
+	_, err := r.repository.FinishPlanJob(ctx, input)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.logger.ErrorContext(ctx, "failed to finish drone plan job", slog.String("job_id", input.Id), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestRunner_Run(t *testing.T) {
+	jobId := uuid.New().String()
+	estateId := uuid.New().String()
+	estate := repository.Estate{Id: estateId, Width: 2, Length: 5}
+	trees := []repository.Tree{
+		{EstateId: estateId, X: 3, Y: 1, Height: 5},
+		{EstateId: estateId, X: 3, Y: 2, Height: 5},
+	}
+	noRows := sql.ErrNoRows
+
+	testCases := []struct {
+		name       string
+		setupMocks func(mockRepository *repository.MockRepositoryInterface)
+	}{
+		{
+			name: "SUCCEEDED",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StartPlanJob(gomock.Any(), repository.StartPlanJobInput{Id: jobId}).
+					Return(repository.PlanJob{Id: jobId, EstateId: estateId, MaxDistance: intPtr(40)}, nil)
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: estateId}, gomock.Any()).
+					DoAndReturn(streamTrees(trees))
+				// the first row is done when the tree of the second one comes
+				mockRepository.EXPECT().UpdatePlanJobProgress(gomock.Any(), repository.UpdatePlanJobProgressInput{Id: jobId, Progress: 45}).Return(repository.PlanJob{}, nil)
+				mockRepository.EXPECT().UpdatePlanJobProgress(gomock.Any(), repository.UpdatePlanJobProgressInput{Id: jobId, Progress: progressTreesAdded}).Return(repository.PlanJob{}, nil)
+				mockRepository.EXPECT().FinishPlanJob(gomock.Any(), repository.FinishPlanJobInput{
+					Id:       jobId,
+					Status:   repository.PlanJobStatusSucceeded,
+					Distance: intPtr(40),
+					RestX:    intPtr(3),
+					RestY:    intPtr(1),
+				}).Return(repository.PlanJob{}, nil)
+			},
+		},
+		{
+			name: "CANCELLED_BEFORE_START",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StartPlanJob(gomock.Any(), repository.StartPlanJobInput{Id: jobId}).Return(repository.PlanJob{}, noRows)
+			},
+		},
+		{
+			name: "CANCELLED_WHILE_RUNNING",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StartPlanJob(gomock.Any(), repository.StartPlanJobInput{Id: jobId}).
+					Return(repository.PlanJob{Id: jobId, EstateId: estateId}, nil)
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: estateId}, gomock.Any()).
+					DoAndReturn(streamTrees(trees))
+				mockRepository.EXPECT().UpdatePlanJobProgress(gomock.Any(), gomock.Any()).Return(repository.PlanJob{}, noRows)
+			},
+		},
+		{
+			name: "FAILED",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StartPlanJob(gomock.Any(), repository.StartPlanJobInput{Id: jobId}).
+					Return(repository.PlanJob{Id: jobId, EstateId: estateId}, nil)
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: estateId}, gomock.Any()).
+					Return(errors.New("connection refused"))
+				message := failedMessage
+				mockRepository.EXPECT().FinishPlanJob(gomock.Any(), repository.FinishPlanJobInput{
+					Id:     jobId,
+					Status: repository.PlanJobStatusFailed,
+					Error:  &message,
+				}).Return(repository.PlanJob{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tc.setupMocks(mockRepository)
+			r := NewRunner(NewRunnerOptions{Repository: mockRepository})
+			r.run(context.Background(), jobId)
+			assert.Empty(t, r.cancels)
+		})
+	}
+}
+
+func TestRunner_Start(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resumedId := uuid.New().String()
+	queuedId := uuid.New().String()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	mockRepository.EXPECT().ListUnfinishedPlanJobs(gomock.Any(), repository.ListUnfinishedPlanJobsInput{}).
+		Return([]repository.PlanJob{{Id: resumedId}}, nil)
+	done := make(chan string, 2)
+	mockRepository.EXPECT().StartPlanJob(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(_ context.Context, input repository.StartPlanJobInput) (repository.PlanJob, error) {
+			done <- input.Id
+			// cancelled in the meantime
+			return repository.PlanJob{}, sql.ErrNoRows
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRunner(NewRunnerOptions{Repository: mockRepository, Workers: 1})
+	assert.NoError(t, r.Start(ctx))
+	assert.NoError(t, r.Enqueue(queuedId))
+
+	var started []string
+	for len(started) < 2 {
+		select {
+		case id := <-done:
+			started = append(started, id)
+		case <-time.After(time.Second):
+			t.Fatal("jobs not started")
+		}
+	}
+	assert.ElementsMatch(t, []string{resumedId, queuedId}, started)
+
+	cancel()
+	r.Wait()
+}
+
+func TestRunner_Enqueue(t *testing.T) {
+	r := NewRunner(NewRunnerOptions{QueueSize: 1})
+	assert.NoError(t, r.Enqueue(uuid.New().String()))
+	assert.ErrorIs(t, r.Enqueue(uuid.New().String()), ErrQueueFull)
+
+	// a nil runner leaves the job pending
+	var nilRunner *Runner
+	assert.NoError(t, nilRunner.Enqueue(uuid.New().String()))
+	nilRunner.Cancel(uuid.New().String())
+}
+
+// streamTrees returns a StreamTreesByEstateId visiting the trees
+func streamTrees(trees []repository.Tree) func(context.Context, repository.StreamTreesByEstateIdInput, func(repository.Tree) error) error {
+	return func(_ context.Context, _ repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) error {
+		for _, tree := range trees {
+			if err := visit(tree); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor is Middleware for the gRPC calls, the request id is read from and sent back in the
+// x-request-id metadata
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, header := grpcRequestId(ctx)
+		_ = grpc.SetHeader(ctx, header)
+		resp, err := handler(ctx, req)
+		logGrpc(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for the streaming calls, logged when the stream ends
+func StreamServerInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, header := grpcRequestId(stream.Context())
+		_ = stream.SetHeader(header)
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		logGrpc(ctx, logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// grpcRequestId returns the context carrying the request id of the call and the header answering it
+func grpcRequestId(ctx context.Context) (context.Context, metadata.MD) {
+	requestId := ""
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(strings.ToLower(echo.HeaderXRequestID)); len(values) > 0 {
+		requestId = values[0]
+	}
+	if requestId == "" || len(requestId) > 128 {
+		requestId = uuid.New().String()
+	}
+	return WithRequestId(ctx, requestId), metadata.Pairs(echo.HeaderXRequestID, requestId)
+}
+
+func logGrpc(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	}
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	logger.LogAttrs(ctx, level, "request",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("remote_addr", remoteAddr),
+	)
+}
+
+// contextStream carries the context with the request id to the stream handler
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
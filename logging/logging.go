@@ -0,0 +1,64 @@
+// This file contains the structured logger setup and the request id correlation.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+type NewLoggerOptions struct {
+	Writer io.Writer
+	// Level is one of debug, info, warn or error, default is info
+	Level string
+}
+
+// NewLogger returns a JSON logger which add the request id and trace id found in the context
+func NewLogger(opts NewLoggerOptions) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(opts.Level))); err != nil {
+		level = slog.LevelInfo
+	}
+	return slog.New(&ContextHandler{
+		Handler: slog.NewJSONHandler(opts.Writer, &slog.HandlerOptions{Level: level}),
+	})
+}
+
+// WithRequestId returns a copy of ctx carrying the request id
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestId)
+}
+
+// RequestIdFromContext returns the request id carried by ctx, or empty string
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(contextKey{}).(string)
+	return requestId
+}
+
+// ContextHandler is a slog.Handler decorator adding correlation attributes from the context
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		r.AddAttrs(slog.String("request_id", requestId))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestMiddleware_RequestId(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	buf := &bytes.Buffer{}
+	logger := NewLogger(NewLoggerOptions{Writer: buf, Level: "info"})
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	repo := WrapRepository(mockRepository, logger)
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("pq: connection refused"))
+
+	e := echo.New()
+	e.Use(Middleware(logger))
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		_, err := repo.GetEstateById(c.Request().Context(), repository.GetEstateByIdInput{Id: c.Param("id")})
+		if err != nil {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name              string
+		requestId         string
+		expectedRequestId func(string) bool
+	}{
+		{
+			name:              "FROM_HEADER",
+			requestId:         "abc-123",
+			expectedRequestId: func(id string) bool { return id == "abc-123" },
+		},
+		{
+			name:              "GENERATED",
+			requestId:         "",
+			expectedRequestId: func(id string) bool { return len(id) == 36 },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, nil).AnyTimes()
+
+			req := httptest.NewRequest(http.MethodGet, "/estate/1/stats", nil)
+			if tc.requestId != "" {
+				req.Header.Set(echo.HeaderXRequestID, tc.requestId)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			requestId := rec.Header().Get(echo.HeaderXRequestID)
+			assert.True(t, tc.expectedRequestId(requestId))
+			for _, line := range decodeLines(t, buf) {
+				assert.Equal(t, requestId, line["request_id"])
+			}
+		})
+	}
+}
+
+func TestRepository_LogFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	buf := &bytes.Buffer{}
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	repo := WrapRepository(mockRepository, NewLogger(NewLoggerOptions{Writer: buf}))
+	mockRepository.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("pq: connection refused"))
+
+	_, err := repo.CreateEstate(WithRequestId(context.Background(), "req-1"), repository.Estate{Width: 1, Length: 1})
+	assert.Error(t, err)
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "ERROR", lines[0]["level"])
+	assert.Equal(t, "repository query failed", lines[0]["msg"])
+	assert.Equal(t, "CreateEstate", lines[0]["method"])
+	assert.Equal(t, "pq: connection refused", lines[0]["error"])
+	assert.Equal(t, "req-1", lines[0]["request_id"])
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	buf := &bytes.Buffer{}
+	interceptor := UnaryServerInterceptor(NewLogger(NewLoggerOptions{Writer: buf}))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "abc-123"))
+
+	var requestId string
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/dronepatrol.v1.DronePatrolService/CreateTree"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			requestId = RequestIdFromContext(ctx)
+			return nil, status.Error(codes.Internal, "internal server error")
+		})
+	assert.Error(t, err)
+
+	assert.Equal(t, "abc-123", requestId)
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "ERROR", lines[0]["level"])
+	assert.Equal(t, "/dronepatrol.v1.DronePatrolService/CreateTree", lines[0]["method"])
+	assert.Equal(t, "Internal", lines[0]["code"])
+	assert.Equal(t, "abc-123", lines[0]["request_id"])
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware assign a request id to each request, from the X-Request-Id header when
+// the client sent one, and write one access log line per request
+func Middleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			requestId := req.Header.Get(echo.HeaderXRequestID)
+			if requestId == "" || len(requestId) > 128 {
+				requestId = uuid.New().String()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, requestId)
+			c.SetRequest(req.WithContext(WithRequestId(req.Context(), requestId)))
+
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(c.Request().Context(), level, "request",
+				slog.String("method", req.Method),
+				slog.String("route", c.Path()),
+				slog.String("uri", req.RequestURI),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("remote_ip", c.RealIP()),
+			)
+			return err
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor record call count and latency per method and code
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeGrpc(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for the streaming calls
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		m.observeGrpc(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (m *Metrics) observeGrpc(method string, start time.Time, err error) {
+	labels := []string{method, status.Code(err).String()}
+	m.grpcRequests.WithLabelValues(labels...).Inc()
+	m.grpcRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,163 @@
+// This file contains the Prometheus collectors exposed on /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "drone_patrol"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	grpcRequests        *prometheus.CounterVec
+	grpcRequestDuration *prometheus.HistogramVec
+	repositoryDuration  *prometheus.HistogramVec
+	repositoryErrors    *prometheus.CounterVec
+	estatesCreated      prometheus.Counter
+	treesCreated        prometheus.Counter
+	findingsCreated     *prometheus.CounterVec
+	dronePlanDistance   prometheus.Histogram
+}
+
+type NewMetricsOptions struct {
+	// Db is optional, when set the connection pool stats are exported.
+	Db *sql.DB
+}
+
+func New(opts NewMetricsOptions) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "grpc_requests_total",
+			Help:      "Total number of gRPC calls by method and code.",
+		}, []string{"method", "code"}),
+		grpcRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "gRPC call latency by method and code, until the end of the stream for the streaming calls.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Repository query latency by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_query_errors_total",
+			Help:      "Total number of failed repository queries by method.",
+		}, []string{"method"}),
+		estatesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "estates_created_total",
+			Help:      "Total number of estates created.",
+		}),
+		treesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "trees_created_total",
+			Help:      "Total number of trees created.",
+		}),
+		findingsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "findings_created_total",
+			Help:      "Total number of inspection findings created by type.",
+		}, []string{"type"}),
+		dronePlanDistance: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "drone_plan_distance_meters",
+			Help:      "Distance of the computed drone plan routes.",
+			// 10 m (one plot) up to 1e11 m, enough for a max size estate
+			Buckets: prometheus.ExponentialBuckets(10, 10, 11),
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.grpcRequests,
+		m.grpcRequestDuration,
+		m.repositoryDuration,
+		m.repositoryErrors,
+		m.estatesCreated,
+		m.treesCreated,
+		m.findingsCreated,
+		m.dronePlanDistance,
+	)
+	if opts.Db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(opts.Db, "postgres"))
+	}
+	return m
+}
+
+// Handler returns the http handler serving the metrics in Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry returns the underlying registry, mainly useful for tests
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// EstateCreated count a new estate. Safe to call on nil Metrics.
+func (m *Metrics) EstateCreated() {
+	if m == nil {
+		return
+	}
+	m.estatesCreated.Inc()
+}
+
+// TreeCreated count a new tree. Safe to call on nil Metrics.
+func (m *Metrics) TreeCreated() {
+	if m == nil {
+		return
+	}
+	m.treesCreated.Inc()
+}
+
+// TreesCreated count the trees created at once, as by an import. Safe to call on nil Metrics.
+func (m *Metrics) TreesCreated(count int) {
+	if m == nil {
+		return
+	}
+	m.treesCreated.Add(float64(count))
+}
+
+// FindingCreated count a new finding of the type. Safe to call on nil Metrics.
+func (m *Metrics) FindingCreated(findingType string) {
+	if m == nil {
+		return
+	}
+	m.findingsCreated.WithLabelValues(findingType).Inc()
+}
+
+// DronePlanComputed record the distance of a computed drone plan. Safe to call on nil Metrics.
+func (m *Metrics) DronePlanComputed(distance int) {
+	if m == nil {
+		return
+	}
+	m.dronePlanDistance.Observe(float64(distance))
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := New(NewMetricsOptions{})
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/metrics", echo.WrapHandler(m.Handler()))
+
+	for _, path := range []string{"/estate/1/stats", "/estate/2/stats", "/unknown"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/estate/:id/stats", "200")))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `drone_patrol_http_requests_total{method="GET",route="/estate/:id/stats",status="200"} 2`))
+	assert.True(t, strings.Contains(rec.Body.String(), `status="404"`))
+}
+
+func TestMetrics_Repository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	m := New(NewMetricsOptions{})
+	repo := m.WrapRepository(mockRepository)
+
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("connection refused"))
+	mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), gomock.Any()).Return(repository.Tree{}, sql.ErrNoRows)
+
+	_, err := repo.GetEstateById(context.Background(), repository.GetEstateByIdInput{Id: "1"})
+	assert.Error(t, err)
+	_, err = repo.GetTreeByPlot(context.Background(), repository.GetTreeByPlot{EstateId: "1", X: 1, Y: 1})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.repositoryDuration))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.repositoryErrors.WithLabelValues("GetEstateById")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.repositoryErrors))
+}
+
+func TestMetrics_NilSafe(t *testing.T) {
+	var m *Metrics
+	m.EstateCreated()
+	m.TreeCreated()
+	m.TreesCreated(2)
+	m.DronePlanComputed(10)
+}
+
+func TestMetrics_UnaryServerInterceptor(t *testing.T) {
+	m := New(NewMetricsOptions{})
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/dronepatrol.v1.DronePatrolService/CreateTree"}
+
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "estate is not found")
+	})
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "OK")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, "NotFound")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.grpcRequestDuration))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware record request count and latency per route and status
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			// use the route template instead of the raw path to keep the label cardinality low
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			labels := []string{c.Request().Method, route, strconv.Itoa(status)}
+			m.httpRequests.WithLabelValues(labels...).Inc()
+			m.httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// Repository is a RepositoryInterface decorator recording the query duration of each method
+type Repository struct {
+	next    repository.RepositoryInterface
+	metrics *Metrics
+}
+
+// WrapRepository wrap the repository so each call is measured
+func (m *Metrics) WrapRepository(next repository.RepositoryInterface) *Repository {
+	return &Repository{next: next, metrics: m}
+}
+
+func (r *Repository) observe(method string, start time.Time, err error) {
+	r.metrics.repositoryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	// no rows is an expected outcome, not a failed query
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.metrics.repositoryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (r *Repository) CreateEstate(ctx context.Context, input repository.Estate) (output repository.Estate, err error) {
+	defer func(start time.Time) { r.observe("CreateEstate", start, err) }(time.Now())
+	return r.next.CreateEstate(ctx, input)
+}
+
+func (r *Repository) GetEstateById(ctx context.Context, input repository.GetEstateByIdInput) (output repository.Estate, err error) {
+	defer func(start time.Time) { r.observe("GetEstateById", start, err) }(time.Now())
+	return r.next.GetEstateById(ctx, input)
+}
+
+func (r *Repository) CreateTree(ctx context.Context, input repository.Tree) (output repository.Tree, err error) {
+	defer func(start time.Time) { r.observe("CreateTree", start, err) }(time.Now())
+	return r.next.CreateTree(ctx, input)
+}
+
+func (r *Repository) GetTreeByPlot(ctx context.Context, input repository.GetTreeByPlot) (output repository.Tree, err error) {
+	defer func(start time.Time) { r.observe("GetTreeByPlot", start, err) }(time.Now())
+	return r.next.GetTreeByPlot(ctx, input)
+}
+
+func (r *Repository) ListTreesByEstateId(ctx context.Context, input repository.ListTreesByEstateIdInput) (output []repository.Tree, err error) {
+	defer func(start time.Time) { r.observe("ListTreesByEstateId", start, err) }(time.Now())
+	return r.next.ListTreesByEstateId(ctx, input)
+}
+
+func (r *Repository) StreamTreesByEstateId(ctx context.Context, input repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) (err error) {
+	defer func(start time.Time) { r.observe("StreamTreesByEstateId", start, err) }(time.Now())
+	return r.next.StreamTreesByEstateId(ctx, input, visit)
+}
+
+func (r *Repository) GetApiKeyByHash(ctx context.Context, input repository.GetApiKeyByHashInput) (output repository.ApiKey, err error) {
+	defer func(start time.Time) { r.observe("GetApiKeyByHash", start, err) }(time.Now())
+	return r.next.GetApiKeyByHash(ctx, input)
+}
+
+func (r *Repository) GetMembership(ctx context.Context, input repository.GetMembershipInput) (output repository.Membership, err error) {
+	defer func(start time.Time) { r.observe("GetMembership", start, err) }(time.Now())
+	return r.next.GetMembership(ctx, input)
+}
+
+func (r *Repository) CreateAuditLog(ctx context.Context, input repository.AuditLog) (output repository.AuditLog, err error) {
+	defer func(start time.Time) { r.observe("CreateAuditLog", start, err) }(time.Now())
+	return r.next.CreateAuditLog(ctx, input)
+}
+
+func (r *Repository) CreatePlanJob(ctx context.Context, input repository.PlanJob) (output repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("CreatePlanJob", start, err) }(time.Now())
+	return r.next.CreatePlanJob(ctx, input)
+}
+
+func (r *Repository) GetPlanJobById(ctx context.Context, input repository.GetPlanJobByIdInput) (output repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("GetPlanJobById", start, err) }(time.Now())
+	return r.next.GetPlanJobById(ctx, input)
+}
+
+func (r *Repository) ListUnfinishedPlanJobs(ctx context.Context, input repository.ListUnfinishedPlanJobsInput) (output []repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("ListUnfinishedPlanJobs", start, err) }(time.Now())
+	return r.next.ListUnfinishedPlanJobs(ctx, input)
+}
+
+func (r *Repository) StartPlanJob(ctx context.Context, input repository.StartPlanJobInput) (output repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("StartPlanJob", start, err) }(time.Now())
+	return r.next.StartPlanJob(ctx, input)
+}
+
+func (r *Repository) UpdatePlanJobProgress(ctx context.Context, input repository.UpdatePlanJobProgressInput) (output repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("UpdatePlanJobProgress", start, err) }(time.Now())
+	return r.next.UpdatePlanJobProgress(ctx, input)
+}
+
+func (r *Repository) FinishPlanJob(ctx context.Context, input repository.FinishPlanJobInput) (output repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("FinishPlanJob", start, err) }(time.Now())
+	return r.next.FinishPlanJob(ctx, input)
+}
+
+func (r *Repository) CancelPlanJob(ctx context.Context, input repository.CancelPlanJobInput) (output repository.PlanJob, err error) {
+	defer func(start time.Time) { r.observe("CancelPlanJob", start, err) }(time.Now())
+	return r.next.CancelPlanJob(ctx, input)
+}
+
+func (r *Repository) CreateFlight(ctx context.Context, input repository.Flight) (output repository.Flight, err error) {
+	defer func(start time.Time) { r.observe("CreateFlight", start, err) }(time.Now())
+	return r.next.CreateFlight(ctx, input)
+}
+
+func (r *Repository) GetFlightById(ctx context.Context, input repository.GetFlightByIdInput) (output repository.Flight, err error) {
+	defer func(start time.Time) { r.observe("GetFlightById", start, err) }(time.Now())
+	return r.next.GetFlightById(ctx, input)
+}
+
+func (r *Repository) ListFlightPointsByFlightId(ctx context.Context, input repository.ListFlightPointsByFlightIdInput) (output []repository.FlightPoint, err error) {
+	defer func(start time.Time) { r.observe("ListFlightPointsByFlightId", start, err) }(time.Now())
+	return r.next.ListFlightPointsByFlightId(ctx, input)
+}
+
+func (r *Repository) CreateMissionSchedule(ctx context.Context, input repository.MissionSchedule) (output repository.MissionSchedule, err error) {
+	defer func(start time.Time) { r.observe("CreateMissionSchedule", start, err) }(time.Now())
+	return r.next.CreateMissionSchedule(ctx, input)
+}
+
+func (r *Repository) GetMissionScheduleById(ctx context.Context, input repository.GetMissionScheduleByIdInput) (output repository.MissionSchedule, err error) {
+	defer func(start time.Time) { r.observe("GetMissionScheduleById", start, err) }(time.Now())
+	return r.next.GetMissionScheduleById(ctx, input)
+}
+
+func (r *Repository) ListMissionSchedulesByEstateId(ctx context.Context, input repository.ListMissionSchedulesByEstateIdInput) (output []repository.MissionSchedule, err error) {
+	defer func(start time.Time) { r.observe("ListMissionSchedulesByEstateId", start, err) }(time.Now())
+	return r.next.ListMissionSchedulesByEstateId(ctx, input)
+}
+
+func (r *Repository) ListActiveMissionSchedules(ctx context.Context, input repository.ListActiveMissionSchedulesInput) (output []repository.MissionSchedule, err error) {
+	defer func(start time.Time) { r.observe("ListActiveMissionSchedules", start, err) }(time.Now())
+	return r.next.ListActiveMissionSchedules(ctx, input)
+}
+
+func (r *Repository) DeactivateMissionSchedule(ctx context.Context, input repository.DeactivateMissionScheduleInput) (output repository.MissionSchedule, err error) {
+	defer func(start time.Time) { r.observe("DeactivateMissionSchedule", start, err) }(time.Now())
+	return r.next.DeactivateMissionSchedule(ctx, input)
+}
+
+func (r *Repository) UpdateMissionScheduleMaterialisedUntil(ctx context.Context, input repository.UpdateMissionScheduleMaterialisedUntilInput) (output repository.MissionSchedule, err error) {
+	defer func(start time.Time) { r.observe("UpdateMissionScheduleMaterialisedUntil", start, err) }(time.Now())
+	return r.next.UpdateMissionScheduleMaterialisedUntil(ctx, input)
+}
+
+func (r *Repository) CreateMission(ctx context.Context, input repository.Mission) (output repository.Mission, err error) {
+	defer func(start time.Time) { r.observe("CreateMission", start, err) }(time.Now())
+	return r.next.CreateMission(ctx, input)
+}
+
+func (r *Repository) ListMissionsByEstateId(ctx context.Context, input repository.ListMissionsByEstateIdInput) (output []repository.Mission, err error) {
+	defer func(start time.Time) { r.observe("ListMissionsByEstateId", start, err) }(time.Now())
+	return r.next.ListMissionsByEstateId(ctx, input)
+}
+
+func (r *Repository) ListDueMissions(ctx context.Context, input repository.ListDueMissionsInput) (output []repository.Mission, err error) {
+	defer func(start time.Time) { r.observe("ListDueMissions", start, err) }(time.Now())
+	return r.next.ListDueMissions(ctx, input)
+}
+
+func (r *Repository) DispatchMission(ctx context.Context, input repository.DispatchMissionInput) (output repository.Mission, err error) {
+	defer func(start time.Time) { r.observe("DispatchMission", start, err) }(time.Now())
+	return r.next.DispatchMission(ctx, input)
+}
+
+func (r *Repository) CreateWebhookSubscription(ctx context.Context, input repository.WebhookSubscription) (output repository.WebhookSubscription, err error) {
+	defer func(start time.Time) { r.observe("CreateWebhookSubscription", start, err) }(time.Now())
+	return r.next.CreateWebhookSubscription(ctx, input)
+}
+
+func (r *Repository) GetWebhookSubscriptionById(ctx context.Context, input repository.GetWebhookSubscriptionByIdInput) (output repository.WebhookSubscription, err error) {
+	defer func(start time.Time) { r.observe("GetWebhookSubscriptionById", start, err) }(time.Now())
+	return r.next.GetWebhookSubscriptionById(ctx, input)
+}
+
+func (r *Repository) ListWebhookSubscriptionsByOrganisationId(ctx context.Context, input repository.ListWebhookSubscriptionsByOrganisationIdInput) (output []repository.WebhookSubscription, err error) {
+	defer func(start time.Time) { r.observe("ListWebhookSubscriptionsByOrganisationId", start, err) }(time.Now())
+	return r.next.ListWebhookSubscriptionsByOrganisationId(ctx, input)
+}
+
+func (r *Repository) DeactivateWebhookSubscription(ctx context.Context, input repository.DeactivateWebhookSubscriptionInput) (output repository.WebhookSubscription, err error) {
+	defer func(start time.Time) { r.observe("DeactivateWebhookSubscription", start, err) }(time.Now())
+	return r.next.DeactivateWebhookSubscription(ctx, input)
+}
+
+func (r *Repository) FanOutOutboxEvents(ctx context.Context, input repository.FanOutOutboxEventsInput) (output int, err error) {
+	defer func(start time.Time) { r.observe("FanOutOutboxEvents", start, err) }(time.Now())
+	return r.next.FanOutOutboxEvents(ctx, input)
+}
+
+func (r *Repository) ClaimDueWebhookDeliveries(ctx context.Context, input repository.ClaimDueWebhookDeliveriesInput) (output []repository.WebhookDelivery, err error) {
+	defer func(start time.Time) { r.observe("ClaimDueWebhookDeliveries", start, err) }(time.Now())
+	return r.next.ClaimDueWebhookDeliveries(ctx, input)
+}
+
+func (r *Repository) UpdateWebhookDelivery(ctx context.Context, input repository.UpdateWebhookDeliveryInput) (output repository.WebhookDelivery, err error) {
+	defer func(start time.Time) { r.observe("UpdateWebhookDelivery", start, err) }(time.Now())
+	return r.next.UpdateWebhookDelivery(ctx, input)
+}
+
+func (r *Repository) ReplayWebhookDeliveries(ctx context.Context, input repository.ReplayWebhookDeliveriesInput) (output int, err error) {
+	defer func(start time.Time) { r.observe("ReplayWebhookDeliveries", start, err) }(time.Now())
+	return r.next.ReplayWebhookDeliveries(ctx, input)
+}
+
+func (r *Repository) ListEstatesByOrganisationId(ctx context.Context, input repository.ListEstatesByOrganisationIdInput) (output []repository.Estate, err error) {
+	defer func(start time.Time) { r.observe("ListEstatesByOrganisationId", start, err) }(time.Now())
+	return r.next.ListEstatesByOrganisationId(ctx, input)
+}
+
+func (r *Repository) ImportEstate(ctx context.Context, input repository.ImportEstateInput) (output repository.Estate, err error) {
+	defer func(start time.Time) { r.observe("ImportEstate", start, err) }(time.Now())
+	return r.next.ImportEstate(ctx, input)
+}
+
+func (r *Repository) CreateFindings(ctx context.Context, input repository.CreateFindingsInput) (output []repository.Finding, err error) {
+	defer func(start time.Time) { r.observe("CreateFindings", start, err) }(time.Now())
+	return r.next.CreateFindings(ctx, input)
+}
+
+func (r *Repository) ListFindingsByEstateId(ctx context.Context, input repository.ListFindingsByEstateIdInput) (output []repository.Finding, err error) {
+	defer func(start time.Time) { r.observe("ListFindingsByEstateId", start, err) }(time.Now())
+	return r.next.ListFindingsByEstateId(ctx, input)
+}
+
+func (r *Repository) CountFindingsByEstateId(ctx context.Context, input repository.CountFindingsByEstateIdInput) (output []repository.FindingCount, err error) {
+	defer func(start time.Time) { r.observe("CountFindingsByEstateId", start, err) }(time.Now())
+	return r.next.CountFindingsByEstateId(ctx, input)
+}
+
+func (r *Repository) ResolveFinding(ctx context.Context, input repository.ResolveFindingInput) (output repository.Finding, err error) {
+	defer func(start time.Time) { r.observe("ResolveFinding", start, err) }(time.Now())
+	return r.next.ResolveFinding(ctx, input)
+}
+
+func (r *Repository) ListFlaggedPlotsByEstateId(ctx context.Context, input repository.ListFlaggedPlotsByEstateIdInput) (output []repository.FlaggedPlot, err error) {
+	defer func(start time.Time) { r.observe("ListFlaggedPlotsByEstateId", start, err) }(time.Now())
+	return r.next.ListFlaggedPlotsByEstateId(ctx, input)
+}
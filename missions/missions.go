@@ -0,0 +1,284 @@
+// This file contains the scheduler of the recurring patrols.
+//
+// A mission schedule is a cron expression evaluated in a time zone. The scheduler materialises the missions
+// of every active schedule over the next days, each with the drone plan of the estate computed in advance,
+// and dispatches the missions whose time came, computing their plan again when a tree changed since.
+package missions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/service"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultHorizon  = 7 * 24 * time.Hour
+	defaultInterval = time.Minute
+	// maxMaterialised bound the missions created from a schedule at once, a schedule running every minute
+	// is then materialised a bit further on every tick
+	maxMaterialised = 100
+	// maxDispatched bound the missions dispatched on a tick
+	maxDispatched = 100
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule is a parsed cron expression with its time zone
+type Schedule struct {
+	schedule cron.Schedule
+	location *time.Location
+}
+
+// ParseSchedule parse a standard 5 fields cron expression (or a descriptor such as @daily) evaluated
+// in an IANA time zone
+func ParseSchedule(expression, timeZone string) (Schedule, error) {
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") {
+		return Schedule{}, fmt.Errorf("%w: set the time zone apart from the cron expression", ErrInvalidSchedule)
+	}
+	schedule, err := parser.Parse(expression)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return Schedule{schedule: schedule, location: location}, nil
+}
+
+// Next returns the first time of the schedule after t, in UTC. The zero time is returned when the
+// schedule never runs (e.g. on February 30).
+func (s Schedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.In(s.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.UTC()
+}
+
+type Scheduler struct {
+	repository repository.RepositoryInterface
+	plans      *service.DronePlanService
+	logger     *slog.Logger
+	horizon    time.Duration
+	interval   time.Duration
+	now        func() time.Time
+	wg         sync.WaitGroup
+}
+
+type NewSchedulerOptions struct {
+	Repository repository.RepositoryInterface
+	// DronePlans compute the plans of the missions, default to a service without cache
+	DronePlans *service.DronePlanService
+	Logger     *slog.Logger
+	// Horizon is how far in advance the missions are materialised, default to 7 days
+	Horizon time.Duration
+	// Interval is the time between two runs of the scheduler, default to a minute
+	Interval time.Duration
+}
+
+func NewScheduler(opts NewSchedulerOptions) *Scheduler {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	horizon := opts.Horizon
+	if horizon <= 0 {
+		horizon = defaultHorizon
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	plans := opts.DronePlans
+	if plans == nil {
+		plans = service.NewDronePlanService(service.NewDronePlanServiceOptions{
+			Repository: opts.Repository,
+			Logger:     logger,
+		})
+	}
+	return &Scheduler{
+		repository: opts.Repository,
+		plans:      plans,
+		logger:     logger,
+		horizon:    horizon,
+		interval:   interval,
+		now:        time.Now,
+	}
+}
+
+// Start run the scheduler every interval until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.Tick(ctx); err != nil && ctx.Err() == nil {
+				s.logger.ErrorContext(ctx, "mission scheduler failed", slog.String("error", err.Error()))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait block until the scheduler stopped
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Tick materialise the upcoming missions of every active schedule and dispatch the due missions
+func (s *Scheduler) Tick(ctx context.Context) error {
+	schedules, err := s.repository.ListActiveMissionSchedules(ctx, repository.ListActiveMissionSchedulesInput{})
+	if err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if err := s.Materialise(ctx, schedule); err != nil {
+			// an estate failing should not stop the others
+			s.logger.ErrorContext(ctx, "failed to materialise missions",
+				slog.String("schedule_id", schedule.Id),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return s.dispatch(ctx)
+}
+
+// Materialise create the missions of the schedule up to the horizon. A nil scheduler does nothing, the
+// missions are then created by the next run of a scheduler.
+func (s *Scheduler) Materialise(ctx context.Context, schedule repository.MissionSchedule) error {
+	if s == nil {
+		return nil
+	}
+	parsed, err := ParseSchedule(schedule.Cron, schedule.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	from := now
+	if schedule.MaterialisedUntil != nil && schedule.MaterialisedUntil.After(from) {
+		from = *schedule.MaterialisedUntil
+	}
+	var times []time.Time
+	for t := parsed.Next(from); !t.IsZero() && !t.After(now.Add(s.horizon)) && len(times) < maxMaterialised; t = parsed.Next(t) {
+		times = append(times, t)
+	}
+	if len(times) == 0 {
+		return nil
+	}
+
+	plan, version, err := s.plan(ctx, schedule.EstateId, schedule.MaxDistance)
+	if err != nil {
+		return err
+	}
+	for _, t := range times {
+		_, err := s.repository.CreateMission(ctx, repository.Mission{
+			ScheduleId:    schedule.Id,
+			EstateId:      schedule.EstateId,
+			ScheduledAt:   t,
+			Distance:      &plan.Distance,
+			RestX:         &plan.Rest.X,
+			RestY:         &plan.Rest.Y,
+			EstateVersion: version,
+		})
+		// no rows when the mission already exists
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	_, err = s.repository.UpdateMissionScheduleMaterialisedUntil(ctx, repository.UpdateMissionScheduleMaterialisedUntilInput{
+		Id:                schedule.Id,
+		MaterialisedUntil: times[len(times)-1],
+	})
+	return err
+}
+
+func (s *Scheduler) dispatch(ctx context.Context) error {
+	due, err := s.repository.ListDueMissions(ctx, repository.ListDueMissionsInput{
+		Now:   s.now().UTC(),
+		Limit: maxDispatched,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, mission := range due {
+		if err := s.dispatchMission(ctx, mission); err != nil {
+			// a mission failing should not hold the others back
+			s.logger.ErrorContext(ctx, "failed to dispatch mission",
+				slog.String("mission_id", mission.Id),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) dispatchMission(ctx context.Context, mission repository.Mission) error {
+	input := repository.DispatchMissionInput{
+		Id:            mission.Id,
+		Distance:      mission.Distance,
+		RestX:         mission.RestX,
+		RestY:         mission.RestY,
+		EstateVersion: mission.EstateVersion,
+	}
+
+	estate, err := s.repository.GetEstateById(ctx, repository.GetEstateByIdInput{Id: mission.EstateId})
+	if err != nil {
+		return err
+	}
+	if estate.Version != mission.EstateVersion {
+		// a tree changed since the mission was materialised
+		schedule, err := s.repository.GetMissionScheduleById(ctx, repository.GetMissionScheduleByIdInput{
+			Id:       mission.ScheduleId,
+			EstateId: mission.EstateId,
+		})
+		if err != nil {
+			return err
+		}
+		plan, version, err := s.plan(ctx, mission.EstateId, schedule.MaxDistance)
+		if err != nil {
+			return err
+		}
+		input.Distance, input.RestX, input.RestY, input.EstateVersion = &plan.Distance, &plan.Rest.X, &plan.Rest.Y, version
+	}
+
+	_, err = s.repository.DispatchMission(ctx, input)
+	// no rows when the mission was cancelled in the meantime
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// plan compute the drone plan of the estate and returns the estate version it was computed for
+func (s *Scheduler) plan(ctx context.Context, estateId string, maxDistance *int) (planner.Plan, int64, error) {
+	estate, err := s.repository.GetEstateById(ctx, repository.GetEstateByIdInput{Id: estateId})
+	if err != nil {
+		return planner.Plan{}, 0, err
+	}
+	plan, err := s.plans.Plan(ctx, estate, maxDistance)
+	if err != nil {
+		return planner.Plan{}, 0, err
+	}
+	return plan, estate.Version, nil
+}
@@ -0,0 +1,226 @@
+package missions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func timePtr(v time.Time) *time.Time {
+	return &v
+}
+
+func TestParseSchedule(t *testing.T) {
+	testCases := []struct {
+		name          string
+		expression    string
+		timeZone      string
+		expectedNext  time.Time
+		expectedError string
+	}{
+		{
+			name:       "EVERY_MONDAY_IN_JAKARTA",
+			expression: "0 6 * * 1",
+			timeZone:   "Asia/Jakarta",
+			// 06:00 on the first Monday is already past in Jakarta
+			expectedNext: time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "DESCRIPTOR",
+			expression:   "@daily",
+			timeZone:     "UTC",
+			expectedNext: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "INVALID_EXPRESSION",
+			expression:    "0 6 * *",
+			timeZone:      "UTC",
+			expectedError: "invalid schedule: expected exactly 5 fields, found 4: [0 6 * *]",
+		},
+		{
+			name:          "INVALID_TIME_ZONE",
+			expression:    "0 6 * * 1",
+			timeZone:      "Mars/Olympus",
+			expectedError: "invalid schedule: unknown time zone Mars/Olympus",
+		},
+		{
+			name:          "TIME_ZONE_IN_EXPRESSION",
+			expression:    "CRON_TZ=Asia/Jakarta 0 6 * * 1",
+			timeZone:      "UTC",
+			expectedError: "invalid schedule: set the time zone apart from the cron expression",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tc.expression, tc.timeZone)
+			if tc.expectedError != "" {
+				assert.ErrorIs(t, err, ErrInvalidSchedule)
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNext, schedule.Next(now))
+		})
+	}
+}
+
+func TestScheduler_Materialise(t *testing.T) {
+	scheduleId := uuid.New().String()
+	estateId := uuid.New().String()
+	estate := repository.Estate{Id: estateId, Width: 2, Length: 5, Version: 3}
+	trees := []repository.Tree{
+		{EstateId: estateId, X: 3, Y: 1, Height: 5},
+		{EstateId: estateId, X: 3, Y: 2, Height: 5},
+	}
+	mission := func(day int) repository.Mission {
+		return repository.Mission{
+			ScheduleId:    scheduleId,
+			EstateId:      estateId,
+			ScheduledAt:   time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			Distance:      intPtr(40),
+			RestX:         intPtr(3),
+			RestY:         intPtr(1),
+			EstateVersion: 3,
+		}
+	}
+
+	testCases := []struct {
+		name              string
+		materialisedUntil *time.Time
+		setupMocks        func(mockRepository *repository.MockRepositoryInterface)
+	}{
+		{
+			name: "UP_TO_THE_HORIZON",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: estateId}, gomock.Any()).DoAndReturn(streamTrees(trees))
+				mockRepository.EXPECT().CreateMission(gomock.Any(), mission(2)).Return(repository.Mission{}, nil)
+				// created by another instance in the meantime
+				mockRepository.EXPECT().CreateMission(gomock.Any(), mission(3)).Return(repository.Mission{}, sql.ErrNoRows)
+				mockRepository.EXPECT().CreateMission(gomock.Any(), mission(4)).Return(repository.Mission{}, nil)
+				mockRepository.EXPECT().UpdateMissionScheduleMaterialisedUntil(gomock.Any(), repository.UpdateMissionScheduleMaterialisedUntilInput{
+					Id:                scheduleId,
+					MaterialisedUntil: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+				}).Return(repository.MissionSchedule{}, nil)
+			},
+		},
+		{
+			name:              "AFTER_THE_MATERIALISED_MISSIONS",
+			materialisedUntil: timePtr(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)),
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: estateId}, gomock.Any()).DoAndReturn(streamTrees(trees))
+				mockRepository.EXPECT().CreateMission(gomock.Any(), mission(4)).Return(repository.Mission{}, nil)
+				mockRepository.EXPECT().UpdateMissionScheduleMaterialisedUntil(gomock.Any(), gomock.Any()).Return(repository.MissionSchedule{}, nil)
+			},
+		},
+		{
+			name:              "ALREADY_MATERIALISED",
+			materialisedUntil: timePtr(time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)),
+			setupMocks:        func(mockRepository *repository.MockRepositoryInterface) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tc.setupMocks(mockRepository)
+
+			s := NewScheduler(NewSchedulerOptions{Repository: mockRepository, Horizon: 3 * 24 * time.Hour})
+			s.now = func() time.Time { return now }
+			err := s.Materialise(context.Background(), repository.MissionSchedule{
+				Id:                scheduleId,
+				EstateId:          estateId,
+				Cron:              "@daily",
+				TimeZone:          "UTC",
+				MaxDistance:       intPtr(40),
+				Active:            true,
+				MaterialisedUntil: tc.materialisedUntil,
+			})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewScheduler(NewSchedulerOptions{Repository: mockRepository})
+	s.now = func() time.Time { return now }
+
+	scheduleId := uuid.New().String()
+	estateId := uuid.New().String()
+	otherEstateId := uuid.New().String()
+	unchanged := repository.Mission{
+		Id: uuid.New().String(), ScheduleId: scheduleId, EstateId: estateId,
+		Distance: intPtr(112), RestX: intPtr(1), RestY: intPtr(2), EstateVersion: 3,
+	}
+	failing := repository.Mission{
+		Id: uuid.New().String(), ScheduleId: scheduleId, EstateId: otherEstateId,
+		Distance: intPtr(112), RestX: intPtr(1), RestY: intPtr(2), EstateVersion: 1,
+	}
+	treeAdded := repository.Mission{
+		Id: uuid.New().String(), ScheduleId: scheduleId, EstateId: estateId,
+		Distance: intPtr(22), RestX: intPtr(1), RestY: intPtr(2), EstateVersion: 2,
+	}
+	estate := repository.Estate{Id: estateId, Width: 2, Length: 5, Version: 3}
+
+	mockRepository.EXPECT().ListActiveMissionSchedules(gomock.Any(), repository.ListActiveMissionSchedulesInput{}).Return(nil, nil)
+	mockRepository.EXPECT().ListDueMissions(gomock.Any(), repository.ListDueMissionsInput{Now: now, Limit: maxDispatched}).
+		Return([]repository.Mission{unchanged, failing, treeAdded}, nil)
+
+	// the plan is still up to date
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+	mockRepository.EXPECT().DispatchMission(gomock.Any(), repository.DispatchMissionInput{
+		Id: unchanged.Id, Distance: intPtr(112), RestX: intPtr(1), RestY: intPtr(2), EstateVersion: 3,
+	}).Return(repository.Mission{}, nil)
+
+	// a failing mission does not hold the next one back
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: otherEstateId}).
+		Return(repository.Estate{}, errors.New("connection refused"))
+
+	// a tree was added since the mission was materialised
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+	mockRepository.EXPECT().GetMissionScheduleById(gomock.Any(), repository.GetMissionScheduleByIdInput{Id: scheduleId, EstateId: estateId}).
+		Return(repository.MissionSchedule{Id: scheduleId, EstateId: estateId, MaxDistance: intPtr(40)}, nil)
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: estateId}).Return(estate, nil)
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: estateId}, gomock.Any()).
+		DoAndReturn(streamTrees([]repository.Tree{{EstateId: estateId, X: 3, Y: 1, Height: 5}, {EstateId: estateId, X: 3, Y: 2, Height: 5}}))
+	mockRepository.EXPECT().DispatchMission(gomock.Any(), repository.DispatchMissionInput{
+		Id: treeAdded.Id, Distance: intPtr(40), RestX: intPtr(3), RestY: intPtr(1), EstateVersion: 3,
+	}).Return(repository.Mission{}, nil)
+
+	assert.NoError(t, s.Tick(context.Background()))
+}
+
+// streamTrees returns a StreamTreesByEstateId visiting the trees
+func streamTrees(trees []repository.Tree) func(context.Context, repository.StreamTreesByEstateIdInput, func(repository.Tree) error) error {
+	return func(_ context.Context, _ repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) error {
+		for _, tree := range trees {
+			if err := visit(tree); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+// Package pagination is the cursor pagination shared by the repository and the list endpoints.
+//
+// A page is read after the cursor of the last item of the previous page, on the keys of the order of the list:
+// (time, id) for the lists in chronological order and (y, x) for the trees. Unlike an offset, a cursor stays
+// right when items are added before it. The clients get the cursor as an opaque string and send it back as it
+// is, its content is not part of the API.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxLimit is the largest page of every list
+const MaxLimit = 1000
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Kind is the keys of the order of a list, a cursor of a list of another kind is invalid
+type Kind int
+
+const (
+	// KindTimeId is the kind of the lists in chronological order, their cursors are (time, id)
+	KindTimeId Kind = iota
+	// KindPlot is the kind of the lists of trees, their cursors are (y, x)
+	KindPlot
+)
+
+// Cursor is the position of an item in a list, only the keys of the order of the list are set
+type Cursor struct {
+	Time time.Time `json:"t,omitempty"`
+	Id   string    `json:"i,omitempty"`
+	X    int       `json:"x,omitempty"`
+	Y    int       `json:"y,omitempty"`
+}
+
+// Encode returns the opaque string of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode returns the cursor of an opaque string, it must have the keys of the kind of list and only them
+func Decode(s string, kind Kind) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if !c.valid(kind) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// valid returns whether the cursor has the keys of the kind, so it is not compared with the columns of another
+// list
+func (c Cursor) valid(kind Kind) bool {
+	switch kind {
+	case KindTimeId:
+		_, err := uuid.Parse(c.Id)
+		return !c.Time.IsZero() && err == nil && c.X == 0 && c.Y == 0
+	case KindPlot:
+		return c.X >= 1 && c.Y >= 1 && c.Time.IsZero() && c.Id == ""
+	}
+	return false
+}
+
+// Request is a page asked by a client, After is nil for the first page
+type Request struct {
+	After *Cursor
+	Limit int
+}
+
+// NewRequest returns the request of the cursor and limit parameters of a list of the kind, the limit defaults to
+// defaultLimit and is kept up to MaxLimit
+func NewRequest(cursor *string, limit *int, defaultLimit int, kind Kind) (Request, error) {
+	request := Request{Limit: defaultLimit}
+	if limit != nil {
+		request.Limit = *limit
+	}
+	if request.Limit < 1 {
+		request.Limit = 1
+	}
+	if request.Limit > MaxLimit {
+		request.Limit = MaxLimit
+	}
+	if cursor != nil && *cursor != "" {
+		after, err := Decode(*cursor, kind)
+		if err != nil {
+			return Request{}, err
+		}
+		request.After = &after
+	}
+	return request, nil
+}
+
+// Fetch is the limit asked to the repository, one more item than the page tells whether there is a next page
+func (r Request) Fetch() int {
+	return r.Limit + 1
+}
+
+// Page of a list, Next is nil on the last page
+type Page[T any] struct {
+	Items []T
+	Next  *Cursor
+}
+
+// NewPage returns the page of the items fetched for the request, the extra item is removed and the cursor of
+// the last item of the page is the next one
+func NewPage[T any](items []T, request Request, cursor func(T) Cursor) Page[T] {
+	if len(items) <= request.Limit {
+		return Page[T]{Items: items}
+	}
+	items = items[:request.Limit]
+	next := cursor(items[len(items)-1])
+	return Page[T]{Items: items, Next: &next}
+}
@@ -0,0 +1,89 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	for kind, cursor := range map[Kind]Cursor{
+		KindTimeId: {Time: time.Date(2024, 1, 1, 0, 0, 0, 123, time.UTC), Id: "343d61a2-19ff-402b-ba3b-c474a6c3968c"},
+		KindPlot:   {X: 3, Y: 7},
+	} {
+		decoded, err := Decode(cursor.Encode(), kind)
+		require.NoError(t, err)
+		assert.True(t, cursor.Time.Equal(decoded.Time))
+		assert.Equal(t, cursor.Id, decoded.Id)
+		assert.Equal(t, cursor.X, decoded.X)
+		assert.Equal(t, cursor.Y, decoded.Y)
+	}
+}
+
+func TestDecode_OtherKind(t *testing.T) {
+	timeId := Cursor{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Id: "343d61a2-19ff-402b-ba3b-c474a6c3968c"}
+	plot := Cursor{X: 3, Y: 7}
+
+	tests := []struct {
+		name   string
+		cursor Cursor
+		kind   Kind
+	}{
+		{name: "plot cursor in a chronological list", cursor: plot, kind: KindTimeId},
+		{name: "time cursor in a list of trees", cursor: timeId, kind: KindPlot},
+		{name: "id is not a uuid", cursor: Cursor{Time: timeId.Time, Id: "1"}, kind: KindTimeId},
+		{name: "missing time", cursor: Cursor{Id: timeId.Id}, kind: KindTimeId},
+		{name: "missing x", cursor: Cursor{Y: 7}, kind: KindPlot},
+		{name: "both keys", cursor: Cursor{Time: timeId.Time, Id: timeId.Id, X: 3, Y: 7}, kind: KindPlot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.cursor.Encode(), tt.kind)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	ptr := func(v int) *int { return &v }
+	invalid := "not-a-cursor"
+	cursor := Cursor{X: 1, Y: 2}
+	encoded := cursor.Encode()
+
+	tests := []struct {
+		name    string
+		cursor  *string
+		limit   *int
+		want    Request
+		wantErr error
+	}{
+		{name: "default", want: Request{Limit: 100}},
+		{name: "limit", limit: ptr(10), want: Request{Limit: 10}},
+		{name: "limit too small", limit: ptr(0), want: Request{Limit: 1}},
+		{name: "limit too large", limit: ptr(5000), want: Request{Limit: MaxLimit}},
+		{name: "cursor", cursor: &encoded, want: Request{After: &cursor, Limit: 100}},
+		{name: "invalid cursor", cursor: &invalid, wantErr: ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRequest(tt.cursor, tt.limit, 100, KindPlot)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cursor := func(v int) Cursor { return Cursor{X: v} }
+	request := Request{Limit: 2}
+
+	page := NewPage([]int{1, 2}, request, cursor)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.Nil(t, page.Next)
+
+	page = NewPage([]int{1, 2, 3}, request, cursor)
+	assert.Equal(t, []int{1, 2}, page.Items)
+	assert.Equal(t, &Cursor{X: 2}, page.Next)
+}
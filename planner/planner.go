@@ -0,0 +1,229 @@
+// This file contains the drone route planner.
+//
+// The drone starts 1 meter above the south west plot (1, 1) and sweeps the estate row by row in a
+// boustrophedon: west to east on odd rows, east to west on even rows. Each move to the next plot
+// costs 10 meters plus the height difference between the two plots, a move to the next row costs
+// 10 meters plus the height of the tree it leaves, and the landing costs 1 meter.
+//
+// Instead of walking every plot, the distance is the horizontal 10 meters per move plus the height
+// deltas, which are only non zero for moves leaving or reaching a tree. The planner therefore works
+// in O(t log t) for t trees whatever the size of the estate.
+package planner
+
+import (
+	"sort"
+)
+
+const (
+	plotDistance    = 10 // meters between two plots
+	takeOffDistance = 1  // meter flown at take off
+	landingDistance = 1  // meter flown at landing
+	flyOver         = 1  // meter above the ground or the top of the tree
+)
+
+type Plot struct {
+	X int
+	Y int
+}
+
+type Plan struct {
+	// Distance flown, capped to the max distance when one is given
+	Distance int
+	// Rest is the plot where the drone lands
+	Rest Plot
+}
+
+type Planner struct {
+	width   int
+	length  int
+	heights map[int]int // tree height by route index
+}
+
+// New returns a planner for an estate of width rows (y) and length plots per row (x)
+func New(width, length int) *Planner {
+	return &Planner{
+		width:   width,
+		length:  length,
+		heights: make(map[int]int),
+	}
+}
+
+// AddTree register the tree of a plot, plots outside the estate are ignored
+func (p *Planner) AddTree(x, y, height int) {
+	if x < 1 || x > p.length || y < 1 || y > p.width {
+		return
+	}
+	p.heights[p.index(Plot{X: x, Y: y})] = height
+}
+
+// Plan compute the route distance, and where the drone lands when maxDistance is reached
+func (p *Planner) Plan(maxDistance *int) Plan {
+	plots := p.width * p.length
+	steps := p.heightSteps()
+
+	total := takeOffDistance + plotDistance*(plots-1) + landingDistance
+	for _, s := range steps {
+		total += s.height
+	}
+	if maxDistance == nil || total <= *maxDistance {
+		return Plan{Distance: total, Rest: p.plotAt(plots - 1)}
+	}
+
+	if k, ok := p.firstStepOver(steps, *maxDistance); ok {
+		return Plan{Distance: *maxDistance, Rest: p.plotAt(k)}
+	}
+	// only the landing goes over the max distance
+	return Plan{Distance: *maxDistance, Rest: p.plotAt(plots - 1)}
+}
+
+// Waypoint is a plot of the route with the altitude of the drone above it, 1 meter above the ground or the
+// top of the tree, and the distance flown once the drone is above it
+type Waypoint struct {
+	Plot
+	Altitude int
+	Distance int
+}
+
+// Walk calls visit with the waypoints of the route in order, up to the plot where the drone lands when
+// maxDistance is reached, until visit returns false. Unlike Plan it walks every plot of the route.
+func (p *Planner) Walk(maxDistance *int, visit func(Waypoint) bool) {
+	last := p.width*p.length - 1
+	rest := last
+	if maxDistance != nil {
+		rest = p.index(p.Plan(maxDistance).Rest)
+	}
+	distance := takeOffDistance
+	for i := 0; i <= rest; i++ {
+		if !visit(Waypoint{Plot: p.plotAt(i), Altitude: p.heights[i] + flyOver, Distance: distance}) {
+			return
+		}
+		if i < last {
+			distance += plotDistance + p.stepHeight(i)
+		}
+	}
+}
+
+// step is a move from the plot of route index index to the next one with a non zero height delta
+type step struct {
+	index  int
+	height int
+	// cumulative is the sum of the heights of this step and the previous ones
+	cumulative int
+}
+
+// heightSteps returns the moves climbing or descending, sorted by route index
+func (p *Planner) heightSteps() []step {
+	trees := make([]int, 0, len(p.heights))
+	for i := range p.heights {
+		trees = append(trees, i)
+	}
+	sort.Ints(trees)
+
+	last := p.width*p.length - 1
+	steps := make([]step, 0, 2*len(trees))
+	cumulative := 0
+	add := func(i int) {
+		if len(steps) > 0 && steps[len(steps)-1].index >= i {
+			return // already added as the move leaving the previous tree
+		}
+		if h := p.stepHeight(i); h != 0 {
+			cumulative += h
+			steps = append(steps, step{index: i, height: h, cumulative: cumulative})
+		}
+	}
+	for _, i := range trees {
+		// the move reaching the tree, unless it comes from the previous row
+		if i > 0 && !p.rowEnd(i-1) {
+			add(i - 1)
+		}
+		// the move leaving the tree
+		if i < last {
+			add(i)
+		}
+	}
+	return steps
+}
+
+// stepHeight is the height delta of the move leaving the plot of route index i
+func (p *Planner) stepHeight(i int) int {
+	if p.rowEnd(i) {
+		// moving to the next row, the drone leaves the tree to the ground
+		return p.heights[i]
+	}
+	return abs(p.heights[i] - p.heights[i+1])
+}
+
+// distanceAfter is the distance flown once the move leaving the plot of route index i is done,
+// cumulative being the heights of the moves up to i
+func distanceAfter(i, cumulative int) int {
+	return takeOffDistance + plotDistance*(i+1) + cumulative
+}
+
+// firstStepOver returns the route index of the first move ending over maxDistance. The distance
+// only grows along the route so the height steps are binary searched, then the flat moves between
+// two height steps are solved directly.
+func (p *Planner) firstStepOver(steps []step, maxDistance int) (int, bool) {
+	j := sort.Search(len(steps), func(j int) bool {
+		return distanceAfter(steps[j].index, steps[j].cumulative) > maxDistance
+	})
+
+	from, cumulative := 0, 0
+	if j > 0 {
+		from, cumulative = steps[j-1].index+1, steps[j-1].cumulative
+	}
+	to := p.width*p.length - 2 // last move
+	if j < len(steps) {
+		to = steps[j].index
+	}
+
+	// first flat move with takeOff + 10 * (k + 1) + cumulative > maxDistance
+	k := floorDiv(maxDistance-takeOffDistance-cumulative, plotDistance)
+	if k < from {
+		k = from
+	}
+	if j < len(steps) && k >= to {
+		return to, true
+	}
+	if k > to {
+		return 0, false
+	}
+	return k, true
+}
+
+// index returns the position of the plot along the route
+func (p *Planner) index(plot Plot) int {
+	row := plot.Y - 1
+	if row%2 == 0 {
+		return row*p.length + plot.X - 1
+	}
+	return row*p.length + p.length - plot.X
+}
+
+// plotAt returns the plot of a route index
+func (p *Planner) plotAt(i int) Plot {
+	row, pos := i/p.length, i%p.length
+	if row%2 == 0 {
+		return Plot{X: pos + 1, Y: row + 1}
+	}
+	return Plot{X: p.length - pos, Y: row + 1}
+}
+
+// rowEnd returns true when the route index is the last plot of its row
+func (p *Planner) rowEnd(i int) bool {
+	return i%p.length == p.length-1
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
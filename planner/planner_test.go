@@ -0,0 +1,294 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tree struct {
+	x, y, height int
+}
+
+// walkPlan is the original plot by plot walk, kept as the reference of the closed form planner
+func walkPlan(width, length int, trees []tree, maxDistance *int) Plan {
+	mapTrees := make(map[int]map[int]int)
+	for _, tree := range trees {
+		if mapTrees[tree.x] == nil {
+			mapTrees[tree.x] = make(map[int]int)
+		}
+		mapTrees[tree.x][tree.y] = tree.height
+	}
+
+	var rest Plot
+	x, y := 1, 1
+	turnBack := false
+	distance := 1
+	for y <= width {
+		if turnBack {
+			x = length
+			for x >= 1 {
+				rest = Plot{X: x, Y: y}
+				if x == 1 && y == width {
+					break
+				}
+				distance += 10 + int(math.Abs(float64(mapTrees[x][y]-mapTrees[x-1][y])))
+				if maxDistance != nil && *maxDistance < distance {
+					x, y = length, width
+					break
+				}
+				x--
+			}
+			turnBack = false
+		} else {
+			x = 1
+			for x <= length {
+				rest = Plot{X: x, Y: y}
+				if x == length && y == width {
+					break
+				}
+				distance += 10 + int(math.Abs(float64(mapTrees[x][y]-mapTrees[x+1][y])))
+				if maxDistance != nil && *maxDistance < distance {
+					x, y = length, width
+					break
+				}
+				x++
+			}
+			turnBack = true
+		}
+		y++
+	}
+	distance += 1
+	if maxDistance != nil && *maxDistance < distance {
+		distance = *maxDistance
+	}
+	return Plan{Distance: distance, Rest: rest}
+}
+
+func plan(width, length int, trees []tree, maxDistance *int) Plan {
+	p := New(width, length)
+	for _, t := range trees {
+		p.AddTree(t.x, t.y, t.height)
+	}
+	return p.Plan(maxDistance)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestPlanner_Plan(t *testing.T) {
+	twoTrees := []tree{{x: 3, y: 1, height: 5}, {x: 3, y: 2, height: 5}}
+
+	testCases := []struct {
+		name         string
+		width        int
+		length       int
+		trees        []tree
+		maxDistance  *int
+		expectedPlan Plan
+	}{
+		{
+			name:         "SINGLE_PLOT",
+			width:        1,
+			length:       1,
+			expectedPlan: Plan{Distance: 2, Rest: Plot{X: 1, Y: 1}},
+		},
+		{
+			name:         "SINGLE_PLOT_LANDING_OVER_MAX_DISTANCE",
+			width:        1,
+			length:       1,
+			maxDistance:  intPtr(1),
+			expectedPlan: Plan{Distance: 1, Rest: Plot{X: 1, Y: 1}},
+		},
+		{
+			name:         "NO_TREE",
+			width:        3,
+			length:       4,
+			expectedPlan: Plan{Distance: 112, Rest: Plot{X: 4, Y: 3}},
+		},
+		{
+			name:         "TWO_TREES",
+			width:        2,
+			length:       5,
+			trees:        twoTrees,
+			expectedPlan: Plan{Distance: 112, Rest: Plot{X: 1, Y: 2}},
+		},
+		{
+			name:         "TWO_TREES_MAX_DISTANCE_40",
+			width:        2,
+			length:       5,
+			trees:        twoTrees,
+			maxDistance:  intPtr(40),
+			expectedPlan: Plan{Distance: 40, Rest: Plot{X: 3, Y: 1}},
+		},
+		{
+			name:         "TWO_TREES_MAX_DISTANCE_90",
+			width:        2,
+			length:       5,
+			trees:        twoTrees,
+			maxDistance:  intPtr(90),
+			expectedPlan: Plan{Distance: 90, Rest: Plot{X: 3, Y: 2}},
+		},
+		{
+			name:         "ROW_END_TREE",
+			width:        2,
+			length:       2,
+			trees:        []tree{{x: 2, y: 1, height: 7}, {x: 2, y: 2, height: 3}},
+			expectedPlan: Plan{Distance: 1 + 30 + 7 + 7 + 3 + 1, Rest: Plot{X: 1, Y: 2}},
+		},
+		{
+			name:         "SINGLE_COLUMN",
+			width:        3,
+			length:       1,
+			trees:        []tree{{x: 1, y: 2, height: 4}},
+			expectedPlan: Plan{Distance: 1 + 20 + 4 + 1, Rest: Plot{X: 1, Y: 3}},
+		},
+		{
+			name:         "OUT_OF_BOUND_TREE_IGNORED",
+			width:        1,
+			length:       2,
+			trees:        []tree{{x: 3, y: 1, height: 4}},
+			expectedPlan: Plan{Distance: 12, Rest: Plot{X: 2, Y: 1}},
+		},
+		{
+			name:         "MAX_SIZE_ESTATE",
+			width:        50000,
+			length:       50000,
+			trees:        []tree{{x: 50000, y: 50000, height: 30}},
+			expectedPlan: Plan{Distance: 1 + 10*(50000*50000-1) + 30 + 1, Rest: Plot{X: 1, Y: 50000}},
+		},
+		{
+			name:         "MAX_SIZE_ESTATE_WITH_MAX_DISTANCE",
+			width:        50000,
+			length:       50000,
+			trees:        []tree{{x: 2, y: 1, height: 30}},
+			maxDistance:  intPtr(1_000_000),
+			expectedPlan: Plan{Distance: 1_000_000, Rest: Plot{X: 7, Y: 2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedPlan, plan(tc.width, tc.length, tc.trees, tc.maxDistance))
+			if tc.width*tc.length <= 10000 {
+				assert.Equal(t, walkPlan(tc.width, tc.length, tc.trees, tc.maxDistance), plan(tc.width, tc.length, tc.trees, tc.maxDistance))
+			}
+		})
+	}
+}
+
+func TestPlanner_PlanMatchesWalk(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		width, length := rnd.Intn(8)+1, rnd.Intn(8)+1
+		var trees []tree
+		for y := 1; y <= width; y++ {
+			for x := 1; x <= length; x++ {
+				if rnd.Intn(3) == 0 {
+					trees = append(trees, tree{x: x, y: y, height: rnd.Intn(30) + 1})
+				}
+			}
+		}
+		var maxDistance *int
+		if rnd.Intn(4) != 0 {
+			maxDistance = intPtr(rnd.Intn(width*length*25) + 1)
+		}
+
+		expected := walkPlan(width, length, trees, maxDistance)
+		actual := plan(width, length, trees, maxDistance)
+		if !assert.Equal(t, expected, actual, "estate %dx%d trees %v max %v", width, length, trees, maxDistance) {
+			return
+		}
+	}
+}
+
+func TestPlanner_Walk(t *testing.T) {
+	p := New(2, 3)
+	p.AddTree(2, 1, 5)
+	p.AddTree(3, 2, 2)
+
+	var waypoints []Waypoint
+	p.Walk(nil, func(w Waypoint) bool {
+		waypoints = append(waypoints, w)
+		return true
+	})
+	assert.Equal(t, []Waypoint{
+		{Plot: Plot{X: 1, Y: 1}, Altitude: 1, Distance: 1},
+		{Plot: Plot{X: 2, Y: 1}, Altitude: 6, Distance: 16},
+		{Plot: Plot{X: 3, Y: 1}, Altitude: 1, Distance: 31},
+		{Plot: Plot{X: 3, Y: 2}, Altitude: 3, Distance: 41},
+		{Plot: Plot{X: 2, Y: 2}, Altitude: 1, Distance: 53},
+		{Plot: Plot{X: 1, Y: 2}, Altitude: 1, Distance: 63},
+	}, waypoints)
+
+	// stopped by visit
+	count := 0
+	p.Walk(nil, func(w Waypoint) bool {
+		count++
+		return count < 2
+	})
+	assert.Equal(t, 2, count)
+}
+
+func TestPlanner_WalkMatchesPlan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		width, length := rnd.Intn(8)+1, rnd.Intn(8)+1
+		p := New(width, length)
+		for y := 1; y <= width; y++ {
+			for x := 1; x <= length; x++ {
+				if rnd.Intn(3) == 0 {
+					p.AddTree(x, y, rnd.Intn(30)+1)
+				}
+			}
+		}
+		var maxDistance *int
+		if rnd.Intn(2) == 0 {
+			maxDistance = intPtr(rnd.Intn(width*length*25) + 1)
+		}
+
+		var last Waypoint
+		p.Walk(maxDistance, func(w Waypoint) bool {
+			last = w
+			return true
+		})
+		plan := p.Plan(maxDistance)
+		if !assert.Equal(t, plan.Rest, last.Plot) {
+			return
+		}
+		if maxDistance == nil {
+			assert.Equal(t, plan.Distance, last.Distance+landingDistance)
+		} else {
+			assert.LessOrEqual(t, last.Distance, *maxDistance)
+		}
+	}
+}
+
+func benchmarkPlan(b *testing.B, width, length, trees int) {
+	rnd := rand.New(rand.NewSource(1))
+	p := New(width, length)
+	for i := 0; i < trees; i++ {
+		p.AddTree(rnd.Intn(length)+1, rnd.Intn(width)+1, rnd.Intn(30)+1)
+	}
+	maxDistance := 10 * width * length / 2
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Plan(nil)
+		p.Plan(&maxDistance)
+	}
+}
+
+// The plan cost grows with the number of trees and stays flat with the estate area.
+func BenchmarkPlanner_Plan(b *testing.B) {
+	for _, size := range []int{100, 50000} {
+		for _, trees := range []int{10, 1000, 100000} {
+			b.Run(fmt.Sprintf("estate=%dx%d/trees=%d", size, size, trees), func(b *testing.B) {
+				benchmarkPlan(b, size, size, trees)
+			})
+		}
+	}
+}
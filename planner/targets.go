@@ -0,0 +1,187 @@
+// This file contains the targeted route, a patrol of a few plots instead of the whole estate.
+//
+// The drone takes off from the launch plot, visits every target and comes back to land on the launch plot.
+// Between two stops it flies from plot to plot along one of the two L shaped paths, the cheaper one, with the
+// cost of the sweep: 10 meters plus the height difference for a move to a neighbour plot, 1 meter for the take
+// off and the landing. The order of the targets is the nearest neighbour tour improved by 2-opt on the
+// horizontal distance, the climbs are small next to the 10 meters of a plot.
+package planner
+
+// maxTwoOptPasses bounds the improvement of the tour, a pass is quadratic in the number of targets
+const maxTwoOptPasses = 8
+
+type TargetedPlan struct {
+	Plan
+	// Targets are the distinct targets of the estate in the order of the route
+	Targets []Plot
+	// Visited is the number of targets reached before the drone lands, all of them unless the max distance
+	// is reached
+	Visited int
+}
+
+// Targeted compute the route from the launch plot visiting the targets and back, and where the drone lands
+// when maxDistance is reached. Targets outside the estate and repeated targets are ignored.
+func (p *Planner) Targeted(launch Plot, targets []Plot, maxDistance *int) TargetedPlan {
+	stops := p.tour(launch, targets)
+	plan := TargetedPlan{Targets: stops[1 : len(stops)-1]}
+
+	distance := takeOffDistance
+	current := launch
+	for i := 1; i < len(stops); i++ {
+		path := p.path(current, stops[i])
+		for _, next := range path {
+			move := plotDistance + abs(p.height(current)-p.height(next))
+			if maxDistance != nil && distance+move > *maxDistance {
+				plan.Plan = Plan{Distance: *maxDistance, Rest: current}
+				return plan
+			}
+			distance += move
+			current = next
+		}
+		if i < len(stops)-1 {
+			plan.Visited++
+		}
+	}
+
+	distance += landingDistance
+	if maxDistance != nil && distance > *maxDistance {
+		// only the landing goes over the max distance
+		distance = *maxDistance
+	}
+	plan.Plan = Plan{Distance: distance, Rest: launch}
+	return plan
+}
+
+// tour returns the stops of the route, the launch plot, the targets in the order to visit them and the launch
+// plot again
+func (p *Planner) tour(launch Plot, targets []Plot) []Plot {
+	seen := make(map[Plot]bool, len(targets))
+	remaining := make([]Plot, 0, len(targets))
+	for _, target := range targets {
+		if !p.inside(target) || seen[target] {
+			continue
+		}
+		seen[target] = true
+		remaining = append(remaining, target)
+	}
+
+	// nearest neighbour
+	stops := make([]Plot, 0, len(remaining)+2)
+	stops = append(stops, launch)
+	current := launch
+	for len(remaining) > 0 {
+		nearest := 0
+		for i := range remaining {
+			if manhattan(current, remaining[i]) < manhattan(current, remaining[nearest]) {
+				nearest = i
+			}
+		}
+		current = remaining[nearest]
+		stops = append(stops, current)
+		remaining[nearest] = remaining[len(remaining)-1]
+		remaining = remaining[:len(remaining)-1]
+	}
+	stops = append(stops, launch)
+
+	// 2-opt, the launch plot stays at both ends
+	for pass := 0; pass < maxTwoOptPasses; pass++ {
+		improved := false
+		for i := 1; i < len(stops)-2; i++ {
+			for j := i + 1; j < len(stops)-1; j++ {
+				before := manhattan(stops[i-1], stops[i]) + manhattan(stops[j], stops[j+1])
+				after := manhattan(stops[i-1], stops[j]) + manhattan(stops[i], stops[j+1])
+				if after < before {
+					for a, b := i, j; a < b; a, b = a+1, b-1 {
+						stops[a], stops[b] = stops[b], stops[a]
+					}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return stops
+}
+
+// path returns the plots flown over from a plot to another, the first one excluded, along the cheaper of the
+// two L shaped paths
+func (p *Planner) path(from, to Plot) []Plot {
+	xFirst := lPath(from, to, true)
+	yFirst := lPath(from, to, false)
+	if p.cost(from, yFirst) < p.cost(from, xFirst) {
+		return yFirst
+	}
+	return xFirst
+}
+
+// cost returns the distance flown along a path from a plot
+func (p *Planner) cost(from Plot, path []Plot) int {
+	cost := 0
+	current := from
+	for _, next := range path {
+		cost += plotDistance + abs(p.height(current)-p.height(next))
+		current = next
+	}
+	return cost
+}
+
+// lPath returns the plots from a plot to another, the first one excluded, moving along x then y when xFirst
+// is set, along y then x otherwise
+func lPath(from, to Plot, xFirst bool) []Plot {
+	path := make([]Plot, 0, abs(to.X-from.X)+abs(to.Y-from.Y))
+	current := from
+	moveX := func() {
+		for current.X != to.X {
+			current.X += sign(to.X - current.X)
+			path = append(path, current)
+		}
+	}
+	moveY := func() {
+		for current.Y != to.Y {
+			current.Y += sign(to.Y - current.Y)
+			path = append(path, current)
+		}
+	}
+	if xFirst {
+		moveX()
+		moveY()
+	} else {
+		moveY()
+		moveX()
+	}
+	return path
+}
+
+// height returns the height of the tree of a plot, 0 without tree
+func (p *Planner) height(plot Plot) int {
+	return p.heights[p.index(plot)]
+}
+
+// Height returns the height of the tree of the plot (x, y), 0 without tree or outside the estate
+func (p *Planner) Height(x, y int) int {
+	if !p.inside(Plot{X: x, Y: y}) {
+		return 0
+	}
+	return p.height(Plot{X: x, Y: y})
+}
+
+// inside returns true when the plot is in the estate
+func (p *Planner) inside(plot Plot) bool {
+	return plot.X >= 1 && plot.X <= p.length && plot.Y >= 1 && plot.Y <= p.width
+}
+
+func manhattan(a, b Plot) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y)
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanner_Targeted(t *testing.T) {
+	maxDistance := func(d int) *int { return &d }
+	tests := []struct {
+		name        string
+		trees       []tree
+		launch      Plot
+		targets     []Plot
+		maxDistance *int
+		want        TargetedPlan
+	}{
+		{
+			name:    "no target",
+			launch:  Plot{X: 1, Y: 1},
+			targets: nil,
+			want:    TargetedPlan{Plan: Plan{Distance: 2, Rest: Plot{X: 1, Y: 1}}, Targets: []Plot{}},
+		},
+		{
+			name:    "round trip",
+			launch:  Plot{X: 1, Y: 1},
+			targets: []Plot{{X: 3, Y: 1}},
+			want:    TargetedPlan{Plan: Plan{Distance: 42, Rest: Plot{X: 1, Y: 1}}, Targets: []Plot{{X: 3, Y: 1}}, Visited: 1},
+		},
+		{
+			name:    "climb over a small tree",
+			trees:   []tree{{x: 2, y: 1, height: 5}},
+			launch:  Plot{X: 1, Y: 1},
+			targets: []Plot{{X: 3, Y: 1}},
+			want:    TargetedPlan{Plan: Plan{Distance: 62, Rest: Plot{X: 1, Y: 1}}, Targets: []Plot{{X: 3, Y: 1}}, Visited: 1},
+		},
+		{
+			name:    "fly around a tall tree",
+			trees:   []tree{{x: 2, y: 1, height: 30}},
+			launch:  Plot{X: 1, Y: 1},
+			targets: []Plot{{X: 3, Y: 2}},
+			want:    TargetedPlan{Plan: Plan{Distance: 62, Rest: Plot{X: 1, Y: 1}}, Targets: []Plot{{X: 3, Y: 2}}, Visited: 1},
+		},
+		{
+			name:        "max distance",
+			launch:      Plot{X: 1, Y: 1},
+			targets:     []Plot{{X: 3, Y: 1}},
+			maxDistance: maxDistance(25),
+			want:        TargetedPlan{Plan: Plan{Distance: 25, Rest: Plot{X: 3, Y: 1}}, Targets: []Plot{{X: 3, Y: 1}}, Visited: 1},
+		},
+		{
+			name:        "max distance at landing",
+			launch:      Plot{X: 1, Y: 1},
+			targets:     []Plot{{X: 3, Y: 1}},
+			maxDistance: maxDistance(41),
+			want:        TargetedPlan{Plan: Plan{Distance: 41, Rest: Plot{X: 1, Y: 1}}, Targets: []Plot{{X: 3, Y: 1}}, Visited: 1},
+		},
+		{
+			name:    "nearest first, repeated and outside targets ignored",
+			launch:  Plot{X: 1, Y: 1},
+			targets: []Plot{{X: 5, Y: 3}, {X: 2, Y: 1}, {X: 9, Y: 9}, {X: 4, Y: 1}, {X: 2, Y: 1}},
+			want: TargetedPlan{
+				Plan:    Plan{Distance: 122, Rest: Plot{X: 1, Y: 1}},
+				Targets: []Plot{{X: 2, Y: 1}, {X: 4, Y: 1}, {X: 5, Y: 3}},
+				Visited: 3,
+			},
+		},
+		{
+			name:    "launch inside the estate",
+			launch:  Plot{X: 5, Y: 3},
+			targets: []Plot{{X: 5, Y: 1}},
+			want:    TargetedPlan{Plan: Plan{Distance: 42, Rest: Plot{X: 5, Y: 3}}, Targets: []Plot{{X: 5, Y: 1}}, Visited: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(3, 5)
+			for _, tree := range tt.trees {
+				p.AddTree(tree.x, tree.y, tree.height)
+			}
+			assert.Equal(t, tt.want, p.Targeted(tt.launch, tt.targets, tt.maxDistance))
+		})
+	}
+}
+
+func TestPlanner_Targeted_TwoOpt(t *testing.T) {
+	// the nearest neighbour tour is 38 plots long, 2-opt shortens it to the shortest tour of 32 plots
+	p := New(10, 10)
+	targets := []Plot{{X: 2, Y: 4}, {X: 10, Y: 5}, {X: 5, Y: 2}, {X: 2, Y: 8}, {X: 8, Y: 2}}
+	plan := p.Targeted(Plot{X: 1, Y: 1}, targets, nil)
+
+	assert.Equal(t, []Plot{{X: 2, Y: 4}, {X: 2, Y: 8}, {X: 10, Y: 5}, {X: 8, Y: 2}, {X: 5, Y: 2}}, plan.Targets)
+	assert.Equal(t, takeOffDistance+plotDistance*32+landingDistance, plan.Distance)
+}
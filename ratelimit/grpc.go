@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+
+	"github.com/SawitProRecruitment/UserService/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type InterceptorOptions struct {
+	Store Store
+	// Default apply to every method without a dedicated limit
+	Default Limit
+	// Methods are the stricter limits of the expensive methods, keyed by full method name
+	Methods map[string]Limit
+	// Key returns the bucket key of the client of a call, GrpcClientKey when nil
+	Key    func(ctx context.Context) string
+	Logger *slog.Logger
+}
+
+// UnaryServerInterceptor is Middleware for the gRPC calls, a rejected call gets ResourceExhausted and the
+// retry-after header
+func UnaryServerInterceptor(opts InterceptorOptions) grpc.UnaryServerInterceptor {
+	if opts.Key == nil {
+		opts.Key = GrpcClientKey
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if header, err := take(ctx, opts, info.FullMethod); err != nil {
+			_ = grpc.SetHeader(ctx, header)
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for the streaming calls
+func StreamServerInterceptor(opts InterceptorOptions) grpc.StreamServerInterceptor {
+	if opts.Key == nil {
+		opts.Key = GrpcClientKey
+	}
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if header, err := take(stream.Context(), opts, info.FullMethod); err != nil {
+			_ = stream.SetHeader(header)
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// take returns the ResourceExhausted status and the retry-after header when the call is over the limit
+func take(ctx context.Context, opts InterceptorOptions, method string) (metadata.MD, error) {
+	limit, ok := opts.Methods[method]
+	if !ok {
+		limit = opts.Default
+	}
+	result, err := opts.Store.Take(ctx, opts.Key(ctx), limit)
+	if err != nil {
+		// fail open, an unavailable store should not take the api down
+		opts.Logger.ErrorContext(ctx, "rate limit store failed", slog.String("error", err.Error()))
+		return nil, nil
+	}
+	if result.Allowed {
+		return nil, nil
+	}
+	header := metadata.Pairs(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+	return header, status.Error(codes.ResourceExhausted, "too many requests")
+}
+
+// GrpcClientKey returns the api key or jwt subject of the authenticated caller, the ip otherwise
+func GrpcClientKey(ctx context.Context) string {
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		return p.Method + ":" + p.Subject
+	}
+	return GrpcIPKey(ctx)
+}
+
+// GrpcIPKey returns the ip of the client, to limit the calls before the authentication
+func GrpcIPKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:" + host
+}
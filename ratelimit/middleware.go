@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/auth"
+	"github.com/SawitProRecruitment/UserService/generated"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+type MiddlewareOptions struct {
+	Store Store
+	// Default apply to every route without a dedicated limit
+	Default Limit
+	// Routes are the stricter limits of the expensive routes, keyed by route template, or by method and route
+	// template as "POST /estate/:id/drone-plan/jobs" to limit a single method of the route
+	Routes map[string]Limit
+	// Key returns the bucket key of the client of a request, ClientKey when nil
+	Key    func(c echo.Context) string
+	Logger *slog.Logger
+}
+
+// Middleware limit the request rate per api key or jwt subject, and per ip for anonymous requests
+func Middleware(opts MiddlewareOptions) echo.MiddlewareFunc {
+	if opts.Key == nil {
+		opts.Key = ClientKey
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			limit, ok := opts.Routes[c.Request().Method+" "+c.Path()]
+			if !ok {
+				limit, ok = opts.Routes[c.Path()]
+			}
+			if !ok {
+				limit = opts.Default
+			}
+
+			result, err := opts.Store.Take(c.Request().Context(), opts.Key(c), limit)
+			if err != nil {
+				// fail open, an unavailable store should not take the api down
+				opts.Logger.ErrorContext(c.Request().Context(), "rate limit store failed", slog.String("error", err.Error()))
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			header.Set(HeaderRateLimitReset, strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+			if !result.Allowed {
+				header.Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				return c.JSON(http.StatusTooManyRequests, generated.ErrorResponse{Message: "too many requests"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// ClientKey returns the api key or jwt subject of the authenticated caller, the ip otherwise
+func ClientKey(c echo.Context) string {
+	if p, ok := auth.PrincipalFromContext(c.Request().Context()); ok {
+		return p.Method + ":" + p.Subject
+	}
+	return IPKey(c)
+}
+
+// IPKey returns the ip of the client, to limit the requests before the authentication
+func IPKey(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// IPExtractor returns how echo reads the ip of the client, which keys the buckets before the authentication. The
+// X-Forwarded-For header is only trusted from the trustedProxies CIDRs, without them the ip is the one of the
+// connection: a header set by the client would give it a new bucket on every request.
+func IPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,105 @@
+// This file contains the token bucket rate limiter and its stores.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst tokens
+type Limit struct {
+	// Name separate the buckets of different limits for the same client
+	Name  string
+	Rate  float64
+	Burst int
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the wait before the next token is available, zero when allowed
+	RetryAfter time.Duration
+	// Reset is the wait before the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps the buckets, the in memory store is enough for a single instance while
+// a shared store (e.g. redis) is needed when the service is scaled horizontally
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is the time to refill the empty bucket, after that an idle bucket can be dropped
+	full time.Duration
+}
+
+// MemoryStore is an in process Store
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[limit.Name+":"+key]
+	if !ok {
+		b = &bucket{
+			tokens: float64(limit.Burst),
+			last:   now,
+			full:   secondsToDuration(float64(limit.Burst) / limit.Rate),
+		}
+		s.buckets[limit.Name+":"+key] = b
+	}
+
+	// refill since the last take
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return result, nil
+}
+
+// sweep drop the buckets idle long enough to be full again, at most once a minute
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > b.full {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
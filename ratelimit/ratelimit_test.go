@@ -0,0 +1,243 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	limit := Limit{Name: "test", Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	result, err := store.Take(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, result)
+
+	result, _ = store.Take(ctx, "client", limit)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, result)
+
+	result, _ = store.Take(ctx, "client", limit)
+	assert.Equal(t, Result{Allowed: false, Limit: 2, Remaining: 0, RetryAfter: time.Second, Reset: 2 * time.Second}, result)
+
+	// an other client has its own bucket
+	result, _ = store.Take(ctx, "other", limit)
+	assert.True(t, result.Allowed)
+
+	// an other limit of the same client has its own bucket
+	result, _ = store.Take(ctx, "client", Limit{Name: "other", Rate: 1, Burst: 1})
+	assert.True(t, result.Allowed)
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	result, _ = store.Take(ctx, "client", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	clock.now = clock.now.Add(time.Second)
+	result, _ = store.Take(ctx, "client", limit)
+	assert.True(t, result.Allowed)
+
+	// idle buckets are dropped once full again
+	clock.now = clock.now.Add(time.Hour)
+	_, _ = store.Take(ctx, "client", limit)
+	assert.Len(t, store.buckets, 1)
+}
+
+func TestMiddleware(t *testing.T) {
+	store := NewMemoryStore()
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if key := c.Request().Header.Get(auth.HeaderApiKey); key != "" {
+				c.SetRequest(c.Request().WithContext(auth.WithPrincipal(c.Request().Context(), auth.Principal{
+					Subject: key,
+					Method:  auth.MethodApiKey,
+				})))
+			}
+			return next(c)
+		}
+	})
+	e.Use(Middleware(MiddlewareOptions{
+		Store:   store,
+		Default: Limit{Name: "default", Rate: 1, Burst: 5},
+		Routes: map[string]Limit{
+			"/estate/:id/drone-plan":           {Name: "drone-plan", Rate: 0.1, Burst: 1},
+			"POST /estate/:id/drone-plan/jobs": {Name: "drone-plan", Rate: 0.1, Burst: 1},
+		},
+		Logger: slog.Default(),
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/estate/:id/stats", ok)
+	e.GET("/estate/:id/drone-plan", ok)
+	e.POST("/estate/:id/drone-plan/jobs", ok)
+	e.GET("/estate/:id/drone-plan/jobs", ok)
+
+	doMethod := func(method, path, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if apiKey != "" {
+			req.Header.Set(auth.HeaderApiKey, apiKey)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(path, apiKey string) *httptest.ResponseRecorder {
+		return doMethod(http.MethodGet, path, apiKey)
+	}
+
+	rec := do("/estate/1/drone-plan", "key-1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "0", rec.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, "10", rec.Header().Get(HeaderRateLimitReset))
+
+	rec = do("/estate/2/drone-plan", "key-1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get(HeaderRetryAfter))
+	assert.JSONEq(t, `{"message":"too many requests"}`, rec.Body.String())
+
+	// the stricter drone plan limit does not consume the default quota
+	rec = do("/estate/1/stats", "key-1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(HeaderRateLimitLimit))
+	assert.Equal(t, "4", rec.Header().Get(HeaderRateLimitRemaining))
+
+	// only the submission of a job shares the drone plan bucket, polling the jobs is on the default quota
+	assert.Equal(t, http.StatusTooManyRequests, doMethod(http.MethodPost, "/estate/1/drone-plan/jobs", "key-1").Code)
+	rec = do("/estate/1/drone-plan/jobs", "key-1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(HeaderRateLimitLimit))
+
+	// other api keys and anonymous clients have their own quota
+	assert.Equal(t, http.StatusOK, do("/estate/1/drone-plan", "key-2").Code)
+	assert.Equal(t, http.StatusOK, do("/estate/1/drone-plan", "").Code)
+}
+
+func TestMiddleware_BeforeAuthentication(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(MiddlewareOptions{
+		Store:   NewMemoryStore(),
+		Default: Limit{Name: "ip", Rate: 0.1, Burst: 2},
+		Key:     IPKey,
+		Logger:  slog.Default(),
+	}))
+	// the authentication rejects every request
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+	})
+	e.GET("/estate/:id/stats", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	do := func(ip, apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/estate/1/stats", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set(auth.HeaderApiKey, apiKey)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// the invalid credentials are counted per ip, whatever the api key
+	assert.Equal(t, http.StatusUnauthorized, do("10.0.0.1", "guess-1"))
+	assert.Equal(t, http.StatusUnauthorized, do("10.0.0.1", "guess-2"))
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.1", "guess-3"))
+	assert.Equal(t, http.StatusUnauthorized, do("10.0.0.2", "guess-3"))
+}
+
+func TestIPExtractor(t *testing.T) {
+	limited := func(extractor echo.IPExtractor) *echo.Echo {
+		e := echo.New()
+		e.IPExtractor = extractor
+		e.Use(Middleware(MiddlewareOptions{
+			Store:   NewMemoryStore(),
+			Default: Limit{Name: "ip", Rate: 0.1, Burst: 1},
+			Key:     IPKey,
+			Logger:  slog.Default(),
+		}))
+		e.GET("/estate/:id/stats", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+		return e
+	}
+	do := func(e *echo.Echo, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/estate/1/stats", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		req.Header.Set(echo.HeaderXRealIP, forwardedFor)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// without trusted proxies a spoofed header does not give a new bucket
+	direct, err := IPExtractor(nil)
+	require.NoError(t, err)
+	e := limited(direct)
+	assert.Equal(t, http.StatusOK, do(e, "203.0.113.7:1234", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, do(e, "203.0.113.7:1234", "198.51.100.2"))
+
+	// behind a trusted proxy the clients are told apart by the header, not by the proxy
+	proxied, err := IPExtractor([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	e = limited(proxied)
+	assert.Equal(t, http.StatusOK, do(e, "10.0.0.1:1234", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, do(e, "10.0.0.1:1234", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, do(e, "10.0.0.1:1234", "198.51.100.2"))
+	// a client outside the trusted proxies can not spoof the header
+	assert.Equal(t, http.StatusOK, do(e, "203.0.113.7:1234", "198.51.100.3"))
+	assert.Equal(t, http.StatusTooManyRequests, do(e, "203.0.113.7:1234", "198.51.100.4"))
+
+	_, err = IPExtractor([]string{"not-a-cidr"})
+	assert.Error(t, err)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(InterceptorOptions{
+		Store:   NewMemoryStore(),
+		Default: Limit{Name: "default", Rate: 1, Burst: 5},
+		Methods: map[string]Limit{
+			"/dronepatrol.v1.DronePatrolService/CreateTree": {Name: "tree", Rate: 0.1, Burst: 1},
+		},
+		Logger: slog.Default(),
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(method, apiKey string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}})
+		if apiKey != "" {
+			ctx = auth.WithPrincipal(ctx, auth.Principal{Subject: apiKey, Method: auth.MethodApiKey})
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	assert.NoError(t, call("/dronepatrol.v1.DronePatrolService/CreateTree", "key-1"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("/dronepatrol.v1.DronePatrolService/CreateTree", "key-1")))
+	// the stricter limit does not consume the default quota, other callers have their own quota
+	assert.NoError(t, call("/dronepatrol.v1.DronePatrolService/GetEstateStats", "key-1"))
+	assert.NoError(t, call("/dronepatrol.v1.DronePatrolService/CreateTree", "key-2"))
+	assert.NoError(t, call("/dronepatrol.v1.DronePatrolService/CreateTree", ""))
+	assert.Equal(t, "ip:10.0.0.1", GrpcClientKey(peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}})))
+}
@@ -0,0 +1,55 @@
+// This file contains the roles of an organisation member and the permissions granted to each role.
+package rbac
+
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleSurveyor Role = "surveyor"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermissionEstateRead    Permission = "estate:read"
+	PermissionEstateManage  Permission = "estate:manage"
+	PermissionTreeWrite     Permission = "tree:write"
+	PermissionPlanRead      Permission = "plan:read"
+	PermissionMissionRun    Permission = "mission:run"
+	PermissionWebhookManage Permission = "webhook:manage"
+)
+
+var viewerPermissions = []Permission{
+	PermissionEstateRead,
+	PermissionPlanRead,
+}
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:   viewerPermissions,
+	RoleSurveyor: append([]Permission{PermissionTreeWrite}, viewerPermissions...),
+	RoleOperator: append([]Permission{PermissionMissionRun}, viewerPermissions...),
+	RoleAdmin: append([]Permission{
+		PermissionEstateManage,
+		PermissionTreeWrite,
+		PermissionMissionRun,
+		PermissionWebhookManage,
+	}, viewerPermissions...),
+}
+
+// Valid returns true for the known roles
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Allowed returns true when the role is granted the permission, unknown roles have no permission
+func Allowed(role Role, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
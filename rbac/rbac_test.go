@@ -0,0 +1,50 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	testCases := []struct {
+		role     Role
+		allowed  []Permission
+		rejected []Permission
+	}{
+		{
+			role:     RoleViewer,
+			allowed:  []Permission{PermissionEstateRead, PermissionPlanRead},
+			rejected: []Permission{PermissionTreeWrite, PermissionMissionRun, PermissionEstateManage, PermissionWebhookManage},
+		},
+		{
+			role:     RoleSurveyor,
+			allowed:  []Permission{PermissionEstateRead, PermissionPlanRead, PermissionTreeWrite},
+			rejected: []Permission{PermissionMissionRun, PermissionEstateManage, PermissionWebhookManage},
+		},
+		{
+			role:     RoleOperator,
+			allowed:  []Permission{PermissionEstateRead, PermissionPlanRead, PermissionMissionRun},
+			rejected: []Permission{PermissionTreeWrite, PermissionEstateManage, PermissionWebhookManage},
+		},
+		{
+			role:    RoleAdmin,
+			allowed: []Permission{PermissionEstateRead, PermissionPlanRead, PermissionTreeWrite, PermissionMissionRun, PermissionEstateManage, PermissionWebhookManage},
+		},
+		{
+			role:     Role("owner"),
+			rejected: []Permission{PermissionEstateRead},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.role), func(t *testing.T) {
+			for _, p := range tc.allowed {
+				assert.True(t, Allowed(tc.role, p), p)
+			}
+			for _, p := range tc.rejected {
+				assert.False(t, Allowed(tc.role, p), p)
+			}
+		})
+	}
+}
@@ -2,55 +2,270 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // CreateEstate this function is to store new estate
 func (r *Repository) CreateEstate(ctx context.Context, input Estate) (output Estate, err error) {
-	err = r.Db.QueryRowContext(ctx, "INSERT INTO estates (length, width) VALUES ($1, $2) RETURNING id, width, length, created_at, updated_at",
-		input.Length, input.Width,
-	).Scan(&output.Id, &output.Width, &output.Length, &output.CreatedAt, &output.UpdatedAt)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO estates (organisation_id, length, width) VALUES ($1, $2, $3) RETURNING id, organisation_id, width, length, version, created_at, updated_at",
+		input.OrganisationId, input.Length, input.Width,
+	).Scan(&output.Id, &output.OrganisationId, &output.Width, &output.Length, &output.Version, &output.CreatedAt, &output.UpdatedAt)
 	if err != nil {
 		return
 	}
+	if err = insertOutboxEvent(ctx, tx, output.Id, EventEstateCreated, output); err != nil {
+		return
+	}
+
+	err = tx.Commit()
 	return
 }
 
 // GetEstateById this function is for get estate by id
 func (r *Repository) GetEstateById(ctx context.Context, input GetEstateByIdInput) (output Estate, err error) {
-	err = r.Db.QueryRowContext(ctx, "SELECT id, width, length, created_at, updated_at FROM estates WHERE id = $1",
+	err = r.Db.QueryRowContext(ctx, "SELECT id, organisation_id, width, length, version, created_at, updated_at FROM estates WHERE id = $1",
 		input.Id,
-	).Scan(&output.Id, &output.Width, &output.Length, &output.CreatedAt, &output.UpdatedAt)
+	).Scan(&output.Id, &output.OrganisationId, &output.Width, &output.Length, &output.Version, &output.CreatedAt, &output.UpdatedAt)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ListEstatesByOrganisationId this function is for list the estates of an organisation, oldest first
+func (r *Repository) ListEstatesByOrganisationId(ctx context.Context, input ListEstatesByOrganisationIdInput) (output []Estate, err error) {
+	query, args := paginate("SELECT id, organisation_id, width, length, version, created_at, updated_at FROM estates WHERE organisation_id = $1",
+		[]any{input.OrganisationId}, []string{"created_at", "id"}, false, timeIdCursor(input.After), input.Limit)
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var estates []Estate
+	for rows.Next() {
+		var estate Estate
+		if err := rows.Scan(&estate.Id, &estate.OrganisationId, &estate.Width, &estate.Length, &estate.Version, &estate.CreatedAt, &estate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		estates = append(estates, estate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return estates, nil
+}
+
+// ImportEstate this function is for create an estate with its trees in a single transaction
+func (r *Repository) ImportEstate(ctx context.Context, input ImportEstateInput) (output Estate, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO estates (id, organisation_id, length, width) VALUES (COALESCE(NULLIF($1, '')::uuid, uuid_generate_v4()), $2, $3, $4) RETURNING id",
+		input.Estate.Id, input.Estate.OrganisationId, input.Estate.Length, input.Estate.Width,
+	).Scan(&output.Id)
+	if err != nil {
+		return
+	}
+
+	// copy the trees in bulk, with their ids when they are kept. The copy does not apply the defaults of the
+	// columns, every column is set here, so the tree.created events are written from the rows copied without
+	// reading the trees back.
+	var now time.Time
+	if err = tx.QueryRowContext(ctx, "SELECT NOW()::timestamp").Scan(&now); err != nil {
+		return
+	}
+	ids := make([]string, len(input.Trees))
+	for i, tree := range input.Trees {
+		ids[i] = tree.Id
+		if ids[i] == "" {
+			ids[i] = uuid.NewString()
+		}
+	}
+	row := func(i int) Tree {
+		return importedTree(input.Trees[i], ids[i], output.Id, now)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("trees", "id", "estate_id", "x", "y", "height", "species", "variety",
+		"planted_at", "health", "attributes", "created_at", "updated_at"))
+	if err != nil {
+		return
+	}
+	for i := range input.Trees {
+		tree := row(i)
+		if _, err = stmt.ExecContext(ctx, tree.Id, tree.EstateId, tree.X, tree.Y, tree.Height, tree.Species, tree.Variety,
+			tree.PlantedAt, tree.Health, string(tree.Attributes), tree.CreatedAt, tree.UpdatedAt); err != nil {
+			_ = stmt.Close()
+			return
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return
+	}
+	if err = stmt.Close(); err != nil {
+		return
+	}
+
+	// read the estate again, its version was bumped by every tree
+	err = tx.QueryRowContext(ctx, "SELECT id, organisation_id, width, length, version, created_at, updated_at FROM estates WHERE id = $1",
+		output.Id,
+	).Scan(&output.Id, &output.OrganisationId, &output.Width, &output.Length, &output.Version, &output.CreatedAt, &output.UpdatedAt)
+	if err != nil {
+		return
+	}
+	if err = insertOutboxEvent(ctx, tx, output.Id, EventEstateCreated, output); err != nil {
+		return
+	}
+
+	// a tree.created event per tree, as for the trees created one by one, each payload copied as it is encoded
+	stmt, err = tx.PrepareContext(ctx, pq.CopyIn("outbox_events", "organisation_id", "estate_id", "event_type", "payload"))
 	if err != nil {
 		return
 	}
+	for i := range input.Trees {
+		var payload []byte
+		if payload, err = json.Marshal(row(i)); err != nil {
+			_ = stmt.Close()
+			return
+		}
+		if _, err = stmt.ExecContext(ctx, output.OrganisationId, output.Id, EventTreeCreated, string(payload)); err != nil {
+			_ = stmt.Close()
+			return
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return
+	}
+	if err = stmt.Close(); err != nil {
+		return
+	}
+
+	err = tx.Commit()
 	return
 }
 
+// importedTree returns the row of an imported tree with the defaults of the columns, as it is stored
+func importedTree(tree Tree, id, estateId string, now time.Time) Tree {
+	tree.Id = id
+	tree.EstateId = estateId
+	if tree.Health == "" {
+		tree.Health = TreeHealthHealthy
+	}
+	if len(tree.Attributes) == 0 {
+		tree.Attributes = json.RawMessage("{}")
+	}
+	if tree.PlantedAt != nil {
+		// planted_at is a date
+		year, month, day := tree.PlantedAt.Date()
+		plantedAt := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		tree.PlantedAt = &plantedAt
+	}
+	tree.CreatedAt, tree.UpdatedAt = now, now
+	return tree
+}
+
 // CreateTree this function is for store tree
 func (r *Repository) CreateTree(ctx context.Context, input Tree) (output Tree, err error) {
-	err = r.Db.QueryRowContext(ctx, "INSERT INTO trees (estate_id, x, y, height) VALUES ($1, $2, $3, $4) RETURNING id, estate_id, x, y, height, created_at, updated_at",
-		input.EstateId, input.X, input.Y, input.Height,
-	).Scan(&output.Id, &output.EstateId, &output.X, &output.Y, &output.Height, &output.CreatedAt, &output.UpdatedAt)
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = scanTree(tx.QueryRowContext(ctx, `INSERT INTO trees (estate_id, x, y, height, species, variety, planted_at, health, attributes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), 'healthy'), COALESCE($9::jsonb, '{}')) RETURNING `+treeColumns,
+		input.EstateId, input.X, input.Y, input.Height, input.Species, input.Variety, input.PlantedAt, input.Health, treeAttributes(input.Attributes),
+	), &output)
 	if err != nil {
 		return
 	}
+	if err = insertOutboxEvent(ctx, tx, output.EstateId, EventTreeCreated, output); err != nil {
+		return
+	}
+
+	err = tx.Commit()
 	return
 }
 
+const treeColumns = "id, estate_id, x, y, height, species, variety, planted_at, health, attributes, created_at, updated_at"
+
+func scanTree(row interface{ Scan(dest ...any) error }, tree *Tree) error {
+	return row.Scan(&tree.Id, &tree.EstateId, &tree.X, &tree.Y, &tree.Height, &tree.Species, &tree.Variety, &tree.PlantedAt,
+		&tree.Health, (*[]byte)(&tree.Attributes), &tree.CreatedAt, &tree.UpdatedAt)
+}
+
+// treeAttributes returns the attributes parameter of a tree, NULL when they are not set
+func treeAttributes(attributes json.RawMessage) any {
+	if len(attributes) == 0 {
+		return nil
+	}
+	return string(attributes)
+}
+
+// filterTrees append to a query of trees the conditions of the filter
+func filterTrees(query string, args []any, filter TreeFilter) (string, []any) {
+	if filter.Species != "" {
+		args = append(args, filter.Species)
+		query += fmt.Sprintf(" AND species = $%d", len(args))
+	}
+	if filter.Health != "" {
+		args = append(args, filter.Health)
+		query += fmt.Sprintf(" AND health = $%d", len(args))
+	}
+	return query, args
+}
+
 // GetTreeByPlot this function is for get tree by plot x and y
 func (r *Repository) GetTreeByPlot(ctx context.Context, input GetTreeByPlot) (output Tree, err error) {
-	err = r.Db.QueryRowContext(ctx, "SELECT id, estate_id, x, y, height, created_at, updated_at FROM trees WHERE estate_id = $1 AND x = $2 AND y = $3",
+	err = scanTree(r.Db.QueryRowContext(ctx, "SELECT "+treeColumns+" FROM trees WHERE estate_id = $1 AND x = $2 AND y = $3",
 		input.EstateId, input.X, input.Y,
-	).Scan(&output.Id, &output.EstateId, &output.X, &output.Y, &output.Height, &output.CreatedAt, &output.UpdatedAt)
+	), &output)
 	if err != nil {
 		return
 	}
 	return
 }
 
-// ListTreesByEstateId this function is for get list trees by estate id
+// ListTreesByEstateId this function is for get list trees by estate id, ordered by y then x
 func (r *Repository) ListTreesByEstateId(ctx context.Context, input ListTreesByEstateIdInput) (output []Tree, err error) {
-	rows, err := r.Db.QueryContext(ctx, "SELECT id, estate_id, x, y, height, created_at, updated_at FROM trees WHERE estate_id = $1", input.EstateId)
+	var after []any
+	if input.After != nil {
+		after = []any{input.After.Y, input.After.X}
+	}
+	query, args := filterTrees("SELECT "+treeColumns+" FROM trees WHERE estate_id = $1", []any{input.EstateId}, input.Filter)
+	query, args = paginate(query, args, []string{"y", "x"}, false, after, input.Limit)
+	rows, err := r.Db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +276,7 @@ func (r *Repository) ListTreesByEstateId(ctx context.Context, input ListTreesByE
 	// Iterate over the rows
 	for rows.Next() {
 		var tree Tree
-		if err := rows.Scan(&tree.Id, &tree.EstateId, &tree.X, &tree.Y, &tree.Height, &tree.CreatedAt, &tree.UpdatedAt); err != nil {
+		if err := scanTree(rows, &tree); err != nil {
 			return nil, err
 		}
 		trees = append(trees, tree)
@@ -72,3 +287,764 @@ func (r *Repository) ListTreesByEstateId(ctx context.Context, input ListTreesByE
 
 	return trees, nil
 }
+
+// StreamTreesByEstateId this function is for visit the trees of an estate one by one, ordered by y then x, so a
+// large estate is never loaded in memory. The visit stops at the first error, which is returned.
+func (r *Repository) StreamTreesByEstateId(ctx context.Context, input StreamTreesByEstateIdInput, visit func(Tree) error) (err error) {
+	var after []any
+	if input.After != nil {
+		after = []any{input.After.Y, input.After.X}
+	}
+	query, args := filterTrees("SELECT "+treeColumns+" FROM trees WHERE estate_id = $1", []any{input.EstateId}, input.Filter)
+	query, args = paginate(query, args, []string{"y", "x"}, false, after, 0)
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tree Tree
+		if err := scanTree(rows, &tree); err != nil {
+			return err
+		}
+		if err := visit(tree); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetApiKeyByHash this function is for get a non revoked api key by its hash
+func (r *Repository) GetApiKeyByHash(ctx context.Context, input GetApiKeyByHashInput) (output ApiKey, err error) {
+	err = r.Db.QueryRowContext(ctx, "SELECT id, organisation_id, name, role, key_hash, created_at, revoked_at FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL",
+		input.KeyHash,
+	).Scan(&output.Id, &output.OrganisationId, &output.Name, &output.Role, &output.KeyHash, &output.CreatedAt, &output.RevokedAt)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// GetMembership this function is for get the role of a subject in an organisation
+func (r *Repository) GetMembership(ctx context.Context, input GetMembershipInput) (output Membership, err error) {
+	err = r.Db.QueryRowContext(ctx, "SELECT organisation_id, subject, role, created_at, updated_at FROM memberships WHERE organisation_id = $1 AND subject = $2",
+		input.OrganisationId, input.Subject,
+	).Scan(&output.OrganisationId, &output.Subject, &output.Role, &output.CreatedAt, &output.UpdatedAt)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// CreateAuditLog this function is for store an audit log entry
+func (r *Repository) CreateAuditLog(ctx context.Context, input AuditLog) (output AuditLog, err error) {
+	err = r.Db.QueryRowContext(ctx, "INSERT INTO audit_logs (organisation_id, subject, role, action, resource, outcome) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, organisation_id, subject, role, action, resource, outcome, created_at",
+		input.OrganisationId, input.Subject, input.Role, input.Action, input.Resource, input.Outcome,
+	).Scan(&output.Id, &output.OrganisationId, &output.Subject, &output.Role, &output.Action, &output.Resource, &output.Outcome, &output.CreatedAt)
+	if err != nil {
+		return
+	}
+	return
+}
+
+const planJobColumns = "id, estate_id, max_distance, status, progress, distance, rest_x, rest_y, error, created_at, updated_at, started_at, finished_at"
+
+func scanPlanJob(row interface{ Scan(dest ...any) error }, job *PlanJob) error {
+	return row.Scan(&job.Id, &job.EstateId, &job.MaxDistance, &job.Status, &job.Progress, &job.Distance, &job.RestX, &job.RestY,
+		&job.Error, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.FinishedAt)
+}
+
+// CreatePlanJob this function is for store a new pending drone plan job
+func (r *Repository) CreatePlanJob(ctx context.Context, input PlanJob) (output PlanJob, err error) {
+	err = scanPlanJob(r.Db.QueryRowContext(ctx, "INSERT INTO plan_jobs (estate_id, max_distance) VALUES ($1, $2) RETURNING "+planJobColumns,
+		input.EstateId, input.MaxDistance,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// GetPlanJobById this function is for get a drone plan job of an estate
+func (r *Repository) GetPlanJobById(ctx context.Context, input GetPlanJobByIdInput) (output PlanJob, err error) {
+	err = scanPlanJob(r.Db.QueryRowContext(ctx, "SELECT "+planJobColumns+" FROM plan_jobs WHERE id = $1 AND estate_id = $2",
+		input.Id, input.EstateId,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ListUnfinishedPlanJobs this function is for get the pending and running drone plan jobs, oldest first
+func (r *Repository) ListUnfinishedPlanJobs(ctx context.Context, input ListUnfinishedPlanJobsInput) (output []PlanJob, err error) {
+	rows, err := r.Db.QueryContext(ctx, "SELECT "+planJobColumns+" FROM plan_jobs WHERE status IN ('pending', 'running') ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []PlanJob
+	for rows.Next() {
+		var job PlanJob
+		if err := scanPlanJob(rows, &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// StartPlanJob this function is for mark a drone plan job as running. A running job is started again
+// after a restart, a cancelled or finished job is not found.
+func (r *Repository) StartPlanJob(ctx context.Context, input StartPlanJobInput) (output PlanJob, err error) {
+	err = scanPlanJob(r.Db.QueryRowContext(ctx, "UPDATE plan_jobs SET status = 'running', progress = 0, started_at = NOW(), updated_at = NOW() WHERE id = $1 AND status IN ('pending', 'running') RETURNING "+planJobColumns,
+		input.Id,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// UpdatePlanJobProgress this function is for update the progress of a running drone plan job,
+// a job no longer running (e.g. cancelled) is not found
+func (r *Repository) UpdatePlanJobProgress(ctx context.Context, input UpdatePlanJobProgressInput) (output PlanJob, err error) {
+	err = scanPlanJob(r.Db.QueryRowContext(ctx, "UPDATE plan_jobs SET progress = $2, updated_at = NOW() WHERE id = $1 AND status = 'running' RETURNING "+planJobColumns,
+		input.Id, input.Progress,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// FinishPlanJob this function is for store the result or the error of a running drone plan job
+func (r *Repository) FinishPlanJob(ctx context.Context, input FinishPlanJobInput) (output PlanJob, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = scanPlanJob(tx.QueryRowContext(ctx, "UPDATE plan_jobs SET status = $2, progress = CASE WHEN $2 = 'succeeded' THEN 100 ELSE progress END, distance = $3, rest_x = $4, rest_y = $5, error = $6, finished_at = NOW(), updated_at = NOW() WHERE id = $1 AND status = 'running' RETURNING "+planJobColumns,
+		input.Id, input.Status, input.Distance, input.RestX, input.RestY, input.Error,
+	), &output)
+	if err != nil {
+		return
+	}
+	if err = insertOutboxEvent(ctx, tx, output.EstateId, "plan_job."+output.Status, output); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// CancelPlanJob this function is for cancel a pending or running drone plan job of an estate
+func (r *Repository) CancelPlanJob(ctx context.Context, input CancelPlanJobInput) (output PlanJob, err error) {
+	err = scanPlanJob(r.Db.QueryRowContext(ctx, "UPDATE plan_jobs SET status = 'cancelled', finished_at = NOW(), updated_at = NOW() WHERE id = $1 AND estate_id = $2 AND status IN ('pending', 'running') RETURNING "+planJobColumns,
+		input.Id, input.EstateId,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// CreateFlight this function is for store a flight and its points in a single transaction
+func (r *Repository) CreateFlight(ctx context.Context, input Flight) (output Flight, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO flights (estate_id, started_at, finished_at, point_count, distance, battery_used) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, estate_id, started_at, finished_at, point_count, distance, battery_used, created_at",
+		input.EstateId, input.StartedAt, input.FinishedAt, input.PointCount, input.Distance, input.BatteryUsed,
+	).Scan(&output.Id, &output.EstateId, &output.StartedAt, &output.FinishedAt, &output.PointCount, &output.Distance, &output.BatteryUsed, &output.CreatedAt)
+	if err != nil {
+		return
+	}
+
+	// a flight log has up to thousands of points, copy them in bulk
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("flight_points", "flight_id", "seq", "recorded_at", "x", "y", "altitude", "battery"))
+	if err != nil {
+		return
+	}
+	for _, point := range input.Points {
+		if _, err = stmt.ExecContext(ctx, output.Id, point.Seq, point.RecordedAt, point.X, point.Y, point.Altitude, point.Battery); err != nil {
+			_ = stmt.Close()
+			return
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return
+	}
+	if err = stmt.Close(); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// GetFlightById this function is for get a flight of an estate
+func (r *Repository) GetFlightById(ctx context.Context, input GetFlightByIdInput) (output Flight, err error) {
+	err = r.Db.QueryRowContext(ctx, "SELECT id, estate_id, started_at, finished_at, point_count, distance, battery_used, created_at FROM flights WHERE id = $1 AND estate_id = $2",
+		input.Id, input.EstateId,
+	).Scan(&output.Id, &output.EstateId, &output.StartedAt, &output.FinishedAt, &output.PointCount, &output.Distance, &output.BatteryUsed, &output.CreatedAt)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ListFlightPointsByFlightId this function is for get the points of a flight in chronological order
+func (r *Repository) ListFlightPointsByFlightId(ctx context.Context, input ListFlightPointsByFlightIdInput) (output []FlightPoint, err error) {
+	rows, err := r.Db.QueryContext(ctx, "SELECT flight_id, seq, recorded_at, x, y, altitude, battery FROM flight_points WHERE flight_id = $1 ORDER BY seq", input.FlightId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []FlightPoint
+	for rows.Next() {
+		var point FlightPoint
+		if err := rows.Scan(&point.FlightId, &point.Seq, &point.RecordedAt, &point.X, &point.Y, &point.Altitude, &point.Battery); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+const findingColumns = "id, estate_id, flight_id, tree_id, x, y, type, severity, confidence, image_ref, status, detected_at, created_at, updated_at, resolved_at"
+
+func scanFinding(row interface{ Scan(dest ...any) error }, finding *Finding) error {
+	return row.Scan(&finding.Id, &finding.EstateId, &finding.FlightId, &finding.TreeId, &finding.X, &finding.Y, &finding.Type,
+		&finding.Severity, &finding.Confidence, &finding.ImageRef, &finding.Status, &finding.DetectedAt, &finding.CreatedAt,
+		&finding.UpdatedAt, &finding.ResolvedAt)
+}
+
+// CreateFindings this function is for store the findings of a flight, each linked to the tree of its plot
+func (r *Repository) CreateFindings(ctx context.Context, input CreateFindingsInput) (output []Finding, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO findings (estate_id, flight_id, tree_id, x, y, type, severity, confidence, image_ref, detected_at)
+		VALUES ($1, $2, (SELECT id FROM trees WHERE estate_id = $1 AND x = $3 AND y = $4), $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+findingColumns)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+
+	output = make([]Finding, 0, len(input.Findings))
+	for _, finding := range input.Findings {
+		var created Finding
+		err = scanFinding(stmt.QueryRowContext(ctx, finding.EstateId, finding.FlightId, finding.X, finding.Y, finding.Type,
+			finding.Severity, finding.Confidence, finding.ImageRef, finding.DetectedAt,
+		), &created)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, created)
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// filterFindings append to a query of findings the conditions of the filter
+func filterFindings(query string, args []any, filter FindingFilter) (string, []any) {
+	if filter.TreeId != "" {
+		args = append(args, filter.TreeId)
+		query += fmt.Sprintf(" AND tree_id = $%d", len(args))
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.MinSeverity > 0 {
+		args = append(args, filter.MinSeverity)
+		query += fmt.Sprintf(" AND severity >= $%d", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		query += fmt.Sprintf(" AND detected_at >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		query += fmt.Sprintf(" AND detected_at < $%d", len(args))
+	}
+	return query, args
+}
+
+// ListFindingsByEstateId this function is for get the findings of an estate, the most recent first
+func (r *Repository) ListFindingsByEstateId(ctx context.Context, input ListFindingsByEstateIdInput) (output []Finding, err error) {
+	query, args := filterFindings("SELECT "+findingColumns+" FROM findings WHERE estate_id = $1", []any{input.EstateId}, input.Filter)
+	query, args = paginate(query, args, []string{"detected_at", "id"}, true, timeIdCursor(input.After), input.Limit)
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var findings []Finding
+	for rows.Next() {
+		var finding Finding
+		if err := scanFinding(rows, &finding); err != nil {
+			return nil, err
+		}
+		findings = append(findings, finding)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// ListFlaggedPlotsByEstateId this function is for get the distinct plots of the trees of an estate having a finding,
+// the findings of an empty plot are left out
+func (r *Repository) ListFlaggedPlotsByEstateId(ctx context.Context, input ListFlaggedPlotsByEstateIdInput) (output []FlaggedPlot, err error) {
+	query, args := filterFindings("SELECT DISTINCT x, y FROM findings WHERE estate_id = $1 AND tree_id IS NOT NULL", []any{input.EstateId}, input.Filter)
+	query, args = paginate(query, args, []string{"y", "x"}, false, nil, input.Limit)
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plots []FlaggedPlot
+	for rows.Next() {
+		var plot FlaggedPlot
+		if err := rows.Scan(&plot.X, &plot.Y); err != nil {
+			return nil, err
+		}
+		plots = append(plots, plot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return plots, nil
+}
+
+// CountFindingsByEstateId this function is for count the findings of an estate by type, severity and status
+func (r *Repository) CountFindingsByEstateId(ctx context.Context, input CountFindingsByEstateIdInput) (output []FindingCount, err error) {
+	query, args := filterFindings("SELECT type, severity, status, COUNT(*), MAX(detected_at) FROM findings WHERE estate_id = $1", []any{input.EstateId}, FindingFilter{
+		From: input.From,
+		To:   input.To,
+	})
+	rows, err := r.Db.QueryContext(ctx, query+" GROUP BY type, severity, status ORDER BY type, severity, status", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []FindingCount
+	for rows.Next() {
+		var count FindingCount
+		if err := rows.Scan(&count.Type, &count.Severity, &count.Status, &count.Count, &count.LastDetectedAt); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// ResolveFinding this function is for mark a finding of an estate as resolved, a resolved finding keeps the time
+// it was first resolved
+func (r *Repository) ResolveFinding(ctx context.Context, input ResolveFindingInput) (output Finding, err error) {
+	err = scanFinding(r.Db.QueryRowContext(ctx, "UPDATE findings SET status = 'resolved', resolved_at = COALESCE(resolved_at, NOW()), updated_at = NOW() WHERE id = $1 AND estate_id = $2 RETURNING "+findingColumns,
+		input.Id, input.EstateId,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+const missionScheduleColumns = "id, estate_id, name, cron, time_zone, max_distance, active, materialised_until, created_at, updated_at"
+
+func scanMissionSchedule(row interface{ Scan(dest ...any) error }, schedule *MissionSchedule) error {
+	return row.Scan(&schedule.Id, &schedule.EstateId, &schedule.Name, &schedule.Cron, &schedule.TimeZone, &schedule.MaxDistance,
+		&schedule.Active, &schedule.MaterialisedUntil, &schedule.CreatedAt, &schedule.UpdatedAt)
+}
+
+const missionColumns = "id, schedule_id, estate_id, scheduled_at, status, distance, rest_x, rest_y, estate_version, created_at, updated_at, dispatched_at"
+
+func scanMission(row interface{ Scan(dest ...any) error }, mission *Mission) error {
+	return row.Scan(&mission.Id, &mission.ScheduleId, &mission.EstateId, &mission.ScheduledAt, &mission.Status, &mission.Distance,
+		&mission.RestX, &mission.RestY, &mission.EstateVersion, &mission.CreatedAt, &mission.UpdatedAt, &mission.DispatchedAt)
+}
+
+// CreateMissionSchedule this function is for store a new recurring patrol schedule
+func (r *Repository) CreateMissionSchedule(ctx context.Context, input MissionSchedule) (output MissionSchedule, err error) {
+	err = scanMissionSchedule(r.Db.QueryRowContext(ctx, "INSERT INTO mission_schedules (estate_id, name, cron, time_zone, max_distance) VALUES ($1, $2, $3, $4, $5) RETURNING "+missionScheduleColumns,
+		input.EstateId, input.Name, input.Cron, input.TimeZone, input.MaxDistance,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// GetMissionScheduleById this function is for get an active patrol schedule of an estate
+func (r *Repository) GetMissionScheduleById(ctx context.Context, input GetMissionScheduleByIdInput) (output MissionSchedule, err error) {
+	err = scanMissionSchedule(r.Db.QueryRowContext(ctx, "SELECT "+missionScheduleColumns+" FROM mission_schedules WHERE id = $1 AND estate_id = $2 AND active",
+		input.Id, input.EstateId,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ListMissionSchedulesByEstateId this function is for get the active patrol schedules of an estate
+func (r *Repository) ListMissionSchedulesByEstateId(ctx context.Context, input ListMissionSchedulesByEstateIdInput) (output []MissionSchedule, err error) {
+	query, args := paginate("SELECT "+missionScheduleColumns+" FROM mission_schedules WHERE estate_id = $1 AND active",
+		[]any{input.EstateId}, []string{"created_at", "id"}, false, timeIdCursor(input.After), input.Limit)
+	return r.listMissionSchedules(ctx, query, args...)
+}
+
+// ListActiveMissionSchedules this function is for get the active patrol schedules of every estate
+func (r *Repository) ListActiveMissionSchedules(ctx context.Context, input ListActiveMissionSchedulesInput) (output []MissionSchedule, err error) {
+	return r.listMissionSchedules(ctx, "SELECT "+missionScheduleColumns+" FROM mission_schedules WHERE active ORDER BY created_at")
+}
+
+func (r *Repository) listMissionSchedules(ctx context.Context, query string, args ...any) ([]MissionSchedule, error) {
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []MissionSchedule
+	for rows.Next() {
+		var schedule MissionSchedule
+		if err := scanMissionSchedule(rows, &schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// DeactivateMissionSchedule this function is for stop a patrol schedule and cancel its upcoming missions
+func (r *Repository) DeactivateMissionSchedule(ctx context.Context, input DeactivateMissionScheduleInput) (output MissionSchedule, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = scanMissionSchedule(tx.QueryRowContext(ctx, "UPDATE mission_schedules SET active = FALSE, updated_at = NOW() WHERE id = $1 AND estate_id = $2 AND active RETURNING "+missionScheduleColumns,
+		input.Id, input.EstateId,
+	), &output)
+	if err != nil {
+		return
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE missions SET status = 'cancelled', updated_at = NOW() WHERE schedule_id = $1 AND status = 'scheduled'", output.Id)
+	if err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// UpdateMissionScheduleMaterialisedUntil this function is for store the time of the last mission created from a schedule
+func (r *Repository) UpdateMissionScheduleMaterialisedUntil(ctx context.Context, input UpdateMissionScheduleMaterialisedUntilInput) (output MissionSchedule, err error) {
+	err = scanMissionSchedule(r.Db.QueryRowContext(ctx, "UPDATE mission_schedules SET materialised_until = $2, updated_at = NOW() WHERE id = $1 RETURNING "+missionScheduleColumns,
+		input.Id, input.MaterialisedUntil,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// CreateMission this function is for store an upcoming mission, a mission already created for the same
+// schedule and time is not found
+func (r *Repository) CreateMission(ctx context.Context, input Mission) (output Mission, err error) {
+	err = scanMission(r.Db.QueryRowContext(ctx, "INSERT INTO missions (schedule_id, estate_id, scheduled_at, distance, rest_x, rest_y, estate_version) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (schedule_id, scheduled_at) DO NOTHING RETURNING "+missionColumns,
+		input.ScheduleId, input.EstateId, input.ScheduledAt, input.Distance, input.RestX, input.RestY, input.EstateVersion,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ListMissionsByEstateId this function is for get the upcoming or past missions of an estate
+func (r *Repository) ListMissionsByEstateId(ctx context.Context, input ListMissionsByEstateIdInput) (output []Mission, err error) {
+	query := "SELECT " + missionColumns + " FROM missions WHERE estate_id = $1 AND scheduled_at < $2"
+	if input.Upcoming {
+		query = "SELECT " + missionColumns + " FROM missions WHERE estate_id = $1 AND scheduled_at >= $2"
+	}
+	query, args := paginate(query, []any{input.EstateId, input.Now}, []string{"scheduled_at", "id"}, !input.Upcoming, timeIdCursor(input.After), input.Limit)
+	return r.listMissions(ctx, query, args...)
+}
+
+// ListDueMissions this function is for get the scheduled missions whose time came, oldest first
+func (r *Repository) ListDueMissions(ctx context.Context, input ListDueMissionsInput) (output []Mission, err error) {
+	return r.listMissions(ctx, "SELECT "+missionColumns+" FROM missions WHERE status = 'scheduled' AND scheduled_at <= $1 ORDER BY scheduled_at LIMIT $2", input.Now, input.Limit)
+}
+
+func (r *Repository) listMissions(ctx context.Context, query string, args ...any) ([]Mission, error) {
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missions []Mission
+	for rows.Next() {
+		var mission Mission
+		if err := scanMission(rows, &mission); err != nil {
+			return nil, err
+		}
+		missions = append(missions, mission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return missions, nil
+}
+
+// DispatchMission this function is for mark a scheduled mission as dispatched with its final plan
+func (r *Repository) DispatchMission(ctx context.Context, input DispatchMissionInput) (output Mission, err error) {
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = scanMission(tx.QueryRowContext(ctx, "UPDATE missions SET status = 'dispatched', distance = $2, rest_x = $3, rest_y = $4, estate_version = $5, dispatched_at = NOW(), updated_at = NOW() WHERE id = $1 AND status = 'scheduled' RETURNING "+missionColumns,
+		input.Id, input.Distance, input.RestX, input.RestY, input.EstateVersion,
+	), &output)
+	if err != nil {
+		return
+	}
+	if err = insertOutboxEvent(ctx, tx, output.EstateId, EventMissionDispatched, output); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// insertOutboxEvent store an event of the estate in the outbox, in the transaction of the change so the event is
+// sent if and only if the change is committed
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, estateId string, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO outbox_events (organisation_id, estate_id, event_type, payload) SELECT organisation_id, id, $2, $3 FROM estates WHERE id = $1",
+		estateId, eventType, payload,
+	)
+	return err
+}
+
+const webhookSubscriptionColumns = "id, organisation_id, url, event_types, secret, active, created_at, updated_at"
+
+func scanWebhookSubscription(row interface{ Scan(dest ...any) error }, subscription *WebhookSubscription) error {
+	return row.Scan(&subscription.Id, &subscription.OrganisationId, &subscription.Url, pq.Array(&subscription.EventTypes), &subscription.Secret,
+		&subscription.Active, &subscription.CreatedAt, &subscription.UpdatedAt)
+}
+
+// CreateWebhookSubscription this function is for store a webhook subscription of an organisation
+func (r *Repository) CreateWebhookSubscription(ctx context.Context, input WebhookSubscription) (output WebhookSubscription, err error) {
+	err = scanWebhookSubscription(r.Db.QueryRowContext(ctx, "INSERT INTO webhook_subscriptions (organisation_id, url, event_types, secret) VALUES ($1, $2, $3, $4) RETURNING "+webhookSubscriptionColumns,
+		input.OrganisationId, input.Url, pq.Array(input.EventTypes), input.Secret,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// GetWebhookSubscriptionById this function is for get an active webhook subscription of an organisation
+func (r *Repository) GetWebhookSubscriptionById(ctx context.Context, input GetWebhookSubscriptionByIdInput) (output WebhookSubscription, err error) {
+	err = scanWebhookSubscription(r.Db.QueryRowContext(ctx, "SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE id = $1 AND organisation_id = $2 AND active",
+		input.Id, input.OrganisationId,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ListWebhookSubscriptionsByOrganisationId this function is for get the active webhook subscriptions of an organisation
+func (r *Repository) ListWebhookSubscriptionsByOrganisationId(ctx context.Context, input ListWebhookSubscriptionsByOrganisationIdInput) (output []WebhookSubscription, err error) {
+	query, args := paginate("SELECT "+webhookSubscriptionColumns+" FROM webhook_subscriptions WHERE organisation_id = $1 AND active",
+		[]any{input.OrganisationId}, []string{"created_at", "id"}, false, timeIdCursor(input.After), input.Limit)
+	rows, err := r.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []WebhookSubscription
+	for rows.Next() {
+		var subscription WebhookSubscription
+		if err := scanWebhookSubscription(rows, &subscription); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// DeactivateWebhookSubscription this function is for stop a webhook subscription, its pending deliveries are not sent
+func (r *Repository) DeactivateWebhookSubscription(ctx context.Context, input DeactivateWebhookSubscriptionInput) (output WebhookSubscription, err error) {
+	err = scanWebhookSubscription(r.Db.QueryRowContext(ctx, "UPDATE webhook_subscriptions SET active = FALSE, updated_at = NOW() WHERE id = $1 AND organisation_id = $2 AND active RETURNING "+webhookSubscriptionColumns,
+		input.Id, input.OrganisationId,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// FanOutOutboxEvents this function is for create the deliveries of the outbox events to the subscriptions of their
+// organisation, and returns the number of deliveries created
+func (r *Repository) FanOutOutboxEvents(ctx context.Context, input FanOutOutboxEventsInput) (output int, err error) {
+	// a single statement, an event is marked processed if and only if its deliveries are created
+	result, err := r.Db.ExecContext(ctx, `WITH events AS (
+		UPDATE outbox_events SET processed_at = NOW()
+		WHERE id IN (SELECT id FROM outbox_events WHERE processed_at IS NULL ORDER BY created_at LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING id, organisation_id, event_type
+	)
+	INSERT INTO webhook_deliveries (subscription_id, event_id)
+	SELECT s.id, e.id FROM events e JOIN webhook_subscriptions s ON s.organisation_id = e.organisation_id AND s.active AND e.event_type = ANY(s.event_types)`,
+		input.Limit,
+	)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+const webhookDeliveryColumns = "id, subscription_id, event_id, status, attempts, next_attempt_at, last_status_code, last_error, created_at, updated_at, delivered_at"
+
+func scanWebhookDelivery(row interface{ Scan(dest ...any) error }, delivery *WebhookDelivery, joined ...any) error {
+	return row.Scan(append([]any{&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt,
+		&delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &delivery.UpdatedAt, &delivery.DeliveredAt}, joined...)...)
+}
+
+// ClaimDueWebhookDeliveries this function is for take the pending deliveries whose attempt is due, with the
+// subscription and the event to send. The claimed deliveries are not due again before the lease ends so another
+// worker does not send them at the same time.
+func (r *Repository) ClaimDueWebhookDeliveries(ctx context.Context, input ClaimDueWebhookDeliveriesInput) (output []WebhookDelivery, err error) {
+	rows, err := r.Db.QueryContext(ctx, `WITH claimed AS (
+		UPDATE webhook_deliveries SET next_attempt_at = $1::timestamp + $3 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.active
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 ORDER BY d.next_attempt_at LIMIT $2 FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns+`
+	)
+	SELECT c.`+strings.ReplaceAll(webhookDeliveryColumns, ", ", ", c.")+`, s.url, s.secret, e.event_type, e.payload, e.created_at
+	FROM claimed c JOIN webhook_subscriptions s ON s.id = c.subscription_id JOIN outbox_events e ON e.id = c.event_id
+	ORDER BY e.created_at`,
+		input.Now, input.Limit, input.Lease.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var delivery WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery, &delivery.Url, &delivery.Secret, &delivery.EventType, &delivery.Payload, &delivery.EventCreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery this function is for store the outcome of a delivery attempt
+func (r *Repository) UpdateWebhookDelivery(ctx context.Context, input UpdateWebhookDeliveryInput) (output WebhookDelivery, err error) {
+	err = scanWebhookDelivery(r.Db.QueryRowContext(ctx, "UPDATE webhook_deliveries SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = $4, next_attempt_at = $5, delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE NULL END, updated_at = NOW() WHERE id = $1 AND status = 'pending' RETURNING "+webhookDeliveryColumns,
+		input.Id, input.Status, input.LastStatusCode, input.LastError, input.NextAttemptAt,
+	), &output)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// ReplayWebhookDeliveries this function is for send again the dead deliveries of a subscription, and returns the
+// number of deliveries replayed
+func (r *Repository) ReplayWebhookDeliveries(ctx context.Context, input ReplayWebhookDeliveriesInput) (output int, err error) {
+	result, err := r.Db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $2, updated_at = NOW() WHERE subscription_id = $1 AND status = 'dead'",
+		input.SubscriptionId, input.Now,
+	)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
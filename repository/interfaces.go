@@ -9,7 +9,46 @@ import "context"
 type RepositoryInterface interface {
 	CreateEstate(ctx context.Context, input Estate) (output Estate, err error)
 	GetEstateById(ctx context.Context, input GetEstateByIdInput) (output Estate, err error)
+	ListEstatesByOrganisationId(ctx context.Context, input ListEstatesByOrganisationIdInput) (output []Estate, err error)
+	ImportEstate(ctx context.Context, input ImportEstateInput) (output Estate, err error)
 	CreateTree(ctx context.Context, input Tree) (output Tree, err error)
 	GetTreeByPlot(ctx context.Context, input GetTreeByPlot) (output Tree, err error)
 	ListTreesByEstateId(ctx context.Context, input ListTreesByEstateIdInput) (output []Tree, err error)
+	StreamTreesByEstateId(ctx context.Context, input StreamTreesByEstateIdInput, visit func(Tree) error) (err error)
+	GetApiKeyByHash(ctx context.Context, input GetApiKeyByHashInput) (output ApiKey, err error)
+	GetMembership(ctx context.Context, input GetMembershipInput) (output Membership, err error)
+	CreateAuditLog(ctx context.Context, input AuditLog) (output AuditLog, err error)
+	CreatePlanJob(ctx context.Context, input PlanJob) (output PlanJob, err error)
+	GetPlanJobById(ctx context.Context, input GetPlanJobByIdInput) (output PlanJob, err error)
+	ListUnfinishedPlanJobs(ctx context.Context, input ListUnfinishedPlanJobsInput) (output []PlanJob, err error)
+	StartPlanJob(ctx context.Context, input StartPlanJobInput) (output PlanJob, err error)
+	UpdatePlanJobProgress(ctx context.Context, input UpdatePlanJobProgressInput) (output PlanJob, err error)
+	FinishPlanJob(ctx context.Context, input FinishPlanJobInput) (output PlanJob, err error)
+	CancelPlanJob(ctx context.Context, input CancelPlanJobInput) (output PlanJob, err error)
+	CreateFlight(ctx context.Context, input Flight) (output Flight, err error)
+	GetFlightById(ctx context.Context, input GetFlightByIdInput) (output Flight, err error)
+	ListFlightPointsByFlightId(ctx context.Context, input ListFlightPointsByFlightIdInput) (output []FlightPoint, err error)
+	CreateFindings(ctx context.Context, input CreateFindingsInput) (output []Finding, err error)
+	ListFindingsByEstateId(ctx context.Context, input ListFindingsByEstateIdInput) (output []Finding, err error)
+	ListFlaggedPlotsByEstateId(ctx context.Context, input ListFlaggedPlotsByEstateIdInput) (output []FlaggedPlot, err error)
+	CountFindingsByEstateId(ctx context.Context, input CountFindingsByEstateIdInput) (output []FindingCount, err error)
+	ResolveFinding(ctx context.Context, input ResolveFindingInput) (output Finding, err error)
+	CreateMissionSchedule(ctx context.Context, input MissionSchedule) (output MissionSchedule, err error)
+	GetMissionScheduleById(ctx context.Context, input GetMissionScheduleByIdInput) (output MissionSchedule, err error)
+	ListMissionSchedulesByEstateId(ctx context.Context, input ListMissionSchedulesByEstateIdInput) (output []MissionSchedule, err error)
+	ListActiveMissionSchedules(ctx context.Context, input ListActiveMissionSchedulesInput) (output []MissionSchedule, err error)
+	DeactivateMissionSchedule(ctx context.Context, input DeactivateMissionScheduleInput) (output MissionSchedule, err error)
+	UpdateMissionScheduleMaterialisedUntil(ctx context.Context, input UpdateMissionScheduleMaterialisedUntilInput) (output MissionSchedule, err error)
+	CreateMission(ctx context.Context, input Mission) (output Mission, err error)
+	ListMissionsByEstateId(ctx context.Context, input ListMissionsByEstateIdInput) (output []Mission, err error)
+	ListDueMissions(ctx context.Context, input ListDueMissionsInput) (output []Mission, err error)
+	DispatchMission(ctx context.Context, input DispatchMissionInput) (output Mission, err error)
+	CreateWebhookSubscription(ctx context.Context, input WebhookSubscription) (output WebhookSubscription, err error)
+	GetWebhookSubscriptionById(ctx context.Context, input GetWebhookSubscriptionByIdInput) (output WebhookSubscription, err error)
+	ListWebhookSubscriptionsByOrganisationId(ctx context.Context, input ListWebhookSubscriptionsByOrganisationIdInput) (output []WebhookSubscription, err error)
+	DeactivateWebhookSubscription(ctx context.Context, input DeactivateWebhookSubscriptionInput) (output WebhookSubscription, err error)
+	FanOutOutboxEvents(ctx context.Context, input FanOutOutboxEventsInput) (output int, err error)
+	ClaimDueWebhookDeliveries(ctx context.Context, input ClaimDueWebhookDeliveriesInput) (output []WebhookDelivery, err error)
+	UpdateWebhookDelivery(ctx context.Context, input UpdateWebhookDeliveryInput) (output WebhookDelivery, err error)
+	ReplayWebhookDeliveries(ctx context.Context, input ReplayWebhookDeliveriesInput) (output int, err error)
 }
@@ -39,6 +39,66 @@ func (m *MockRepositoryInterface) EXPECT() *MockRepositoryInterfaceMockRecorder
 	return m.recorder
 }
 
+// CancelPlanJob mocks base method.
+func (m *MockRepositoryInterface) CancelPlanJob(ctx context.Context, input CancelPlanJobInput) (PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelPlanJob", ctx, input)
+	ret0, _ := ret[0].(PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelPlanJob indicates an expected call of CancelPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) CancelPlanJob(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).CancelPlanJob), ctx, input)
+}
+
+// ClaimDueWebhookDeliveries mocks base method.
+func (m *MockRepositoryInterface) ClaimDueWebhookDeliveries(ctx context.Context, input ClaimDueWebhookDeliveriesInput) ([]WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueWebhookDeliveries", ctx, input)
+	ret0, _ := ret[0].([]WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueWebhookDeliveries indicates an expected call of ClaimDueWebhookDeliveries.
+func (mr *MockRepositoryInterfaceMockRecorder) ClaimDueWebhookDeliveries(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueWebhookDeliveries", reflect.TypeOf((*MockRepositoryInterface)(nil).ClaimDueWebhookDeliveries), ctx, input)
+}
+
+// CountFindingsByEstateId mocks base method.
+func (m *MockRepositoryInterface) CountFindingsByEstateId(ctx context.Context, input CountFindingsByEstateIdInput) ([]FindingCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFindingsByEstateId", ctx, input)
+	ret0, _ := ret[0].([]FindingCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFindingsByEstateId indicates an expected call of CountFindingsByEstateId.
+func (mr *MockRepositoryInterfaceMockRecorder) CountFindingsByEstateId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFindingsByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).CountFindingsByEstateId), ctx, input)
+}
+
+// CreateAuditLog mocks base method.
+func (m *MockRepositoryInterface) CreateAuditLog(ctx context.Context, input AuditLog) (AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditLog", ctx, input)
+	ret0, _ := ret[0].(AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuditLog indicates an expected call of CreateAuditLog.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateAuditLog(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditLog", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateAuditLog), ctx, input)
+}
+
 // CreateEstate mocks base method.
 func (m *MockRepositoryInterface) CreateEstate(ctx context.Context, input Estate) (Estate, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +114,81 @@ func (mr *MockRepositoryInterfaceMockRecorder) CreateEstate(ctx, input any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateEstate), ctx, input)
 }
 
+// CreateFindings mocks base method.
+func (m *MockRepositoryInterface) CreateFindings(ctx context.Context, input CreateFindingsInput) ([]Finding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFindings", ctx, input)
+	ret0, _ := ret[0].([]Finding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFindings indicates an expected call of CreateFindings.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateFindings(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFindings", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateFindings), ctx, input)
+}
+
+// CreateFlight mocks base method.
+func (m *MockRepositoryInterface) CreateFlight(ctx context.Context, input Flight) (Flight, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFlight", ctx, input)
+	ret0, _ := ret[0].(Flight)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFlight indicates an expected call of CreateFlight.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateFlight(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFlight", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateFlight), ctx, input)
+}
+
+// CreateMission mocks base method.
+func (m *MockRepositoryInterface) CreateMission(ctx context.Context, input Mission) (Mission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMission", ctx, input)
+	ret0, _ := ret[0].(Mission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMission indicates an expected call of CreateMission.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateMission(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMission", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateMission), ctx, input)
+}
+
+// CreateMissionSchedule mocks base method.
+func (m *MockRepositoryInterface) CreateMissionSchedule(ctx context.Context, input MissionSchedule) (MissionSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMissionSchedule", ctx, input)
+	ret0, _ := ret[0].(MissionSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMissionSchedule indicates an expected call of CreateMissionSchedule.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateMissionSchedule(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMissionSchedule", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateMissionSchedule), ctx, input)
+}
+
+// CreatePlanJob mocks base method.
+func (m *MockRepositoryInterface) CreatePlanJob(ctx context.Context, input PlanJob) (PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePlanJob", ctx, input)
+	ret0, _ := ret[0].(PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePlanJob indicates an expected call of CreatePlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) CreatePlanJob(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).CreatePlanJob), ctx, input)
+}
+
 // CreateTree mocks base method.
 func (m *MockRepositoryInterface) CreateTree(ctx context.Context, input Tree) (Tree, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +204,111 @@ func (mr *MockRepositoryInterfaceMockRecorder) CreateTree(ctx, input any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTree", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateTree), ctx, input)
 }
 
+// CreateWebhookSubscription mocks base method.
+func (m *MockRepositoryInterface) CreateWebhookSubscription(ctx context.Context, input WebhookSubscription) (WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookSubscription", ctx, input)
+	ret0, _ := ret[0].(WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhookSubscription indicates an expected call of CreateWebhookSubscription.
+func (mr *MockRepositoryInterfaceMockRecorder) CreateWebhookSubscription(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookSubscription", reflect.TypeOf((*MockRepositoryInterface)(nil).CreateWebhookSubscription), ctx, input)
+}
+
+// DeactivateMissionSchedule mocks base method.
+func (m *MockRepositoryInterface) DeactivateMissionSchedule(ctx context.Context, input DeactivateMissionScheduleInput) (MissionSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeactivateMissionSchedule", ctx, input)
+	ret0, _ := ret[0].(MissionSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeactivateMissionSchedule indicates an expected call of DeactivateMissionSchedule.
+func (mr *MockRepositoryInterfaceMockRecorder) DeactivateMissionSchedule(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeactivateMissionSchedule", reflect.TypeOf((*MockRepositoryInterface)(nil).DeactivateMissionSchedule), ctx, input)
+}
+
+// DeactivateWebhookSubscription mocks base method.
+func (m *MockRepositoryInterface) DeactivateWebhookSubscription(ctx context.Context, input DeactivateWebhookSubscriptionInput) (WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeactivateWebhookSubscription", ctx, input)
+	ret0, _ := ret[0].(WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeactivateWebhookSubscription indicates an expected call of DeactivateWebhookSubscription.
+func (mr *MockRepositoryInterfaceMockRecorder) DeactivateWebhookSubscription(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeactivateWebhookSubscription", reflect.TypeOf((*MockRepositoryInterface)(nil).DeactivateWebhookSubscription), ctx, input)
+}
+
+// DispatchMission mocks base method.
+func (m *MockRepositoryInterface) DispatchMission(ctx context.Context, input DispatchMissionInput) (Mission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DispatchMission", ctx, input)
+	ret0, _ := ret[0].(Mission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DispatchMission indicates an expected call of DispatchMission.
+func (mr *MockRepositoryInterfaceMockRecorder) DispatchMission(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DispatchMission", reflect.TypeOf((*MockRepositoryInterface)(nil).DispatchMission), ctx, input)
+}
+
+// FanOutOutboxEvents mocks base method.
+func (m *MockRepositoryInterface) FanOutOutboxEvents(ctx context.Context, input FanOutOutboxEventsInput) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FanOutOutboxEvents", ctx, input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FanOutOutboxEvents indicates an expected call of FanOutOutboxEvents.
+func (mr *MockRepositoryInterfaceMockRecorder) FanOutOutboxEvents(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FanOutOutboxEvents", reflect.TypeOf((*MockRepositoryInterface)(nil).FanOutOutboxEvents), ctx, input)
+}
+
+// FinishPlanJob mocks base method.
+func (m *MockRepositoryInterface) FinishPlanJob(ctx context.Context, input FinishPlanJobInput) (PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishPlanJob", ctx, input)
+	ret0, _ := ret[0].(PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FinishPlanJob indicates an expected call of FinishPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) FinishPlanJob(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).FinishPlanJob), ctx, input)
+}
+
+// GetApiKeyByHash mocks base method.
+func (m *MockRepositoryInterface) GetApiKeyByHash(ctx context.Context, input GetApiKeyByHashInput) (ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeyByHash", ctx, input)
+	ret0, _ := ret[0].(ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApiKeyByHash indicates an expected call of GetApiKeyByHash.
+func (mr *MockRepositoryInterfaceMockRecorder) GetApiKeyByHash(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeyByHash", reflect.TypeOf((*MockRepositoryInterface)(nil).GetApiKeyByHash), ctx, input)
+}
+
 // GetEstateById mocks base method.
 func (m *MockRepositoryInterface) GetEstateById(ctx context.Context, input GetEstateByIdInput) (Estate, error) {
 	m.ctrl.T.Helper()
@@ -84,6 +324,66 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetEstateById(ctx, input any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEstateById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetEstateById), ctx, input)
 }
 
+// GetFlightById mocks base method.
+func (m *MockRepositoryInterface) GetFlightById(ctx context.Context, input GetFlightByIdInput) (Flight, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFlightById", ctx, input)
+	ret0, _ := ret[0].(Flight)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFlightById indicates an expected call of GetFlightById.
+func (mr *MockRepositoryInterfaceMockRecorder) GetFlightById(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFlightById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetFlightById), ctx, input)
+}
+
+// GetMembership mocks base method.
+func (m *MockRepositoryInterface) GetMembership(ctx context.Context, input GetMembershipInput) (Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembership", ctx, input)
+	ret0, _ := ret[0].(Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembership indicates an expected call of GetMembership.
+func (mr *MockRepositoryInterfaceMockRecorder) GetMembership(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembership", reflect.TypeOf((*MockRepositoryInterface)(nil).GetMembership), ctx, input)
+}
+
+// GetMissionScheduleById mocks base method.
+func (m *MockRepositoryInterface) GetMissionScheduleById(ctx context.Context, input GetMissionScheduleByIdInput) (MissionSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMissionScheduleById", ctx, input)
+	ret0, _ := ret[0].(MissionSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMissionScheduleById indicates an expected call of GetMissionScheduleById.
+func (mr *MockRepositoryInterfaceMockRecorder) GetMissionScheduleById(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMissionScheduleById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetMissionScheduleById), ctx, input)
+}
+
+// GetPlanJobById mocks base method.
+func (m *MockRepositoryInterface) GetPlanJobById(ctx context.Context, input GetPlanJobByIdInput) (PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlanJobById", ctx, input)
+	ret0, _ := ret[0].(PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlanJobById indicates an expected call of GetPlanJobById.
+func (mr *MockRepositoryInterfaceMockRecorder) GetPlanJobById(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlanJobById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetPlanJobById), ctx, input)
+}
+
 // GetTreeByPlot mocks base method.
 func (m *MockRepositoryInterface) GetTreeByPlot(ctx context.Context, input GetTreeByPlot) (Tree, error) {
 	m.ctrl.T.Helper()
@@ -99,6 +399,156 @@ func (mr *MockRepositoryInterfaceMockRecorder) GetTreeByPlot(ctx, input any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTreeByPlot", reflect.TypeOf((*MockRepositoryInterface)(nil).GetTreeByPlot), ctx, input)
 }
 
+// GetWebhookSubscriptionById mocks base method.
+func (m *MockRepositoryInterface) GetWebhookSubscriptionById(ctx context.Context, input GetWebhookSubscriptionByIdInput) (WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookSubscriptionById", ctx, input)
+	ret0, _ := ret[0].(WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookSubscriptionById indicates an expected call of GetWebhookSubscriptionById.
+func (mr *MockRepositoryInterfaceMockRecorder) GetWebhookSubscriptionById(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookSubscriptionById", reflect.TypeOf((*MockRepositoryInterface)(nil).GetWebhookSubscriptionById), ctx, input)
+}
+
+// ImportEstate mocks base method.
+func (m *MockRepositoryInterface) ImportEstate(ctx context.Context, input ImportEstateInput) (Estate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportEstate", ctx, input)
+	ret0, _ := ret[0].(Estate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportEstate indicates an expected call of ImportEstate.
+func (mr *MockRepositoryInterfaceMockRecorder) ImportEstate(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportEstate", reflect.TypeOf((*MockRepositoryInterface)(nil).ImportEstate), ctx, input)
+}
+
+// ListActiveMissionSchedules mocks base method.
+func (m *MockRepositoryInterface) ListActiveMissionSchedules(ctx context.Context, input ListActiveMissionSchedulesInput) ([]MissionSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActiveMissionSchedules", ctx, input)
+	ret0, _ := ret[0].([]MissionSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListActiveMissionSchedules indicates an expected call of ListActiveMissionSchedules.
+func (mr *MockRepositoryInterfaceMockRecorder) ListActiveMissionSchedules(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActiveMissionSchedules", reflect.TypeOf((*MockRepositoryInterface)(nil).ListActiveMissionSchedules), ctx, input)
+}
+
+// ListDueMissions mocks base method.
+func (m *MockRepositoryInterface) ListDueMissions(ctx context.Context, input ListDueMissionsInput) ([]Mission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDueMissions", ctx, input)
+	ret0, _ := ret[0].([]Mission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDueMissions indicates an expected call of ListDueMissions.
+func (mr *MockRepositoryInterfaceMockRecorder) ListDueMissions(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDueMissions", reflect.TypeOf((*MockRepositoryInterface)(nil).ListDueMissions), ctx, input)
+}
+
+// ListEstatesByOrganisationId mocks base method.
+func (m *MockRepositoryInterface) ListEstatesByOrganisationId(ctx context.Context, input ListEstatesByOrganisationIdInput) ([]Estate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEstatesByOrganisationId", ctx, input)
+	ret0, _ := ret[0].([]Estate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEstatesByOrganisationId indicates an expected call of ListEstatesByOrganisationId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListEstatesByOrganisationId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEstatesByOrganisationId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListEstatesByOrganisationId), ctx, input)
+}
+
+// ListFindingsByEstateId mocks base method.
+func (m *MockRepositoryInterface) ListFindingsByEstateId(ctx context.Context, input ListFindingsByEstateIdInput) ([]Finding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFindingsByEstateId", ctx, input)
+	ret0, _ := ret[0].([]Finding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFindingsByEstateId indicates an expected call of ListFindingsByEstateId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListFindingsByEstateId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFindingsByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListFindingsByEstateId), ctx, input)
+}
+
+// ListFlaggedPlotsByEstateId mocks base method.
+func (m *MockRepositoryInterface) ListFlaggedPlotsByEstateId(ctx context.Context, input ListFlaggedPlotsByEstateIdInput) ([]FlaggedPlot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFlaggedPlotsByEstateId", ctx, input)
+	ret0, _ := ret[0].([]FlaggedPlot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFlaggedPlotsByEstateId indicates an expected call of ListFlaggedPlotsByEstateId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListFlaggedPlotsByEstateId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFlaggedPlotsByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListFlaggedPlotsByEstateId), ctx, input)
+}
+
+// ListFlightPointsByFlightId mocks base method.
+func (m *MockRepositoryInterface) ListFlightPointsByFlightId(ctx context.Context, input ListFlightPointsByFlightIdInput) ([]FlightPoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFlightPointsByFlightId", ctx, input)
+	ret0, _ := ret[0].([]FlightPoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFlightPointsByFlightId indicates an expected call of ListFlightPointsByFlightId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListFlightPointsByFlightId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFlightPointsByFlightId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListFlightPointsByFlightId), ctx, input)
+}
+
+// ListMissionSchedulesByEstateId mocks base method.
+func (m *MockRepositoryInterface) ListMissionSchedulesByEstateId(ctx context.Context, input ListMissionSchedulesByEstateIdInput) ([]MissionSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMissionSchedulesByEstateId", ctx, input)
+	ret0, _ := ret[0].([]MissionSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMissionSchedulesByEstateId indicates an expected call of ListMissionSchedulesByEstateId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListMissionSchedulesByEstateId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMissionSchedulesByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListMissionSchedulesByEstateId), ctx, input)
+}
+
+// ListMissionsByEstateId mocks base method.
+func (m *MockRepositoryInterface) ListMissionsByEstateId(ctx context.Context, input ListMissionsByEstateIdInput) ([]Mission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMissionsByEstateId", ctx, input)
+	ret0, _ := ret[0].([]Mission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMissionsByEstateId indicates an expected call of ListMissionsByEstateId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListMissionsByEstateId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMissionsByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListMissionsByEstateId), ctx, input)
+}
+
 // ListTreesByEstateId mocks base method.
 func (m *MockRepositoryInterface) ListTreesByEstateId(ctx context.Context, input ListTreesByEstateIdInput) ([]Tree, error) {
 	m.ctrl.T.Helper()
@@ -113,3 +563,137 @@ func (mr *MockRepositoryInterfaceMockRecorder) ListTreesByEstateId(ctx, input an
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTreesByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListTreesByEstateId), ctx, input)
 }
+
+// ListUnfinishedPlanJobs mocks base method.
+func (m *MockRepositoryInterface) ListUnfinishedPlanJobs(ctx context.Context, input ListUnfinishedPlanJobsInput) ([]PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnfinishedPlanJobs", ctx, input)
+	ret0, _ := ret[0].([]PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnfinishedPlanJobs indicates an expected call of ListUnfinishedPlanJobs.
+func (mr *MockRepositoryInterfaceMockRecorder) ListUnfinishedPlanJobs(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnfinishedPlanJobs", reflect.TypeOf((*MockRepositoryInterface)(nil).ListUnfinishedPlanJobs), ctx, input)
+}
+
+// ListWebhookSubscriptionsByOrganisationId mocks base method.
+func (m *MockRepositoryInterface) ListWebhookSubscriptionsByOrganisationId(ctx context.Context, input ListWebhookSubscriptionsByOrganisationIdInput) ([]WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookSubscriptionsByOrganisationId", ctx, input)
+	ret0, _ := ret[0].([]WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookSubscriptionsByOrganisationId indicates an expected call of ListWebhookSubscriptionsByOrganisationId.
+func (mr *MockRepositoryInterfaceMockRecorder) ListWebhookSubscriptionsByOrganisationId(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookSubscriptionsByOrganisationId", reflect.TypeOf((*MockRepositoryInterface)(nil).ListWebhookSubscriptionsByOrganisationId), ctx, input)
+}
+
+// ReplayWebhookDeliveries mocks base method.
+func (m *MockRepositoryInterface) ReplayWebhookDeliveries(ctx context.Context, input ReplayWebhookDeliveriesInput) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayWebhookDeliveries", ctx, input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayWebhookDeliveries indicates an expected call of ReplayWebhookDeliveries.
+func (mr *MockRepositoryInterfaceMockRecorder) ReplayWebhookDeliveries(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayWebhookDeliveries", reflect.TypeOf((*MockRepositoryInterface)(nil).ReplayWebhookDeliveries), ctx, input)
+}
+
+// ResolveFinding mocks base method.
+func (m *MockRepositoryInterface) ResolveFinding(ctx context.Context, input ResolveFindingInput) (Finding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveFinding", ctx, input)
+	ret0, _ := ret[0].(Finding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveFinding indicates an expected call of ResolveFinding.
+func (mr *MockRepositoryInterfaceMockRecorder) ResolveFinding(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveFinding", reflect.TypeOf((*MockRepositoryInterface)(nil).ResolveFinding), ctx, input)
+}
+
+// StartPlanJob mocks base method.
+func (m *MockRepositoryInterface) StartPlanJob(ctx context.Context, input StartPlanJobInput) (PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartPlanJob", ctx, input)
+	ret0, _ := ret[0].(PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartPlanJob indicates an expected call of StartPlanJob.
+func (mr *MockRepositoryInterfaceMockRecorder) StartPlanJob(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartPlanJob", reflect.TypeOf((*MockRepositoryInterface)(nil).StartPlanJob), ctx, input)
+}
+
+// StreamTreesByEstateId mocks base method.
+func (m *MockRepositoryInterface) StreamTreesByEstateId(ctx context.Context, input StreamTreesByEstateIdInput, visit func(Tree) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamTreesByEstateId", ctx, input, visit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamTreesByEstateId indicates an expected call of StreamTreesByEstateId.
+func (mr *MockRepositoryInterfaceMockRecorder) StreamTreesByEstateId(ctx, input, visit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamTreesByEstateId", reflect.TypeOf((*MockRepositoryInterface)(nil).StreamTreesByEstateId), ctx, input, visit)
+}
+
+// UpdateMissionScheduleMaterialisedUntil mocks base method.
+func (m *MockRepositoryInterface) UpdateMissionScheduleMaterialisedUntil(ctx context.Context, input UpdateMissionScheduleMaterialisedUntilInput) (MissionSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMissionScheduleMaterialisedUntil", ctx, input)
+	ret0, _ := ret[0].(MissionSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateMissionScheduleMaterialisedUntil indicates an expected call of UpdateMissionScheduleMaterialisedUntil.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateMissionScheduleMaterialisedUntil(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMissionScheduleMaterialisedUntil", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateMissionScheduleMaterialisedUntil), ctx, input)
+}
+
+// UpdatePlanJobProgress mocks base method.
+func (m *MockRepositoryInterface) UpdatePlanJobProgress(ctx context.Context, input UpdatePlanJobProgressInput) (PlanJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePlanJobProgress", ctx, input)
+	ret0, _ := ret[0].(PlanJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePlanJobProgress indicates an expected call of UpdatePlanJobProgress.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdatePlanJobProgress(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePlanJobProgress", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdatePlanJobProgress), ctx, input)
+}
+
+// UpdateWebhookDelivery mocks base method.
+func (m *MockRepositoryInterface) UpdateWebhookDelivery(ctx context.Context, input UpdateWebhookDeliveryInput) (WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookDelivery", ctx, input)
+	ret0, _ := ret[0].(WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWebhookDelivery indicates an expected call of UpdateWebhookDelivery.
+func (mr *MockRepositoryInterfaceMockRecorder) UpdateWebhookDelivery(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookDelivery", reflect.TypeOf((*MockRepositoryInterface)(nil).UpdateWebhookDelivery), ctx, input)
+}
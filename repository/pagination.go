@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SawitProRecruitment/UserService/pagination"
+)
+
+// paginate append to a query the condition of the cursor, the order and the limit of a page. The keys are the
+// columns of the order, after their values in the cursor, nil for the first page, and a zero limit reads every
+// row.
+func paginate(query string, args []any, keys []string, desc bool, after []any, limit int) (string, []any) {
+	if after != nil {
+		placeholders := make([]string, len(after))
+		for i, value := range after {
+			args = append(args, value)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		operator := ">"
+		if desc {
+			operator = "<"
+		}
+		query += fmt.Sprintf(" AND (%s) %s (%s)", strings.Join(keys, ", "), operator, strings.Join(placeholders, ", "))
+	}
+
+	order := keys
+	if desc {
+		order = make([]string, len(keys))
+		for i, key := range keys {
+			order[i] = key + " DESC"
+		}
+	}
+	query += " ORDER BY " + strings.Join(order, ", ")
+
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
+
+// timeIdCursor returns the values of a (time, id) cursor, nil for the first page
+func timeIdCursor(after *pagination.Cursor) []any {
+	if after == nil {
+		return nil
+	}
+	return []any{after.Time, after.Id}
+}
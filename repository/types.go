@@ -1,18 +1,39 @@
 // This file contains types that are used in the repository layer.
 package repository
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/pagination"
+)
 
 type GetEstateByIdInput struct {
 	Id string
 }
 
+// ListEstatesByOrganisationIdInput the estates are read after the (created_at, id) of After, up to Limit when
+// it is set
+type ListEstatesByOrganisationIdInput struct {
+	OrganisationId string
+	After          *pagination.Cursor
+	Limit          int
+}
+
 type Estate struct {
-	Id        string    `json:"id" db:"id"`
-	Length    int       `json:"length" db:"length"`
-	Width     int       `json:"width" db:"width"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Id             string    `json:"id" db:"id"`
+	OrganisationId string    `json:"organisation_id" db:"organisation_id"`
+	Length         int       `json:"length" db:"length"`
+	Width          int       `json:"width" db:"width"`
+	Version        int64     `json:"version" db:"version"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ImportEstateInput the ids of the estate and of the trees are kept when they are set, created otherwise
+type ImportEstateInput struct {
+	Estate Estate
+	Trees  []Tree
 }
 
 type GetTreeByPlot struct {
@@ -21,16 +42,451 @@ type GetTreeByPlot struct {
 	EstateId string
 }
 
+// ListTreesByEstateIdInput the trees are ordered by y then x and read after the (y, x) of After, up to Limit when
+// it is set
 type ListTreesByEstateIdInput struct {
 	EstateId string
+	Filter   TreeFilter
+	After    *pagination.Cursor
+	Limit    int
+}
+
+// StreamTreesByEstateIdInput the trees are visited ordered by y then x, after the (y, x) of After when it is set
+type StreamTreesByEstateIdInput struct {
+	EstateId string
+	Filter   TreeFilter
+	After    *pagination.Cursor
 }
 
 type Tree struct {
-	Id        string    `json:"id" db:"id"`
-	EstateId  string    `json:"estate_id" db:"estate_id"`
-	X         int       `json:"x" db:"x"`
-	Y         int       `json:"y" db:"y"`
-	Height    int       `json:"height" db:"height"`
+	Id        string     `json:"id" db:"id"`
+	EstateId  string     `json:"estate_id" db:"estate_id"`
+	X         int        `json:"x" db:"x"`
+	Y         int        `json:"y" db:"y"`
+	Height    int        `json:"height" db:"height"`
+	Species   *string    `json:"species,omitempty" db:"species"`
+	Variety   *string    `json:"variety,omitempty" db:"variety"`
+	PlantedAt *time.Time `json:"planted_at,omitempty" db:"planted_at"`
+	// Health is one of the TreeHealth values, healthy when it is not set on creation
+	Health string `json:"health,omitempty" db:"health"`
+	// Attributes is a JSON object, an empty one when it is not set on creation
+	Attributes json.RawMessage `json:"attributes,omitempty" db:"attributes"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+const (
+	TreeHealthHealthy   = "healthy"
+	TreeHealthDiseased  = "diseased"
+	TreeHealthDead      = "dead"
+	TreeHealthReplanted = "replanted"
+)
+
+// TreeHealths are the health status of a tree, in the order of the stats
+var TreeHealths = []string{TreeHealthHealthy, TreeHealthDiseased, TreeHealthDead, TreeHealthReplanted}
+
+// TreeFilter the trees of a species or of a health status, an empty field matches every tree
+type TreeFilter struct {
+	Species string
+	Health  string
+}
+
+type GetApiKeyByHashInput struct {
+	KeyHash string
+}
+
+type ApiKey struct {
+	Id             string     `json:"id" db:"id"`
+	OrganisationId string     `json:"organisation_id" db:"organisation_id"`
+	Name           string     `json:"name" db:"name"`
+	Role           string     `json:"role" db:"role"`
+	KeyHash        string     `json:"-" db:"key_hash"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt      *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+type GetMembershipInput struct {
+	OrganisationId string
+	Subject        string
+}
+
+type Membership struct {
+	OrganisationId string    `json:"organisation_id" db:"organisation_id"`
+	Subject        string    `json:"subject" db:"subject"`
+	Role           string    `json:"role" db:"role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type AuditLog struct {
+	Id             string    `json:"id" db:"id"`
+	OrganisationId string    `json:"organisation_id" db:"organisation_id"`
+	Subject        string    `json:"subject" db:"subject"`
+	Role           string    `json:"role" db:"role"`
+	Action         string    `json:"action" db:"action"`
+	Resource       string    `json:"resource" db:"resource"`
+	Outcome        string    `json:"outcome" db:"outcome"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+const (
+	PlanJobStatusPending   = "pending"
+	PlanJobStatusRunning   = "running"
+	PlanJobStatusSucceeded = "succeeded"
+	PlanJobStatusFailed    = "failed"
+	PlanJobStatusCancelled = "cancelled"
+)
+
+type PlanJob struct {
+	Id          string     `json:"id" db:"id"`
+	EstateId    string     `json:"estate_id" db:"estate_id"`
+	MaxDistance *int       `json:"max_distance" db:"max_distance"`
+	Status      string     `json:"status" db:"status"`
+	Progress    int        `json:"progress" db:"progress"`
+	Distance    *int       `json:"distance" db:"distance"`
+	RestX       *int       `json:"rest_x" db:"rest_x"`
+	RestY       *int       `json:"rest_y" db:"rest_y"`
+	Error       *string    `json:"error" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	StartedAt   *time.Time `json:"started_at" db:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at" db:"finished_at"`
+}
+
+type GetPlanJobByIdInput struct {
+	Id       string
+	EstateId string
+}
+
+type ListUnfinishedPlanJobsInput struct{}
+
+type StartPlanJobInput struct {
+	Id string
+}
+
+type UpdatePlanJobProgressInput struct {
+	Id       string
+	Progress int
+}
+
+type FinishPlanJobInput struct {
+	Id       string
+	Status   string
+	Distance *int
+	RestX    *int
+	RestY    *int
+	Error    *string
+}
+
+type CancelPlanJobInput struct {
+	Id       string
+	EstateId string
+}
+
+type Flight struct {
+	Id          string    `json:"id" db:"id"`
+	EstateId    string    `json:"estate_id" db:"estate_id"`
+	StartedAt   time.Time `json:"started_at" db:"started_at"`
+	FinishedAt  time.Time `json:"finished_at" db:"finished_at"`
+	PointCount  int       `json:"point_count" db:"point_count"`
+	Distance    float64   `json:"distance" db:"distance"`
+	BatteryUsed *float64  `json:"battery_used" db:"battery_used"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// Points are stored with the flight by CreateFlight
+	Points []FlightPoint `json:"-" db:"-"`
+}
+
+type FlightPoint struct {
+	FlightId   string    `json:"flight_id" db:"flight_id"`
+	Seq        int       `json:"seq" db:"seq"`
+	RecordedAt time.Time `json:"recorded_at" db:"recorded_at"`
+	X          float64   `json:"x" db:"x"`
+	Y          float64   `json:"y" db:"y"`
+	Altitude   float64   `json:"altitude" db:"altitude"`
+	Battery    *float64  `json:"battery" db:"battery"`
+}
+
+type GetFlightByIdInput struct {
+	Id       string
+	EstateId string
+}
+
+type ListFlightPointsByFlightIdInput struct {
+	FlightId string
+}
+
+const (
+	FindingTypePestDamage      = "pest_damage"
+	FindingTypeYellowingFronds = "yellowing_fronds"
+	FindingTypeRipeBunches     = "ripe_bunches"
+	FindingTypeDisease         = "disease"
+	FindingTypeMissingTree     = "missing_tree"
+	FindingTypeOther           = "other"
+)
+
+// FindingTypes are the types of the findings, in the order of the summary
+var FindingTypes = []string{
+	FindingTypePestDamage,
+	FindingTypeYellowingFronds,
+	FindingTypeRipeBunches,
+	FindingTypeDisease,
+	FindingTypeMissingTree,
+	FindingTypeOther,
+}
+
+const (
+	FindingStatusOpen     = "open"
+	FindingStatusResolved = "resolved"
+)
+
+// Finding is an issue detected on a plot by a flight of a drone. TreeId is the tree standing on the plot when
+// the finding was posted, nil for an empty plot.
+type Finding struct {
+	Id         string     `json:"id" db:"id"`
+	EstateId   string     `json:"estate_id" db:"estate_id"`
+	FlightId   string     `json:"flight_id" db:"flight_id"`
+	TreeId     *string    `json:"tree_id" db:"tree_id"`
+	X          int        `json:"x" db:"x"`
+	Y          int        `json:"y" db:"y"`
+	Type       string     `json:"type" db:"type"`
+	Severity   int        `json:"severity" db:"severity"`
+	Confidence float64    `json:"confidence" db:"confidence"`
+	ImageRef   *string    `json:"image_ref" db:"image_ref"`
+	Status     string     `json:"status" db:"status"`
+	DetectedAt time.Time  `json:"detected_at" db:"detected_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+}
+
+// CreateFindingsInput the findings are of one flight, they are created all or none
+type CreateFindingsInput struct {
+	Findings []Finding
+}
+
+// FindingFilter narrow a list of findings, the zero values do not filter. From and To bound the detection time,
+// To excluded.
+type FindingFilter struct {
+	TreeId      string
+	Type        string
+	Status      string
+	MinSeverity int
+	From        *time.Time
+	To          *time.Time
+}
+
+// ListFindingsByEstateIdInput the findings are read the most recent first, after the (detected_at, id) of After,
+// up to Limit when it is set
+type ListFindingsByEstateIdInput struct {
+	EstateId string
+	Filter   FindingFilter
+	After    *pagination.Cursor
+	Limit    int
+}
+
+// ListFlaggedPlotsByEstateIdInput the plots of the trees having a finding of the filter are read once each, ordered
+// by y then x, up to Limit when it is set
+type ListFlaggedPlotsByEstateIdInput struct {
+	EstateId string
+	Filter   FindingFilter
+	Limit    int
+}
+
+// FlaggedPlot is the plot of a tree having a finding
+type FlaggedPlot struct {
+	X int `json:"x" db:"x"`
+	Y int `json:"y" db:"y"`
+}
+
+type CountFindingsByEstateIdInput struct {
+	EstateId string
+	From     *time.Time
+	To       *time.Time
+}
+
+// FindingCount is the number of findings of a type, a severity and a status
+type FindingCount struct {
+	Type           string
+	Severity       int
+	Status         string
+	Count          int
+	LastDetectedAt time.Time
+}
+
+type ResolveFindingInput struct {
+	Id       string
+	EstateId string
+}
+
+const (
+	MissionStatusScheduled  = "scheduled"
+	MissionStatusDispatched = "dispatched"
+	MissionStatusCancelled  = "cancelled"
+)
+
+type MissionSchedule struct {
+	Id          string `json:"id" db:"id"`
+	EstateId    string `json:"estate_id" db:"estate_id"`
+	Name        string `json:"name" db:"name"`
+	Cron        string `json:"cron" db:"cron"`
+	TimeZone    string `json:"time_zone" db:"time_zone"`
+	MaxDistance *int   `json:"max_distance" db:"max_distance"`
+	Active      bool   `json:"active" db:"active"`
+	// MaterialisedUntil is the time of the last mission created from the schedule
+	MaterialisedUntil *time.Time `json:"materialised_until" db:"materialised_until"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type GetMissionScheduleByIdInput struct {
+	Id       string
+	EstateId string
+}
+
+// ListMissionSchedulesByEstateIdInput the schedules are read after the (created_at, id) of After, up to Limit
+// when it is set
+type ListMissionSchedulesByEstateIdInput struct {
+	EstateId string
+	After    *pagination.Cursor
+	Limit    int
+}
+
+type ListActiveMissionSchedulesInput struct{}
+
+type DeactivateMissionScheduleInput struct {
+	Id       string
+	EstateId string
+}
+
+type UpdateMissionScheduleMaterialisedUntilInput struct {
+	Id                string
+	MaterialisedUntil time.Time
+}
+
+type Mission struct {
+	Id          string    `json:"id" db:"id"`
+	ScheduleId  string    `json:"schedule_id" db:"schedule_id"`
+	EstateId    string    `json:"estate_id" db:"estate_id"`
+	ScheduledAt time.Time `json:"scheduled_at" db:"scheduled_at"`
+	Status      string    `json:"status" db:"status"`
+	Distance    *int      `json:"distance" db:"distance"`
+	RestX       *int      `json:"rest_x" db:"rest_x"`
+	RestY       *int      `json:"rest_y" db:"rest_y"`
+	// EstateVersion is the version of the estate the plan was computed for
+	EstateVersion int64      `json:"estate_version" db:"estate_version"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	DispatchedAt  *time.Time `json:"dispatched_at" db:"dispatched_at"`
+}
+
+type ListMissionsByEstateIdInput struct {
+	EstateId string
+	// Upcoming list the missions scheduled from Now in chronological order, else the missions scheduled
+	// before Now, the most recent first
+	Upcoming bool
+	Now      time.Time
+	// After is the (scheduled_at, id) of the last mission of the previous page
+	After *pagination.Cursor
+	Limit int
+}
+
+type ListDueMissionsInput struct {
+	Now   time.Time
+	Limit int
+}
+
+type DispatchMissionInput struct {
+	Id            string
+	Distance      *int
+	RestX         *int
+	RestY         *int
+	EstateVersion int64
+}
+
+const (
+	EventEstateCreated     = "estate.created"
+	EventTreeCreated       = "tree.created"
+	EventPlanJobSucceeded  = "plan_job.succeeded"
+	EventPlanJobFailed     = "plan_job.failed"
+	EventMissionDispatched = "mission.dispatched"
+)
+
+type WebhookSubscription struct {
+	Id             string   `json:"id" db:"id"`
+	OrganisationId string   `json:"organisation_id" db:"organisation_id"`
+	Url            string   `json:"url" db:"url"`
+	EventTypes     []string `json:"event_types" db:"event_types"`
+	// Secret is the key of the HMAC-SHA256 signature of the payloads
+	Secret    string    `json:"-" db:"secret"`
+	Active    bool      `json:"active" db:"active"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
+
+type GetWebhookSubscriptionByIdInput struct {
+	Id             string
+	OrganisationId string
+}
+
+// ListWebhookSubscriptionsByOrganisationIdInput the subscriptions are read after the (created_at, id) of After,
+// up to Limit when it is set
+type ListWebhookSubscriptionsByOrganisationIdInput struct {
+	OrganisationId string
+	After          *pagination.Cursor
+	Limit          int
+}
+
+type DeactivateWebhookSubscriptionInput struct {
+	Id             string
+	OrganisationId string
+}
+
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusDelivered = "delivered"
+	WebhookDeliveryStatusDead      = "dead"
+)
+
+type WebhookDelivery struct {
+	Id             string     `json:"id" db:"id"`
+	SubscriptionId string     `json:"subscription_id" db:"subscription_id"`
+	EventId        string     `json:"event_id" db:"event_id"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code" db:"last_status_code"`
+	LastError      *string    `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
+	// the fields below are joined from the subscription and the event to send
+	Url            string    `json:"-" db:"url"`
+	Secret         string    `json:"-" db:"secret"`
+	EventType      string    `json:"-" db:"event_type"`
+	Payload        []byte    `json:"-" db:"payload"`
+	EventCreatedAt time.Time `json:"-" db:"event_created_at"`
+}
+
+type FanOutOutboxEventsInput struct {
+	Limit int
+}
+
+type ClaimDueWebhookDeliveriesInput struct {
+	Now   time.Time
+	Limit int
+	// Lease is how long the claimed deliveries are hidden from the other workers
+	Lease time.Duration
+}
+
+type UpdateWebhookDeliveryInput struct {
+	Id             string
+	Status         string
+	LastStatusCode *int
+	LastError      *string
+	NextAttemptAt  time.Time
+}
+
+type ReplayWebhookDeliveriesInput struct {
+	SubscriptionId string
+	Now            time.Time
+}
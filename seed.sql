@@ -0,0 +1,7 @@
+-- Development data loaded after database.sql by docker compose, do not use in production.
+-- The api key of the development organisation is `dev-api-key`.
+
+INSERT INTO organisations (id, name) VALUES ('6f1c2f9e-3b7a-4c55-9a51-2d4c1b0e8a01', 'Development');
+
+INSERT INTO api_keys (organisation_id, name, role, key_hash)
+VALUES ('6f1c2f9e-3b7a-4c55-9a51-2d4c1b0e8a01', 'development', 'admin', '6e1e4e1b8f8b36d08901cdb51b97841dfe20f5efd2fd2fd00768971408c46274');
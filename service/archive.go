@@ -0,0 +1,473 @@
+// This file contains the export archive of an estate, to move an estate between environments or back it up.
+//
+// The archive is the estate and its trees, as a JSON document or as a tar.gz of manifest.json, the version and
+// the estate, and trees.json. The version is bumped on every breaking change of the schema of the archive, an
+// archive of another version is rejected before anything else is read from it. New optional fields, as the
+// species and the health of the trees, keep the version: the archives without them are still valid.
+package service
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+)
+
+// ArchiveVersion is the version of the archives exported, the only one imported
+const ArchiveVersion = 1
+
+const (
+	ArchiveFormatJSON  = "json"
+	ArchiveFormatTarGz = "tar.gz"
+)
+
+// The files of a tar.gz archive
+const (
+	archiveManifestFile = "manifest.json"
+	archiveTreesFile    = "trees.json"
+)
+
+const (
+	// maxArchiveDecompressedSize bounds a tar.gz archive once decompressed, the skipped files included, so a small
+	// compressed archive can not make the server inflate gigabytes
+	maxArchiveDecompressedSize = 256 << 20
+	// maxManifestSize bounds manifest.json, the estate without its trees
+	maxManifestSize = 1 << 20
+)
+
+var (
+	ErrUnsupportedArchiveVersion = errors.New("unsupported archive version")
+	ErrInvalidArchive            = errors.New("invalid archive")
+	ErrEstateExist               = errors.New("estate already exist")
+
+	errArchiveTooLarge = fmt.Errorf("larger than %d bytes decompressed", maxArchiveDecompressedSize)
+)
+
+type Archive struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Estate     ArchiveEstate `json:"estate"`
+	Trees      []ArchiveTree `json:"trees"`
+}
+
+type ArchiveEstate struct {
+	Id        string    `json:"id"`
+	Length    int       `json:"length" validate:"required,gte=1,lte=50000"`
+	Width     int       `json:"width" validate:"required,gte=1,lte=50000"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ArchiveTree struct {
+	Id         string          `json:"id"`
+	X          int             `json:"x" validate:"required,gte=1,lte=50000"`
+	Y          int             `json:"y" validate:"required,gte=1,lte=50000"`
+	Height     int             `json:"height" validate:"required,gte=1,lte=30"`
+	Species    *string         `json:"species,omitempty" validate:"omitempty,max=64"`
+	Variety    *string         `json:"variety,omitempty" validate:"omitempty,max=64"`
+	PlantedAt  *time.Time      `json:"planted_at,omitempty"`
+	Health     string          `json:"health,omitempty" validate:"omitempty,oneof=healthy diseased dead replanted"`
+	Attributes json.RawMessage `json:"attributes,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// archiveManifest is the manifest.json of a tar.gz archive, the archive without its trees
+type archiveManifest struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Estate     ArchiveEstate `json:"estate"`
+}
+
+// ArchiveTrees visit the trees of an archive one by one, the visit stops at the first error which is returned
+type ArchiveTrees func(visit func(ArchiveTree) error) error
+
+// VisitTrees is the ArchiveTrees of an archive read in memory
+func (a Archive) VisitTrees(visit func(ArchiveTree) error) error {
+	for _, tree := range a.Trees {
+		if err := visit(tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export returns the archive of an estate of the organisation without its trees, and its trees ordered by y then
+// x. The trees are streamed from the database while the archive is written, so a large estate is never in memory.
+func (s *EstateService) Export(ctx context.Context, organisationId, id string) (Archive, ArchiveTrees, error) {
+	estate, err := getEstate(ctx, s.repository, organisationId, id)
+	if err != nil {
+		return Archive{}, nil, err
+	}
+
+	archive := Archive{
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Estate: ArchiveEstate{
+			Id:        estate.Id,
+			Length:    estate.Length,
+			Width:     estate.Width,
+			CreatedAt: estate.CreatedAt,
+		},
+	}
+	trees := func(visit func(ArchiveTree) error) error {
+		return s.repository.StreamTreesByEstateId(ctx, repository.StreamTreesByEstateIdInput{
+			EstateId: estate.Id,
+		}, func(tree repository.Tree) error {
+			archiveTree := ArchiveTree{
+				Id:        tree.Id,
+				X:         tree.X,
+				Y:         tree.Y,
+				Height:    tree.Height,
+				Species:   tree.Species,
+				Variety:   tree.Variety,
+				PlantedAt: tree.PlantedAt,
+				Health:    tree.Health,
+				CreatedAt: tree.CreatedAt,
+			}
+			// an empty object is the default of the attributes, it is left out
+			if string(tree.Attributes) != "{}" {
+				archiveTree.Attributes = tree.Attributes
+			}
+			return visit(archiveTree)
+		})
+	}
+	return archive, trees, nil
+}
+
+// Import create the estate of an archive and its trees in the organisation, with the ids of the archive when
+// preserveIds is set or new ones otherwise. Nothing is created when the archive is rejected. The trees are
+// notified as the trees created one by one: a tree.created webhook event each, counted in the metrics, but the
+// stream of the estate gets a single stats event instead of an event per tree.
+func (s *EstateService) Import(ctx context.Context, organisationId string, archive Archive, preserveIds bool) (repository.Estate, error) {
+	if archive.Version != ArchiveVersion {
+		return repository.Estate{}, fmt.Errorf("%w %d, expected %d", ErrUnsupportedArchiveVersion, archive.Version, ArchiveVersion)
+	}
+	if err := validateRequest(archive.Estate); err != nil {
+		return repository.Estate{}, err
+	}
+
+	input := repository.ImportEstateInput{
+		Estate: repository.Estate{
+			OrganisationId: organisationId,
+			Length:         archive.Estate.Length,
+			Width:          archive.Estate.Width,
+		},
+		Trees: make([]repository.Tree, 0, len(archive.Trees)),
+	}
+	plots := make(map[[2]int]bool, len(archive.Trees))
+	ids := make(map[string]bool, len(archive.Trees))
+	for i, tree := range archive.Trees {
+		if err := validateRequest(tree); err != nil {
+			return repository.Estate{}, fmt.Errorf("tree %d: %w", i, err)
+		}
+		if err := validateTreeAttributes(tree.Attributes); err != nil {
+			return repository.Estate{}, fmt.Errorf("tree %d: %w", i, err)
+		}
+		if tree.X > archive.Estate.Length || tree.Y > archive.Estate.Width {
+			return repository.Estate{}, fmt.Errorf("tree %d: %w", i, ErrOutOfBound)
+		}
+		plot := [2]int{tree.X, tree.Y}
+		if plots[plot] {
+			return repository.Estate{}, fmt.Errorf("tree %d: %w", i, ErrPlotExist)
+		}
+		plots[plot] = true
+		// the ids of the trees are all exported or none, an archive mixing both was not exported
+		if (tree.Id == "") != (archive.Trees[0].Id == "") {
+			return repository.Estate{}, fmt.Errorf("%w: tree %d has an id not given for every tree", ErrInvalidArchive, i)
+		}
+
+		importTree := repository.Tree{
+			X:          tree.X,
+			Y:          tree.Y,
+			Height:     tree.Height,
+			Species:    tree.Species,
+			Variety:    tree.Variety,
+			PlantedAt:  tree.PlantedAt,
+			Health:     tree.Health,
+			Attributes: tree.Attributes,
+		}
+		if preserveIds {
+			if !validId(tree.Id) || ids[tree.Id] {
+				return repository.Estate{}, fmt.Errorf("%w: tree %d has an invalid or duplicated id", ErrInvalidArchive, i)
+			}
+			ids[tree.Id] = true
+			importTree.Id = tree.Id
+		}
+		input.Trees = append(input.Trees, importTree)
+	}
+
+	if preserveIds {
+		if !validId(archive.Estate.Id) {
+			return repository.Estate{}, fmt.Errorf("%w: the estate has an invalid id", ErrInvalidArchive)
+		}
+		// an estate of another organisation is also an estate already existing, its id can not be reused
+		_, err := s.repository.GetEstateById(ctx, repository.GetEstateByIdInput{Id: archive.Estate.Id})
+		if err == nil {
+			return repository.Estate{}, ErrEstateExist
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return repository.Estate{}, err
+		}
+		input.Estate.Id = archive.Estate.Id
+	}
+
+	estate, err := s.repository.ImportEstate(ctx, input)
+	if err != nil {
+		return estate, err
+	}
+	s.metrics.EstateCreated()
+	s.metrics.TreesCreated(len(input.Trees))
+	s.events.stats(ctx, estate.Id)
+	return estate, nil
+}
+
+// validId returns whether a preserved id is an id of the database
+func validId(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// WriteArchive encode the archive in the format, with the trees visited instead of the trees of the archive
+func WriteArchive(w io.Writer, archive Archive, trees ArchiveTrees, format string) error {
+	manifest, err := json.Marshal(archiveManifest{
+		Version:    archive.Version,
+		ExportedAt: archive.ExportedAt,
+		Estate:     archive.Estate,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ArchiveFormatJSON:
+		// the manifest is the archive without its trees, the trees are appended to it
+		bw := bufio.NewWriter(w)
+		bw.Write(manifest[:len(manifest)-1])
+		bw.WriteString(`,"trees":`)
+		if err := writeTrees(bw, trees); err != nil {
+			return err
+		}
+		bw.WriteString("}\n")
+		return bw.Flush()
+	case ArchiveFormatTarGz:
+		// the size of a file is in the tar header before its content, the trees are encoded in a temporary file
+		// to know it
+		treesFile, err := os.CreateTemp("", "estate-trees-*.json")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(treesFile.Name())
+		defer treesFile.Close()
+		bw := bufio.NewWriter(treesFile)
+		if err := writeTrees(bw, trees); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		treesSize, err := treesFile.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if _, err := treesFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		for _, file := range []struct {
+			name string
+			size int64
+			data io.Reader
+		}{
+			{name: archiveManifestFile, size: int64(len(manifest)), data: bytes.NewReader(manifest)},
+			{name: archiveTreesFile, size: treesSize, data: treesFile},
+		} {
+			if err := tw.WriteHeader(&tar.Header{
+				Name:    file.name,
+				Mode:    0o644,
+				Size:    file.size,
+				ModTime: archive.ExportedAt,
+			}); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, file.data); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	return fmt.Errorf("unknown archive format %q", format)
+}
+
+// writeTrees encode the trees as a JSON array, one tree at a time
+func writeTrees(w *bufio.Writer, trees ArchiveTrees) error {
+	w.WriteByte('[')
+	first := true
+	err := trees(func(tree ArchiveTree) error {
+		data, err := json.Marshal(tree)
+		if err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return w.WriteByte(']')
+}
+
+// ReadArchive decode an archive of the format, the version is checked before the estate and the trees are
+// decoded as they may have another schema in another version. The trees.json of a tar.gz archive is decoded as
+// it is decompressed, after manifest.json, instead of being buffered.
+func ReadArchive(r io.Reader, format string) (Archive, error) {
+	switch format {
+	case ArchiveFormatJSON:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return Archive{}, err
+		}
+		archive, err := decodeManifest(data)
+		if err != nil {
+			return Archive{}, err
+		}
+		if err := json.Unmarshal(data, &archive); err != nil {
+			return Archive{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		return archive, nil
+	case ArchiveFormatTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return Archive{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		tr := tar.NewReader(&decompressedLimitReader{r: gz, n: maxArchiveDecompressedSize})
+		var archive Archive
+		read := make(map[string]bool, 2)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return Archive{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+			}
+			if header.Name != archiveManifestFile && header.Name != archiveTreesFile {
+				// the other files are skipped without being buffered, tar.Reader.Next discards them
+				continue
+			}
+			if read[header.Name] {
+				return Archive{}, fmt.Errorf("%w: duplicated %s", ErrInvalidArchive, header.Name)
+			}
+			read[header.Name] = true
+
+			switch header.Name {
+			case archiveManifestFile:
+				data, err := io.ReadAll(io.LimitReader(tr, maxManifestSize+1))
+				if err != nil {
+					return Archive{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+				}
+				if len(data) > maxManifestSize {
+					return Archive{}, fmt.Errorf("%w: %s is too large", ErrInvalidArchive, header.Name)
+				}
+				if archive, err = decodeManifest(data); err != nil {
+					return Archive{}, err
+				}
+			case archiveTreesFile:
+				// the schema of the trees is known once the version is
+				if !read[archiveManifestFile] {
+					return Archive{}, fmt.Errorf("%w: %s must come before %s", ErrInvalidArchive, archiveManifestFile, archiveTreesFile)
+				}
+				if archive.Trees, err = decodeTrees(tr); err != nil {
+					return Archive{}, err
+				}
+			}
+		}
+		if !read[archiveManifestFile] {
+			return Archive{}, fmt.Errorf("%w: missing %s", ErrInvalidArchive, archiveManifestFile)
+		}
+		if !read[archiveTreesFile] {
+			return Archive{}, fmt.Errorf("%w: missing %s", ErrInvalidArchive, archiveTreesFile)
+		}
+		return archive, nil
+	}
+	return Archive{}, fmt.Errorf("unknown archive format %q", format)
+}
+
+// decodeManifest returns the archive of a manifest, or of a JSON archive without its trees, once its version is
+// checked
+func decodeManifest(data []byte) (Archive, error) {
+	var version struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return Archive{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	if version.Version != ArchiveVersion {
+		return Archive{}, fmt.Errorf("%w %d, expected %d", ErrUnsupportedArchiveVersion, version.Version, ArchiveVersion)
+	}
+	var manifest archiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Archive{}, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	return Archive{
+		Version:    manifest.Version,
+		ExportedAt: manifest.ExportedAt,
+		Estate:     manifest.Estate,
+	}, nil
+}
+
+// decodeTrees decode a JSON array of trees one tree at a time
+func decodeTrees(r io.Reader) ([]ArchiveTree, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("%w: %s is not an array", ErrInvalidArchive, archiveTreesFile)
+	}
+	trees := []ArchiveTree{}
+	for decoder.More() {
+		var tree ArchiveTree
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		trees = append(trees, tree)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	return trees, nil
+}
+
+// decompressedLimitReader fails once more than n bytes are read
+type decompressedLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *decompressedLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errArchiveTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func testArchive() Archive {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Archive{
+		Version:    ArchiveVersion,
+		ExportedAt: createdAt,
+		Estate:     ArchiveEstate{Id: uuid.New().String(), Length: 10, Width: 5, CreatedAt: createdAt},
+		Trees: []ArchiveTree{
+			{Id: uuid.New().String(), X: 1, Y: 1, Height: 10, CreatedAt: createdAt},
+			{Id: uuid.New().String(), X: 10, Y: 5, Height: 30, CreatedAt: createdAt},
+		},
+	}
+}
+
+func TestArchive_WriteRead(t *testing.T) {
+	for _, format := range []string{ArchiveFormatJSON, ArchiveFormatTarGz} {
+		t.Run(format, func(t *testing.T) {
+			archive := testArchive()
+			var buf bytes.Buffer
+			require.NoError(t, WriteArchive(&buf, archive, archive.VisitTrees, format))
+
+			read, err := ReadArchive(&buf, format)
+			assert.NoError(t, err)
+			assert.Equal(t, archive, read)
+		})
+	}
+}
+
+// tarGz returns a tar.gz of the files
+func tarGz(t *testing.T, files map[string]string) *bytes.Buffer {
+	entries := make([][2]string, 0, len(files))
+	for name, data := range files {
+		entries = append(entries, [2]string{name, data})
+	}
+	return tarGzEntries(t, entries...)
+}
+
+// tarGzEntries returns a tar.gz of the name and data entries in order, a name may be repeated
+func tarGzEntries(t *testing.T, entries ...[2]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: entry[0], Mode: 0o644, Size: int64(len(entry[1]))}))
+		_, err := tw.Write([]byte(entry[1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func TestReadArchive_OtherFiles(t *testing.T) {
+	archive := testArchive()
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, archive, archive.VisitTrees, ArchiveFormatJSON))
+	manifest := strings.Replace(buf.String(), `"trees":`, `"ignored":`, 1)
+	trees, err := json.Marshal(archive.Trees)
+	require.NoError(t, err)
+
+	// the unknown files are skipped, whatever their size
+	read, err := ReadArchive(tarGzEntries(t,
+		[2]string{"README.md", strings.Repeat("x", 1<<20)},
+		[2]string{archiveManifestFile, manifest},
+		[2]string{archiveTreesFile, string(trees)},
+	), ArchiveFormatTarGz)
+	require.NoError(t, err)
+	assert.Equal(t, archive, read)
+}
+
+func TestReadArchive_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		body    func(t *testing.T) *bytes.Buffer
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:   "newer json version",
+			format: ArchiveFormatJSON,
+			body: func(t *testing.T) *bytes.Buffer {
+				return bytes.NewBufferString(`{"version":2,"estate":"a new schema"}`)
+			},
+			wantErr: ErrUnsupportedArchiveVersion,
+			wantMsg: "unsupported archive version 2, expected 1",
+		},
+		{
+			name:    "invalid json",
+			format:  ArchiveFormatJSON,
+			body:    func(t *testing.T) *bytes.Buffer { return bytes.NewBufferString(`{"version":`) },
+			wantErr: ErrInvalidArchive,
+		},
+		{
+			name:   "newer tar.gz version",
+			format: ArchiveFormatTarGz,
+			body: func(t *testing.T) *bytes.Buffer {
+				return tarGzEntries(t, [2]string{archiveManifestFile, `{"version":2}`}, [2]string{archiveTreesFile, `{}`})
+			},
+			wantErr: ErrUnsupportedArchiveVersion,
+		},
+		{
+			name:   "missing trees",
+			format: ArchiveFormatTarGz,
+			body: func(t *testing.T) *bytes.Buffer {
+				return tarGz(t, map[string]string{archiveManifestFile: `{"version":1}`})
+			},
+			wantErr: ErrInvalidArchive,
+			wantMsg: "invalid archive: missing trees.json",
+		},
+		{
+			name:   "duplicated trees",
+			format: ArchiveFormatTarGz,
+			body: func(t *testing.T) *bytes.Buffer {
+				return tarGzEntries(t,
+					[2]string{archiveManifestFile, `{"version":1}`},
+					[2]string{archiveTreesFile, `[]`},
+					[2]string{archiveTreesFile, `[{"x":1,"y":1,"height":1}]`},
+				)
+			},
+			wantErr: ErrInvalidArchive,
+			wantMsg: "invalid archive: duplicated trees.json",
+		},
+		{
+			name:   "trees before the manifest",
+			format: ArchiveFormatTarGz,
+			body: func(t *testing.T) *bytes.Buffer {
+				return tarGzEntries(t, [2]string{archiveTreesFile, `[]`}, [2]string{archiveManifestFile, `{"version":1}`})
+			},
+			wantErr: ErrInvalidArchive,
+			wantMsg: "invalid archive: manifest.json must come before trees.json",
+		},
+		{
+			name:   "trees not an array",
+			format: ArchiveFormatTarGz,
+			body: func(t *testing.T) *bytes.Buffer {
+				return tarGzEntries(t, [2]string{archiveManifestFile, `{"version":1}`}, [2]string{archiveTreesFile, `{}`})
+			},
+			wantErr: ErrInvalidArchive,
+			wantMsg: "invalid archive: trees.json is not an array",
+		},
+		{
+			name:   "gzip bomb",
+			format: ArchiveFormatTarGz,
+			body: func(t *testing.T) *bytes.Buffer {
+				// a few hundred kilobytes compressed, more than the limit decompressed
+				return tarGzEntries(t,
+					[2]string{archiveManifestFile, `{"version":1}`},
+					[2]string{"padding", strings.Repeat("0", maxArchiveDecompressedSize)},
+					[2]string{archiveTreesFile, `[]`},
+				)
+			},
+			wantErr: ErrInvalidArchive,
+			wantMsg: fmt.Sprintf("invalid archive: larger than %d bytes decompressed", maxArchiveDecompressedSize),
+		},
+		{
+			name:    "not gzip",
+			format:  ArchiveFormatTarGz,
+			body:    func(t *testing.T) *bytes.Buffer { return bytes.NewBufferString(`{"version":1}`) },
+			wantErr: ErrInvalidArchive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadArchive(tt.body(t), tt.format)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantMsg != "" {
+				assert.EqualError(t, err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestEstateService_Export(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).
+		Return(repository.Estate{Id: id, OrganisationId: orgId, Length: 10, Width: 5}, nil)
+	// the trees are streamed ordered by y then x
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: id}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) error {
+			for _, tree := range []repository.Tree{
+				{Id: "t-1", X: 2, Y: 1, Height: 7, Attributes: json.RawMessage(`{}`)},
+				{Id: "t-2", X: 4, Y: 1, Height: 6, Attributes: json.RawMessage(`{"row":"north"}`)},
+				{Id: "t-3", X: 1, Y: 2, Height: 5},
+			} {
+				if err := visit(tree); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	s := NewEstateService(NewEstateServiceOptions{Repository: mockRepository})
+
+	archive, trees, err := s.Export(context.Background(), orgId, id)
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveVersion, archive.Version)
+	assert.Equal(t, ArchiveEstate{Id: id, Length: 10, Width: 5}, archive.Estate)
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, archive, trees, ArchiveFormatJSON))
+	read, err := ReadArchive(&buf, ArchiveFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []ArchiveTree{
+		{Id: "t-1", X: 2, Y: 1, Height: 7},
+		{Id: "t-2", X: 4, Y: 1, Height: 6, Attributes: json.RawMessage(`{"row":"north"}`)},
+		{Id: "t-3", X: 1, Y: 2, Height: 5},
+	}, read.Trees)
+}
+
+func TestEstateService_Import(t *testing.T) {
+	orgId := uuid.New().String()
+	newId := uuid.New().String()
+	archive := testArchive()
+
+	tests := []struct {
+		name        string
+		archive     func() Archive
+		preserveIds bool
+		setupMocks  func(mockRepository *repository.MockRepositoryInterface)
+		wantErr     error
+		wantMsg     string
+	}{
+		{
+			name: "unsupported version",
+			archive: func() Archive {
+				a := testArchive()
+				a.Version = 0
+				return a
+			},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    ErrUnsupportedArchiveVersion,
+		},
+		{
+			name: "tree out of bound",
+			archive: func() Archive {
+				a := testArchive()
+				a.Trees[1].X = 11
+				return a
+			},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    ErrOutOfBound,
+			wantMsg:    "tree 1: index out of bound",
+		},
+		{
+			name: "duplicated plot",
+			archive: func() Archive {
+				a := testArchive()
+				a.Trees[1].X, a.Trees[1].Y = 1, 1
+				return a
+			},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    ErrPlotExist,
+		},
+		{
+			name: "invalid tree id",
+			archive: func() Archive {
+				a := testArchive()
+				a.Trees[0].Id = "t-1"
+				return a
+			},
+			preserveIds: true,
+			setupMocks:  func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:     ErrInvalidArchive,
+		},
+		{
+			name: "tree ids mixed",
+			archive: func() Archive {
+				a := testArchive()
+				a.Trees[1].Id = ""
+				return a
+			},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    ErrInvalidArchive,
+		},
+		{
+			name: "tree ids mixed, preserve ids",
+			archive: func() Archive {
+				a := testArchive()
+				a.Trees[0].Id = ""
+				return a
+			},
+			preserveIds: true,
+			setupMocks:  func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:     ErrInvalidArchive,
+		},
+		{
+			name:        "estate already exist",
+			archive:     testArchive,
+			preserveIds: true,
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, nil)
+			},
+			wantErr: ErrEstateExist,
+		},
+		{
+			name:        "preserve ids",
+			archive:     func() Archive { return archive },
+			preserveIds: true,
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: archive.Estate.Id}).
+					Return(repository.Estate{}, sql.ErrNoRows)
+				mockRepository.EXPECT().ImportEstate(gomock.Any(), repository.ImportEstateInput{
+					Estate: repository.Estate{Id: archive.Estate.Id, OrganisationId: orgId, Length: 10, Width: 5},
+					Trees: []repository.Tree{
+						{Id: archive.Trees[0].Id, X: 1, Y: 1, Height: 10},
+						{Id: archive.Trees[1].Id, X: 10, Y: 5, Height: 30},
+					},
+				}).Return(repository.Estate{Id: archive.Estate.Id}, nil)
+			},
+		},
+		{
+			name:    "new ids",
+			archive: func() Archive { return archive },
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().ImportEstate(gomock.Any(), repository.ImportEstateInput{
+					Estate: repository.Estate{OrganisationId: orgId, Length: 10, Width: 5},
+					Trees: []repository.Tree{
+						{X: 1, Y: 1, Height: 10},
+						{X: 10, Y: 5, Height: 30},
+					},
+				}).Return(repository.Estate{Id: newId}, nil)
+			},
+		},
+		{
+			name:    "database error",
+			archive: func() Archive { return archive },
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().ImportEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("connection refused"))
+			},
+			wantMsg: "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewEstateService(NewEstateServiceOptions{Repository: mockRepository})
+
+			estate, err := s.Import(context.Background(), orgId, tt.archive(), tt.preserveIds)
+			if tt.wantErr != nil || tt.wantMsg != "" {
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+				if tt.wantMsg != "" {
+					assert.True(t, strings.HasPrefix(err.Error(), tt.wantMsg), err.Error())
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, estate.Id)
+		})
+	}
+}
+
+func TestEstateService_Import_Notify(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orgId := uuid.New().String()
+	archive := testArchive()
+	hub := events.NewHub(events.NewHubOptions{})
+	defer hub.Close()
+	sub, err := hub.Subscribe(archive.Estate.Id, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, sql.ErrNoRows)
+	mockRepository.EXPECT().ImportEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Id: archive.Estate.Id}, nil)
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{EstateId: archive.Estate.Id}, gomock.Any()).
+		DoAndReturn(streamTrees([]repository.Tree{{Height: 10}, {Height: 30}}))
+	s := NewEstateService(NewEstateServiceOptions{Repository: mockRepository, Events: hub})
+
+	_, err = s.Import(context.Background(), orgId, archive, true)
+	require.NoError(t, err)
+
+	// a single stats event instead of an event per tree
+	event := <-sub.C
+	assert.Equal(t, events.TypeStats, event.Type)
+	assert.JSONEq(t, `{"count":2,"max":30,"median":20,"min":10}`, string(event.Data))
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/SawitProRecruitment/UserService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxPlanTargets bounds the targets of a targeted plan, ordering them is quadratic
+const maxPlanTargets = 1000
+
+// TargetedPlanRequest are the targets of a targeted plan, either the plots or the trees having an open finding
+// of MinSeverity or more. The launch plot default to (1, 1).
+type TargetedPlanRequest struct {
+	Launch      *planner.Plot
+	MaxDistance *int
+	Plots       []planner.Plot `validate:"omitempty,max=1000"`
+	MinSeverity *int           `validate:"omitempty,gte=1,lte=5"`
+}
+
+type DronePlanService struct {
+	repository repository.RepositoryInterface
+	metrics    *metrics.Metrics
+	cache      *cache.Cache
+	logger     *slog.Logger
+}
+
+type NewDronePlanServiceOptions struct {
+	Repository repository.RepositoryInterface
+	Metrics    *metrics.Metrics
+	// Cache of the plans, nil to compute them on every call
+	Cache  *cache.Cache
+	Logger *slog.Logger
+}
+
+func NewDronePlanService(opts NewDronePlanServiceOptions) *DronePlanService {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &DronePlanService{
+		repository: opts.Repository,
+		metrics:    opts.Metrics,
+		cache:      opts.Cache,
+		logger:     logger,
+	}
+}
+
+// ValidateMaxDistance returns ErrInvalidMaxDistance unless the max distance is unset or positive, it lets
+// the callers reject a request before loading the estate
+func ValidateMaxDistance(maxDistance *int) error {
+	if maxDistance != nil && *maxDistance < 1 {
+		return ErrInvalidMaxDistance
+	}
+	return nil
+}
+
+// Plan returns the drone plan of the estate, cached until a tree of the estate changes
+func (s *DronePlanService) Plan(ctx context.Context, estate repository.Estate, maxDistance *int) (planner.Plan, error) {
+	return s.PlanWithProgress(ctx, estate, maxDistance, nil)
+}
+
+// PlanWithProgress is Plan reporting the loading of the trees, row by row: progress is called with the number of
+// rows loaded each time a row is done, and with the width of the estate once every tree is loaded. An error of
+// progress stops the computation. A cached plan is returned without progress.
+func (s *DronePlanService) PlanWithProgress(ctx context.Context, estate repository.Estate, maxDistance *int, progress func(rows int) error) (planner.Plan, error) {
+	if err := ValidateMaxDistance(maxDistance); err != nil {
+		return planner.Plan{}, err
+	}
+	var cacheKey string
+	if maxDistance != nil {
+		cacheKey = cache.Key(cacheKindDronePlan, estate.Id, estate.Version, *maxDistance)
+	} else {
+		cacheKey = cache.Key(cacheKindDronePlan, estate.Id, estate.Version)
+	}
+	var plan planner.Plan
+	if cacheGet(ctx, s.cache, s.logger, cacheKey, &plan) {
+		return plan, nil
+	}
+
+	p, trees, err := s.estatePlanner(ctx, estate, progress)
+	if err != nil {
+		return plan, err
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "DronePlan.Compute")
+	span.SetAttributes(
+		attribute.Int("estate.width", estate.Width),
+		attribute.Int("estate.length", estate.Length),
+		attribute.Int("estate.trees", trees),
+	)
+	plan = p.Plan(maxDistance)
+	span.SetAttributes(attribute.Int("drone_plan.distance", plan.Distance))
+	span.End()
+	s.metrics.DronePlanComputed(plan.Distance)
+
+	cacheSet(ctx, s.cache, s.logger, cacheKey, plan)
+	return plan, nil
+}
+
+// Walk call visit for every plot of the route of the estate until it returns false, then returns the drone
+// plan. The route of a large estate is long, the walk is not cached.
+func (s *DronePlanService) Walk(ctx context.Context, estate repository.Estate, maxDistance *int, visit func(planner.Waypoint) bool) (planner.Plan, error) {
+	if err := ValidateMaxDistance(maxDistance); err != nil {
+		return planner.Plan{}, err
+	}
+	p, _, err := s.estatePlanner(ctx, estate, nil)
+	if err != nil {
+		return planner.Plan{}, err
+	}
+
+	p.Walk(maxDistance, visit)
+	plan := p.Plan(maxDistance)
+	s.metrics.DronePlanComputed(plan.Distance)
+	return plan, nil
+}
+
+// Targeted returns the route of the estate from the launch plot visiting the targets of the request and back. The
+// open findings change without the estate, the plan is cached by its targets once they are read.
+func (s *DronePlanService) Targeted(ctx context.Context, estate repository.Estate, request TargetedPlanRequest) (planner.TargetedPlan, error) {
+	if err := validateRequest(request); err != nil {
+		return planner.TargetedPlan{}, err
+	}
+	if err := ValidateMaxDistance(request.MaxDistance); err != nil {
+		return planner.TargetedPlan{}, err
+	}
+	if (len(request.Plots) == 0) == (request.MinSeverity == nil) {
+		return planner.TargetedPlan{}, ErrInvalidTargets
+	}
+	launch := planner.Plot{X: 1, Y: 1}
+	if request.Launch != nil {
+		launch = *request.Launch
+	}
+	inside := func(plot planner.Plot) bool {
+		return plot.X >= 1 && plot.X <= estate.Length && plot.Y >= 1 && plot.Y <= estate.Width
+	}
+	if !inside(launch) {
+		return planner.TargetedPlan{}, fmt.Errorf("launch: %w", ErrOutOfBound)
+	}
+
+	targets := request.Plots
+	for i, plot := range targets {
+		if !inside(plot) {
+			return planner.TargetedPlan{}, fmt.Errorf("target %d: %w", i, ErrOutOfBound)
+		}
+	}
+	if request.MinSeverity != nil {
+		var err error
+		if targets, err = s.flaggedTrees(ctx, estate, *request.MinSeverity); err != nil {
+			return planner.TargetedPlan{}, err
+		}
+	}
+
+	maxDistance := 0
+	if request.MaxDistance != nil {
+		maxDistance = *request.MaxDistance
+	}
+	cacheKey := cache.Key(cacheKindTargetedPlan, estate.Id, estate.Version, launch.X, launch.Y, maxDistance, targetsKey(targets))
+	var plan planner.TargetedPlan
+	if cacheGet(ctx, s.cache, s.logger, cacheKey, &plan) {
+		return plan, nil
+	}
+
+	p, _, err := s.estatePlanner(ctx, estate, nil)
+	if err != nil {
+		return planner.TargetedPlan{}, err
+	}
+	plan = p.Targeted(launch, targets, request.MaxDistance)
+	s.metrics.DronePlanComputed(plan.Distance)
+
+	cacheSet(ctx, s.cache, s.logger, cacheKey, plan)
+	return plan, nil
+}
+
+// flaggedTrees returns the plots of the trees of the estate having an open finding of minSeverity or more,
+// ErrTooManyTargets when there are more than a targeted plan visits
+func (s *DronePlanService) flaggedTrees(ctx context.Context, estate repository.Estate, minSeverity int) ([]planner.Plot, error) {
+	// one more plot than the targets tells there are too many, without reading them all
+	flagged, err := s.repository.ListFlaggedPlotsByEstateId(ctx, repository.ListFlaggedPlotsByEstateIdInput{
+		EstateId: estate.Id,
+		Filter: repository.FindingFilter{
+			Status:      repository.FindingStatusOpen,
+			MinSeverity: minSeverity,
+		},
+		Limit: maxPlanTargets + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(flagged) > maxPlanTargets {
+		return nil, ErrTooManyTargets
+	}
+	plots := make([]planner.Plot, 0, len(flagged))
+	for _, plot := range flagged {
+		plots = append(plots, planner.Plot{X: plot.X, Y: plot.Y})
+	}
+	return plots, nil
+}
+
+// targetsKey returns a short key of the targets of a targeted plan, for its cache key
+func targetsKey(targets []planner.Plot) string {
+	h := sha256.New()
+	for _, plot := range targets {
+		fmt.Fprintf(h, "%d,%d;", plot.X, plot.Y)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// estatePlanner returns the planner of the route over the trees plot and the number of trees, the trees are
+// streamed into the planner so only its grid is in memory
+func (s *DronePlanService) estatePlanner(ctx context.Context, estate repository.Estate, progress func(rows int) error) (*planner.Planner, int, error) {
+	p := planner.New(estate.Width, estate.Length)
+	trees := 0
+	rows := 0
+	err := s.repository.StreamTreesByEstateId(ctx, repository.StreamTreesByEstateIdInput{
+		EstateId: estate.Id,
+	}, func(tree repository.Tree) error {
+		// the trees are ordered by row
+		if progress != nil && tree.Y-1 > rows {
+			rows = tree.Y - 1
+			if err := progress(rows); err != nil {
+				return err
+			}
+		}
+		p.AddTree(tree.X, tree.Y, tree.Height)
+		trees++
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if progress != nil {
+		if err := progress(estate.Width); err != nil {
+			return nil, 0, err
+		}
+	}
+	return p, trees, nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/planner"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDronePlanService_Plan(t *testing.T) {
+	id := uuid.New().String()
+	estate := repository.Estate{Id: id, Width: 2, Length: 3}
+	trees := []repository.Tree{
+		{X: 2, Y: 1, Height: 5},
+		{X: 3, Y: 2, Height: 2},
+	}
+	zero, short := 0, 20
+	tests := []struct {
+		name        string
+		maxDistance *int
+		setupMocks  func(mockRepository *repository.MockRepositoryInterface)
+		want        planner.Plan
+		wantErr     string
+	}{
+		{
+			name:        "invalid max distance",
+			maxDistance: &zero,
+			setupMocks:  func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:     "invalid max distance",
+		},
+		{
+			name: "database error",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+			},
+			wantErr: "connection refused",
+		},
+		{
+			name: "full route",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+					EstateId: id,
+				}, gomock.Any()).DoAndReturn(streamTrees(trees))
+			},
+			want: planner.Plan{Distance: 64, Rest: planner.Plot{X: 1, Y: 2}},
+		},
+		{
+			name:        "max distance",
+			maxDistance: &short,
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees(trees))
+			},
+			want: planner.Plan{Distance: 20, Rest: planner.Plot{X: 2, Y: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewDronePlanService(NewDronePlanServiceOptions{Repository: mockRepository})
+
+			plan, err := s.Plan(context.Background(), estate, tt.maxDistance)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+}
+
+func TestDronePlanService_Walk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewDronePlanService(NewDronePlanServiceOptions{Repository: mockRepository})
+	estate := repository.Estate{Id: uuid.New().String(), Width: 2, Length: 3}
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+		{X: 2, Y: 1, Height: 5},
+	}))
+
+	var plots []planner.Plot
+	plan, err := s.Walk(context.Background(), estate, nil, func(w planner.Waypoint) bool {
+		plots = append(plots, w.Plot)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Len(t, plots, 6)
+	assert.Equal(t, plots[len(plots)-1], plan.Rest)
+}
+
+func TestDronePlanService_PlanWithProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewDronePlanService(NewDronePlanServiceOptions{Repository: mockRepository})
+	estate := repository.Estate{Id: uuid.New().String(), Width: 5, Length: 2}
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+		{X: 1, Y: 1, Height: 5},
+		{X: 2, Y: 3, Height: 5},
+		{X: 1, Y: 4, Height: 5},
+		{X: 2, Y: 4, Height: 5},
+	}))
+
+	var rows []int
+	_, err := s.PlanWithProgress(context.Background(), estate, nil, func(done int) error {
+		rows = append(rows, done)
+		return nil
+	})
+	require.NoError(t, err)
+	// the rows done when a tree of a further row is loaded, then every row
+	assert.Equal(t, []int{2, 3, 5}, rows)
+
+	// an error of the progress stops the computation
+	stop := errors.New("cancelled")
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{
+		{X: 1, Y: 3, Height: 5},
+	}))
+	_, err = s.PlanWithProgress(context.Background(), estate, nil, func(done int) error { return stop })
+	assert.ErrorIs(t, err, stop)
+}
+
+func TestDronePlanService_Targeted(t *testing.T) {
+	id := uuid.New().String()
+	estate := repository.Estate{Id: id, Width: 2, Length: 3}
+	trees := []repository.Tree{
+		{X: 2, Y: 1, Height: 5},
+		{X: 3, Y: 2, Height: 2},
+	}
+	severity := 3
+	tests := []struct {
+		name       string
+		request    TargetedPlanRequest
+		setupMocks func(mockRepository *repository.MockRepositoryInterface)
+		want       planner.TargetedPlan
+		wantErr    string
+	}{
+		{
+			name:       "no target",
+			request:    TargetedPlanRequest{},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    "targets must be either plots or a min severity",
+		},
+		{
+			name:       "plots and min severity",
+			request:    TargetedPlanRequest{Plots: []planner.Plot{{X: 1, Y: 1}}, MinSeverity: &severity},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    "targets must be either plots or a min severity",
+		},
+		{
+			name:       "launch out of bound",
+			request:    TargetedPlanRequest{Launch: &planner.Plot{X: 1, Y: 3}, Plots: []planner.Plot{{X: 1, Y: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    "launch: index out of bound",
+		},
+		{
+			name:       "target out of bound",
+			request:    TargetedPlanRequest{Plots: []planner.Plot{{X: 1, Y: 1}, {X: 4, Y: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    "target 1: index out of bound",
+		},
+		{
+			name:    "plots",
+			request: TargetedPlanRequest{Plots: []planner.Plot{{X: 3, Y: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees(trees))
+			},
+			want: planner.TargetedPlan{
+				Plan:    planner.Plan{Distance: 62, Rest: planner.Plot{X: 1, Y: 1}},
+				Targets: []planner.Plot{{X: 3, Y: 1}},
+				Visited: 1,
+			},
+		},
+		{
+			name:    "trees with open findings",
+			request: TargetedPlanRequest{MinSeverity: &severity},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				// one more plot than the targets is read to tell there are too many
+				mockRepository.EXPECT().ListFlaggedPlotsByEstateId(gomock.Any(), repository.ListFlaggedPlotsByEstateIdInput{
+					EstateId: id,
+					Filter:   repository.FindingFilter{Status: repository.FindingStatusOpen, MinSeverity: 3},
+					Limit:    maxPlanTargets + 1,
+				}).Return([]repository.FlaggedPlot{{X: 3, Y: 2}}, nil)
+				mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamTrees(trees))
+			},
+			want: planner.TargetedPlan{
+				Plan:    planner.Plan{Distance: 66, Rest: planner.Plot{X: 1, Y: 1}},
+				Targets: []planner.Plot{{X: 3, Y: 2}},
+				Visited: 1,
+			},
+		},
+		{
+			name:    "too many trees with open findings",
+			request: TargetedPlanRequest{MinSeverity: &severity},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().ListFlaggedPlotsByEstateId(gomock.Any(), gomock.Any()).
+					Return(make([]repository.FlaggedPlot, maxPlanTargets+1), nil)
+			},
+			wantErr: ErrTooManyTargets.Error(),
+		},
+		{
+			name:    "findings database error",
+			request: TargetedPlanRequest{MinSeverity: &severity},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().ListFlaggedPlotsByEstateId(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+			},
+			wantErr: "connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewDronePlanService(NewDronePlanServiceOptions{Repository: mockRepository})
+
+			plan, err := s.Targeted(context.Background(), estate, tt.request)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+}
+
+func TestDronePlanService_Targeted_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	lru, err := cache.NewLRU(16)
+	require.NoError(t, err)
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewDronePlanService(NewDronePlanServiceOptions{
+		Repository: mockRepository,
+		Cache:      cache.New(cache.NewCacheOptions{Backend: lru}),
+	})
+	estate := repository.Estate{Id: uuid.New().String(), Width: 2, Length: 3, Version: 1}
+	request := TargetedPlanRequest{Plots: []planner.Plot{{X: 3, Y: 1}}}
+
+	// the trees are streamed once, the second call is cached
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(streamTrees([]repository.Tree{{X: 2, Y: 1, Height: 5}})).Times(1)
+	first, err := s.Targeted(context.Background(), estate, request)
+	require.NoError(t, err)
+	second, err := s.Targeted(context.Background(), estate, request)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// other targets are computed again
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+	_, err = s.Targeted(context.Background(), estate, TargetedPlanRequest{Plots: []planner.Plot{{X: 1, Y: 2}}})
+	assert.EqualError(t, err, "connection refused")
+}
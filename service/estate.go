@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// CreateEstateRequest mirror the estate request of the API
+type CreateEstateRequest struct {
+	Length int `validate:"required,gte=1,lte=50000"`
+	Width  int `validate:"required,gte=1,lte=50000"`
+}
+
+type EstateService struct {
+	repository repository.RepositoryInterface
+	metrics    *metrics.Metrics
+	events     eventPublisher
+}
+
+type NewEstateServiceOptions struct {
+	Repository repository.RepositoryInterface
+	Metrics    *metrics.Metrics
+	Logger     *slog.Logger
+	// Events receives the stats of the imported estates, nil to publish nothing
+	Events *events.Hub
+}
+
+func NewEstateService(opts NewEstateServiceOptions) *EstateService {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EstateService{
+		repository: opts.Repository,
+		metrics:    opts.Metrics,
+		events: eventPublisher{
+			hub:        opts.Events,
+			repository: opts.Repository,
+			logger:     logger,
+		},
+	}
+}
+
+// CreateEstate create an estate in the organisation
+func (s *EstateService) CreateEstate(ctx context.Context, organisationId string, request CreateEstateRequest) (repository.Estate, error) {
+	if err := validateRequest(request); err != nil {
+		return repository.Estate{}, err
+	}
+	estate, err := s.repository.CreateEstate(ctx, repository.Estate{
+		OrganisationId: organisationId,
+		Width:          request.Width,
+		Length:         request.Length,
+	})
+	if err != nil {
+		return estate, err
+	}
+	s.metrics.EstateCreated()
+	return estate, nil
+}
+
+// GetEstate returns the estate of the organisation, ErrEstateNotFound when it does not exist or belongs to
+// another organisation
+func (s *EstateService) GetEstate(ctx context.Context, organisationId, id string) (repository.Estate, error) {
+	return getEstate(ctx, s.repository, organisationId, id)
+}
+
+// ListEstates returns a page of the estates of the organisation, oldest first
+func (s *EstateService) ListEstates(ctx context.Context, organisationId string, request pagination.Request) (pagination.Page[repository.Estate], error) {
+	estates, err := s.repository.ListEstatesByOrganisationId(ctx, repository.ListEstatesByOrganisationIdInput{
+		OrganisationId: organisationId,
+		After:          request.After,
+		Limit:          request.Fetch(),
+	})
+	if err != nil {
+		return pagination.Page[repository.Estate]{}, err
+	}
+	return pagination.NewPage(estates, request, func(estate repository.Estate) pagination.Cursor {
+		return pagination.Cursor{Time: estate.CreatedAt, Id: estate.Id}
+	}), nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEstateService_CreateEstate(t *testing.T) {
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	tests := []struct {
+		name       string
+		request    CreateEstateRequest
+		setupMocks func(mockRepository *repository.MockRepositoryInterface)
+		wantId     string
+		wantErr    string
+	}{
+		{
+			name:       "invalid width",
+			request:    CreateEstateRequest{Length: 10, Width: 0},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    "Key: 'CreateEstateRequest.Width' Error:Field validation for 'Width' failed on the 'required' tag",
+		},
+		{
+			name:    "database error",
+			request: CreateEstateRequest{Length: 10, Width: 5},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("connection refused"))
+			},
+			wantErr: "connection refused",
+		},
+		{
+			name:    "success",
+			request: CreateEstateRequest{Length: 10, Width: 5},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().CreateEstate(gomock.Any(), repository.Estate{
+					OrganisationId: orgId,
+					Width:          5,
+					Length:         10,
+				}).Return(repository.Estate{Id: id}, nil)
+			},
+			wantId: id,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewEstateService(NewEstateServiceOptions{Repository: mockRepository})
+
+			estate, err := s.CreateEstate(context.Background(), orgId, tt.request)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantId, estate.Id)
+		})
+	}
+}
+
+func TestEstateService_CreateEstate_ValidationError(t *testing.T) {
+	s := NewEstateService(NewEstateServiceOptions{})
+
+	_, err := s.CreateEstate(context.Background(), uuid.New().String(), CreateEstateRequest{Length: 50001, Width: 1})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
+
+func TestEstateService_GetEstate(t *testing.T) {
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	tests := []struct {
+		name           string
+		organisationId string
+		setupMocks     func(mockRepository *repository.MockRepositoryInterface)
+		wantErr        error
+	}{
+		{
+			name:           "not found",
+			organisationId: orgId,
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			wantErr: ErrEstateNotFound,
+		},
+		{
+			name:           "other organisation",
+			organisationId: uuid.New().String(),
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId}, nil)
+			},
+			wantErr: ErrEstateNotFound,
+		},
+		{
+			name:           "no organisation",
+			organisationId: "",
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id}, nil)
+			},
+			wantErr: ErrEstateNotFound,
+		},
+		{
+			name:           "success",
+			organisationId: orgId,
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{Id: id, OrganisationId: orgId}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewEstateService(NewEstateServiceOptions{Repository: mockRepository})
+
+			estate, err := s.GetEstate(context.Background(), tt.organisationId, id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, id, estate.Id)
+		})
+	}
+}
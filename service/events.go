@@ -0,0 +1,55 @@
+// This file contains the publishing of the estate events streamed to the dashboards.
+//
+// The events are published by the services writing the trees, so every API creating trees publishes them the
+// same way. A stats event follows the changes of the trees, it is only computed when a client listens.
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+type eventPublisher struct {
+	hub        *events.Hub
+	repository repository.RepositoryInterface
+	logger     *slog.Logger
+}
+
+// treeCreated publish a created tree and the updated stats of its estate
+func (p eventPublisher) treeCreated(ctx context.Context, estateId string, tree repository.Tree) {
+	if err := p.hub.Publish(estateId, events.TypeTreeCreated, tree); err != nil {
+		p.logger.ErrorContext(ctx, "failed to publish event", slog.String("error", err.Error()))
+		return
+	}
+	p.stats(ctx, estateId)
+}
+
+// stats publish the up to date stats of the estate, when a client listens
+func (p eventPublisher) stats(ctx context.Context, estateId string) {
+	if !p.hub.HasSubscribers(estateId) {
+		return
+	}
+	stats, err := liveStats(ctx, p.repository, estateId)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "failed to list trees for the stats event", slog.String("error", err.Error()))
+		return
+	}
+	if err := p.hub.Publish(estateId, events.TypeStats, stats); err != nil {
+		p.logger.ErrorContext(ctx, "failed to publish event", slog.String("error", err.Error()))
+	}
+}
+
+// liveStats returns the up to date stats of the trees of the estate, unlike the cached stats
+func liveStats(ctx context.Context, repo repository.RepositoryInterface, estateId string) (Stats, error) {
+	var counter StatsCounter
+	err := repo.StreamTreesByEstateId(ctx, repository.StreamTreesByEstateIdInput{
+		EstateId: estateId,
+	}, func(tree repository.Tree) error {
+		counter.Add(tree)
+		return nil
+	})
+	return counter.Stats(), err
+}
@@ -0,0 +1,237 @@
+// This file contains the inspections: the findings a drone posts for the plots it flew over.
+//
+// A finding is posted for a recorded flight and linked to the tree standing on its plot, it stays open until
+// somebody resolves it on the ground. The summary counts the findings of an estate by type and by severity.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// maxFindingSeverity is the highest severity of a finding, the lowest is 1
+const maxFindingSeverity = 5
+
+// CreateFindingRequest mirror the finding request of the API, DetectedAt default to the end of the flight
+type CreateFindingRequest struct {
+	X          int     `validate:"required,gte=1,lte=50000"`
+	Y          int     `validate:"required,gte=1,lte=50000"`
+	Type       string  `validate:"required,oneof=pest_damage yellowing_fronds ripe_bunches disease missing_tree other"`
+	Severity   int     `validate:"required,gte=1,lte=5"`
+	Confidence float64 `validate:"gte=0,lte=1"`
+	ImageRef   *string `validate:"omitempty,max=2048"`
+	DetectedAt *time.Time
+}
+
+type CreateFindingsRequest struct {
+	Findings []CreateFindingRequest `validate:"required,min=1,max=10000,dive"`
+}
+
+// FindingsSummary is the summary of the findings of an estate, the types in the order of
+// repository.FindingTypes and the severities from the lowest. Types and severities without finding are left out.
+type FindingsSummary struct {
+	Total          int
+	Open           int
+	LastDetectedAt *time.Time
+	ByType         []FindingTypeSummary
+	BySeverity     []FindingSeveritySummary
+}
+
+type FindingTypeSummary struct {
+	Type  string
+	Count int
+	Open  int
+	// MaxOpenSeverity is the highest severity of the open findings, 0 when all are resolved
+	MaxOpenSeverity int
+}
+
+type FindingSeveritySummary struct {
+	Severity int
+	Count    int
+	Open     int
+}
+
+type InspectionService struct {
+	repository repository.RepositoryInterface
+	metrics    *metrics.Metrics
+}
+
+type NewInspectionServiceOptions struct {
+	Repository repository.RepositoryInterface
+	Metrics    *metrics.Metrics
+}
+
+func NewInspectionService(opts NewInspectionServiceOptions) *InspectionService {
+	return &InspectionService{
+		repository: opts.Repository,
+		metrics:    opts.Metrics,
+	}
+}
+
+// CreateFindings store the findings of a flight of an estate of the organisation. The findings are rejected
+// together when one of them is invalid or out of the estate.
+func (s *InspectionService) CreateFindings(ctx context.Context, organisationId, estateId, flightId string, request CreateFindingsRequest) ([]repository.Finding, error) {
+	if err := validateRequest(request); err != nil {
+		return nil, err
+	}
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return nil, err
+	}
+	flight, err := s.repository.GetFlightById(ctx, repository.GetFlightByIdInput{
+		Id:       flightId,
+		EstateId: estate.Id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFlightNotFound
+		}
+		return nil, err
+	}
+
+	input := repository.CreateFindingsInput{Findings: make([]repository.Finding, 0, len(request.Findings))}
+	for i, finding := range request.Findings {
+		if estate.Length < finding.X || estate.Width < finding.Y {
+			return nil, fmt.Errorf("finding %d: %w", i, ErrOutOfBound)
+		}
+		detectedAt := flight.FinishedAt
+		if finding.DetectedAt != nil {
+			detectedAt = finding.DetectedAt.UTC()
+		}
+		input.Findings = append(input.Findings, repository.Finding{
+			EstateId:   estate.Id,
+			FlightId:   flight.Id,
+			X:          finding.X,
+			Y:          finding.Y,
+			Type:       finding.Type,
+			Severity:   finding.Severity,
+			Confidence: finding.Confidence,
+			ImageRef:   finding.ImageRef,
+			DetectedAt: detectedAt,
+		})
+	}
+
+	findings, err := s.repository.CreateFindings(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	for _, finding := range findings {
+		s.metrics.FindingCreated(finding.Type)
+	}
+	return findings, nil
+}
+
+// ListFindings returns a page of the findings of an estate of the organisation matching the filter, the most
+// recently detected first
+func (s *InspectionService) ListFindings(ctx context.Context, organisationId, estateId string, filter repository.FindingFilter, request pagination.Request) (pagination.Page[repository.Finding], error) {
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return pagination.Page[repository.Finding]{}, err
+	}
+	findings, err := s.repository.ListFindingsByEstateId(ctx, repository.ListFindingsByEstateIdInput{
+		EstateId: estate.Id,
+		Filter:   filter,
+		After:    request.After,
+		Limit:    request.Fetch(),
+	})
+	if err != nil {
+		return pagination.Page[repository.Finding]{}, err
+	}
+	return pagination.NewPage(findings, request, func(finding repository.Finding) pagination.Cursor {
+		return pagination.Cursor{Time: finding.DetectedAt, Id: finding.Id}
+	}), nil
+}
+
+// Summary returns the summary of the findings of an estate of the organisation detected between from and to,
+// to excluded. A nil bound does not bound.
+func (s *InspectionService) Summary(ctx context.Context, organisationId, estateId string, from, to *time.Time) (FindingsSummary, error) {
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return FindingsSummary{}, err
+	}
+	counts, err := s.repository.CountFindingsByEstateId(ctx, repository.CountFindingsByEstateIdInput{
+		EstateId: estate.Id,
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		return FindingsSummary{}, err
+	}
+	return SummariseFindings(counts), nil
+}
+
+// SummariseFindings sum the counts of the findings by type and by severity
+func SummariseFindings(counts []repository.FindingCount) FindingsSummary {
+	summary := FindingsSummary{}
+	byType := make(map[string]*FindingTypeSummary)
+	bySeverity := make(map[int]*FindingSeveritySummary)
+	for _, count := range counts {
+		open := 0
+		if count.Status == repository.FindingStatusOpen {
+			open = count.Count
+		}
+		summary.Total += count.Count
+		summary.Open += open
+		if summary.LastDetectedAt == nil || count.LastDetectedAt.After(*summary.LastDetectedAt) {
+			lastDetectedAt := count.LastDetectedAt
+			summary.LastDetectedAt = &lastDetectedAt
+		}
+
+		findingType, ok := byType[count.Type]
+		if !ok {
+			findingType = &FindingTypeSummary{Type: count.Type}
+			byType[count.Type] = findingType
+		}
+		findingType.Count += count.Count
+		findingType.Open += open
+		if open > 0 && count.Severity > findingType.MaxOpenSeverity {
+			findingType.MaxOpenSeverity = count.Severity
+		}
+
+		severity, ok := bySeverity[count.Severity]
+		if !ok {
+			severity = &FindingSeveritySummary{Severity: count.Severity}
+			bySeverity[count.Severity] = severity
+		}
+		severity.Count += count.Count
+		severity.Open += open
+	}
+
+	for _, name := range repository.FindingTypes {
+		if findingType, ok := byType[name]; ok {
+			summary.ByType = append(summary.ByType, *findingType)
+		}
+	}
+	for level := 1; level <= maxFindingSeverity; level++ {
+		if severity, ok := bySeverity[level]; ok {
+			summary.BySeverity = append(summary.BySeverity, *severity)
+		}
+	}
+	return summary
+}
+
+// ResolveFinding mark a finding of an estate of the organisation as resolved, resolving it again changes nothing
+func (s *InspectionService) ResolveFinding(ctx context.Context, organisationId, estateId, findingId string) (repository.Finding, error) {
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return repository.Finding{}, err
+	}
+	finding, err := s.repository.ResolveFinding(ctx, repository.ResolveFindingInput{
+		Id:       findingId,
+		EstateId: estate.Id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.Finding{}, ErrFindingNotFound
+		}
+		return repository.Finding{}, err
+	}
+	return finding, nil
+}
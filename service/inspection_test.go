@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestInspectionService_CreateFindings(t *testing.T) {
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	flightId := uuid.New().String()
+	estate := repository.Estate{Id: id, OrganisationId: orgId, Length: 5, Width: 3}
+	finishedAt := time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)
+	detectedAt := time.Date(2024, 1, 1, 13, 10, 0, 0, time.FixedZone("WIB", 7*60*60))
+	tests := []struct {
+		name       string
+		request    CreateFindingsRequest
+		setupMocks func(mockRepository *repository.MockRepositoryInterface)
+		wantErr    error
+	}{
+		{
+			name:       "invalid type",
+			request:    CreateFindingsRequest{Findings: []CreateFindingRequest{{X: 1, Y: 1, Type: "locusts", Severity: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    &ValidationError{},
+		},
+		{
+			name:    "estate of another organisation",
+			request: CreateFindingsRequest{Findings: []CreateFindingRequest{{X: 1, Y: 1, Type: "disease", Severity: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{Id: id, OrganisationId: uuid.New().String()}, nil)
+			},
+			wantErr: ErrEstateNotFound,
+		},
+		{
+			name:    "flight not found",
+			request: CreateFindingsRequest{Findings: []CreateFindingRequest{{X: 1, Y: 1, Type: "disease", Severity: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+				mockRepository.EXPECT().GetFlightById(gomock.Any(), gomock.Any()).Return(repository.Flight{}, sql.ErrNoRows)
+			},
+			wantErr: ErrFlightNotFound,
+		},
+		{
+			name:    "out of bound",
+			request: CreateFindingsRequest{Findings: []CreateFindingRequest{{X: 6, Y: 1, Type: "disease", Severity: 1}}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+				mockRepository.EXPECT().GetFlightById(gomock.Any(), gomock.Any()).Return(repository.Flight{Id: flightId, EstateId: id}, nil)
+			},
+			wantErr: ErrOutOfBound,
+		},
+		{
+			name: "success",
+			request: CreateFindingsRequest{Findings: []CreateFindingRequest{
+				{X: 5, Y: 3, Type: "ripe_bunches", Severity: 1, Confidence: 0.6},
+				{X: 1, Y: 2, Type: "pest_damage", Severity: 4, Confidence: 0.95, DetectedAt: &detectedAt},
+			}},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{Id: id}).Return(estate, nil)
+				mockRepository.EXPECT().GetFlightById(gomock.Any(), repository.GetFlightByIdInput{Id: flightId, EstateId: id}).
+					Return(repository.Flight{Id: flightId, EstateId: id, FinishedAt: finishedAt}, nil)
+				// the detection time default to the end of the flight and is stored in UTC
+				mockRepository.EXPECT().CreateFindings(gomock.Any(), repository.CreateFindingsInput{Findings: []repository.Finding{
+					{EstateId: id, FlightId: flightId, X: 5, Y: 3, Type: "ripe_bunches", Severity: 1, Confidence: 0.6, DetectedAt: finishedAt},
+					{EstateId: id, FlightId: flightId, X: 1, Y: 2, Type: "pest_damage", Severity: 4, Confidence: 0.95, DetectedAt: detectedAt.UTC()},
+				}}).Return([]repository.Finding{{Id: uuid.New().String()}, {Id: uuid.New().String()}}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewInspectionService(NewInspectionServiceOptions{Repository: mockRepository})
+
+			findings, err := s.CreateFindings(context.Background(), orgId, id, flightId, tt.request)
+			var validationErr *ValidationError
+			switch {
+			case errors.As(tt.wantErr, &validationErr):
+				assert.ErrorAs(t, err, &validationErr)
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			default:
+				assert.NoError(t, err)
+				assert.Len(t, findings, 2)
+			}
+		})
+	}
+}
+
+func TestSummariseFindings(t *testing.T) {
+	first := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	last := first.Add(48 * time.Hour)
+	tests := []struct {
+		name   string
+		counts []repository.FindingCount
+		want   FindingsSummary
+	}{
+		{
+			name: "no finding",
+			want: FindingsSummary{},
+		},
+		{
+			name: "by type and severity",
+			counts: []repository.FindingCount{
+				{Type: "ripe_bunches", Severity: 1, Status: "open", Count: 10, LastDetectedAt: first},
+				{Type: "pest_damage", Severity: 2, Status: "resolved", Count: 3, LastDetectedAt: last},
+				{Type: "pest_damage", Severity: 4, Status: "open", Count: 1, LastDetectedAt: first},
+				{Type: "pest_damage", Severity: 5, Status: "resolved", Count: 2, LastDetectedAt: first},
+			},
+			want: FindingsSummary{
+				Total:          16,
+				Open:           11,
+				LastDetectedAt: &last,
+				ByType: []FindingTypeSummary{
+					{Type: "pest_damage", Count: 6, Open: 1, MaxOpenSeverity: 4},
+					{Type: "ripe_bunches", Count: 10, Open: 10, MaxOpenSeverity: 1},
+				},
+				BySeverity: []FindingSeveritySummary{
+					{Severity: 1, Count: 10, Open: 10},
+					{Severity: 2, Count: 3, Open: 0},
+					{Severity: 4, Count: 1, Open: 1},
+					{Severity: 5, Count: 2, Open: 0},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SummariseFindings(tt.counts))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// This file contains the domain services shared by the REST and the gRPC APIs.
+//
+// The services own the rules of the estates, the trees and the drone plans: the validation of the requests,
+// the bounds of the plots, the stats and the route. They return the typed errors below so the callers map
+// them to their own answers (http status, grpc code, exit code) without knowing the rules.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrEstateNotFound     = errors.New("estate is not found")
+	ErrOutOfBound         = errors.New("index out of bound")
+	ErrPlotExist          = errors.New("plot already exist")
+	ErrInvalidMaxDistance = errors.New("invalid max distance")
+	ErrInvalidTargets     = errors.New("targets must be either plots or a min severity")
+	ErrTooManyTargets     = errors.New("too many targets, at most 1000")
+
+	ErrInvalidTreeAttributes = errors.New("attributes must be a JSON object of at most 4096 bytes")
+	ErrInvalidTreeHealth     = errors.New("health must be healthy, diseased, dead or replanted")
+
+	ErrFlightNotFound  = errors.New("flight is not found")
+	ErrFindingNotFound = errors.New("finding is not found")
+)
+
+// ValidationError is returned for a request breaking the rules of its fields, the message is the one of
+// the validator
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	cacheKindStats        = "stats"
+	cacheKindDronePlan    = "drone-plan"
+	cacheKindTargetedPlan = "targeted-plan"
+)
+
+var validate = validator.New()
+
+// validateRequest returns a ValidationError when the request breaks the rules of its fields
+func validateRequest(request interface{}) error {
+	if err := validate.Struct(request); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+// getEstate returns the estate, ErrEstateNotFound when it does not exist or belongs to another organisation.
+// Estates of other organisations are not found so their existence is not disclosed.
+func getEstate(ctx context.Context, repo repository.RepositoryInterface, organisationId, id string) (repository.Estate, error) {
+	estate, err := repo.GetEstateById(ctx, repository.GetEstateByIdInput{
+		Id: id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.Estate{}, ErrEstateNotFound
+		}
+		return repository.Estate{}, err
+	}
+	if organisationId == "" || estate.OrganisationId != organisationId {
+		return repository.Estate{}, ErrEstateNotFound
+	}
+	return estate, nil
+}
+
+// cacheGet read a cached result, a failing cache is logged and treated as a miss
+func cacheGet(ctx context.Context, c *cache.Cache, logger *slog.Logger, key string, v interface{}) bool {
+	ok, err := c.Get(ctx, key, v)
+	if err != nil {
+		logger.WarnContext(ctx, "cache get failed", slog.String("key", key), slog.String("error", err.Error()))
+	}
+	return ok
+}
+
+// cacheSet store a computed result, a failing cache is logged and ignored
+func cacheSet(ctx context.Context, c *cache.Cache, logger *slog.Logger, key string, v interface{}) {
+	if err := c.Set(ctx, key, v); err != nil {
+		logger.WarnContext(ctx, "cache set failed", slog.String("key", key), slog.String("error", err.Error()))
+	}
+}
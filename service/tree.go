@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/events"
+	"github.com/SawitProRecruitment/UserService/metrics"
+	"github.com/SawitProRecruitment/UserService/pagination"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// CreateTreeRequest has the fields and the rules of the request of the API, so the validation messages are
+// the same
+type CreateTreeRequest struct {
+	Height    int     `validate:"required,gte=1,lte=30"`
+	X         int     `validate:"required,gte=1,lte=50000"`
+	Y         int     `validate:"required,gte=1,lte=50000"`
+	Species   *string `validate:"omitempty,max=64"`
+	Variety   *string `validate:"omitempty,max=64"`
+	PlantedAt *time.Time
+	Health    *string `validate:"omitempty,oneof=healthy diseased dead replanted"`
+	// Attributes is a JSON object of at most maxTreeAttributesSize bytes
+	Attributes json.RawMessage
+}
+
+// maxTreeAttributesSize keeps the free-form attributes to a few notes, the listing of a large estate returns them
+// for every tree
+const maxTreeAttributesSize = 4096
+
+// Stats of the trees of an estate, encoded as the stats response of the API
+type Stats struct {
+	Count     int            `json:"count"`
+	Max       int            `json:"max"`
+	Median    int            `json:"median"`
+	Min       int            `json:"min"`
+	BySpecies []SpeciesStats `json:"by_species,omitempty"`
+	ByHealth  []HealthStats  `json:"by_health,omitempty"`
+}
+
+// SpeciesStats are the stats of the trees of a species
+type SpeciesStats struct {
+	Species string `json:"species"`
+	Count   int    `json:"count"`
+	Max     int    `json:"max"`
+	Median  int    `json:"median"`
+	Min     int    `json:"min"`
+}
+
+// HealthStats are the stats of the trees of a health status
+type HealthStats struct {
+	Health string `json:"health"`
+	Count  int    `json:"count"`
+	Max    int    `json:"max"`
+	Median int    `json:"median"`
+	Min    int    `json:"min"`
+}
+
+type TreeService struct {
+	repository repository.RepositoryInterface
+	metrics    *metrics.Metrics
+	cache      *cache.Cache
+	logger     *slog.Logger
+	events     eventPublisher
+}
+
+type NewTreeServiceOptions struct {
+	Repository repository.RepositoryInterface
+	Metrics    *metrics.Metrics
+	// Cache of the stats, nil to compute them on every call
+	Cache  *cache.Cache
+	Logger *slog.Logger
+	// Events receives the created trees and the updated stats, nil to publish nothing
+	Events *events.Hub
+}
+
+func NewTreeService(opts NewTreeServiceOptions) *TreeService {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &TreeService{
+		repository: opts.Repository,
+		metrics:    opts.Metrics,
+		cache:      opts.Cache,
+		logger:     logger,
+		events: eventPublisher{
+			hub:        opts.Events,
+			repository: opts.Repository,
+			logger:     logger,
+		},
+	}
+}
+
+// CreateTree add a tree to a free plot of an estate of the organisation
+func (s *TreeService) CreateTree(ctx context.Context, organisationId, estateId string, request CreateTreeRequest) (repository.Tree, error) {
+	if err := validateRequest(request); err != nil {
+		return repository.Tree{}, err
+	}
+	if err := validateTreeAttributes(request.Attributes); err != nil {
+		return repository.Tree{}, err
+	}
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return repository.Tree{}, err
+	}
+
+	// Check if plot out of bound
+	if estate.Length < request.X || estate.Width < request.Y {
+		return repository.Tree{}, ErrOutOfBound
+	}
+
+	// Check plot exist
+	_, err = s.repository.GetTreeByPlot(ctx, repository.GetTreeByPlot{
+		EstateId: estate.Id,
+		X:        request.X,
+		Y:        request.Y,
+	})
+	if err == nil {
+		return repository.Tree{}, ErrPlotExist
+	}
+
+	tree := repository.Tree{
+		X:          request.X,
+		Y:          request.Y,
+		Height:     request.Height,
+		EstateId:   estate.Id,
+		Species:    request.Species,
+		Variety:    request.Variety,
+		PlantedAt:  request.PlantedAt,
+		Attributes: request.Attributes,
+	}
+	if request.Health != nil {
+		tree.Health = *request.Health
+	}
+	tree, err = s.repository.CreateTree(ctx, tree)
+	if err != nil {
+		return tree, err
+	}
+	s.metrics.TreeCreated()
+	s.events.treeCreated(ctx, estate.Id, tree)
+	return tree, nil
+}
+
+// validateTreeAttributes returns ErrInvalidTreeAttributes unless the attributes are unset or a small JSON object
+func validateTreeAttributes(attributes json.RawMessage) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+	var object map[string]interface{}
+	if len(attributes) > maxTreeAttributesSize || json.Unmarshal(attributes, &object) != nil || object == nil {
+		return ErrInvalidTreeAttributes
+	}
+	return nil
+}
+
+// validateTreeFilter returns ErrInvalidTreeHealth when the health of the filter is not a health status
+func validateTreeFilter(filter repository.TreeFilter) error {
+	if filter.Health == "" {
+		return nil
+	}
+	for _, health := range repository.TreeHealths {
+		if filter.Health == health {
+			return nil
+		}
+	}
+	return ErrInvalidTreeHealth
+}
+
+// ListTrees returns a page of the trees of an estate of the organisation matching the filter, ordered by y then x
+func (s *TreeService) ListTrees(ctx context.Context, organisationId, estateId string, filter repository.TreeFilter, request pagination.Request) (pagination.Page[repository.Tree], error) {
+	if err := validateTreeFilter(filter); err != nil {
+		return pagination.Page[repository.Tree]{}, err
+	}
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return pagination.Page[repository.Tree]{}, err
+	}
+	trees, err := s.repository.ListTreesByEstateId(ctx, repository.ListTreesByEstateIdInput{
+		EstateId: estate.Id,
+		Filter:   filter,
+		After:    request.After,
+		Limit:    request.Fetch(),
+	})
+	if err != nil {
+		return pagination.Page[repository.Tree]{}, err
+	}
+	return pagination.NewPage(trees, request, func(tree repository.Tree) pagination.Cursor {
+		return pagination.Cursor{X: tree.X, Y: tree.Y}
+	}), nil
+}
+
+// StreamTrees visit the trees of an estate of the organisation matching the filter after the cursor, ordered by
+// y then x. The filter and the estate are checked before the first visit.
+func (s *TreeService) StreamTrees(ctx context.Context, organisationId, estateId string, filter repository.TreeFilter, after *pagination.Cursor, visit func(repository.Tree) error) error {
+	if err := validateTreeFilter(filter); err != nil {
+		return err
+	}
+	estate, err := getEstate(ctx, s.repository, organisationId, estateId)
+	if err != nil {
+		return err
+	}
+	return s.repository.StreamTreesByEstateId(ctx, repository.StreamTreesByEstateIdInput{
+		EstateId: estate.Id,
+		Filter:   filter,
+		After:    after,
+	}, visit)
+}
+
+// Stats returns the stats of the trees of the estate, cached until a tree of the estate changes
+func (s *TreeService) Stats(ctx context.Context, estate repository.Estate) (Stats, error) {
+	cacheKey := cache.Key(cacheKindStats, estate.Id, estate.Version)
+	var stats Stats
+	if cacheGet(ctx, s.cache, s.logger, cacheKey, &stats) {
+		return stats, nil
+	}
+
+	stats, err := liveStats(ctx, s.repository, estate.Id)
+	if err != nil {
+		return stats, err
+	}
+	cacheSet(ctx, s.cache, s.logger, cacheKey, stats)
+	return stats, nil
+}
+
+// LiveStats returns the up to date stats of the trees of the estate, without the cache. The estate is expected
+// to be checked by the caller.
+func (s *TreeService) LiveStats(ctx context.Context, estateId string) (Stats, error) {
+	return liveStats(ctx, s.repository, estateId)
+}
+
+// TreeStats returns the count and the min, max and median height of the trees, in total, by species and by
+// health status
+func TreeStats(trees []repository.Tree) Stats {
+	var counter StatsCounter
+	for _, tree := range trees {
+		counter.Add(tree)
+	}
+	return counter.Stats()
+}
+
+// StatsCounter compute the stats of the trees added one by one. The heights are counted by value, the memory
+// is bounded by the number of distinct heights and species whatever the number of trees.
+type StatsCounter struct {
+	all     heightCounter
+	species map[string]*heightCounter
+	health  map[string]*heightCounter
+}
+
+// Add count a tree in the totals, its species and its health status
+func (c *StatsCounter) Add(tree repository.Tree) {
+	c.all.add(tree.Height)
+	if tree.Species != nil && *tree.Species != "" {
+		c.species = addHeight(c.species, *tree.Species, tree.Height)
+	}
+	if tree.Health != "" {
+		c.health = addHeight(c.health, tree.Health, tree.Height)
+	}
+}
+
+// Stats returns the stats of the trees added, the species by name and the health status in the order of
+// repository.TreeHealths
+func (c *StatsCounter) Stats() Stats {
+	stats := Stats{}
+	stats.Count, stats.Min, stats.Max, stats.Median = c.all.stats()
+
+	names := make([]string, 0, len(c.species))
+	for name := range c.species {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		species := SpeciesStats{Species: name}
+		species.Count, species.Min, species.Max, species.Median = c.species[name].stats()
+		stats.BySpecies = append(stats.BySpecies, species)
+	}
+	for _, name := range repository.TreeHealths {
+		if counter, ok := c.health[name]; ok {
+			health := HealthStats{Health: name}
+			health.Count, health.Min, health.Max, health.Median = counter.stats()
+			stats.ByHealth = append(stats.ByHealth, health)
+		}
+	}
+	return stats
+}
+
+func addHeight(counters map[string]*heightCounter, key string, height int) map[string]*heightCounter {
+	if counters == nil {
+		counters = make(map[string]*heightCounter)
+	}
+	counter, ok := counters[key]
+	if !ok {
+		counter = &heightCounter{}
+		counters[key] = counter
+	}
+	counter.add(height)
+	return counters
+}
+
+// heightCounter count the trees by height
+type heightCounter struct {
+	count   int
+	min     int
+	max     int
+	heights map[int]int
+}
+
+func (c *heightCounter) add(height int) {
+	if c.heights == nil {
+		c.heights = make(map[int]int)
+	}
+	if c.count == 0 || height < c.min {
+		c.min = height
+	}
+	if c.count == 0 || height > c.max {
+		c.max = height
+	}
+	c.count++
+	c.heights[height]++
+}
+
+// stats returns the count and the min, max and median height
+func (c *heightCounter) stats() (count, min, max, median int) {
+	if c.count == 0 {
+		return 0, 0, 0, 0
+	}
+	if c.count%2 == 1 {
+		// the middle height of an odd count
+		return c.count, c.min, c.max, c.nth(c.count / 2)
+	}
+	// the average of the two middle heights
+	middle1 := c.nth(c.count/2 - 1)
+	middle2 := c.nth(c.count / 2)
+	median = int(float64(middle1+middle2) / 2)
+	return c.count, c.min, c.max, median
+}
+
+// nth returns the height at the index i of the sorted heights
+func (c *heightCounter) nth(i int) int {
+	values := make([]int, 0, len(c.heights))
+	for height := range c.heights {
+		values = append(values, height)
+	}
+	sort.Ints(values)
+	for _, height := range values {
+		i -= c.heights[height]
+		if i < 0 {
+			return height
+		}
+	}
+	return c.max
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/cache"
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTreeService_CreateTree(t *testing.T) {
+	orgId := uuid.New().String()
+	id := uuid.New().String()
+	treeId := uuid.New().String()
+	estate := repository.Estate{Id: id, OrganisationId: orgId, Length: 5, Width: 3}
+	plantedAt := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		request    CreateTreeRequest
+		setupMocks func(mockRepository *repository.MockRepositoryInterface)
+		wantErr    error
+	}{
+		{
+			name:       "invalid height",
+			request:    CreateTreeRequest{Height: 31, X: 1, Y: 1},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    &ValidationError{},
+		},
+		{
+			name:       "invalid health",
+			request:    CreateTreeRequest{Height: 10, X: 1, Y: 1, Health: ptr("sick")},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    &ValidationError{},
+		},
+		{
+			name:       "attributes not an object",
+			request:    CreateTreeRequest{Height: 10, X: 1, Y: 1, Attributes: json.RawMessage(`["clone"]`)},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {},
+			wantErr:    ErrInvalidTreeAttributes,
+		},
+		{
+			name:    "estate not found",
+			request: CreateTreeRequest{Height: 10, X: 1, Y: 1},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), repository.GetEstateByIdInput{
+					Id: id,
+				}).Return(repository.Estate{}, sql.ErrNoRows)
+			},
+			wantErr: ErrEstateNotFound,
+		},
+		{
+			name:    "out of bound x",
+			request: CreateTreeRequest{Height: 10, X: 6, Y: 1},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+			},
+			wantErr: ErrOutOfBound,
+		},
+		{
+			name:    "out of bound y",
+			request: CreateTreeRequest{Height: 10, X: 5, Y: 4},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+			},
+			wantErr: ErrOutOfBound,
+		},
+		{
+			name:    "plot already exist",
+			request: CreateTreeRequest{Height: 10, X: 5, Y: 3},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), repository.GetTreeByPlot{
+					EstateId: id,
+					X:        5,
+					Y:        3,
+				}).Return(repository.Tree{Id: uuid.New().String()}, nil)
+			},
+			wantErr: ErrPlotExist,
+		},
+		{
+			name:    "success",
+			request: CreateTreeRequest{Height: 10, X: 5, Y: 3},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), gomock.Any()).Return(repository.Tree{}, sql.ErrNoRows)
+				mockRepository.EXPECT().CreateTree(gomock.Any(), repository.Tree{
+					X:        5,
+					Y:        3,
+					Height:   10,
+					EstateId: id,
+				}).Return(repository.Tree{Id: treeId}, nil)
+			},
+		},
+		{
+			name: "success with species and health",
+			request: CreateTreeRequest{
+				Height:     10,
+				X:          5,
+				Y:          3,
+				Species:    ptr("Elaeis guineensis"),
+				Variety:    ptr("Tenera"),
+				PlantedAt:  &plantedAt,
+				Health:     ptr(repository.TreeHealthDiseased),
+				Attributes: json.RawMessage(`{"clone":"MPOB-7"}`),
+			},
+			setupMocks: func(mockRepository *repository.MockRepositoryInterface) {
+				mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(estate, nil)
+				mockRepository.EXPECT().GetTreeByPlot(gomock.Any(), gomock.Any()).Return(repository.Tree{}, sql.ErrNoRows)
+				mockRepository.EXPECT().CreateTree(gomock.Any(), repository.Tree{
+					X:          5,
+					Y:          3,
+					Height:     10,
+					EstateId:   id,
+					Species:    ptr("Elaeis guineensis"),
+					Variety:    ptr("Tenera"),
+					PlantedAt:  &plantedAt,
+					Health:     repository.TreeHealthDiseased,
+					Attributes: json.RawMessage(`{"clone":"MPOB-7"}`),
+				}).Return(repository.Tree{Id: treeId}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			tt.setupMocks(mockRepository)
+			s := NewTreeService(NewTreeServiceOptions{Repository: mockRepository})
+
+			tree, err := s.CreateTree(context.Background(), orgId, id, tt.request)
+			var validationErr *ValidationError
+			switch {
+			case errors.As(tt.wantErr, &validationErr):
+				assert.ErrorAs(t, err, &validationErr)
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, treeId, tree.Id)
+			}
+		})
+	}
+}
+
+func TestTreeService_Stats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	lru, err := cache.NewLRU(16)
+	require.NoError(t, err)
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	s := NewTreeService(NewTreeServiceOptions{
+		Repository: mockRepository,
+		Cache:      cache.New(cache.NewCacheOptions{Backend: lru}),
+	})
+	estate := repository.Estate{Id: uuid.New().String(), Version: 1}
+
+	// the trees are streamed once, the second call is cached
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), repository.StreamTreesByEstateIdInput{
+		EstateId: estate.Id,
+	}, gomock.Any()).DoAndReturn(streamTrees([]repository.Tree{{Height: 5}, {Height: 1}, {Height: 10}, {Height: 3}})).Times(1)
+	for i := 0; i < 2; i++ {
+		stats, err := s.Stats(context.Background(), estate)
+		require.NoError(t, err)
+		assert.Equal(t, Stats{Count: 4, Max: 10, Median: 4, Min: 1}, stats)
+	}
+
+	// a new version of the estate is computed again
+	estate.Version = 2
+	mockRepository.EXPECT().StreamTreesByEstateId(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+	_, err = s.Stats(context.Background(), estate)
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestTreeStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		heights []int
+		want    Stats
+	}{
+		{
+			name: "no tree",
+			want: Stats{},
+		},
+		{
+			name:    "single tree",
+			heights: []int{7},
+			want:    Stats{Count: 1, Max: 7, Median: 7, Min: 7},
+		},
+		{
+			name:    "odd count",
+			heights: []int{10, 1, 2},
+			want:    Stats{Count: 3, Max: 10, Median: 2, Min: 1},
+		},
+		{
+			name:    "odd count, repeated heights",
+			heights: []int{1, 30, 30, 1, 30},
+			want:    Stats{Count: 5, Max: 30, Median: 30, Min: 1},
+		},
+		{
+			name:    "two trees",
+			heights: []int{4, 7},
+			want:    Stats{Count: 2, Max: 7, Median: 5, Min: 4},
+		},
+		{
+			name:    "even count",
+			heights: []int{30, 1, 10, 5},
+			want:    Stats{Count: 4, Max: 30, Median: 7, Min: 1},
+		},
+		{
+			name:    "repeated heights",
+			heights: []int{5, 1, 5, 10, 5, 1},
+			want:    Stats{Count: 6, Max: 10, Median: 5, Min: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trees []repository.Tree
+			for _, height := range tt.heights {
+				trees = append(trees, repository.Tree{Height: height})
+			}
+			assert.Equal(t, tt.want, TreeStats(trees))
+		})
+	}
+}
+
+func TestTreeStats_Breakdown(t *testing.T) {
+	trees := []repository.Tree{
+		{Height: 10, Species: ptr("Elaeis guineensis"), Health: repository.TreeHealthHealthy},
+		{Height: 4, Species: ptr("Elaeis guineensis"), Health: repository.TreeHealthDiseased},
+		{Height: 6, Species: ptr("Cocos nucifera"), Health: repository.TreeHealthHealthy},
+		{Height: 2, Health: repository.TreeHealthDead},
+	}
+	assert.Equal(t, Stats{
+		Count:  4,
+		Max:    10,
+		Median: 5,
+		Min:    2,
+		BySpecies: []SpeciesStats{
+			{Species: "Cocos nucifera", Count: 1, Max: 6, Median: 6, Min: 6},
+			{Species: "Elaeis guineensis", Count: 2, Max: 10, Median: 7, Min: 4},
+		},
+		ByHealth: []HealthStats{
+			{Health: repository.TreeHealthHealthy, Count: 2, Max: 10, Median: 8, Min: 6},
+			{Health: repository.TreeHealthDiseased, Count: 1, Max: 4, Median: 4, Min: 4},
+			{Health: repository.TreeHealthDead, Count: 1, Max: 2, Median: 2, Min: 2},
+		},
+	}, TreeStats(trees))
+}
+
+// streamTrees returns a StreamTreesByEstateId visiting the trees
+func streamTrees(trees []repository.Tree) func(context.Context, repository.StreamTreesByEstateIdInput, func(repository.Tree) error) error {
+	return func(_ context.Context, _ repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) error {
+		for _, tree := range trees {
+			if err := visit(tree); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
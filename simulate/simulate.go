@@ -0,0 +1,150 @@
+// This file contains the generator of synthetic estates, for the load tests and the demos.
+//
+// An estate is planted with a pattern at a density, then every tree is removed at the missing rate as a dead
+// or harvested tree would be. The generator draws from a random source of its seed, so the same seed and
+// options always generate the same estates.
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	PatternSquare     = "square"
+	PatternTriangular = "triangular"
+	PatternRandom     = "random"
+)
+
+// The heights accepted by the api
+const (
+	minHeight = 1
+	maxHeight = 30
+)
+
+type Tree struct {
+	X      int
+	Y      int
+	Height int
+}
+
+type Estate struct {
+	Length int
+	Width  int
+	Trees  []Tree
+}
+
+type EstateOptions struct {
+	// Length and Width of the estate in plots
+	Length int
+	Width  int
+	// Pattern of the planting: square, triangular or random, default to square
+	Pattern string
+	// Density is the share of the plots planted, more than 0 and up to 1
+	Density float64
+	// HeightMean and HeightStdDev of the normal distribution of the tree heights, rounded and kept in 1 to 30
+	HeightMean   float64
+	HeightStdDev float64
+	// MissingRate is the share of the planted trees removed, 0 up to less than 1
+	MissingRate float64
+}
+
+func (o EstateOptions) validate() error {
+	switch {
+	case o.Length < 1 || o.Width < 1:
+		return errors.New("length and width must be at least 1")
+	case o.Pattern != PatternSquare && o.Pattern != PatternTriangular && o.Pattern != PatternRandom:
+		return fmt.Errorf("unknown pattern %q", o.Pattern)
+	case o.Density <= 0 || o.Density > 1:
+		return errors.New("density must be more than 0 and up to 1")
+	case o.HeightMean < minHeight || o.HeightMean > maxHeight:
+		return fmt.Errorf("height mean must be between %d and %d", minHeight, maxHeight)
+	case o.HeightStdDev < 0:
+		return errors.New("height standard deviation must not be negative")
+	case o.MissingRate < 0 || o.MissingRate >= 1:
+		return errors.New("missing rate must be 0 up to less than 1")
+	}
+	return nil
+}
+
+type Generator struct {
+	rand *rand.Rand
+}
+
+type NewGeneratorOptions struct {
+	Seed int64
+}
+
+func NewGenerator(opts NewGeneratorOptions) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(opts.Seed))}
+}
+
+// Estate generate an estate, its trees are ordered by y then x
+func (g *Generator) Estate(opts EstateOptions) (Estate, error) {
+	if opts.Pattern == "" {
+		opts.Pattern = PatternSquare
+	}
+	if err := opts.validate(); err != nil {
+		return Estate{}, err
+	}
+
+	estate := Estate{Length: opts.Length, Width: opts.Width}
+	plant := func(x, y int) {
+		if g.rand.Float64() < opts.MissingRate {
+			return
+		}
+		estate.Trees = append(estate.Trees, Tree{X: x, Y: y, Height: g.height(opts)})
+	}
+
+	switch opts.Pattern {
+	case PatternSquare:
+		// a tree every step plots, so one plot of step*step is planted
+		step := spacing(1 / math.Sqrt(opts.Density))
+		for y := 1; y <= opts.Width; y += step {
+			for x := 1; x <= opts.Length; x += step {
+				plant(x, y)
+			}
+		}
+	case PatternTriangular:
+		// the rows are closer than the trees of a row and every other row is shifted by half a step, so a tree
+		// is at the same distance of its six neighbours
+		step := spacing(math.Sqrt(2 / (math.Sqrt(3) * opts.Density)))
+		rowStep := spacing(float64(step) * math.Sqrt(3) / 2)
+		for row, y := 0, 1; y <= opts.Width; row, y = row+1, y+rowStep {
+			for x := 1 + row%2*(step/2); x <= opts.Length; x += step {
+				plant(x, y)
+			}
+		}
+	case PatternRandom:
+		for y := 1; y <= opts.Width; y++ {
+			for x := 1; x <= opts.Length; x++ {
+				if g.rand.Float64() < opts.Density {
+					plant(x, y)
+				}
+			}
+		}
+	}
+	return estate, nil
+}
+
+func (g *Generator) height(opts EstateOptions) int {
+	height := int(math.Round(opts.HeightMean + g.rand.NormFloat64()*opts.HeightStdDev))
+	if height < minHeight {
+		return minHeight
+	}
+	if height > maxHeight {
+		return maxHeight
+	}
+	return height
+}
+
+// spacing returns the distance in plots between two trees, at least the next plot
+func spacing(distance float64) int {
+	step := int(math.Round(distance))
+	if step < 1 {
+		return 1
+	}
+	return step
+}
@@ -0,0 +1,170 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGenerator_Estate(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      EstateOptions
+		wantTrees []Tree
+		wantErr   string
+	}{
+		{
+			name: "square",
+			opts: EstateOptions{Length: 5, Width: 3, Pattern: PatternSquare, Density: 0.25, HeightMean: 10},
+			wantTrees: []Tree{
+				{X: 1, Y: 1, Height: 10}, {X: 3, Y: 1, Height: 10}, {X: 5, Y: 1, Height: 10},
+				{X: 1, Y: 3, Height: 10}, {X: 3, Y: 3, Height: 10}, {X: 5, Y: 3, Height: 10},
+			},
+		},
+		{
+			name: "triangular shift every other row",
+			opts: EstateOptions{Length: 5, Width: 5, Pattern: PatternTriangular, Density: 0.25, HeightMean: 10},
+			wantTrees: []Tree{
+				{X: 1, Y: 1, Height: 10}, {X: 3, Y: 1, Height: 10}, {X: 5, Y: 1, Height: 10},
+				{X: 2, Y: 3, Height: 10}, {X: 4, Y: 3, Height: 10},
+				{X: 1, Y: 5, Height: 10}, {X: 3, Y: 5, Height: 10}, {X: 5, Y: 5, Height: 10},
+			},
+		},
+		{
+			name: "full random estate",
+			opts: EstateOptions{Length: 2, Width: 2, Pattern: PatternRandom, Density: 1, HeightMean: 30},
+			wantTrees: []Tree{
+				{X: 1, Y: 1, Height: 30}, {X: 2, Y: 1, Height: 30}, {X: 1, Y: 2, Height: 30}, {X: 2, Y: 2, Height: 30},
+			},
+		},
+		{
+			name:    "unknown pattern",
+			opts:    EstateOptions{Length: 5, Width: 5, Pattern: "hexagonal", Density: 0.5, HeightMean: 10},
+			wantErr: `unknown pattern "hexagonal"`,
+		},
+		{
+			name:    "invalid density",
+			opts:    EstateOptions{Length: 5, Width: 5, Density: 0, HeightMean: 10},
+			wantErr: "density must be more than 0 and up to 1",
+		},
+		{
+			name:    "invalid missing rate",
+			opts:    EstateOptions{Length: 5, Width: 5, Density: 0.5, HeightMean: 10, MissingRate: 1},
+			wantErr: "missing rate must be 0 up to less than 1",
+		},
+		{
+			name:    "invalid height",
+			opts:    EstateOptions{Length: 5, Width: 5, Density: 0.5, HeightMean: 31},
+			wantErr: "height mean must be between 1 and 30",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estate, err := NewGenerator(NewGeneratorOptions{Seed: 1}).Estate(tt.opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.opts.Length, estate.Length)
+			assert.Equal(t, tt.opts.Width, estate.Width)
+			assert.Equal(t, tt.wantTrees, estate.Trees)
+		})
+	}
+}
+
+func TestGenerator_Estate_Distribution(t *testing.T) {
+	opts := EstateOptions{
+		Length:       200,
+		Width:        100,
+		Pattern:      PatternRandom,
+		Density:      0.5,
+		HeightMean:   28,
+		HeightStdDev: 10,
+		MissingRate:  0.2,
+	}
+	estate, err := NewGenerator(NewGeneratorOptions{Seed: 42}).Estate(opts)
+	require.NoError(t, err)
+
+	// 20000 plots, half planted and a fifth of them missing
+	assert.InDelta(t, 8000, len(estate.Trees), 400)
+	for _, tree := range estate.Trees {
+		assert.True(t, tree.Height >= 1 && tree.Height <= 30, "height %d", tree.Height)
+	}
+
+	again, err := NewGenerator(NewGeneratorOptions{Seed: 42}).Estate(opts)
+	require.NoError(t, err)
+	assert.Equal(t, estate, again, "the same seed generates the same estate")
+
+	other, err := NewGenerator(NewGeneratorOptions{Seed: 43}).Estate(opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, estate, other)
+}
+
+func TestGenerator_Run(t *testing.T) {
+	opts := EstateOptions{Length: 3, Width: 1, Pattern: PatternSquare, Density: 1, HeightMean: 5}
+	writeErr := errors.New("connection refused")
+
+	tests := []struct {
+		name        string
+		mock        func(repo *repository.MockRepositoryInterface)
+		wantResults []Result
+		wantErr     string
+	}{
+		{
+			name: "success",
+			mock: func(repo *repository.MockRepositoryInterface) {
+				repo.EXPECT().CreateEstate(gomock.Any(), repository.Estate{OrganisationId: "org-1", Length: 3, Width: 1}).
+					Return(repository.Estate{Id: "e-1"}, nil)
+				repo.EXPECT().CreateEstate(gomock.Any(), repository.Estate{OrganisationId: "org-1", Length: 3, Width: 1}).
+					Return(repository.Estate{Id: "e-2"}, nil)
+				for _, estateId := range []string{"e-1", "e-2"} {
+					for x := 1; x <= 3; x++ {
+						repo.EXPECT().CreateTree(gomock.Any(), repository.Tree{EstateId: estateId, X: x, Y: 1, Height: 5}).
+							Return(repository.Tree{}, nil)
+					}
+				}
+			},
+			wantResults: []Result{
+				{EstateId: "e-1", Length: 3, Width: 1, Trees: 3},
+				{EstateId: "e-2", Length: 3, Width: 1, Trees: 3},
+			},
+		},
+		{
+			name: "stop at the first error",
+			mock: func(repo *repository.MockRepositoryInterface) {
+				repo.EXPECT().CreateEstate(gomock.Any(), gomock.Any()).Return(repository.Estate{Id: "e-1"}, nil)
+				repo.EXPECT().CreateTree(gomock.Any(), gomock.Any()).Return(repository.Tree{}, writeErr).MinTimes(1).MaxTimes(3)
+			},
+			wantResults: []Result{},
+			wantErr:     "create tree at",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := repository.NewMockRepositoryInterface(ctrl)
+			tt.mock(repo)
+
+			results, err := NewGenerator(NewGeneratorOptions{Seed: 1}).Run(context.Background(), RunOptions{
+				Writer:  NewRepositoryWriter(NewRepositoryWriterOptions{Repository: repo, OrganisationId: "org-1"}),
+				Estates: 2,
+				Estate:  opts,
+				Workers: 2,
+			})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, writeErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantResults, results)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package simulate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/SawitProRecruitment/UserService/client"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+// Writer saves the generated estates
+type Writer interface {
+	CreateEstate(ctx context.Context, estate Estate) (id string, err error)
+	CreateTree(ctx context.Context, estateId string, tree Tree) error
+}
+
+// RepositoryWriter saves the estates in the database of the repository, without the checks and the cache of the
+// api, for the load tests needing large estates quickly
+type RepositoryWriter struct {
+	repository     repository.RepositoryInterface
+	organisationId string
+}
+
+type NewRepositoryWriterOptions struct {
+	Repository repository.RepositoryInterface
+	// OrganisationId owning the estates
+	OrganisationId string
+}
+
+func NewRepositoryWriter(opts NewRepositoryWriterOptions) *RepositoryWriter {
+	return &RepositoryWriter{
+		repository:     opts.Repository,
+		organisationId: opts.OrganisationId,
+	}
+}
+
+func (w *RepositoryWriter) CreateEstate(ctx context.Context, estate Estate) (string, error) {
+	output, err := w.repository.CreateEstate(ctx, repository.Estate{
+		OrganisationId: w.organisationId,
+		Length:         estate.Length,
+		Width:          estate.Width,
+	})
+	return output.Id, err
+}
+
+func (w *RepositoryWriter) CreateTree(ctx context.Context, estateId string, tree Tree) error {
+	_, err := w.repository.CreateTree(ctx, repository.Tree{
+		EstateId: estateId,
+		X:        tree.X,
+		Y:        tree.Y,
+		Height:   tree.Height,
+	})
+	return err
+}
+
+// ApiWriter saves the estates through the http api, in the organisation of the credentials of its client
+type ApiWriter struct {
+	client *client.Client
+}
+
+type NewApiWriterOptions struct {
+	Client *client.Client
+}
+
+func NewApiWriter(opts NewApiWriterOptions) *ApiWriter {
+	return &ApiWriter{client: opts.Client}
+}
+
+func (w *ApiWriter) CreateEstate(ctx context.Context, estate Estate) (string, error) {
+	return w.client.CreateEstate(ctx, client.CreateEstateRequest{
+		Length: estate.Length,
+		Width:  estate.Width,
+	})
+}
+
+func (w *ApiWriter) CreateTree(ctx context.Context, estateId string, tree Tree) error {
+	_, err := w.client.CreateTree(ctx, estateId, client.CreateTreeRequest{
+		Height: tree.Height,
+		X:      tree.X,
+		Y:      tree.Y,
+	})
+	return err
+}
+
+type RunOptions struct {
+	Writer Writer
+	// Estates is the number of estates generated, default to 1
+	Estates int
+	Estate  EstateOptions
+	// Workers is the number of trees written at the same time, default to 1
+	Workers int
+}
+
+type Result struct {
+	EstateId string
+	Length   int
+	Width    int
+	Trees    int
+}
+
+// Run generate the estates and write them one after the other. The estates are generated before they are
+// written, so the writer does not change what a seed generates. The results of the estates written are
+// returned with the error which stopped the run.
+func (g *Generator) Run(ctx context.Context, opts RunOptions) ([]Result, error) {
+	count := opts.Estates
+	if count < 1 {
+		count = 1
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	estates := make([]Estate, count)
+	for i := range estates {
+		estate, err := g.Estate(opts.Estate)
+		if err != nil {
+			return nil, err
+		}
+		estates[i] = estate
+	}
+
+	results := make([]Result, 0, count)
+	for _, estate := range estates {
+		id, err := opts.Writer.CreateEstate(ctx, estate)
+		if err != nil {
+			return results, fmt.Errorf("create estate: %w", err)
+		}
+		if err := writeTrees(ctx, opts.Writer, id, estate.Trees, workers); err != nil {
+			return results, fmt.Errorf("estate %s: %w", id, err)
+		}
+		results = append(results, Result{
+			EstateId: id,
+			Length:   estate.Length,
+			Width:    estate.Width,
+			Trees:    len(estate.Trees),
+		})
+	}
+	return results, nil
+}
+
+// writeTrees write the trees with the workers and stops at the first error
+func writeTrees(ctx context.Context, w Writer, estateId string, trees []Tree, workers int) error {
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	queue := make(chan Tree)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tree := range queue {
+				if err := w.CreateTree(writeCtx, estateId, tree); err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("create tree at %d,%d: %w", tree.X, tree.Y, err)
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+send:
+	for _, tree := range trees {
+		select {
+		case queue <- tree:
+		case <-writeCtx.Done():
+			break send
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,246 @@
+// This file contains the live tracking of the drones during a patrol.
+//
+// The drones publish their positions, every position is annotated with the horizontal deviation from the planned
+// route and the progress along it, then sent to the dashboards watching the estate. An alert is sent when a drone
+// goes further from the route than the threshold, and when it is back on the route.
+package telemetry
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/flights"
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const (
+	TypePosition = "position"
+	TypeAlert    = "alert"
+
+	AlertOffRoute    = "off_route"
+	AlertBackOnRoute = "back_on_route"
+
+	// MaxDrones is the max number of drones publishing on a connection
+	MaxDrones = 64
+
+	defaultThreshold  = 20
+	defaultBufferSize = 64
+)
+
+var ErrTooManyDrones = errors.New("too many drones on the connection")
+
+// Position is the message published by a drone, x and y are plot coordinates as in the flight logs
+type Position struct {
+	DroneId   string     `json:"drone_id" validate:"required,max=64"`
+	Timestamp *time.Time `json:"timestamp"`
+	X         *float64   `json:"x" validate:"required,min=0,max=50001"`
+	Y         *float64   `json:"y" validate:"required,min=0,max=50001"`
+	Altitude  *float64   `json:"altitude" validate:"required,min=0"`
+	Battery   *float64   `json:"battery" validate:"omitempty,min=0,max=100"`
+}
+
+// Message is sent to the dashboards, a position annotated with the deviation in meters and the progress in
+// percent, or an alert about the position
+type Message struct {
+	Type      string    `json:"type"`
+	Alert     string    `json:"alert,omitempty"`
+	DroneId   string    `json:"drone_id"`
+	Timestamp time.Time `json:"timestamp"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	Altitude  float64   `json:"altitude"`
+	Battery   *float64  `json:"battery,omitempty"`
+	Deviation float64   `json:"deviation"`
+	Progress  float64   `json:"progress"`
+}
+
+type Hub struct {
+	mu         sync.Mutex
+	threshold  float64
+	bufferSize int
+	watchers   map[string]map[*Watcher]struct{}
+	closed     bool
+	done       chan struct{}
+	now        func() time.Time
+}
+
+type NewHubOptions struct {
+	// Threshold is the meters from the route beyond which a drone is off route, default to 20
+	Threshold float64
+	// BufferSize is the number of messages queued for a dashboard, a dashboard falling further behind is
+	// closed. Default to 64.
+	BufferSize int
+}
+
+func NewHub(opts NewHubOptions) *Hub {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = defaultThreshold
+	}
+	bufferSize := opts.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	return &Hub{
+		threshold:  threshold,
+		bufferSize: bufferSize,
+		watchers:   map[string]map[*Watcher]struct{}{},
+		done:       make(chan struct{}),
+		now:        time.Now,
+	}
+}
+
+// Watcher receives the messages of the drones of an estate on C until it is closed
+type Watcher struct {
+	C <-chan Message
+
+	c        chan Message
+	hub      *Hub
+	estateId string
+}
+
+// Watch listen to the messages of the drones of the estate
+func (h *Hub) Watch(estateId string) *Watcher {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan Message, h.bufferSize)
+	w := &Watcher{C: c, c: c, hub: h, estateId: estateId}
+	if h.closed {
+		close(c)
+		return w
+	}
+	if h.watchers[estateId] == nil {
+		h.watchers[estateId] = map[*Watcher]struct{}{}
+	}
+	h.watchers[estateId][w] = struct{}{}
+	return w
+}
+
+// Close stop the watcher, C is closed
+func (w *Watcher) Close() {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+	if _, ok := w.hub.watchers[w.estateId][w]; ok {
+		w.hub.remove(w)
+	}
+}
+
+// Close stop every watcher and close Done, the connections end so the server can shut down
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	close(h.done)
+	for _, watchers := range h.watchers {
+		for w := range watchers {
+			h.remove(w)
+		}
+	}
+}
+
+// Done is closed when the hub is closed
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Tracker annotates the positions published on a connection, it keeps which drones are off route to alert
+// only when it changes. A tracker is not safe for concurrent use.
+type Tracker struct {
+	hub      *Hub
+	estate   repository.Estate
+	offRoute map[string]bool
+}
+
+// Track returns a tracker of the positions of drones patrolling the estate
+func (h *Hub) Track(estate repository.Estate) *Tracker {
+	return &Tracker{hub: h, estate: estate, offRoute: map[string]bool{}}
+}
+
+// Publish annotate a validated position and send it to the dashboards watching the estate. The alert sent
+// with the position, if any, is returned.
+func (t *Tracker) Publish(position Position) (*Message, error) {
+	wasOffRoute, known := t.offRoute[position.DroneId]
+	if !known && len(t.offRoute) >= MaxDrones {
+		return nil, ErrTooManyDrones
+	}
+
+	timestamp := t.hub.now()
+	if position.Timestamp != nil {
+		timestamp = *position.Timestamp
+	}
+	deviation, progress := flights.Track(t.estate, *position.X, *position.Y)
+	message := Message{
+		Type:      TypePosition,
+		DroneId:   position.DroneId,
+		Timestamp: timestamp.UTC(),
+		X:         *position.X,
+		Y:         *position.Y,
+		Altitude:  *position.Altitude,
+		Battery:   position.Battery,
+		Deviation: roundHundredth(deviation),
+		Progress:  roundHundredth(progress),
+	}
+	messages := []Message{message}
+
+	var alert *Message
+	offRoute := deviation > t.hub.threshold
+	t.offRoute[position.DroneId] = offRoute
+	if offRoute != wasOffRoute {
+		a := message
+		a.Type, a.Alert = TypeAlert, AlertOffRoute
+		if !offRoute {
+			a.Alert = AlertBackOnRoute
+		}
+		alert = &a
+		messages = append(messages, a)
+	}
+
+	t.hub.send(t.estate.Id, messages)
+	return alert, nil
+}
+
+func (h *Hub) send(estateId string, messages []Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers[estateId] {
+		for _, message := range messages {
+			if !trySend(w.c, message) {
+				// too slow, the dashboard reconnects and gets the next positions
+				h.remove(w)
+				break
+			}
+		}
+	}
+}
+
+func trySend(c chan Message, message Message) bool {
+	select {
+	case c <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// remove a watcher and close it, h.mu must be held
+func (h *Hub) remove(w *Watcher) {
+	delete(h.watchers[w.estateId], w)
+	if len(h.watchers[w.estateId]) == 0 {
+		delete(h.watchers, w.estateId)
+	}
+	close(w.c)
+}
+
+// roundHundredth round to the centimeter or the hundredth of percent, the telemetry is not more accurate
+func roundHundredth(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,141 @@
+package telemetry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+
+func position(droneId string, x, y float64) Position {
+	altitude := 2.0
+	return Position{DroneId: droneId, X: &x, Y: &y, Altitude: &altitude}
+}
+
+func TestTracker_Publish(t *testing.T) {
+	h := NewHub(NewHubOptions{Threshold: 5})
+	h.now = func() time.Time { return now }
+	estate := repository.Estate{Id: "estate", Length: 5, Width: 3}
+	w := h.Watch(estate.Id)
+	defer w.Close()
+	other := h.Watch("other")
+	defer other.Close()
+	tracker := h.Track(estate)
+
+	testCases := []struct {
+		name             string
+		position         Position
+		expectedAlert    string
+		expectedMessages []Message
+	}{
+		{
+			name:     "ON_ROUTE",
+			position: position("drone", 3, 1),
+			expectedMessages: []Message{
+				{Type: TypePosition, DroneId: "drone", Timestamp: now, X: 3, Y: 1, Altitude: 2, Progress: 14.29},
+			},
+		},
+		{
+			name:          "OFF_ROUTE",
+			position:      position("drone", 3, 4),
+			expectedAlert: AlertOffRoute,
+			expectedMessages: []Message{
+				{Type: TypePosition, DroneId: "drone", Timestamp: now, X: 3, Y: 4, Altitude: 2, Deviation: 10, Progress: 85.71},
+				{Type: TypeAlert, Alert: AlertOffRoute, DroneId: "drone", Timestamp: now, X: 3, Y: 4, Altitude: 2, Deviation: 10, Progress: 85.71},
+			},
+		},
+		{
+			name:     "STILL_OFF_ROUTE",
+			position: position("drone", 3, 4.5),
+			expectedMessages: []Message{
+				{Type: TypePosition, DroneId: "drone", Timestamp: now, X: 3, Y: 4.5, Altitude: 2, Deviation: 15, Progress: 85.71},
+			},
+		},
+		{
+			name:          "BACK_ON_ROUTE",
+			position:      position("drone", 4, 3),
+			expectedAlert: AlertBackOnRoute,
+			expectedMessages: []Message{
+				{Type: TypePosition, DroneId: "drone", Timestamp: now, X: 4, Y: 3, Altitude: 2, Progress: 92.86},
+				{Type: TypeAlert, Alert: AlertBackOnRoute, DroneId: "drone", Timestamp: now, X: 4, Y: 3, Altitude: 2, Progress: 92.86},
+			},
+		},
+		{
+			name:          "ANOTHER_DRONE_OFF_ROUTE",
+			position:      position("other", 8, 1),
+			expectedAlert: AlertOffRoute,
+			expectedMessages: []Message{
+				{Type: TypePosition, DroneId: "other", Timestamp: now, X: 8, Y: 1, Altitude: 2, Deviation: 30, Progress: 28.57},
+				{Type: TypeAlert, Alert: AlertOffRoute, DroneId: "other", Timestamp: now, X: 8, Y: 1, Altitude: 2, Deviation: 30, Progress: 28.57},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			alert, err := tracker.Publish(tc.position)
+			require.NoError(t, err)
+			if tc.expectedAlert == "" {
+				assert.Nil(t, alert)
+			} else {
+				require.NotNil(t, alert)
+				assert.Equal(t, tc.expectedAlert, alert.Alert)
+			}
+			messages := []Message{}
+			for len(w.C) > 0 {
+				messages = append(messages, <-w.C)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+			assert.Len(t, other.C, 0)
+		})
+	}
+}
+
+func TestTracker_Publish_TooManyDrones(t *testing.T) {
+	h := NewHub(NewHubOptions{})
+	tracker := h.Track(repository.Estate{Id: "estate", Length: 5, Width: 3})
+	for i := 0; i < MaxDrones; i++ {
+		_, err := tracker.Publish(position(fmt.Sprint(i), 1, 1))
+		require.NoError(t, err)
+	}
+	_, err := tracker.Publish(position("one more", 1, 1))
+	assert.ErrorIs(t, err, ErrTooManyDrones)
+	_, err = tracker.Publish(position("0", 2, 1))
+	assert.NoError(t, err)
+}
+
+func TestHub_SlowWatcher(t *testing.T) {
+	h := NewHub(NewHubOptions{BufferSize: 1})
+	w := h.Watch("estate")
+	tracker := h.Track(repository.Estate{Id: "estate", Length: 5, Width: 3})
+	_, err := tracker.Publish(position("drone", 1, 1))
+	require.NoError(t, err)
+	_, err = tracker.Publish(position("drone", 2, 1))
+	require.NoError(t, err)
+
+	// the first position is buffered, then the watcher is closed
+	_, ok := <-w.C
+	assert.True(t, ok)
+	_, ok = <-w.C
+	assert.False(t, ok)
+	w.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	h := NewHub(NewHubOptions{})
+	w := h.Watch("estate")
+	h.Close()
+	_, ok := <-w.C
+	assert.False(t, ok)
+	<-h.Done()
+	w.Close()
+
+	_, ok = <-h.Watch("estate").C
+	assert.False(t, ok)
+	h.Close()
+}
@@ -0,0 +1,12 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc/stats"
+)
+
+// GrpcServerHandler start a server span for each gRPC call, continuing the trace from the incoming
+// traceparent metadata when present
+func GrpcServerHandler() stats.Handler {
+	return otelgrpc.NewServerHandler()
+}
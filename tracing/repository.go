@@ -0,0 +1,302 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repository is a RepositoryInterface decorator starting a client span for each method
+type Repository struct {
+	next repository.RepositoryInterface
+}
+
+// WrapRepository wrap the repository so each call is traced
+func WrapRepository(next repository.RepositoryInterface) *Repository {
+	return &Repository{next: next}
+}
+
+func (r *Repository) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "Repository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+}
+
+func end(span trace.Span, err error) {
+	// no rows is an expected outcome, not a failed query
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (r *Repository) CreateEstate(ctx context.Context, input repository.Estate) (output repository.Estate, err error) {
+	ctx, span := r.start(ctx, "CreateEstate")
+	defer func() { end(span, err) }()
+	return r.next.CreateEstate(ctx, input)
+}
+
+func (r *Repository) GetEstateById(ctx context.Context, input repository.GetEstateByIdInput) (output repository.Estate, err error) {
+	ctx, span := r.start(ctx, "GetEstateById")
+	defer func() { end(span, err) }()
+	return r.next.GetEstateById(ctx, input)
+}
+
+func (r *Repository) CreateTree(ctx context.Context, input repository.Tree) (output repository.Tree, err error) {
+	ctx, span := r.start(ctx, "CreateTree")
+	defer func() { end(span, err) }()
+	return r.next.CreateTree(ctx, input)
+}
+
+func (r *Repository) GetTreeByPlot(ctx context.Context, input repository.GetTreeByPlot) (output repository.Tree, err error) {
+	ctx, span := r.start(ctx, "GetTreeByPlot")
+	defer func() { end(span, err) }()
+	return r.next.GetTreeByPlot(ctx, input)
+}
+
+func (r *Repository) ListTreesByEstateId(ctx context.Context, input repository.ListTreesByEstateIdInput) (output []repository.Tree, err error) {
+	ctx, span := r.start(ctx, "ListTreesByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.ListTreesByEstateId(ctx, input)
+}
+
+func (r *Repository) StreamTreesByEstateId(ctx context.Context, input repository.StreamTreesByEstateIdInput, visit func(repository.Tree) error) (err error) {
+	ctx, span := r.start(ctx, "StreamTreesByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.StreamTreesByEstateId(ctx, input, visit)
+}
+
+func (r *Repository) GetApiKeyByHash(ctx context.Context, input repository.GetApiKeyByHashInput) (output repository.ApiKey, err error) {
+	ctx, span := r.start(ctx, "GetApiKeyByHash")
+	defer func() { end(span, err) }()
+	return r.next.GetApiKeyByHash(ctx, input)
+}
+
+func (r *Repository) GetMembership(ctx context.Context, input repository.GetMembershipInput) (output repository.Membership, err error) {
+	ctx, span := r.start(ctx, "GetMembership")
+	defer func() { end(span, err) }()
+	return r.next.GetMembership(ctx, input)
+}
+
+func (r *Repository) CreateAuditLog(ctx context.Context, input repository.AuditLog) (output repository.AuditLog, err error) {
+	ctx, span := r.start(ctx, "CreateAuditLog")
+	defer func() { end(span, err) }()
+	return r.next.CreateAuditLog(ctx, input)
+}
+
+func (r *Repository) CreatePlanJob(ctx context.Context, input repository.PlanJob) (output repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "CreatePlanJob")
+	defer func() { end(span, err) }()
+	return r.next.CreatePlanJob(ctx, input)
+}
+
+func (r *Repository) GetPlanJobById(ctx context.Context, input repository.GetPlanJobByIdInput) (output repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "GetPlanJobById")
+	defer func() { end(span, err) }()
+	return r.next.GetPlanJobById(ctx, input)
+}
+
+func (r *Repository) ListUnfinishedPlanJobs(ctx context.Context, input repository.ListUnfinishedPlanJobsInput) (output []repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "ListUnfinishedPlanJobs")
+	defer func() { end(span, err) }()
+	return r.next.ListUnfinishedPlanJobs(ctx, input)
+}
+
+func (r *Repository) StartPlanJob(ctx context.Context, input repository.StartPlanJobInput) (output repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "StartPlanJob")
+	defer func() { end(span, err) }()
+	return r.next.StartPlanJob(ctx, input)
+}
+
+func (r *Repository) UpdatePlanJobProgress(ctx context.Context, input repository.UpdatePlanJobProgressInput) (output repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "UpdatePlanJobProgress")
+	defer func() { end(span, err) }()
+	return r.next.UpdatePlanJobProgress(ctx, input)
+}
+
+func (r *Repository) FinishPlanJob(ctx context.Context, input repository.FinishPlanJobInput) (output repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "FinishPlanJob")
+	defer func() { end(span, err) }()
+	return r.next.FinishPlanJob(ctx, input)
+}
+
+func (r *Repository) CancelPlanJob(ctx context.Context, input repository.CancelPlanJobInput) (output repository.PlanJob, err error) {
+	ctx, span := r.start(ctx, "CancelPlanJob")
+	defer func() { end(span, err) }()
+	return r.next.CancelPlanJob(ctx, input)
+}
+
+func (r *Repository) CreateFlight(ctx context.Context, input repository.Flight) (output repository.Flight, err error) {
+	ctx, span := r.start(ctx, "CreateFlight")
+	defer func() { end(span, err) }()
+	return r.next.CreateFlight(ctx, input)
+}
+
+func (r *Repository) GetFlightById(ctx context.Context, input repository.GetFlightByIdInput) (output repository.Flight, err error) {
+	ctx, span := r.start(ctx, "GetFlightById")
+	defer func() { end(span, err) }()
+	return r.next.GetFlightById(ctx, input)
+}
+
+func (r *Repository) ListFlightPointsByFlightId(ctx context.Context, input repository.ListFlightPointsByFlightIdInput) (output []repository.FlightPoint, err error) {
+	ctx, span := r.start(ctx, "ListFlightPointsByFlightId")
+	defer func() { end(span, err) }()
+	return r.next.ListFlightPointsByFlightId(ctx, input)
+}
+
+func (r *Repository) CreateMissionSchedule(ctx context.Context, input repository.MissionSchedule) (output repository.MissionSchedule, err error) {
+	ctx, span := r.start(ctx, "CreateMissionSchedule")
+	defer func() { end(span, err) }()
+	return r.next.CreateMissionSchedule(ctx, input)
+}
+
+func (r *Repository) GetMissionScheduleById(ctx context.Context, input repository.GetMissionScheduleByIdInput) (output repository.MissionSchedule, err error) {
+	ctx, span := r.start(ctx, "GetMissionScheduleById")
+	defer func() { end(span, err) }()
+	return r.next.GetMissionScheduleById(ctx, input)
+}
+
+func (r *Repository) ListMissionSchedulesByEstateId(ctx context.Context, input repository.ListMissionSchedulesByEstateIdInput) (output []repository.MissionSchedule, err error) {
+	ctx, span := r.start(ctx, "ListMissionSchedulesByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.ListMissionSchedulesByEstateId(ctx, input)
+}
+
+func (r *Repository) ListActiveMissionSchedules(ctx context.Context, input repository.ListActiveMissionSchedulesInput) (output []repository.MissionSchedule, err error) {
+	ctx, span := r.start(ctx, "ListActiveMissionSchedules")
+	defer func() { end(span, err) }()
+	return r.next.ListActiveMissionSchedules(ctx, input)
+}
+
+func (r *Repository) DeactivateMissionSchedule(ctx context.Context, input repository.DeactivateMissionScheduleInput) (output repository.MissionSchedule, err error) {
+	ctx, span := r.start(ctx, "DeactivateMissionSchedule")
+	defer func() { end(span, err) }()
+	return r.next.DeactivateMissionSchedule(ctx, input)
+}
+
+func (r *Repository) UpdateMissionScheduleMaterialisedUntil(ctx context.Context, input repository.UpdateMissionScheduleMaterialisedUntilInput) (output repository.MissionSchedule, err error) {
+	ctx, span := r.start(ctx, "UpdateMissionScheduleMaterialisedUntil")
+	defer func() { end(span, err) }()
+	return r.next.UpdateMissionScheduleMaterialisedUntil(ctx, input)
+}
+
+func (r *Repository) CreateMission(ctx context.Context, input repository.Mission) (output repository.Mission, err error) {
+	ctx, span := r.start(ctx, "CreateMission")
+	defer func() { end(span, err) }()
+	return r.next.CreateMission(ctx, input)
+}
+
+func (r *Repository) ListMissionsByEstateId(ctx context.Context, input repository.ListMissionsByEstateIdInput) (output []repository.Mission, err error) {
+	ctx, span := r.start(ctx, "ListMissionsByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.ListMissionsByEstateId(ctx, input)
+}
+
+func (r *Repository) ListDueMissions(ctx context.Context, input repository.ListDueMissionsInput) (output []repository.Mission, err error) {
+	ctx, span := r.start(ctx, "ListDueMissions")
+	defer func() { end(span, err) }()
+	return r.next.ListDueMissions(ctx, input)
+}
+
+func (r *Repository) DispatchMission(ctx context.Context, input repository.DispatchMissionInput) (output repository.Mission, err error) {
+	ctx, span := r.start(ctx, "DispatchMission")
+	defer func() { end(span, err) }()
+	return r.next.DispatchMission(ctx, input)
+}
+
+func (r *Repository) CreateWebhookSubscription(ctx context.Context, input repository.WebhookSubscription) (output repository.WebhookSubscription, err error) {
+	ctx, span := r.start(ctx, "CreateWebhookSubscription")
+	defer func() { end(span, err) }()
+	return r.next.CreateWebhookSubscription(ctx, input)
+}
+
+func (r *Repository) GetWebhookSubscriptionById(ctx context.Context, input repository.GetWebhookSubscriptionByIdInput) (output repository.WebhookSubscription, err error) {
+	ctx, span := r.start(ctx, "GetWebhookSubscriptionById")
+	defer func() { end(span, err) }()
+	return r.next.GetWebhookSubscriptionById(ctx, input)
+}
+
+func (r *Repository) ListWebhookSubscriptionsByOrganisationId(ctx context.Context, input repository.ListWebhookSubscriptionsByOrganisationIdInput) (output []repository.WebhookSubscription, err error) {
+	ctx, span := r.start(ctx, "ListWebhookSubscriptionsByOrganisationId")
+	defer func() { end(span, err) }()
+	return r.next.ListWebhookSubscriptionsByOrganisationId(ctx, input)
+}
+
+func (r *Repository) DeactivateWebhookSubscription(ctx context.Context, input repository.DeactivateWebhookSubscriptionInput) (output repository.WebhookSubscription, err error) {
+	ctx, span := r.start(ctx, "DeactivateWebhookSubscription")
+	defer func() { end(span, err) }()
+	return r.next.DeactivateWebhookSubscription(ctx, input)
+}
+
+func (r *Repository) FanOutOutboxEvents(ctx context.Context, input repository.FanOutOutboxEventsInput) (output int, err error) {
+	ctx, span := r.start(ctx, "FanOutOutboxEvents")
+	defer func() { end(span, err) }()
+	return r.next.FanOutOutboxEvents(ctx, input)
+}
+
+func (r *Repository) ClaimDueWebhookDeliveries(ctx context.Context, input repository.ClaimDueWebhookDeliveriesInput) (output []repository.WebhookDelivery, err error) {
+	ctx, span := r.start(ctx, "ClaimDueWebhookDeliveries")
+	defer func() { end(span, err) }()
+	return r.next.ClaimDueWebhookDeliveries(ctx, input)
+}
+
+func (r *Repository) UpdateWebhookDelivery(ctx context.Context, input repository.UpdateWebhookDeliveryInput) (output repository.WebhookDelivery, err error) {
+	ctx, span := r.start(ctx, "UpdateWebhookDelivery")
+	defer func() { end(span, err) }()
+	return r.next.UpdateWebhookDelivery(ctx, input)
+}
+
+func (r *Repository) ReplayWebhookDeliveries(ctx context.Context, input repository.ReplayWebhookDeliveriesInput) (output int, err error) {
+	ctx, span := r.start(ctx, "ReplayWebhookDeliveries")
+	defer func() { end(span, err) }()
+	return r.next.ReplayWebhookDeliveries(ctx, input)
+}
+
+func (r *Repository) ListEstatesByOrganisationId(ctx context.Context, input repository.ListEstatesByOrganisationIdInput) (output []repository.Estate, err error) {
+	ctx, span := r.start(ctx, "ListEstatesByOrganisationId")
+	defer func() { end(span, err) }()
+	return r.next.ListEstatesByOrganisationId(ctx, input)
+}
+
+func (r *Repository) ImportEstate(ctx context.Context, input repository.ImportEstateInput) (output repository.Estate, err error) {
+	ctx, span := r.start(ctx, "ImportEstate")
+	defer func() { end(span, err) }()
+	return r.next.ImportEstate(ctx, input)
+}
+
+func (r *Repository) CreateFindings(ctx context.Context, input repository.CreateFindingsInput) (output []repository.Finding, err error) {
+	ctx, span := r.start(ctx, "CreateFindings")
+	defer func() { end(span, err) }()
+	return r.next.CreateFindings(ctx, input)
+}
+
+func (r *Repository) ListFindingsByEstateId(ctx context.Context, input repository.ListFindingsByEstateIdInput) (output []repository.Finding, err error) {
+	ctx, span := r.start(ctx, "ListFindingsByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.ListFindingsByEstateId(ctx, input)
+}
+
+func (r *Repository) CountFindingsByEstateId(ctx context.Context, input repository.CountFindingsByEstateIdInput) (output []repository.FindingCount, err error) {
+	ctx, span := r.start(ctx, "CountFindingsByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.CountFindingsByEstateId(ctx, input)
+}
+
+func (r *Repository) ResolveFinding(ctx context.Context, input repository.ResolveFindingInput) (output repository.Finding, err error) {
+	ctx, span := r.start(ctx, "ResolveFinding")
+	defer func() { end(span, err) }()
+	return r.next.ResolveFinding(ctx, input)
+}
+
+func (r *Repository) ListFlaggedPlotsByEstateId(ctx context.Context, input repository.ListFlaggedPlotsByEstateIdInput) (output []repository.FlaggedPlot, err error) {
+	ctx, span := r.start(ctx, "ListFlaggedPlotsByEstateId")
+	defer func() { end(span, err) }()
+	return r.next.ListFlaggedPlotsByEstateId(ctx, input)
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/mock/gomock"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestMiddleware_PropagateTraceContext(t *testing.T) {
+	recorder := setupRecorder(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	repo := WrapRepository(mockRepository)
+	mockRepository.EXPECT().GetEstateById(gomock.Any(), gomock.Any()).Return(repository.Estate{}, errors.New("connection refused"))
+
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/estate/:id/stats", func(c echo.Context) error {
+		_, err := repo.GetEstateById(c.Request().Context(), repository.GetEstateByIdInput{Id: c.Param("id")})
+		if err != nil {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/estate/1/stats", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	repoSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /estate/:id/stats", serverSpan.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, serverSpan.Status().Code)
+
+	assert.Equal(t, "Repository.GetEstateById", repoSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), repoSpan.Parent().SpanID())
+	assert.Equal(t, codes.Error, repoSpan.Status().Code)
+}
+
+func TestNewTracerProvider_UnknownExporter(t *testing.T) {
+	_, err := NewTracerProvider(context.Background(), NewTracerProviderOptions{Exporter: "jaeger"})
+	assert.EqualError(t, err, `unknown trace exporter "jaeger"`)
+}
@@ -0,0 +1,83 @@
+// This file contains the guard keeping the webhooks out of the internal network.
+//
+// A tenant chooses the URL of its webhooks, without the guard the server would post signed payloads to the
+// loopback, the private network or the cloud metadata endpoint on its behalf. The URL is checked when the
+// webhook is created, and the address is checked again when a delivery connects, after the DNS resolution,
+// so a name resolving to an internal address later on is rejected too.
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidUrl       = errors.New("webhook url must be an https url of a public host")
+	ErrForbiddenAddress = errors.New("webhook address is not public")
+)
+
+// ValidateUrl returns ErrInvalidUrl unless the url is an https url whose host is a name or a public ip
+func ValidateUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ErrInvalidUrl
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidUrl
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return ErrInvalidUrl
+	}
+	return nil
+}
+
+// publicIP returns false for the loopback, private, link-local, multicast and unspecified addresses
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// dialControl reject the connection to an address which is not public, it runs after the DNS resolution
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// newClient returns the default client of the dispatcher, it does not follow redirects and only connects to
+// public addresses
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   defaultTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would make the guard check the address of the proxy instead of the webhook
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   defaultTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,269 @@
+// This file contains the delivery worker of the webhooks.
+//
+// The events are written to the outbox in the transaction of the change they describe. The worker fans the
+// events out to a delivery per subscription, then posts the deliveries signed with the secret of the
+// subscription. A failed delivery is retried with an exponential backoff and is dead after the last attempt,
+// until it is replayed.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	defaultInterval    = 5 * time.Second
+	defaultMaxAttempts = 8
+	defaultWorkers     = 4
+	defaultTimeout     = 10 * time.Second
+	// firstBackoff is doubled on every failed attempt up to maxBackoff
+	firstBackoff = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	// batchSize bound the events fanned out and the deliveries sent on a tick
+	batchSize = 100
+	// maxResponseSize is read from a response so the connection can be reused
+	maxResponseSize = 64 << 10
+)
+
+// Sign returns the value of the signature header of a payload, the hex HMAC-SHA256 of the payload keyed with
+// the secret of the subscription
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Event is the payload posted to the subscriptions
+type Event struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Dispatcher struct {
+	repository  repository.RepositoryInterface
+	logger      *slog.Logger
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	workers     int
+	now         func() time.Time
+	wg          sync.WaitGroup
+}
+
+type NewDispatcherOptions struct {
+	Repository repository.RepositoryInterface
+	Logger     *slog.Logger
+	// Client default to a client with a 10 seconds timeout which does not follow redirects and only connects
+	// to public addresses
+	Client *http.Client
+	// Interval is the time between two runs of the worker, default to 5 seconds
+	Interval time.Duration
+	// MaxAttempts is the number of attempts before a delivery is dead, default to 8
+	MaxAttempts int
+	// Workers is the number of deliveries sent at the same time, default to 4
+	Workers int
+}
+
+func NewDispatcher(opts NewDispatcherOptions) *Dispatcher {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	client := opts.Client
+	if client == nil {
+		client = newClient()
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	return &Dispatcher{
+		repository:  opts.Repository,
+		logger:      logger,
+		client:      client,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		workers:     workers,
+		now:         time.Now,
+	}
+}
+
+// Start run the worker every interval until ctx is done
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			if err := d.Tick(ctx); err != nil && ctx.Err() == nil {
+				d.logger.ErrorContext(ctx, "webhook dispatcher failed", slog.String("error", err.Error()))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait block until the worker stopped
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Tick fan the outbox events out and send the due deliveries
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	if _, err := d.repository.FanOutOutboxEvents(ctx, repository.FanOutOutboxEventsInput{Limit: batchSize}); err != nil {
+		return err
+	}
+
+	deliveries, err := d.repository.ClaimDueWebhookDeliveries(ctx, repository.ClaimDueWebhookDeliveriesInput{
+		Now:   d.now().UTC(),
+		Limit: batchSize,
+		// long enough to send every claimed delivery
+		Lease: d.client.Timeout*batchSize/time.Duration(d.workers) + time.Minute,
+	})
+	if err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, d.workers)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery repository.WebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := d.deliver(ctx, delivery); err != nil {
+				d.logger.ErrorContext(ctx, "failed to store webhook delivery",
+					slog.String("delivery_id", delivery.Id),
+					slog.String("error", err.Error()),
+				)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliver post the delivery and store the outcome, the returned error is about storing the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery repository.WebhookDelivery) error {
+	input := repository.UpdateWebhookDeliveryInput{
+		Id:     delivery.Id,
+		Status: repository.WebhookDeliveryStatusDelivered,
+	}
+
+	statusCode, err := d.post(ctx, delivery)
+	if statusCode != 0 {
+		input.LastStatusCode = &statusCode
+	}
+	now := d.now().UTC()
+	input.NextAttemptAt = now
+	if err != nil {
+		if ctx.Err() != nil {
+			// stopped, the lease ends and the delivery is sent again
+			return nil
+		}
+		message := err.Error()
+		input.LastError = &message
+		attempts := delivery.Attempts + 1
+		if attempts >= d.maxAttempts {
+			input.Status = repository.WebhookDeliveryStatusDead
+			d.logger.WarnContext(ctx, "webhook delivery is dead",
+				slog.String("delivery_id", delivery.Id),
+				slog.String("subscription_id", delivery.SubscriptionId),
+				slog.String("error", message),
+			)
+		} else {
+			input.Status = repository.WebhookDeliveryStatusPending
+			input.NextAttemptAt = now.Add(backoff(attempts))
+		}
+	}
+
+	_, err = d.repository.UpdateWebhookDelivery(ctx, input)
+	// no rows when another worker stored the outcome first, once the lease ended
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery repository.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(Event{
+		Id:        delivery.EventId,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventCreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+	// the subscriptions created before https was required are not sent in clear
+	if u, err := url.Parse(delivery.Url); err != nil || u.Scheme != "https" {
+		return 0, ErrInvalidUrl
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, body))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.Id)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt following the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	delay := firstBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
@@ -0,0 +1,249 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SawitProRecruitment/UserService/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var now = time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func strPtr(v string) *string {
+	return &v
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=6146142a2ce0159e84c0767881e4ec80bc397da62526e7d19f70795eb79460c0", Sign("secret", []byte(`{"id":"1"}`)))
+	assert.NotEqual(t, Sign("secret", []byte(`{"id":"1"}`)), Sign("other", []byte(`{"id":"1"}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 4*time.Minute, backoff(4))
+	assert.Equal(t, maxBackoff, backoff(20))
+}
+
+func TestDispatcher_Tick(t *testing.T) {
+	const secret = "0123456789abcdef"
+	eventId := uuid.New().String()
+	subscriptionId := uuid.New().String()
+	payload := `{"id":"tree","estate_id":"estate","x":1,"y":1,"height":5}`
+
+	testCases := []struct {
+		name          string
+		status        int
+		attempts      int
+		expectedInput func(deliveryId string) repository.UpdateWebhookDeliveryInput
+	}{
+		{
+			name:   "DELIVERED",
+			status: http.StatusNoContent,
+			expectedInput: func(deliveryId string) repository.UpdateWebhookDeliveryInput {
+				return repository.UpdateWebhookDeliveryInput{
+					Id:             deliveryId,
+					Status:         repository.WebhookDeliveryStatusDelivered,
+					LastStatusCode: intPtr(http.StatusNoContent),
+					NextAttemptAt:  now,
+				}
+			},
+		},
+		{
+			name:     "RETRIED_WITH_BACKOFF",
+			status:   http.StatusInternalServerError,
+			attempts: 2,
+			expectedInput: func(deliveryId string) repository.UpdateWebhookDeliveryInput {
+				return repository.UpdateWebhookDeliveryInput{
+					Id:             deliveryId,
+					Status:         repository.WebhookDeliveryStatusPending,
+					LastStatusCode: intPtr(http.StatusInternalServerError),
+					LastError:      strPtr("unexpected status 500"),
+					NextAttemptAt:  now.Add(2 * time.Minute),
+				}
+			},
+		},
+		{
+			name:     "DEAD_AFTER_THE_LAST_ATTEMPT",
+			status:   http.StatusGone,
+			attempts: defaultMaxAttempts - 1,
+			expectedInput: func(deliveryId string) repository.UpdateWebhookDeliveryInput {
+				return repository.UpdateWebhookDeliveryInput{
+					Id:             deliveryId,
+					Status:         repository.WebhookDeliveryStatusDead,
+					LastStatusCode: intPtr(http.StatusGone),
+					LastError:      strPtr("unexpected status 410"),
+					NextAttemptAt:  now,
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deliveryId := uuid.New().String()
+			received := make(chan *http.Request, 1)
+			var body []byte
+			receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				received <- r
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			mockRepository.EXPECT().FanOutOutboxEvents(gomock.Any(), repository.FanOutOutboxEventsInput{Limit: batchSize}).Return(1, nil)
+			mockRepository.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).Return([]repository.WebhookDelivery{{
+				Id:             deliveryId,
+				SubscriptionId: subscriptionId,
+				EventId:        eventId,
+				Status:         repository.WebhookDeliveryStatusPending,
+				Attempts:       tc.attempts,
+				Url:            receiver.URL,
+				Secret:         secret,
+				EventType:      repository.EventTreeCreated,
+				Payload:        []byte(payload),
+				EventCreatedAt: now,
+			}}, nil)
+			mockRepository.EXPECT().UpdateWebhookDelivery(gomock.Any(), tc.expectedInput(deliveryId)).Return(repository.WebhookDelivery{}, nil)
+
+			// the client of the test server trusts its certificate and connects to the loopback
+			d := NewDispatcher(NewDispatcherOptions{Repository: mockRepository, Client: receiver.Client()})
+			d.now = func() time.Time { return now }
+			require.NoError(t, d.Tick(context.Background()))
+
+			r := <-received
+			expectedBody := `{"id":"` + eventId + `","type":"tree.created","created_at":"2024-01-01T06:00:00Z","data":` + payload + `}`
+			assert.Equal(t, expectedBody, string(body))
+			assert.Equal(t, Sign(secret, []byte(expectedBody)), r.Header.Get(SignatureHeader))
+			assert.Equal(t, repository.EventTreeCreated, r.Header.Get(EventHeader))
+			assert.Equal(t, deliveryId, r.Header.Get(DeliveryHeader))
+		})
+	}
+}
+
+func TestDispatcher_Tick_Unreachable(t *testing.T) {
+	receiver := httptest.NewTLSServer(http.NotFoundHandler())
+	url := receiver.URL
+	client := receiver.Client()
+	receiver.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := repository.NewMockRepositoryInterface(ctrl)
+	deliveryId := uuid.New().String()
+	mockRepository.EXPECT().FanOutOutboxEvents(gomock.Any(), gomock.Any()).Return(0, nil)
+	mockRepository.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).Return([]repository.WebhookDelivery{{
+		Id:        deliveryId,
+		Url:       url,
+		Secret:    "0123456789abcdef",
+		EventType: repository.EventEstateCreated,
+		Payload:   []byte(`{}`),
+	}}, nil)
+	mockRepository.EXPECT().UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ interface{}, input repository.UpdateWebhookDeliveryInput) (repository.WebhookDelivery, error) {
+			assert.Equal(t, repository.WebhookDeliveryStatusPending, input.Status)
+			assert.Nil(t, input.LastStatusCode)
+			assert.NotNil(t, input.LastError)
+			assert.Equal(t, now.Add(firstBackoff), input.NextAttemptAt)
+			return repository.WebhookDelivery{}, sql.ErrNoRows
+		})
+
+	d := NewDispatcher(NewDispatcherOptions{Repository: mockRepository, Client: client})
+	d.now = func() time.Time { return now }
+	assert.NoError(t, d.Tick(context.Background()))
+}
+
+func TestDispatcher_Tick_NotPublic(t *testing.T) {
+	testCases := []struct {
+		name          string
+		url           string
+		expectedError string
+	}{
+		{
+			name:          "LOOPBACK",
+			url:           "https://127.0.0.1:1/hooks",
+			expectedError: ErrForbiddenAddress.Error(),
+		},
+		{
+			name:          "NAME_OF_THE_LOOPBACK",
+			url:           "https://localhost:1/hooks",
+			expectedError: ErrForbiddenAddress.Error(),
+		},
+		{
+			name:          "PLAIN_HTTP",
+			url:           "http://example.com/hooks",
+			expectedError: ErrInvalidUrl.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockRepository := repository.NewMockRepositoryInterface(ctrl)
+			mockRepository.EXPECT().FanOutOutboxEvents(gomock.Any(), gomock.Any()).Return(0, nil)
+			mockRepository.EXPECT().ClaimDueWebhookDeliveries(gomock.Any(), gomock.Any()).Return([]repository.WebhookDelivery{{
+				Id:        uuid.New().String(),
+				Url:       tc.url,
+				Secret:    "0123456789abcdef",
+				EventType: repository.EventEstateCreated,
+				Payload:   []byte(`{}`),
+			}}, nil)
+			mockRepository.EXPECT().UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ interface{}, input repository.UpdateWebhookDeliveryInput) (repository.WebhookDelivery, error) {
+					assert.Equal(t, repository.WebhookDeliveryStatusPending, input.Status)
+					require.NotNil(t, input.LastError)
+					assert.Contains(t, *input.LastError, tc.expectedError)
+					return repository.WebhookDelivery{}, nil
+				})
+
+			d := NewDispatcher(NewDispatcherOptions{Repository: mockRepository})
+			d.now = func() time.Time { return now }
+			assert.NoError(t, d.Tick(context.Background()))
+		})
+	}
+}
+
+func TestValidateUrl(t *testing.T) {
+	for url, valid := range map[string]bool{
+		"https://example.com/hooks":          true,
+		"https://203.0.113.10:8443/hooks":    true,
+		"http://example.com/hooks":           false,
+		"https://localhost/hooks":            false,
+		"https://api.localhost./hooks":       false,
+		"https://127.0.0.1/hooks":            false,
+		"https://10.1.2.3/hooks":             false,
+		"https://169.254.169.254/latest":     false,
+		"https://0.0.0.0/hooks":              false,
+		"https://[::1]/hooks":                false,
+		"https://[fd00::1]/hooks":            false,
+		"https://[::ffff:192.168.1.1]/hooks": false,
+		"https:///hooks":                     false,
+	} {
+		t.Run(url, func(t *testing.T) {
+			err := ValidateUrl(url)
+			if valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidUrl)
+			}
+		})
+	}
+}